@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+)
+
+// contextUserKey key to store the authenticated user in the echo.Context
+const contextUserKey = "auth_user"
+
+// SetUser store the authenticated user in the request context
+func SetUser(c echo.Context, user models.User) {
+	c.Set(contextUserKey, user)
+}
+
+// CurrentUser return the authenticated user of the request
+func CurrentUser(c echo.Context) (models.User, bool) {
+	user, ok := c.Get(contextUserKey).(models.User)
+	return user, ok
+}
+
+// contextMemberKey key to store the project membership of the authenticated user
+const contextMemberKey = "auth_project_member"
+
+// SetProjectMember store the project membership of the authenticated user
+func SetProjectMember(c echo.Context, member models.ProjectUser) {
+	c.Set(contextMemberKey, member)
+}
+
+// CurrentProjectMember return the project membership of the authenticated user
+func CurrentProjectMember(c echo.Context) (models.ProjectUser, bool) {
+	member, ok := c.Get(contextMemberKey).(models.ProjectUser)
+	return member, ok
+}
@@ -0,0 +1,87 @@
+package auth
+
+import "github.com/masihur1989/expense-tracker-api/internal/models"
+
+// Permission an action a role can be granted
+type Permission string
+
+// all the permissions checked by the routes & handlers
+const (
+	PermUsersRead       Permission = "users:read"
+	PermUsersWrite      Permission = "users:write"
+	PermUsersDelete     Permission = "users:delete"
+	PermCategoriesRead  Permission = "categories:read"
+	PermCategoriesWrite Permission = "categories:write"
+	PermExpensesRead    Permission = "expenses:read"
+	PermExpensesWrite   Permission = "expenses:write"     // create expenses & edit own ones
+	PermExpensesManage  Permission = "expenses:write:any" // edit & delete expenses of other users
+	PermExpensesApprove Permission = "expenses:approve"   // approve, reject & reimburse expenses out of projects
+	PermProjectsRead    Permission = "projects:read"
+	PermProjectsWrite   Permission = "projects:write"
+	PermRatesWrite      Permission = "exchange-rates:write"
+)
+
+// rolePermissions the permission matrix for every role
+var rolePermissions = map[models.Role][]Permission{
+	models.RoleAdmin: {
+		PermUsersRead, PermUsersWrite, PermUsersDelete,
+		PermCategoriesRead, PermCategoriesWrite,
+		PermExpensesRead, PermExpensesWrite, PermExpensesManage, PermExpensesApprove,
+		PermProjectsRead, PermProjectsWrite,
+		PermRatesWrite,
+	},
+	models.RoleSupervisor: {
+		PermUsersRead,
+		PermCategoriesRead,
+		PermExpensesRead, PermExpensesWrite, PermExpensesManage, PermExpensesApprove,
+		PermProjectsRead, PermProjectsWrite,
+	},
+	models.RoleStaff: {
+		PermUsersRead,
+		PermCategoriesRead,
+		PermExpensesRead, PermExpensesWrite,
+		PermProjectsRead,
+	},
+	models.RoleUser: {
+		PermCategoriesRead,
+		PermExpensesRead, PermExpensesWrite,
+		PermProjectsRead,
+	},
+}
+
+// Can check if the role is granted the permission
+func Can(role models.Role, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// project level permissions, granted by the role of the project membership
+const (
+	PermProjectView    Permission = "project:view"
+	PermProjectMembers Permission = "project:members"
+	PermProjectApprove Permission = "project:approve"
+	PermProjectBudgets Permission = "project:budgets" // create, change & remove the budgets
+	PermProjectManage  Permission = "project:manage"  // remove the project
+)
+
+// projectRolePermissions the permission matrix for the roles of the project members
+var projectRolePermissions = map[models.Role][]Permission{
+	models.RoleAdmin:      {PermProjectView, PermProjectMembers, PermProjectApprove, PermProjectBudgets, PermProjectManage},
+	models.RoleSupervisor: {PermProjectView, PermProjectMembers, PermProjectApprove, PermProjectBudgets},
+	models.RoleStaff:      {PermProjectView},
+	models.RoleUser:       {PermProjectView},
+}
+
+// CanInProject check if the project role of a member is granted the permission
+func CanInProject(role models.Role, perm Permission) bool {
+	for _, p := range projectRolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
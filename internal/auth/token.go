@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+)
+
+// TokenType distinguish the access token from the refresh token
+type TokenType string
+
+// all the issued token types
+const (
+	AccessToken  TokenType = "access"
+	RefreshToken TokenType = "refresh"
+)
+
+// ErrInvalidToken returned when a token can not be verified
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Claims jwt claims carried by the issued tokens
+// the subject holds the hex representation of the user id
+type Claims struct {
+	Role models.Role `json:"role"`
+	Type TokenType   `json:"typ"`
+	// Password version of the password the token was issued for, see Current
+	Password int64 `json:"pwv,omitempty"`
+	jwt.StandardClaims
+}
+
+// Current report whether the token was issued for the current password of the user,
+// a password change revokes all the tokens issued before
+func (c *Claims) Current(user models.User) bool {
+	return c.Password == passwordVersion(user)
+}
+
+// passwordVersion milliseconds of the last password change, 0 when never changed
+func passwordVersion(user models.User) int64 {
+	if user.PasswordChangedAt.IsZero() {
+		return 0
+	}
+	return user.PasswordChangedAt.UnixNano() / int64(time.Millisecond)
+}
+
+// TokenPair response model for the login & refresh endpoints
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// TokenManager sign and verify the access & refresh tokens
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenManager godoc
+func NewTokenManager(secret string, accessTTL, refreshTTL time.Duration) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Issue sign a new access & refresh token pair for the user
+func (t *TokenManager) Issue(user models.User) (TokenPair, error) {
+	access, err := t.sign(user, AccessToken, t.accessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := t.sign(user, RefreshToken, t.refreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(t.accessTTL.Seconds()),
+	}, nil
+}
+
+// Parse verify the signature, expiry and type of the token
+func (t *TokenManager) Parse(token string, typ TokenType) (*Claims, error) {
+	claims := new(Claims)
+	parsed, err := jwt.ParseWithClaims(token, claims, func(tk *jwt.Token) (interface{}, error) {
+		if _, ok := tk.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", tk.Header["alg"])
+		}
+		return t.secret, nil
+	})
+	if err != nil || !parsed.Valid || claims.Type != typ {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (t *TokenManager) sign(user models.User, typ TokenType, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		Role:     user.Role,
+		Type:     typ,
+		Password: passwordVersion(user),
+		StandardClaims: jwt.StandardClaims{
+			Subject:   user.ID.Hex(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(ttl).Unix(),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.secret)
+}
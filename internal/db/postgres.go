@@ -0,0 +1,42 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+
+	// postgres driver registered for database/sql
+	_ "github.com/lib/pq"
+)
+
+// Used to create a singleton connection pool of PostgreSQL.
+var postgresInstance *sql.DB
+
+var postgresInstanceError error
+
+var postgresOnce sync.Once
+
+// GetPostgres get the postgres connection pool of POSTGRES_URL
+func GetPostgres() (*sql.DB, error) {
+	postgresOnce.Do(func() {
+		db, err := sql.Open("postgres", utils.MustGet("POSTGRES_URL"))
+		if err != nil {
+			log.Printf("error: %v", err)
+			postgresInstanceError = err
+			return
+		}
+		// Check the connection
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			log.Printf("error: %v", err)
+			postgresInstanceError = err
+		}
+		postgresInstance = db
+	})
+	return postgresInstance, postgresInstanceError
+}
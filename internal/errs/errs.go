@@ -0,0 +1,123 @@
+// Package errs typed errors of the domain, the handlers map their kind to a status code
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind category of an error
+type Kind uint8
+
+const (
+	// Internal unexpected failure, the default of the untyped errors
+	Internal Kind = iota
+	// NotFound the resource does not exist
+	NotFound
+	// Conflict the resource already exists or is in a state not allowing the operation
+	Conflict
+	// Validation the input is invalid
+	Validation
+	// Unavailable the storage can not be reached
+	Unavailable
+	// Timeout the operation was aborted by its deadline
+	Timeout
+)
+
+// kindCodes machine readable code of each kind, reported to the clients
+var kindCodes = map[Kind]string{
+	Internal:    "internal",
+	NotFound:    "not_found",
+	Conflict:    "conflict",
+	Validation:  "validation_failed",
+	Unavailable: "unavailable",
+	Timeout:     "timeout",
+}
+
+func (k Kind) String() string {
+	return kindCodes[k]
+}
+
+// FieldError failure of a single field of the input
+type FieldError struct {
+	Field   string `json:"field"`   // path of the field in the request, e.g. `items[0].amount`
+	Code    string `json:"code"`    // the failed rule, e.g. `required`
+	Message string `json:"message"` // translated message
+}
+
+// Error typed error, the message is safe to show to the clients while the cause is only logged
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+	Fields  []FieldError // the failed fields of a validation error
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return e.Message + ": " + e.Err.Error()
+}
+
+// Unwrap the cause of the error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New typed error of the message
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap typed error of the message with the cause
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// NotFoundf the resource does not exist
+func NotFoundf(format string, args ...interface{}) *Error {
+	return New(NotFound, fmt.Sprintf(format, args...))
+}
+
+// Conflictf the resource already exists or is in a state not allowing the operation
+func Conflictf(format string, args ...interface{}) *Error {
+	return New(Conflict, fmt.Sprintf(format, args...))
+}
+
+// Validationf the input is invalid
+func Validationf(format string, args ...interface{}) *Error {
+	return New(Validation, fmt.Sprintf(format, args...))
+}
+
+// FieldsOf the failed fields of the first typed error in the chain
+func FieldsOf(err error) []FieldError {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+	return nil
+}
+
+// KindOf the kind of the first typed error in the chain, Internal for the untyped errors
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Internal
+}
+
+// Is check the kind of the error, a nil error has no kind
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// Message the message of the first typed error in the chain, the error itself for the untyped errors
+func Message(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	return err.Error()
+}
@@ -0,0 +1,37 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKindOf(t *testing.T) {
+	notFound := NotFoundf("%s not found", "expense")
+	tests := []struct {
+		name string
+		err  error
+		want Kind
+	}{
+		{"typed", notFound, NotFound},
+		{"wrapped", fmt.Errorf("reading: %w", notFound), NotFound},
+		{"cause", Wrap(Timeout, "too slow", errors.New("deadline")), Timeout},
+		{"untyped", errors.New("boom"), Internal},
+		{"nil", nil, Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, KindOf(tt.err))
+		})
+	}
+	assert.False(t, Is(nil, Internal))
+}
+
+func TestMessage(t *testing.T) {
+	err := Wrap(Unavailable, "the storage is unavailable", errors.New("connection refused"))
+	assert.Equal(t, "the storage is unavailable: connection refused", err.Error())
+	assert.Equal(t, "the storage is unavailable", Message(err))
+	assert.Equal(t, "boom", Message(errors.New("boom")))
+}
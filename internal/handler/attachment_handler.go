@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/storage"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AttachmentHandler controller for the receipts of the expenses
+type AttachmentHandler struct {
+	expenseModel models.ExpenseModeler
+	projectModel models.ProjectModeler
+	storage      storage.Storage
+	maxSize      int64
+	contents     *contentLocks // serializes the references to the same content
+}
+
+// NewAttachmentHandler godoc
+func NewAttachmentHandler(em models.ExpenseModeler, pm models.ProjectModeler, s storage.Storage, maxSize int64) AttachmentHandler {
+	return AttachmentHandler{em, pm, s, maxSize, &contentLocks{locks: map[string]*contentLock{}}}
+}
+
+// CreateAttachment godoc
+// the content type is sniffed from the content, identical files are stored once
+// @Summary Upload a receipt.
+// @Description attach a receipt to the expense.
+// @Tags expenses
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Expense ID"
+// @Param file formData file true "Receipt"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 413 {object} utils.Response
+// @Failure 415 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/expenses/{id}/attachments [post]
+func (h AttachmentHandler) CreateAttachment(c echo.Context) error {
+	expense, err := h.readableExpense(c)
+	if err != nil {
+		return err
+	}
+	if !canActOn(c, expense.InsertedBy.ID, auth.PermExpensesManage) {
+		return utils.Error(http.StatusForbidden, i18n.AttachmentAuthorOnly, c)
+	}
+
+	// the body is limited before the form is parsed, so a larger upload is never spooled
+	limit := h.maxSize + attachmentFormOverhead
+	if c.Request().ContentLength > limit {
+		return h.tooLarge(c)
+	}
+	body := &limitedBody{ReadCloser: c.Request().Body, left: limit}
+	c.Request().Body = body
+	fh, err := c.FormFile("file")
+	if body.exceeded {
+		return h.tooLarge(c)
+	}
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	if fh.Size > h.maxSize {
+		return h.tooLarge(c)
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	defer file.Close()
+
+	// the header size can't be trusted, read at most one byte over the limit
+	content, err := ioutil.ReadAll(io.LimitReader(file, h.maxSize+1))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	if int64(len(content)) > h.maxSize {
+		return h.tooLarge(c)
+	}
+
+	contentType := http.DetectContentType(content)
+	if !models.AllowedAttachmentTypes[contentType] {
+		return utils.Error(http.StatusUnsupportedMediaType, i18n.T(c, i18n.UnsupportedFileType, contentType), c)
+	}
+
+	sum := sha256.Sum256(content)
+	key := hex.EncodeToString(sum[:])
+	for _, a := range expense.Attachments {
+		if a.SHA256 == key {
+			return utils.Data(http.StatusOK, a, i18n.AttachmentExists, c)
+		}
+	}
+
+	// the content must not be removed by the last attachment sharing it until it is referenced again
+	defer h.contents.lock(key)()
+	exists, err := h.storage.Exists(key)
+	if err != nil {
+		log.Printf("STORAGE ERROR: %v\n", err)
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+	}
+	if !exists {
+		if err := h.storage.Put(key, bytes.NewReader(content)); err != nil {
+			log.Printf("STORAGE ERROR: %v\n", err)
+			return utils.Error(http.StatusInternalServerError, err.Error(), c)
+		}
+	}
+
+	user, _ := auth.CurrentUser(c)
+	attachment := models.Attachment{
+		ID:          primitive.NewObjectID(),
+		FileName:    filepath.Base(fh.Filename),
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		SHA256:      key,
+		UploadedBy:  user.ID,
+		UploadedAt:  time.Now(),
+	}
+	if _, err := h.expenseModel.AddAttachment(c.Request().Context(), expense.ID, attachment); err != nil {
+		return err
+	}
+	return utils.Data(http.StatusCreated, attachment, i18n.AttachmentCreated, c)
+}
+
+// GetAttachments godoc
+// @Summary Get the receipts.
+// @Description get the receipts of the expense.
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Expense ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/expenses/{id}/attachments [get]
+func (h AttachmentHandler) GetAttachments(c echo.Context) error {
+	expense, err := h.readableExpense(c)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, expense.Attachments, i18n.AttachmentDetails, c)
+}
+
+// DownloadAttachment godoc
+// @Summary Download a receipt.
+// @Description download the content of the receipt.
+// @Tags expenses
+// @Produce octet-stream
+// @Param id path string true "Expense ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Success 200 {file} file
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/expenses/{id}/attachments/{attachmentId} [get]
+func (h AttachmentHandler) DownloadAttachment(c echo.Context) error {
+	expense, err := h.readableExpense(c)
+	if err != nil {
+		return err
+	}
+	attachment, err := h.findAttachment(c, expense)
+	if err != nil {
+		return err
+	}
+
+	content, err := h.storage.Get(attachment.SHA256)
+	if err != nil {
+		log.Printf("STORAGE ERROR: %v\n", err)
+		return utils.Error(http.StatusNotFound, err.Error(), c)
+	}
+	defer content.Close()
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", attachment.FileName))
+	c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+	return c.Stream(http.StatusOK, attachment.ContentType, content)
+}
+
+// DeleteAttachment godoc
+// @Summary Delete a receipt.
+// @Description delete the receipt from the expense.
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Expense ID"
+// @Param attachmentId path string true "Attachment ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/expenses/{id}/attachments/{attachmentId} [delete]
+func (h AttachmentHandler) DeleteAttachment(c echo.Context) error {
+	expense, err := h.readableExpense(c)
+	if err != nil {
+		return err
+	}
+	if !canActOn(c, expense.InsertedBy.ID, auth.PermExpensesManage) {
+		return utils.Error(http.StatusForbidden, i18n.AttachmentRemoverOnly, c)
+	}
+	// the receipts of the submitted expenses are part of the audit trail
+	if !expense.Status.IsEditable() {
+		return utils.Error(http.StatusConflict, i18n.ExpenseNotEditable, c)
+	}
+	attachment, err := h.findAttachment(c, expense)
+	if err != nil {
+		return err
+	}
+
+	defer h.contents.lock(attachment.SHA256)()
+	count, err := h.expenseModel.RemoveAttachment(c.Request().Context(), expense.ID, attachment.ID)
+	if err != nil {
+		return err
+	}
+	// removed in between by another request, which takes care of the content
+	if count == 0 {
+		return errs.NotFoundf(i18n.AttachmentNotFound)
+	}
+
+	// the content is only removed when no other attachment shares it
+	refs, err := h.expenseModel.CountAttachmentRefs(c.Request().Context(), attachment.SHA256)
+	if err != nil {
+		log.Printf("STORAGE ERROR: %v\n", err)
+	} else if refs == 0 {
+		if err := h.storage.Delete(attachment.SHA256); err != nil && err != storage.ErrNotFound {
+			log.Printf("STORAGE ERROR: %v\n", err)
+		}
+	}
+	return utils.Data(http.StatusAccepted, count, i18n.AttachmentRemoved, c)
+}
+
+// tooLarge respond the upload is over the size limit
+func (h AttachmentHandler) tooLarge(c echo.Context) error {
+	return utils.Error(http.StatusRequestEntityTooLarge, i18n.T(c, i18n.FileTooLarge, strconv.FormatInt(h.maxSize, 10)), c)
+}
+
+// readableExpense read the expense of the `:id` path param when the user can read it
+func (h AttachmentHandler) readableExpense(c echo.Context) (models.Expense, error) {
+	expenseID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return models.Expense{}, err
+	}
+	return readableExpense(c, h.expenseModel, h.projectModel, expenseID)
+}
+
+// findAttachment find the attachment of the `:attachmentId` path param in the expense
+func (h AttachmentHandler) findAttachment(c echo.Context, expense models.Expense) (models.Attachment, error) {
+	attachmentID, err := objectIDFromStringID(c.Param("attachmentId"))
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	for _, a := range expense.Attachments {
+		if a.ID == attachmentID {
+			return a, nil
+		}
+	}
+	return models.Attachment{}, errs.NotFoundf(i18n.AttachmentNotFound)
+}
+
+// attachmentFormOverhead the size of the multipart body allowed on top of the file, for the boundaries & the part headers
+const attachmentFormOverhead = 16 << 10
+
+// limitedBody request body failing after `left` bytes, recording it was exceeded
+type limitedBody struct {
+	io.ReadCloser
+	left     int64
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		// a single byte over the limit tells a larger body from one of the exact size
+		var one [1]byte
+		if n, _ := b.ReadCloser.Read(one[:]); n > 0 {
+			b.exceeded = true
+			return 0, errs.New(errs.Validation, "request body too large")
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
+
+// contentLocks one lock for each stored content, by its sha256
+type contentLocks struct {
+	mu    sync.Mutex
+	locks map[string]*contentLock
+}
+
+type contentLock struct {
+	sync.Mutex
+	holders int
+}
+
+// lock hold the lock of the content, the returned func releases it
+func (l *contentLocks) lock(key string) func() {
+	l.mu.Lock()
+	cl, ok := l.locks[key]
+	if !ok {
+		cl = &contentLock{}
+		l.locks[key] = cl
+	}
+	cl.holders++
+	l.mu.Unlock()
+
+	cl.Lock()
+	return func() {
+		cl.Unlock()
+		l.mu.Lock()
+		if cl.holders--; cl.holders == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
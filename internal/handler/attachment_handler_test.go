@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateAttachment(t *testing.T) {
+	author, _ := UserModelStub{}.ReadOneUser(context.Background(), models.UserQuery{})
+	staff := models.User{ID: primitive.NewObjectID(), Role: models.RoleStaff, IsActive: true}
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+	tests := []struct {
+		name    string
+		user    models.User
+		content []byte
+		code    int
+	}{
+		{"author uploads a receipt", author, png, http.StatusCreated},
+		{"others can't see the expense", staff, png, http.StatusNotFound},
+		{"unsupported type", author, []byte("plain text receipt"), http.StatusUnsupportedMediaType},
+		{"too large", author, append(png, make([]byte, 128)...), http.StatusRequestEntityTooLarge},
+		{"body over the limit", author, append(png, make([]byte, 64<<10)...), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := storage.NewLocalStorage(t.TempDir())
+			assert.NoError(t, err)
+			h := NewAttachmentHandler(ExpenseModelStub{}, ProjectModelStub{}, s, 128)
+			e := newTestEcho()
+			e.POST("/expenses/:id/attachments", h.CreateAttachment, asUser(tt.user))
+
+			body := &bytes.Buffer{}
+			w := multipart.NewWriter(body)
+			part, _ := w.CreateFormFile("file", "receipt.png")
+			part.Write(tt.content)
+			w.Close()
+
+			req := httptest.NewRequest(echo.POST, "/expenses/6009be17d6a899ab8340eb79/attachments", body)
+			req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+			req.ContentLength = -1 // streamed, the size is only known by reading the body
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestAttachmentAccess(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	s, err := storage.NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+	var current models.User
+	e := newTestEcho()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth.SetUser(c, current)
+			return next(c)
+		}
+	})
+	h := NewAttachmentHandler(m.Expenses, m.Projects, s, 1024)
+	e.POST("/expenses/:id/attachments", h.CreateAttachment)
+	e.GET("/expenses/:id/attachments", h.GetAttachments)
+	e.GET("/expenses/:id/attachments/:attachmentId", h.DownloadAttachment)
+	e.DELETE("/expenses/:id/attachments/:attachmentId", h.DeleteAttachment)
+
+	alice := models.User{ID: primitive.NewObjectID(), Role: models.RoleStaff, IsActive: true}
+	bob := models.User{ID: primitive.NewObjectID(), Role: models.RoleStaff, IsActive: true}
+	project := primitive.NewObjectID()
+	_, err = m.Projects.InsertProjectUser(ctx, &models.ProjectUser{ID: primitive.NewObjectID(), ProjectID: project, UserID: alice.ID, Role: models.RoleStaff, IsActive: true})
+	assert.NoError(t, err)
+	expense := func(status models.ExpenseStatus) primitive.ObjectID {
+		id := primitive.NewObjectID()
+		_, err := m.Expenses.Insert(ctx, models.Expense{ID: id, ProjectID: project, InsertedBy: models.UserSnapshot{ID: alice.ID}, Status: status})
+		assert.NoError(t, err)
+		return id
+	}
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	sum := sha256.Sum256(png)
+	key := hex.EncodeToString(sum[:])
+
+	do := func(method, path string) (int, json.RawMessage) {
+		var req *http.Request
+		if method == echo.POST {
+			body := &bytes.Buffer{}
+			w := multipart.NewWriter(body)
+			part, _ := w.CreateFormFile("file", "receipt.png")
+			part.Write(png)
+			w.Close()
+			req = httptest.NewRequest(method, path, body)
+			req.Header.Set(echo.HeaderContentType, w.FormDataContentType())
+		} else {
+			req = httptest.NewRequest(method, path, nil)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		return rec.Code, res.Data
+	}
+	upload := func(expenseID primitive.ObjectID) string {
+		code, data := do(echo.POST, "/expenses/"+expenseID.Hex()+"/attachments")
+		assert.Equal(t, http.StatusCreated, code)
+		var attachment models.Attachment
+		assert.NoError(t, json.Unmarshal(data, &attachment))
+		return "/expenses/" + expenseID.Hex() + "/attachments/" + attachment.ID.Hex()
+	}
+
+	current = alice
+	draft := expense(models.StatusDraft)
+	receipt := upload(draft)
+	code, _ := do(echo.GET, receipt)
+	assert.Equal(t, http.StatusOK, code)
+
+	// the receipts are read by the members of the project only
+	current = bob
+	code, _ = do(echo.GET, "/expenses/"+draft.Hex()+"/attachments")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = do(echo.GET, receipt)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// and managed by them, a supervisor out of the project doesn't see the expense
+	current = models.User{ID: primitive.NewObjectID(), Role: models.RoleSupervisor, IsActive: true}
+	code, _ = do(echo.POST, "/expenses/"+draft.Hex()+"/attachments")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = do(echo.DELETE, receipt)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	current = alice
+	code, _ = do(echo.DELETE, receipt)
+	assert.Equal(t, http.StatusAccepted, code)
+	exists, err := s.Exists(key)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	code, _ = do(echo.DELETE, receipt)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	// an upload racing the removal of the last reference keeps its content
+	other := expense(models.StatusDraft)
+	for i := 0; i < 20; i++ {
+		receipt := upload(draft)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			do(echo.DELETE, receipt)
+		}()
+		var shared string
+		go func() {
+			defer wg.Done()
+			shared = upload(other)
+		}()
+		wg.Wait()
+		exists, err := s.Exists(key)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		code, _ := do(echo.DELETE, shared)
+		assert.Equal(t, http.StatusAccepted, code)
+	}
+
+	// the receipts of the submitted expenses are kept
+	submitted := expense(models.StatusDraft)
+	kept := upload(submitted)
+	_, err = m.Expenses.Transition(ctx, submitted, models.StatusTransition{Action: models.ActionSubmit, From: models.StatusDraft, To: models.StatusSubmitted})
+	assert.NoError(t, err)
+	code, _ = do(echo.DELETE, kept)
+	assert.Equal(t, http.StatusConflict, code)
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Mailer email a message to a user, the email channel of the notifications
+type Mailer interface {
+	Send(ctx context.Context, user models.User, prefs models.NotificationPreferences, n models.Notification) error
+}
+
+// AuthHandler controller for login, token refresh & password reset
+type AuthHandler struct {
+	userModel  models.UserModel
+	resetModel models.PasswordResetModeler
+	tokens     *auth.TokenManager
+	resetTTL   time.Duration
+	mailer     Mailer // delivers the reset tokens, nil without a mail server
+}
+
+// NewAuthHandler godoc
+func NewAuthHandler(um models.UserModel, rm models.PasswordResetModeler, tm *auth.TokenManager, resetTTL time.Duration, mailer Mailer) AuthHandler {
+	return AuthHandler{um, rm, tm, resetTTL, mailer}
+}
+
+// Login godoc
+// @Summary Login.
+// @Description issue access & refresh tokens for valid credentials.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.LoginInput true "Login"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/auth/login [post]
+func (a AuthHandler) Login(c echo.Context) error {
+	input := new(models.LoginInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	user, err := a.userModel.ReadOneUser(c.Request().Context(), models.UserQuery{Email: input.Email})
+	if err != nil && !errs.Is(err, errs.NotFound) {
+		return err
+	}
+	if user.ID.IsZero() || user.PasswordHash == "" {
+		return utils.Error(http.StatusUnauthorized, i18n.InvalidCredentials, c)
+	}
+
+	if err := auth.ComparePassword(user.PasswordHash, input.Password); err != nil {
+		return utils.Error(http.StatusUnauthorized, i18n.InvalidCredentials, c)
+	}
+
+	if !user.IsActive {
+		return utils.Error(http.StatusForbidden, i18n.UserNotActive, c)
+	}
+
+	tokens, err := a.tokens.Issue(user)
+	if err != nil {
+		log.Printf("TOKEN SIGNING ERROR: %v\n", err)
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+	}
+	return utils.Data(http.StatusOK, tokens, i18n.LoginSuccessful, c)
+}
+
+// Refresh godoc
+// @Summary Refresh tokens.
+// @Description exchange a refresh token for a new token pair.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param token body models.RefreshInput true "Refresh Token"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Router /api/v1/auth/refresh [post]
+func (a AuthHandler) Refresh(c echo.Context) error {
+	input := new(models.RefreshInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	claims, err := a.tokens.Parse(input.RefreshToken, auth.RefreshToken)
+	if err != nil {
+		return utils.Error(http.StatusUnauthorized, i18n.InvalidToken, c)
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return utils.Error(http.StatusUnauthorized, i18n.InvalidToken, c)
+	}
+
+	user, err := a.userModel.ReadOneUser(c.Request().Context(), models.UserQuery{ID: userID})
+	if err != nil && !errs.Is(err, errs.NotFound) {
+		return err
+	}
+	if user.ID.IsZero() || !user.IsActive {
+		return utils.Error(http.StatusUnauthorized, i18n.UserNotFoundOrInactive, c)
+	}
+	if !claims.Current(user) {
+		return utils.Error(http.StatusUnauthorized, i18n.InvalidToken, c)
+	}
+
+	tokens, err := a.tokens.Issue(user)
+	if err != nil {
+		log.Printf("TOKEN SIGNING ERROR: %v\n", err)
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+	}
+	return utils.Data(http.StatusOK, tokens, i18n.TokenRefreshed, c)
+}
+
+// ForgotPassword godoc
+// the token is emailed to the user, no token is issued without a mail server
+// @Summary Request a password reset.
+// @Description issue a single use password reset token for the user.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param email body models.ForgotPasswordInput true "Forgot Password"
+// @Success 202 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/auth/password/forgot [post]
+func (a AuthHandler) ForgotPassword(c echo.Context) error {
+	input := new(models.ForgotPasswordInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	// same response whether the email exists or not, to not leak accounts
+	const msg = i18n.PasswordResetIssued
+	user, err := a.userModel.ReadOneUser(c.Request().Context(), models.UserQuery{Email: input.Email})
+	if err != nil || user.ID.IsZero() || !user.IsActive {
+		return utils.Data(http.StatusAccepted, nil, msg, c)
+	}
+	if a.mailer == nil {
+		log.Printf("PASSWORD RESET: no mail server to deliver the token, set SMTP_HOST\n")
+		return utils.Data(http.StatusAccepted, nil, msg, c)
+	}
+
+	token, hash, err := auth.NewResetToken()
+	if err != nil {
+		log.Printf("RESET TOKEN ERROR: %v\n", err)
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+	}
+
+	reset := &models.PasswordReset{
+		ID:        primitive.NewObjectID(),
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(a.resetTTL),
+		UserID:    user.ID,
+		TokenHash: hash,
+	}
+	if _, err := a.resetModel.Insert(c.Request().Context(), reset); err != nil {
+		return err
+	}
+
+	email := models.Notification{
+		ID:        primitive.NewObjectID(),
+		CreatedAt: reset.CreatedAt,
+		UserID:    user.ID,
+		Title:     i18n.T(c, i18n.PasswordResetTitle),
+		Body:      i18n.T(c, i18n.PasswordResetBody, token, reset.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")),
+	}
+	// a failed delivery is answered the same, to not leak accounts
+	if err := a.mailer.Send(c.Request().Context(), user, models.NotificationPreferences{UserID: user.ID, Email: true}, email); err != nil {
+		log.Printf("PASSWORD RESET EMAIL ERROR: %v\n", err)
+	}
+	return utils.Data(http.StatusAccepted, nil, msg, c)
+}
+
+// ResetPassword godoc
+// @Summary Reset the password.
+// @Description set a new password with a password reset token.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param reset body models.ResetPasswordInput true "Reset Password"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/auth/password/reset [post]
+func (a AuthHandler) ResetPassword(c echo.Context) error {
+	input := new(models.ResetPasswordInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	const invalid = i18n.InvalidResetToken
+	reset, err := a.resetModel.ReadOneByTokenHash(c.Request().Context(), auth.HashResetToken(input.Token))
+	if err != nil && !errs.Is(err, errs.NotFound) {
+		return err
+	}
+	if reset.ID.IsZero() || reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return utils.Error(http.StatusBadRequest, invalid, c)
+	}
+
+	// consume the token before changing the password so it can't be replayed
+	count, err := a.resetModel.MarkUsed(c.Request().Context(), reset.ID)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return utils.Error(http.StatusBadRequest, invalid, c)
+	}
+
+	hash, err := auth.HashPassword(input.NewPassword)
+	if err != nil {
+		log.Printf("PASSWORD HASHING ERROR: %v\n", err)
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+	}
+
+	if _, err := a.userModel.UpdateOneUser(c.Request().Context(), reset.UserID, models.UserUpdate{PasswordHash: &hash}); err != nil {
+		return err
+	}
+	// the other reset tokens issued for the old password are revoked with it
+	if _, err := a.resetModel.MarkUserUsed(c.Request().Context(), reset.UserID); err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, nil, i18n.PasswordReset, c)
+}
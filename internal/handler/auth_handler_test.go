@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/middleware"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/go-playground/validator.v9"
+	en_translations "gopkg.in/go-playground/validator.v9/translations/en"
+)
+
+// AuthUserModelStub user stub with a known password
+type AuthUserModelStub struct {
+	UserModelStub
+	hash string
+}
+
+func (u AuthUserModelStub) ReadOneUser(ctx context.Context, q models.UserQuery) (models.User, error) {
+	user, _ := u.UserModelStub.ReadOneUser(ctx, q)
+	user.PasswordHash = u.hash
+	return user, nil
+}
+
+func newTestEcho() *echo.Echo {
+	e := echo.New()
+	uni := i18n.New()
+	trans, _ := uni.GetTranslator(i18n.DefaultLocale)
+	v := validator.New()
+	en_translations.RegisterDefaultTranslations(v, trans)
+	models.RegisterCustomValidations(v, trans)
+	for locale, messages := range i18n.ValidationMessages {
+		localeTrans, _ := uni.GetTranslator(locale)
+		models.RegisterTranslations(v, localeTrans, messages)
+	}
+	e.Validator = &models.Validator{Validator: v, Trans: trans}
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(middleware.Locale(uni))
+	return e
+}
+
+func newAuthStub(t *testing.T) AuthUserModelStub {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	return AuthUserModelStub{hash: string(hash)}
+}
+
+func TestLogin(t *testing.T) {
+	tm := auth.NewTokenManager("test-secret", time.Minute, time.Hour)
+	h := NewAuthHandler(newAuthStub(t), nil, tm, time.Hour, nil)
+
+	tests := []struct {
+		name     string
+		password string
+		code     int
+	}{
+		{"valid credentials", "s3cret-pass", http.StatusOK},
+		{"wrong password", "wrong-pass", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEcho()
+			body, _ := json.Marshal(models.LoginInput{Email: "marufrahman1349@gmail.com", Password: tt.password})
+			req := httptest.NewRequest(echo.POST, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if assert.NoError(t, h.Login(c)) {
+				assert.Equal(t, tt.code, rec.Code)
+			}
+		})
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	tm := auth.NewTokenManager("test-secret", time.Minute, time.Hour)
+	h := NewAuthHandler(newAuthStub(t), nil, tm, time.Hour, nil)
+	user, _ := UserModelStub{}.ReadOneUser(context.Background(), models.UserQuery{})
+	pair, err := tm.Issue(user)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"refresh token", pair.RefreshToken, http.StatusOK},
+		{"access token is rejected", pair.AccessToken, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEcho()
+			body, _ := json.Marshal(models.RefreshInput{RefreshToken: tt.token})
+			req := httptest.NewRequest(echo.POST, "/", bytes.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if assert.NoError(t, h.Refresh(c)) {
+				assert.Equal(t, tt.code, rec.Code)
+			}
+		})
+	}
+}
+
+// mailerStub Mailer keeping the sent emails
+type mailerStub struct {
+	sent []models.Notification
+}
+
+func (m *mailerStub) Send(ctx context.Context, user models.User, prefs models.NotificationPreferences, n models.Notification) error {
+	m.sent = append(m.sent, n)
+	return nil
+}
+
+func TestForgotPassword(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	user := models.User{ID: primitive.NewObjectID(), Name: "alice", Email: "alice@example.com", Role: models.RoleStaff, IsActive: true}
+	_, err := m.Users.InsertNewUser(ctx, &user)
+	assert.NoError(t, err)
+	tm := auth.NewTokenManager("test-secret", time.Minute, time.Hour)
+	mailer := &mailerStub{}
+	e := newTestEcho()
+	h := NewAuthHandler(m.Users, m.PasswordResets, tm, time.Hour, mailer)
+	e.POST("/password/forgot", h.ForgotPassword)
+	e.POST("/password/reset", h.ResetPassword)
+	do := func(path, body string) int {
+		req := httptest.NewRequest(echo.POST, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// the unknown emails are answered the same, with no email
+	assert.Equal(t, http.StatusAccepted, do("/password/forgot", `{"email":"bob@example.com"}`))
+	assert.Empty(t, mailer.sent)
+
+	assert.Equal(t, http.StatusAccepted, do("/password/forgot", `{"email":"alice@example.com"}`))
+	if !assert.Len(t, mailer.sent, 1) {
+		return
+	}
+	assert.Equal(t, "reset your password", mailer.sent[0].Title)
+	token := strings.Fields(strings.TrimPrefix(mailer.sent[0].Body, "use the token "))[0]
+	assert.Equal(t, http.StatusAccepted, do("/password/forgot", `{"email":"alice@example.com"}`))
+	other := strings.Fields(strings.TrimPrefix(mailer.sent[1].Body, "use the token "))[0]
+	assert.Equal(t, http.StatusOK, do("/password/reset", `{"token":"`+token+`","new_password":"N3w-password"}`))
+	assert.Equal(t, http.StatusBadRequest, do("/password/reset", `{"token":"`+token+`","new_password":"N3w-password"}`))
+	// the other tokens of the user are revoked with the old password
+	assert.Equal(t, http.StatusBadRequest, do("/password/reset", `{"token":"`+other+`","new_password":"N3w-password"}`))
+
+	// no token is issued without a mail server
+	e = newTestEcho()
+	e.POST("/password/forgot", NewAuthHandler(m.Users, m.PasswordResets, tm, time.Hour, nil).ForgotPassword)
+	assert.Equal(t, http.StatusAccepted, do("/password/forgot", `{"email":"alice@example.com"}`))
+	assert.Len(t, mailer.sent, 2)
+}
+
+func TestChangePasswordRevokesTokens(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := models.User{ID: primitive.NewObjectID(), Name: "alice", Email: "alice@example.com", Role: models.RoleStaff, IsActive: true, PasswordHash: string(hash)}
+	_, err = m.Users.InsertNewUser(ctx, &user)
+	assert.NoError(t, err)
+	tm := auth.NewTokenManager("test-secret", time.Minute, time.Hour)
+	mailer := &mailerStub{}
+	e := newTestEcho()
+	h := NewAuthHandler(m.Users, m.PasswordResets, tm, time.Hour, mailer)
+	e.POST("/password/forgot", h.ForgotPassword)
+	e.POST("/password/reset", h.ResetPassword)
+	e.POST("/refresh", h.Refresh)
+	e.PUT("/users/:id/password", NewUserHandler(m.Users, m.PasswordResets).ChangePassword, middleware.JWT(tm, m.Users))
+	do := func(method, path, token, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	pair, err := tm.Issue(user)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusAccepted, do(echo.POST, "/password/forgot", "", `{"email":"alice@example.com"}`))
+	if !assert.Len(t, mailer.sent, 1) {
+		return
+	}
+	token := strings.Fields(strings.TrimPrefix(mailer.sent[0].Body, "use the token "))[0]
+
+	change := `{"current_password":"s3cret-pass","new_password":"N3w-password"}`
+	assert.Equal(t, http.StatusOK, do(echo.PUT, "/users/"+user.ID.Hex()+"/password", pair.AccessToken, change))
+
+	// the tokens issued for the old password are revoked
+	assert.Equal(t, http.StatusUnauthorized, do(echo.PUT, "/users/"+user.ID.Hex()+"/password", pair.AccessToken, change))
+	assert.Equal(t, http.StatusUnauthorized, do(echo.POST, "/refresh", "", `{"refresh_token":"`+pair.RefreshToken+`"}`))
+	assert.Equal(t, http.StatusBadRequest, do(echo.POST, "/password/reset", "", `{"token":"`+token+`","new_password":"An0ther-password"}`))
+
+	// the tokens issued for the new password are accepted
+	user, err = m.Users.ReadOneUser(ctx, models.UserQuery{ID: user.ID})
+	assert.NoError(t, err)
+	pair, err = tm.Issue(user)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, do(echo.POST, "/refresh", "", `{"refresh_token":"`+pair.RefreshToken+`"}`))
+}
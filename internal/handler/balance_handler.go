@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BalanceHandler controller for the balances of the shared expenses of the projects & their settlements
+type BalanceHandler struct {
+	models models.Models
+}
+
+// NewBalanceHandler godoc
+func NewBalanceHandler(m models.Models) BalanceHandler {
+	return BalanceHandler{m}
+}
+
+// GetBalances godoc
+// what each member paid and owes for the split expenses of the project, in its base currency,
+// with the fewest transfers settling the balances. The rejected expenses are left out.
+// @Summary Get the Project Balances.
+// @Description get the net balances of the members of the project and the settle up plan
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 422 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/projects/{id}/balances [get]
+func (h BalanceHandler) GetBalances(c echo.Context) error {
+	projectID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	sheet, err := models.ProjectBalances(c.Request().Context(), h.models, projectID)
+	if errors.Is(err, models.ErrRateNotFound) {
+		return utils.Error(http.StatusUnprocessableEntity, err.Error(), c)
+	}
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, sheet, i18n.ProjectBalances, c)
+}
+
+// CreateSettlement godoc
+// a payment between two members paying back the shared expenses, the amount is in the base currency of the project by default
+// @Summary Record a Project Settlement.
+// @Description record a settlement payment between two members of the project
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param settlement body models.SettlementInput true "Create Settlement"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/projects/{id}/settlements [post]
+func (h BalanceHandler) CreateSettlement(c echo.Context) error {
+	projectID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	input := new(models.SettlementInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	project, err := h.models.Projects.ReadOne(c.Request().Context(), projectID)
+	if err != nil {
+		return err
+	}
+	currency := input.Currency
+	if currency == "" {
+		currency = project.BaseCurrency
+	}
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	amount, err := models.ParseMoney(input.Amount.String(), currency)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	if amount.IsZero() {
+		return utils.Error(http.StatusBadRequest, i18n.SettlementAmountZero, c)
+	}
+	date, err := parseDateToFormat(models.DateLayout, input.Date)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	settlement := &models.Settlement{
+		ID:        primitive.NewObjectID(),
+		CreatedAt: time.Now(),
+		ProjectID: projectID,
+		Amount:    amount,
+		Date:      date,
+		Note:      input.Note,
+	}
+	if settlement.FromID, err = objectIDFromStringID(input.FromID); err != nil {
+		return err
+	}
+	if settlement.ToID, err = objectIDFromStringID(input.ToID); err != nil {
+		return err
+	}
+	for _, id := range []primitive.ObjectID{settlement.FromID, settlement.ToID} {
+		_, err := h.models.Projects.ReadOneProjectUser(c.Request().Context(), models.ProjectUserFilter{ProjectID: projectID, UserID: id})
+		if errs.Is(err, errs.NotFound) {
+			return utils.Error(http.StatusBadRequest, i18n.SettlementNotMember, c)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	user, err := h.authorizeSettlement(c, *settlement)
+	if err != nil {
+		return err
+	}
+	settlement.InsertedBy = user.ID
+
+	if _, err := h.models.Settlements.Insert(c.Request().Context(), settlement); err != nil {
+		return err
+	}
+	return utils.Data(http.StatusCreated, settlement, i18n.SettlementCreated, c)
+}
+
+// GetSettlements godoc
+// @Summary Get the Project Settlements.
+// @Description get the settlements of the project, oldest first
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/projects/{id}/settlements [get]
+func (h BalanceHandler) GetSettlements(c echo.Context) error {
+	projectID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	settlements, err := h.models.Settlements.ReadAll(c.Request().Context(), projectID)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, settlements, i18n.SettlementList, c)
+}
+
+// DeleteSettlement godoc
+// @Summary Delete a Project Settlement.
+// @Description remove a settlement recorded by mistake, the balances move back
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param settlementId path string true "Settlement ID"
+// @Success 202 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/projects/{id}/settlements/{settlementId} [delete]
+func (h BalanceHandler) DeleteSettlement(c echo.Context) error {
+	projectID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	settlementID, err := objectIDFromStringID(c.Param("settlementId"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	settlement, err := h.models.Settlements.ReadOne(c.Request().Context(), settlementID)
+	if err != nil {
+		return err
+	}
+	if settlement.ProjectID != projectID {
+		return errs.NotFoundf(i18n.SettlementNotFound)
+	}
+	if _, err := h.authorizeSettlement(c, settlement); err != nil {
+		return err
+	}
+	count, err := h.models.Settlements.RemoveOne(c.Request().Context(), settlement.ID)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusAccepted, count, i18n.SettlementRemoved, c)
+}
+
+// authorizeSettlement allow the change only to the payer or the receiver of the settlement,
+// to the members approving the expenses of the project and to the ADMIN
+func (h BalanceHandler) authorizeSettlement(c echo.Context, settlement models.Settlement) (models.User, error) {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return user, echo.NewHTTPError(http.StatusUnauthorized, i18n.Unauthenticated)
+	}
+	if user.ID == settlement.FromID || user.ID == settlement.ToID || user.Role == models.RoleAdmin {
+		return user, nil
+	}
+	if member, ok := auth.CurrentProjectMember(c); ok && auth.CanInProject(member.Role, auth.PermProjectApprove) {
+		return user, nil
+	}
+	return user, echo.NewHTTPError(http.StatusForbidden, i18n.SettlementPartiesOnly)
+}
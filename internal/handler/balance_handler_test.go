@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBalances(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	e := newTestEcho()
+	var current models.User
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth.SetUser(c, current)
+			return next(c)
+		}
+	})
+	h := NewBalanceHandler(m)
+	eh := NewExpenseHandler(m.Expenses, m.Users, m.Categories, m.Projects, nil)
+	e.POST("/expenses", eh.CreateExpense)
+	e.PUT("/expenses/:id", eh.UpdateExpense)
+	e.GET("/projects/:id/balances", h.GetBalances)
+	e.GET("/projects/:id/settlements", h.GetSettlements)
+	e.POST("/projects/:id/settlements", h.CreateSettlement)
+	e.DELETE("/projects/:id/settlements/:settlementId", h.DeleteSettlement)
+
+	do := func(method, path, body string) (int, json.RawMessage) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		return rec.Code, res.Data
+	}
+
+	project := models.Project{ID: primitive.NewObjectID(), Title: "flat", BaseCurrency: "EUR", IsActive: true}
+	_, err := m.Projects.Insert(ctx, &project)
+	assert.NoError(t, err)
+	food := models.Category{ID: primitive.NewObjectID(), Name: "Food", Slug: "food"}
+	_, err = m.Categories.Insert(ctx, &food)
+	assert.NoError(t, err)
+	var alice, bob, carol, dave models.User
+	for _, u := range []*models.User{&alice, &bob, &carol, &dave} {
+		*u = models.User{ID: primitive.NewObjectID(), Role: models.RoleStaff, IsActive: true}
+	}
+	alice.Name, bob.Name, carol.Name, dave.Name = "alice", "bob", "carol", "dave"
+	for _, u := range []models.User{alice, bob, carol, dave} {
+		u := u
+		u.Email = u.Name + "@example.com"
+		_, err := m.Users.InsertNewUser(ctx, &u)
+		assert.NoError(t, err)
+	}
+	for _, u := range []models.User{alice, bob, carol} {
+		_, err := m.Projects.InsertProjectUser(ctx, &models.ProjectUser{ID: primitive.NewObjectID(), ProjectID: project.ID, UserID: u.ID, Role: models.RoleStaff, IsActive: true})
+		assert.NoError(t, err)
+	}
+	current = alice
+
+	expense := func(project, extra string) string {
+		return `{"date":"2021-03-01","title":"groceries","description":"weekly","total":"90.00","currency":"EUR","category_id":"` +
+			food.ID.Hex() + `","project_id":"` + project + `"` + extra + `}`
+	}
+	splits := func(method string, users ...models.User) string {
+		var entries []string
+		for _, u := range users {
+			entries = append(entries, `{"user_id":"`+u.ID.Hex()+`","value":"1"}`)
+		}
+		return `,"split_method":"` + method + `","splits":[` + strings.Join(entries, ",") + `]`
+	}
+	for _, c := range []struct {
+		name string
+		body string
+	}{
+		{"not a member", expense(project.ID.Hex(), splits("equal", alice, dave))},
+		{"payer not a member", expense(project.ID.Hex(), `,"paid_by":"`+dave.ID.Hex()+`"`)},
+		{"without project", expense("", splits("equal", alice, bob))},
+		{"method without splits", expense(project.ID.Hex(), `,"split_method":"equal"`)},
+		{"splits without method", expense(project.ID.Hex(), `,"splits":[{"user_id":"`+bob.ID.Hex()+`"}]`)},
+		{"unknown method", expense(project.ID.Hex(), splits("halves", alice, bob))},
+		{"percent not adding up", expense(project.ID.Hex(), splits("percent", alice, bob))},
+	} {
+		code, _ := do(echo.POST, "/expenses", c.body)
+		assert.Equal(t, http.StatusBadRequest, code, c.name)
+	}
+
+	// alice paid 90 for the three of them, bob paid 30 split 1:2 between alice & bob
+	code, data := do(echo.POST, "/expenses", expense(project.ID.Hex(), splits("equal", alice, bob, carol)))
+	assert.Equal(t, http.StatusCreated, code)
+	var id primitive.ObjectID
+	assert.NoError(t, json.Unmarshal(data, &id))
+	shared, err := m.Expenses.ReadOne(ctx, id)
+	assert.NoError(t, err)
+	assert.Equal(t, alice.ID, shared.Payer())
+	assert.Equal(t, models.SplitEqual, shared.SplitMethod)
+	if assert.Len(t, shared.Splits, 3) {
+		assert.Equal(t, models.NewMoney(3000, "EUR"), shared.Splits[2].Amount)
+	}
+	code, data = do(echo.POST, "/expenses", expense(project.ID.Hex(), ""))
+	assert.Equal(t, http.StatusCreated, code)
+	assert.NoError(t, json.Unmarshal(data, &id))
+	body := strings.Replace(expense(project.ID.Hex(), `,"paid_by":"`+bob.ID.Hex()+`","split_method":"shares","splits":[{"user_id":"`+
+		alice.ID.Hex()+`","value":"1"},{"user_id":"`+bob.ID.Hex()+`","value":"2"}]`), "90.00", "30", 1)
+	code, _ = do(echo.PUT, "/expenses/"+id.Hex(), body)
+	assert.Equal(t, http.StatusOK, code)
+
+	type sheet struct {
+		BaseCurrency string `json:"base_currency"`
+		Balances     []struct {
+			UserID primitive.ObjectID `json:"user_id"`
+			Name   string             `json:"name"`
+			Net    struct {
+				Amount string `json:"amount"`
+			} `json:"net"`
+		} `json:"balances"`
+		SettleUp []struct {
+			FromName string `json:"from_name"`
+			ToName   string `json:"to_name"`
+			Amount   struct {
+				Amount string `json:"amount"`
+			} `json:"amount"`
+		} `json:"settle_up"`
+	}
+	balances := func() (map[string]string, []string) {
+		code, data := do(echo.GET, "/projects/"+project.ID.Hex()+"/balances", "")
+		assert.Equal(t, http.StatusOK, code)
+		var s sheet
+		assert.NoError(t, json.Unmarshal(data, &s))
+		assert.Equal(t, "EUR", s.BaseCurrency)
+		nets := map[string]string{}
+		for _, b := range s.Balances {
+			nets[b.Name] = b.Net.Amount
+		}
+		transfers := []string{}
+		for _, tr := range s.SettleUp {
+			transfers = append(transfers, tr.FromName+" > "+tr.ToName+" "+tr.Amount.Amount)
+		}
+		return nets, transfers
+	}
+	nets, transfers := balances()
+	assert.Equal(t, map[string]string{"alice": "50.00", "bob": "-20.00", "carol": "-30.00"}, nets)
+	assert.Equal(t, []string{"carol > alice 30.00", "bob > alice 20.00"}, transfers)
+
+	settlements := "/projects/" + project.ID.Hex() + "/settlements"
+	settlement := func(from, to models.User, amount string) string {
+		return `{"from_id":"` + from.ID.Hex() + `","to_id":"` + to.ID.Hex() + `","amount":"` + amount + `","date":"2021-03-05"}`
+	}
+	for _, c := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"to oneself", settlement(bob, bob, "20"), http.StatusBadRequest},
+		{"not a member", settlement(dave, alice, "20"), http.StatusBadRequest},
+		{"zero amount", settlement(bob, alice, "0"), http.StatusBadRequest},
+		{"too precise", settlement(bob, alice, "0.001"), http.StatusBadRequest},
+		{"between the others", settlement(bob, carol, "20"), http.StatusForbidden},
+	} {
+		code, _ := do(echo.POST, settlements, c.body)
+		assert.Equal(t, c.code, code, c.name)
+	}
+
+	// bob paid alice back
+	code, data = do(echo.POST, settlements, settlement(bob, alice, "20"))
+	assert.Equal(t, http.StatusCreated, code)
+	var paid models.Settlement
+	assert.NoError(t, json.Unmarshal(data, &paid))
+	assert.Equal(t, models.NewMoney(2000, "EUR"), paid.Amount)
+	assert.Equal(t, alice.ID, paid.InsertedBy)
+	nets, transfers = balances()
+	assert.Equal(t, map[string]string{"alice": "30.00", "bob": "0.00", "carol": "-30.00"}, nets)
+	assert.Equal(t, []string{"carol > alice 30.00"}, transfers)
+	code, data = do(echo.GET, settlements, "")
+	assert.Equal(t, http.StatusOK, code)
+	var list []models.Settlement
+	assert.NoError(t, json.Unmarshal(data, &list))
+	assert.Len(t, list, 1)
+
+	// only the parties remove the settlement
+	current = carol
+	code, _ = do(echo.DELETE, settlements+"/"+paid.ID.Hex(), "")
+	assert.Equal(t, http.StatusForbidden, code)
+	current = bob
+	code, _ = do(echo.DELETE, settlements+"/"+paid.ID.Hex(), "")
+	assert.Equal(t, http.StatusAccepted, code)
+	_, transfers = balances()
+	assert.Len(t, transfers, 2)
+	code, _ = do(echo.DELETE, settlements+"/"+paid.ID.Hex(), "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
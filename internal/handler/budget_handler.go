@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BudgetHandler controller for the budgets of the projects
+type BudgetHandler struct {
+	budgetModel   models.BudgetModeler
+	projectModel  models.ProjectModeler
+	categoryModel models.CategoryModeler
+	tracker       *models.BudgetTracker
+}
+
+// NewBudgetHandler godoc
+func NewBudgetHandler(bm models.BudgetModeler, pm models.ProjectModeler, cm models.CategoryModeler, t *models.BudgetTracker) BudgetHandler {
+	return BudgetHandler{bm, pm, cm, t}
+}
+
+// CreateBudget godoc
+// the amount is in the base currency of the project, a zero category budgets the whole project
+// @Summary Create a Project Budget.
+// @Description create a budget of the project, per category & period.
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param budget body models.BudgetInput true "Create Budget"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/projects/{id}/budgets [post]
+func (h BudgetHandler) CreateBudget(c echo.Context) error {
+	projectID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	input := new(models.BudgetInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	project, err := h.projectModel.ReadOne(c.Request().Context(), projectID)
+	if err != nil {
+		return err
+	}
+	var categoryID primitive.ObjectID
+	if input.CategoryID != "" {
+		if categoryID, err = objectIDFromStringID(input.CategoryID); err != nil {
+			return err
+		}
+		if _, err := h.categoryModel.ReadOne(c.Request().Context(), categoryID); err != nil {
+			return err
+		}
+	}
+
+	currency := project.BaseCurrency
+	if currency == "" {
+		currency = models.DefaultCurrency
+	}
+	amount, err := budgetAmount(input.Amount.String(), currency)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	budget := &models.Budget{
+		ID:         primitive.NewObjectID(),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		ProjectID:  projectID,
+		CategoryID: categoryID,
+		Period:     input.Period,
+		Amount:     amount,
+	}
+	id, err := h.budgetModel.Insert(c.Request().Context(), budget)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusCreated, id, i18n.BudgetCreated, c)
+}
+
+// GetBudgets godoc
+// @Summary Get the Project Budgets.
+// @Description get the budgets of the project
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/projects/{id}/budgets [get]
+func (h BudgetHandler) GetBudgets(c echo.Context) error {
+	projectID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	budgets, err := h.budgetModel.ReadAll(c.Request().Context(), projectID)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, budgets, i18n.BudgetDetails, c)
+}
+
+// UpdateBudget godoc
+// @Summary Update a Project Budget.
+// @Description change the amount of the budget
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param budgetId path string true "Budget ID"
+// @Param budget body models.BudgetUpdateInput true "Update Budget"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/projects/{id}/budgets/{budgetId} [put]
+func (h BudgetHandler) UpdateBudget(c echo.Context) error {
+	budget, err := h.readBudget(c)
+	if err != nil {
+		return err
+	}
+
+	input := new(models.BudgetUpdateInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+	amount, err := budgetAmount(input.Amount.String(), budget.Amount.Currency)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	count, err := h.budgetModel.UpdateOne(c.Request().Context(), budget.ID, amount)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, count, i18n.BudgetUpdated, c)
+}
+
+// DeleteBudget godoc
+// @Summary Delete a Project Budget.
+// @Description remove the budget
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param budgetId path string true "Budget ID"
+// @Success 202 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/projects/{id}/budgets/{budgetId} [delete]
+func (h BudgetHandler) DeleteBudget(c echo.Context) error {
+	budget, err := h.readBudget(c)
+	if err != nil {
+		return err
+	}
+	count, err := h.budgetModel.RemoveOne(c.Request().Context(), budget.ID)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusAccepted, count, i18n.BudgetRemoved, c)
+}
+
+// GetBudgetReport godoc
+// spent vs. budgeted vs. remaining of every budget in each of its periods overlapping the window,
+// the window is the one of the project details. The monthly & quarterly periods are reported whole.
+// @Summary Get the Project Budget utilization.
+// @Description get the utilization of the budgets of the project
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param start query string false "start period with a string representation of date 'YYYY-MM-DD'"
+// @Param end query string false "end period with a string representation of date 'YYYY-MM-DD'"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 422 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/projects/{id}/budgets/report [get]
+func (h BudgetHandler) GetBudgetReport(c echo.Context) error {
+	projectID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	filter, err := projectDetailsQS(c)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	lines, err := h.tracker.Report(c.Request().Context(), projectID, filter)
+	if errors.Is(err, models.ErrRateNotFound) {
+		return utils.Error(http.StatusUnprocessableEntity, err.Error(), c)
+	}
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, lines, i18n.BudgetReport, c)
+}
+
+// readBudget read the budget of the `:budgetId` path param, it must belong to the project of the `:id` path param
+func (h BudgetHandler) readBudget(c echo.Context) (models.Budget, error) {
+	projectID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return models.Budget{}, err
+	}
+	budgetID, err := objectIDFromStringID(c.Param("budgetId"))
+	if err != nil {
+		return models.Budget{}, err
+	}
+	budget, err := h.budgetModel.ReadOne(c.Request().Context(), budgetID)
+	if err != nil {
+		return budget, err
+	}
+	if budget.ProjectID != projectID {
+		return models.Budget{}, errs.NotFoundf(i18n.BudgetNotFound)
+	}
+	return budget, nil
+}
+
+// budgetAmount parse the amount of a budget, a budget can not be zero
+func budgetAmount(value, currency string) (models.Money, error) {
+	amount, err := models.ParseMoney(value, currency)
+	if err != nil {
+		return amount, err
+	}
+	if amount.IsZero() {
+		return amount, errors.New(i18n.BudgetAmountZero)
+	}
+	return amount, nil
+}
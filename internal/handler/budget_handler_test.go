@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBudgets(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	tracker := models.NewBudgetTracker(m, 80)
+	e := newTestEcho()
+	admin := models.User{ID: primitive.NewObjectID(), Name: "admin", Role: models.RoleAdmin, IsActive: true}
+	e.Use(asUser(admin))
+	h := NewBudgetHandler(m.Budgets, m.Projects, m.Categories, tracker)
+	eh := NewExpenseHandler(m.Expenses, m.Users, m.Categories, m.Projects, tracker)
+	e.POST("/projects/:id/budgets", h.CreateBudget)
+	e.GET("/projects/:id/budgets", h.GetBudgets)
+	e.GET("/projects/:id/budgets/report", h.GetBudgetReport)
+	e.PUT("/projects/:id/budgets/:budgetId", h.UpdateBudget)
+	e.DELETE("/projects/:id/budgets/:budgetId", h.DeleteBudget)
+	e.POST("/expenses", eh.CreateExpense)
+
+	do := func(method, path, body string) (int, json.RawMessage, json.RawMessage) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var res struct {
+			Data json.RawMessage `json:"data"`
+			Meta json.RawMessage `json:"meta"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		return rec.Code, res.Data, res.Meta
+	}
+
+	project := models.Project{ID: primitive.NewObjectID(), Title: "trip", BaseCurrency: "JPY", IsActive: true}
+	_, err := m.Projects.Insert(ctx, &project)
+	assert.NoError(t, err)
+	other := models.Project{ID: primitive.NewObjectID(), Title: "other", BaseCurrency: "EUR", IsActive: true}
+	_, err = m.Projects.Insert(ctx, &other)
+	assert.NoError(t, err)
+	hotel := models.Category{ID: primitive.NewObjectID(), Name: "Hotel", Slug: "hotel"}
+	_, err = m.Categories.Insert(ctx, &hotel)
+	assert.NoError(t, err)
+	budgets := "/projects/" + project.ID.Hex() + "/budgets"
+
+	code, data, _ := do(echo.POST, budgets, `{"category_id":"`+hotel.ID.Hex()+`","period":"monthly","amount":"10000"}`)
+	assert.Equal(t, http.StatusCreated, code)
+	var budgetID primitive.ObjectID
+	assert.NoError(t, json.Unmarshal(data, &budgetID))
+	for _, c := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"duplicate", `{"category_id":"` + hotel.ID.Hex() + `","period":"monthly","amount":"500"}`, http.StatusConflict},
+		{"unknown period", `{"period":"weekly","amount":"500"}`, http.StatusBadRequest},
+		{"zero amount", `{"period":"project","amount":"0"}`, http.StatusBadRequest},
+		{"too precise", `{"period":"project","amount":"10.5"}`, http.StatusBadRequest},
+		{"unknown category", `{"category_id":"` + primitive.NewObjectID().Hex() + `","period":"project","amount":"500"}`, http.StatusNotFound},
+	} {
+		code, _, _ := do(echo.POST, budgets, c.body)
+		assert.Equal(t, c.code, code, c.name)
+	}
+
+	// the amount is in the base currency of the project
+	budget, err := m.Budgets.ReadOne(ctx, budgetID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewMoney(10000, "JPY"), budget.Amount)
+
+	code, _, _ = do(echo.PUT, budgets+"/"+budgetID.Hex(), `{"amount":"20000"}`)
+	assert.Equal(t, http.StatusOK, code)
+	code, _, _ = do(echo.DELETE, "/projects/"+other.ID.Hex()+"/budgets/"+budgetID.Hex(), "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	expense := func(amount string) (int, models.BudgetAlert) {
+		code, _, meta := do(echo.POST, "/expenses", `{"date":"2021-03-04","title":"night","description":"hotel","total":"`+amount+
+			`","currency":"JPY","category_id":"`+hotel.ID.Hex()+`","project_id":"`+project.ID.Hex()+`"}`)
+		var alert models.BudgetAlert
+		assert.NoError(t, json.Unmarshal(meta, &alert))
+		return code, alert
+	}
+	code, alert := expense("15000")
+	assert.Equal(t, http.StatusCreated, code)
+	assert.False(t, alert.Alert)
+	assert.Equal(t, int64(80), alert.Threshold)
+	code, alert = expense("1000")
+	assert.Equal(t, http.StatusCreated, code)
+	assert.True(t, alert.Alert)
+	if assert.Len(t, alert.Budgets, 1) {
+		assert.Equal(t, budgetID, alert.Budgets[0].BudgetID)
+	}
+
+	code, data, _ = do(echo.GET, budgets+"/report?start=2021-03-01&end=2021-04-01", "")
+	assert.Equal(t, http.StatusOK, code)
+	var lines []struct {
+		Spent       models.Money `json:"spent"`
+		Remaining   models.Money `json:"remaining"`
+		Utilization float64      `json:"utilization"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &lines))
+	if assert.Len(t, lines, 1) {
+		assert.Equal(t, models.NewMoney(16000, "JPY"), lines[0].Spent)
+		assert.Equal(t, models.NewMoney(4000, "JPY"), lines[0].Remaining)
+		assert.Equal(t, 80.0, lines[0].Utilization)
+	}
+	code, _, _ = do(echo.GET, budgets+"/report?start=March", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _, _ = do(echo.DELETE, budgets+"/"+budgetID.Hex(), "")
+	assert.Equal(t, http.StatusAccepted, code)
+	code, data, _ = do(echo.GET, budgets, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `[]`, string(data))
+}
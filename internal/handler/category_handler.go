@@ -6,20 +6,24 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
 	"github.com/masihur1989/expense-tracker-api/internal/models"
 	"github.com/masihur1989/expense-tracker-api/internal/utils"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // CategoryHandler godoc
 type CategoryHandler struct {
-	catModel models.CategoryModeler
+	catModel       models.CategoryModeler
+	expenseModel   models.ExpenseModeler
+	budgetModel    models.BudgetModeler
+	recurringModel models.RecurringExpenseModeler
 }
 
 // NewCategoryHandler godoc
-func NewCategoryHandler(cm models.CategoryModeler) CategoryHandler {
-	return CategoryHandler{cm}
+func NewCategoryHandler(cm models.CategoryModeler, em models.ExpenseModeler, bm models.BudgetModeler, rm models.RecurringExpenseModeler) CategoryHandler {
+	return CategoryHandler{cm, em, bm, rm}
 }
 
 // CreateCategory godoc
@@ -28,20 +32,46 @@ func NewCategoryHandler(cm models.CategoryModeler) CategoryHandler {
 // @Tags categories
 // @Accept json
 // @Produce json
-// @Param category body models.Category true "Create Category"
+// @Param category body models.CategoryInput true "Create Category"
 // @Success 201 {object} utils.Response
 // @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/categories [post]
 func (c CategoryHandler) CreateCategory(e echo.Context) error {
-	cat := new(models.Category)
-	if err := e.Bind(cat); err != nil {
+	catInput := new(models.CategoryInput)
+	if err := e.Bind(catInput); err != nil {
 		log.Printf("ECHO BINDING ERROR: %v\n", err)
 		return err
 	}
 
-	if err := e.Validate(cat); err != nil {
-		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	if err := e.Validate(catInput); err != nil {
+		return err
+	}
+
+	cat := &models.Category{
+		Name:  catInput.Name,
+		Slug:  catInput.Slug,
+		Color: catInput.Color,
+		Icon:  catInput.Icon,
+	}
+	if cat.Slug == "" {
+		cat.Slug = models.Slugify(cat.Name)
+	}
+	if cat.Slug == "" {
+		return utils.Error(http.StatusBadRequest, i18n.CategorySlugEmpty, e)
+	}
+	if catInput.ParentID != "" {
+		parentID, err := objectIDFromStringID(catInput.ParentID)
+		if err != nil {
+			return utils.Error(http.StatusBadRequest, err.Error(), e)
+		}
+		parent, err := c.catModel.ReadOne(e.Request().Context(), parentID)
+		if err != nil {
+			return err
+		}
+		cat.ParentID = parent.ID
 	}
 
 	// fill the nil values
@@ -49,14 +79,13 @@ func (c CategoryHandler) CreateCategory(e echo.Context) error {
 	cat.CreatedAt = time.Now()
 	cat.UpdatedAt = time.Now()
 
-	id, err := c.catModel.Insert(cat)
+	id, err := c.catModel.Insert(e.Request().Context(), cat)
 
 	if err != nil {
-		log.Printf("RESPONSE ERROR: %v\n", err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), e)
+		return err
 	}
 
-	return utils.Data(http.StatusCreated, id, "category created", e)
+	return utils.Data(http.StatusCreated, id, i18n.CategoryCreated, e)
 }
 
 // GetCategories godoc
@@ -69,41 +98,41 @@ func (c CategoryHandler) CreateCategory(e echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param name query string false "name search by name"
+// @Param limit query int false "page size, 50 by default and at most 500"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "comma separated sort fields, prefix with - for descending"
+// @Param fields query string false "comma separated fields to return"
+// @Param count query bool false "include the total count"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/categories [get]
 func (c CategoryHandler) GetCategories(e echo.Context) error {
-	qs := e.QueryParams()
-	var filter interface{}
-	if len(e.QueryParams()) == 0 {
-		filter = bson.D{}
-	} else {
-		f := bson.M{}
-		if x, ok := qs["name"]; ok {
-			f["name"] = x[0]
-		}
-		filter = f
+	opts, err := listOptions(e, models.CategoryListFields, nil)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
+	filter := models.CategoryFilter{Name: e.QueryParam("name")}
 
-	cats, err := c.catModel.ReadAll(filter)
+	cats, page, err := c.catModel.ReadAll(e.Request().Context(), filter, opts)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), e)
+		return err
 	}
-	return utils.Data(http.StatusOK, cats, "category details", e)
+	return listData(cats, page, opts, i18n.CategoryDetails, e)
 }
 
 // DeleteCategory godoc
 // @Summary Delete a Category.
-// @Description get category by ID
+// @Description delete category by ID, the expenses of a category in use are moved to the replacement
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Param id path string true "Category ID"
+// @Param replacement query string false "category receiving the expenses of the removed one"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Router /api/v1/categories/{id} [delete]
 func (c CategoryHandler) DeleteCategory(e echo.Context) error {
 	ID, err := objectIDFromStringID(e.Param("id"))
@@ -111,12 +140,68 @@ func (c CategoryHandler) DeleteCategory(e echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
 
-	count, err := c.catModel.RemoveOne(bson.M{"_id": ID})
+	// the subcategories would become roots, they are moved or merged explicitly
+	tree, err := c.readTree(e)
+	if err != nil {
+		return err
+	}
+	if len(tree.Children(ID)) > 0 {
+		return utils.Error(http.StatusConflict, i18n.CategoryHasChildren, e)
+	}
+
+	// the expenses, budgets & recurring expenses must never lose their category, they are moved to the replacement first
+	ctx := e.Request().Context()
+	if param := e.QueryParam("replacement"); param != "" {
+		replacementID, err := objectIDFromStringID(param)
+		if err != nil {
+			return utils.Error(http.StatusBadRequest, err.Error(), e)
+		}
+		if replacementID == ID {
+			return utils.Error(http.StatusBadRequest, i18n.CategoryReplacementSelf, e)
+		}
+		replacement, err := c.catModel.ReadOne(ctx, replacementID)
+		if err != nil {
+			return err
+		}
+		if err := c.reassignBudgets(e, ID, replacementID); err != nil {
+			return err
+		}
+		if _, err := c.expenseModel.ReassignCategory(ctx, ID, replacement.Snapshot()); err != nil {
+			return err
+		}
+		if _, err := c.recurringModel.ReassignCategory(ctx, ID, replacement.Snapshot()); err != nil {
+			return err
+		}
+	} else {
+		inUse, _, err := c.expenseModel.ReadAll(ctx, models.ExpenseFilter{Categories: []string{ID.Hex()}}, models.ListOptions{Limit: 1})
+		if err != nil {
+			return err
+		}
+		if len(inUse) > 0 {
+			return utils.Error(http.StatusConflict, i18n.CategoryInUse, e)
+		}
+		// a removed category would end its recurring expenses
+		series, err := c.recurringModel.CountCategory(ctx, ID)
+		if err != nil {
+			return err
+		}
+		if series > 0 {
+			return utils.Error(http.StatusConflict, i18n.CategoryInUse, e)
+		}
+		budgets, err := c.budgetModel.CountCategory(ctx, ID)
+		if err != nil {
+			return err
+		}
+		if budgets > 0 {
+			return utils.Error(http.StatusConflict, i18n.CategoryHasBudgets, e)
+		}
+	}
+
+	count, err := c.catModel.RemoveOne(ctx, ID)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), e)
+		return err
 	}
-	return utils.Data(http.StatusAccepted, count, "category removed", e)
+	return utils.Data(http.StatusAccepted, count, i18n.CategoryRemoved, e)
 }
 
 // UpdateCategory godoc
@@ -130,6 +215,7 @@ func (c CategoryHandler) DeleteCategory(e echo.Context) error {
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Router /api/v1/categories/{id} [put]
 func (c CategoryHandler) UpdateCategory(e echo.Context) error {
 	ID, err := objectIDFromStringID(e.Param("id"))
@@ -145,18 +231,165 @@ func (c CategoryHandler) UpdateCategory(e echo.Context) error {
 	}
 
 	if err := e.Validate(catInput); err != nil {
+		return err
+	}
+
+	if catInput.Slug == "" {
+		catInput.Slug = models.Slugify(catInput.Name)
+	}
+	if catInput.Slug == "" {
+		return utils.Error(http.StatusBadRequest, i18n.CategorySlugEmpty, e)
+	}
+
+	// update fields - name, slug, color, icon
+	count, err := c.catModel.UpdateOne(e.Request().Context(), ID, *catInput)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, count, i18n.CategoryUpdated, e)
+}
+
+// MoveCategory godoc
+// @Summary Move a Category.
+// @Description change the parent of the category, with its subcategories
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param move body models.CategoryMoveInput true "New parent, empty for a root category"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/categories/{id}/move [post]
+func (c CategoryHandler) MoveCategory(e echo.Context) error {
+	ID, err := objectIDFromStringID(e.Param("id"))
+	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
 
-	// update fields - name
-	update := bson.M{
-		"name": catInput.Name,
+	moveInput := new(models.CategoryMoveInput)
+	if err := e.Bind(moveInput); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
 	}
+	if err := e.Validate(moveInput); err != nil {
+		return err
+	}
+
+	if _, err := c.catModel.ReadOne(e.Request().Context(), ID); err != nil {
+		return err
+	}
+	parentID := primitive.NilObjectID
+	if moveInput.ParentID != "" {
+		if parentID, err = objectIDFromStringID(moveInput.ParentID); err != nil {
+			return utils.Error(http.StatusBadRequest, err.Error(), e)
+		}
+		if _, err := c.catModel.ReadOne(e.Request().Context(), parentID); err != nil {
+			return err
+		}
+		tree, err := c.readTree(e)
+		if err != nil {
+			return err
+		}
+		// a category moved below its own subtree would be cut off from the roots
+		if tree.IsDescendant(parentID, ID) {
+			return utils.Error(http.StatusConflict, i18n.CategoryCycle, e)
+		}
+	}
+
+	count, err := c.catModel.Move(e.Request().Context(), ID, parentID)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, count, i18n.CategoryMoved, e)
+}
+
+// MergeCategory godoc
+// @Summary Merge a Category.
+// @Description move the expenses & the subcategories of the category into another one and remove it
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param merge body models.CategoryMergeInput true "Category receiving the expenses"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/categories/{id}/merge [post]
+func (c CategoryHandler) MergeCategory(e echo.Context) error {
+	ID, err := objectIDFromStringID(e.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	}
+
+	mergeInput := new(models.CategoryMergeInput)
+	if err := e.Bind(mergeInput); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := e.Validate(mergeInput); err != nil {
+		return err
+	}
+	intoID, err := objectIDFromStringID(mergeInput.IntoID)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	}
+
+	if _, err := c.catModel.ReadOne(e.Request().Context(), ID); err != nil {
+		return err
+	}
+	into, err := c.catModel.ReadOne(e.Request().Context(), intoID)
+	if err != nil {
+		return err
+	}
+	tree, err := c.readTree(e)
+	if err != nil {
+		return err
+	}
+	if tree.IsDescendant(intoID, ID) {
+		return utils.Error(http.StatusConflict, i18n.CategoryMergeCycle, e)
+	}
+
+	// the budgets & expenses first, a failed merge can be run again without losing any of them
+	ctx := e.Request().Context()
+	if err := c.reassignBudgets(e, ID, intoID); err != nil {
+		return err
+	}
+	count, err := c.expenseModel.ReassignCategory(ctx, ID, into.Snapshot())
+	if err != nil {
+		return err
+	}
+	if _, err := c.recurringModel.ReassignCategory(ctx, ID, into.Snapshot()); err != nil {
+		return err
+	}
+	for _, child := range tree.Children(ID) {
+		if _, err := c.catModel.Move(ctx, child.ID, intoID); err != nil {
+			return err
+		}
+	}
+	if _, err := c.catModel.RemoveOne(ctx, ID); err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, count, i18n.CategoryMerged, e)
+}
+
+// reassignBudgets move the budgets of the category to another one,
+// refused when a project would have two budgets on the same category & period
+func (c CategoryHandler) reassignBudgets(e echo.Context, from, to primitive.ObjectID) error {
+	_, err := c.budgetModel.ReassignCategory(e.Request().Context(), from, to)
+	if errs.Is(err, errs.Conflict) {
+		return echo.NewHTTPError(http.StatusConflict, i18n.CategoryBudgetsOverlap)
+	}
+	return err
+}
 
-	count, err := c.catModel.UpdateOne(update, bson.M{"_id": ID})
+// readTree the tree of every category
+func (c CategoryHandler) readTree(e echo.Context) (models.CategoryTree, error) {
+	categories, err := c.catModel.ReadTree(e.Request().Context())
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), e)
+		return models.CategoryTree{}, err
 	}
-	return utils.Data(http.StatusOK, count, "category updated", e)
+	return models.NewCategoryTree(categories), nil
 }
@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCategoryTree(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	e := newTestEcho()
+	e.Use(asUser(models.User{ID: primitive.NewObjectID(), Role: models.RoleAdmin, IsActive: true}))
+	h := NewCategoryHandler(m.Categories, m.Expenses, m.Budgets, m.Recurring)
+	eh := NewExpenseHandler(m.Expenses, m.Users, m.Categories, m.Projects, nil)
+	e.POST("/categories", h.CreateCategory)
+	e.DELETE("/categories/:id", h.DeleteCategory)
+	e.POST("/categories/:id/move", h.MoveCategory)
+	e.POST("/categories/:id/merge", h.MergeCategory)
+	e.GET("/expenses", eh.GetExpenses)
+	e.GET("/expenses/category-totals", eh.GetCategoryTotals)
+
+	do := func(method, path, body string) (int, json.RawMessage) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		return rec.Code, res.Data
+	}
+	create := func(body string) primitive.ObjectID {
+		code, data := do(echo.POST, "/categories", body)
+		assert.Equal(t, http.StatusCreated, code, body)
+		var id primitive.ObjectID
+		assert.NoError(t, json.Unmarshal(data, &id))
+		return id
+	}
+
+	travel := create(`{"name":"Travel > Abroad","color":"#00aaff","icon":"plane"}`)
+	hotel := create(`{"name":"Hotel","parent_id":"` + travel.Hex() + `"}`)
+	suite := create(`{"name":"Suite","parent_id":"` + hotel.Hex() + `"}`)
+	lodging := create(`{"name":"Unterkunft & Übernachtung"}`)
+
+	category, err := m.Categories.ReadOne(ctx, travel)
+	assert.NoError(t, err)
+	assert.Equal(t, "travel-abroad", category.Slug)
+	category, err = m.Categories.ReadOne(ctx, lodging)
+	assert.NoError(t, err)
+	assert.Equal(t, "unterkunft-übernachtung", category.Slug)
+
+	for _, c := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"duplicate slug", `{"name":"hotel"}`, http.StatusConflict},
+		{"invalid slug", `{"name":"Hotel","slug":"Hotel Suite"}`, http.StatusBadRequest},
+		{"invalid color", `{"name":"Food","color":"blue"}`, http.StatusBadRequest},
+		{"unknown parent", `{"name":"Food","parent_id":"` + primitive.NewObjectID().Hex() + `"}`, http.StatusNotFound},
+		{"no slug", `{"name":"!!!"}`, http.StatusBadRequest},
+	} {
+		code, _ := do(echo.POST, "/categories", c.body)
+		assert.Equal(t, c.code, code, c.name)
+	}
+
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, x := range []struct {
+		category primitive.ObjectID
+		amount   int64
+	}{{travel, 500}, {hotel, 12000}, {suite, 30000}, {lodging, 8000}} {
+		category, _ := m.Categories.ReadOne(ctx, x.category)
+		_, err := m.Expenses.Insert(ctx, models.Expense{ID: primitive.NewObjectID(), Date: day, Total: models.NewMoney(x.amount, "EUR"), Category: category.Snapshot()})
+		assert.NoError(t, err)
+	}
+
+	// the subcategories are matched on demand
+	code, data := do(echo.GET, "/expenses?category="+hotel.Hex(), "")
+	assert.Equal(t, http.StatusOK, code)
+	var expenses []models.Expense
+	assert.NoError(t, json.Unmarshal(data, &expenses))
+	assert.Len(t, expenses, 1)
+	code, data = do(echo.GET, "/expenses?subcategories=true&category="+hotel.Hex(), "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.NoError(t, json.Unmarshal(data, &expenses))
+	assert.Len(t, expenses, 2)
+
+	// no cycles and no orphans
+	code, _ = do(echo.POST, "/categories/"+travel.Hex()+"/move", `{"parent_id":"`+suite.Hex()+`"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = do(echo.POST, "/categories/"+hotel.Hex()+"/merge", `{"into_id":"`+suite.Hex()+`"}`)
+	assert.Equal(t, http.StatusConflict, code)
+	code, _ = do(echo.DELETE, "/categories/"+travel.Hex(), "")
+	assert.Equal(t, http.StatusConflict, code)
+
+	// lodging takes the place of hotel, with its expenses, its recurring expenses & its subcategories
+	hotelCategory, _ := m.Categories.ReadOne(ctx, hotel)
+	series := models.RecurringExpense{ID: primitive.NewObjectID(), Category: hotelCategory.Snapshot(), RRule: "FREQ=MONTHLY", Status: models.RecurringActive}
+	_, err = m.Recurring.Insert(ctx, &series)
+	assert.NoError(t, err)
+	code, _ = do(echo.POST, "/categories/"+lodging.Hex()+"/move", `{"parent_id":"`+travel.Hex()+`"}`)
+	assert.Equal(t, http.StatusOK, code)
+	code, data = do(echo.POST, "/categories/"+hotel.Hex()+"/merge", `{"into_id":"`+lodging.Hex()+`"}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "1", string(data))
+	_, err = m.Categories.ReadOne(ctx, hotel)
+	assert.Error(t, err)
+	category, err = m.Categories.ReadOne(ctx, suite)
+	assert.NoError(t, err)
+	assert.Equal(t, lodging, category.ParentID)
+	series, err = m.Recurring.ReadOne(ctx, series.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, lodging, series.Category.ID)
+
+	code, data = do(echo.GET, "/expenses/category-totals", "")
+	assert.Equal(t, http.StatusOK, code)
+	var nodes []models.CategoryNode
+	assert.NoError(t, json.Unmarshal(data, &nodes))
+	if assert.Len(t, nodes, 1) && assert.Len(t, nodes[0].Children, 1) {
+		assert.Equal(t, travel, nodes[0].Category.ID)
+		assert.Equal(t, []models.Money{models.NewMoney(50500, "EUR")}, nodes[0].Total)
+		assert.Equal(t, int64(4), nodes[0].Count)
+		assert.Equal(t, lodging, nodes[0].Children[0].Category.ID)
+		assert.Equal(t, []models.Money{models.NewMoney(20000, "EUR")}, nodes[0].Children[0].Own)
+		assert.Equal(t, []models.Money{models.NewMoney(50000, "EUR")}, nodes[0].Children[0].Total)
+	}
+}
+
+func TestDeleteCategoryInUse(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	e := newTestEcho()
+	h := NewCategoryHandler(m.Categories, m.Expenses, m.Budgets, m.Recurring)
+	e.DELETE("/categories/:id", h.DeleteCategory)
+
+	food := models.Category{ID: primitive.NewObjectID(), Name: "Food", Slug: "food"}
+	meals := models.Category{ID: primitive.NewObjectID(), Name: "Meals", Slug: "meals"}
+	unused := models.Category{ID: primitive.NewObjectID(), Name: "Unused", Slug: "unused"}
+	scheduled := models.Category{ID: primitive.NewObjectID(), Name: "Scheduled", Slug: "scheduled"}
+	for _, c := range []models.Category{food, meals, unused, scheduled} {
+		c := c
+		_, err := m.Categories.Insert(ctx, &c)
+		assert.NoError(t, err)
+	}
+	expense := models.Expense{ID: primitive.NewObjectID(), Total: models.NewMoney(1500, "EUR"), Category: food.Snapshot()}
+	_, err := m.Expenses.Insert(ctx, expense)
+	assert.NoError(t, err)
+	series := models.RecurringExpense{ID: primitive.NewObjectID(), Category: food.Snapshot(), RRule: "FREQ=MONTHLY", Status: models.RecurringActive}
+	_, err = m.Recurring.Insert(ctx, &series)
+	assert.NoError(t, err)
+	// a paused series is resumed later, it keeps its category
+	paused := models.RecurringExpense{ID: primitive.NewObjectID(), Category: scheduled.Snapshot(), RRule: "FREQ=MONTHLY", Status: models.RecurringPaused}
+	_, err = m.Recurring.Insert(ctx, &paused)
+	assert.NoError(t, err)
+
+	for _, c := range []struct {
+		name string
+		path string
+		code int
+	}{
+		{"unused", "/categories/" + unused.ID.Hex(), http.StatusAccepted},
+		{"in use", "/categories/" + food.ID.Hex(), http.StatusConflict},
+		{"used by a recurring expense", "/categories/" + scheduled.ID.Hex(), http.StatusConflict},
+		{"replaced by itself", "/categories/" + food.ID.Hex() + "?replacement=" + food.ID.Hex(), http.StatusBadRequest},
+		{"unknown replacement", "/categories/" + food.ID.Hex() + "?replacement=" + primitive.NewObjectID().Hex(), http.StatusNotFound},
+		{"replaced", "/categories/" + food.ID.Hex() + "?replacement=" + meals.ID.Hex(), http.StatusAccepted},
+	} {
+		req := httptest.NewRequest(echo.DELETE, c.path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, c.code, rec.Code, c.name)
+	}
+
+	stored, err := m.Expenses.ReadOne(ctx, expense.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, meals.Snapshot(), stored.Category)
+	series, err = m.Recurring.ReadOne(ctx, series.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, meals.Snapshot(), series.Category)
+	_, err = m.Categories.ReadOne(ctx, food.ID)
+	assert.Error(t, err)
+}
+
+func TestCategoryBudgets(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	e := newTestEcho()
+	h := NewCategoryHandler(m.Categories, m.Expenses, m.Budgets, m.Recurring)
+	e.DELETE("/categories/:id", h.DeleteCategory)
+	e.POST("/categories/:id/merge", h.MergeCategory)
+
+	food := models.Category{ID: primitive.NewObjectID(), Name: "Food", Slug: "food"}
+	meals := models.Category{ID: primitive.NewObjectID(), Name: "Meals", Slug: "meals"}
+	drinks := models.Category{ID: primitive.NewObjectID(), Name: "Drinks", Slug: "drinks"}
+	for _, c := range []models.Category{food, meals, drinks} {
+		c := c
+		_, err := m.Categories.Insert(ctx, &c)
+		assert.NoError(t, err)
+	}
+	projectID := primitive.NewObjectID()
+	budget := func(category primitive.ObjectID, period models.BudgetPeriod) primitive.ObjectID {
+		b := models.Budget{ID: primitive.NewObjectID(), ProjectID: projectID, CategoryID: category, Period: period, Amount: models.NewMoney(10000, "EUR")}
+		_, err := m.Budgets.Insert(ctx, &b)
+		assert.NoError(t, err)
+		return b.ID
+	}
+	onFood := budget(food.ID, models.BudgetMonthly)
+	onMeals := budget(meals.ID, models.BudgetMonthly)
+	onDrinks := budget(drinks.ID, models.BudgetQuarterly)
+
+	for _, c := range []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"budgets without replacement", echo.DELETE, "/categories/" + drinks.ID.Hex(), "", http.StatusConflict},
+		{"budgets in the same period", echo.POST, "/categories/" + food.ID.Hex() + "/merge", `{"into_id":"` + meals.ID.Hex() + `"}`, http.StatusConflict},
+		{"replaced", echo.DELETE, "/categories/" + drinks.ID.Hex() + "?replacement=" + food.ID.Hex(), "", http.StatusAccepted},
+	} {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, c.code, rec.Code, c.name)
+	}
+
+	// the refused merge left both categories & their budgets, the replaced one moved its budget
+	for _, c := range []struct {
+		budget   primitive.ObjectID
+		category primitive.ObjectID
+	}{
+		{onFood, food.ID},
+		{onMeals, meals.ID},
+		{onDrinks, food.ID},
+	} {
+		stored, err := m.Budgets.ReadOne(ctx, c.budget)
+		assert.NoError(t, err)
+		assert.Equal(t, c.category, stored.CategoryID)
+	}
+	_, err := m.Categories.ReadOne(ctx, meals.ID)
+	assert.NoError(t, err)
+
+	_, err = m.Budgets.RemoveOne(ctx, onMeals)
+	assert.NoError(t, err)
+	req := httptest.NewRequest(echo.POST, "/categories/"+food.ID.Hex()+"/merge", strings.NewReader(`{"into_id":"`+meals.ID.Hex()+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	for _, id := range []primitive.ObjectID{onFood, onDrinks} {
+		stored, err := m.Budgets.ReadOne(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, meals.ID, stored.CategoryID)
+	}
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+)
+
+// statusCodes status code of each kind of typed error
+var statusCodes = map[errs.Kind]int{
+	errs.Internal:    http.StatusInternalServerError,
+	errs.NotFound:    http.StatusNotFound,
+	errs.Conflict:    http.StatusConflict,
+	errs.Validation:  http.StatusBadRequest,
+	errs.Unavailable: http.StatusServiceUnavailable,
+	errs.Timeout:     http.StatusGatewayTimeout,
+}
+
+// HTTPErrorHandler respond to the errors returned by the handlers in the utils.Response envelope
+// or as problem details, the typed errors of the models are mapped to the status code of their kind
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	err = models.LocalizeValidation(err, i18n.Translator(c))
+	kind := errs.KindOf(err)
+	status, code, message := statusCodes[kind], kind.String(), errs.Message(err)
+	var typed *errs.Error
+	if he, ok := err.(*echo.HTTPError); ok {
+		status, message = he.Code, fmt.Sprint(he.Message)
+		code = utils.StatusCode(status)
+	} else if !errors.As(err, &typed) {
+		// the untyped errors are unexpected, their details are only logged
+		message = http.StatusText(status)
+	}
+	if status >= http.StatusInternalServerError {
+		log.Printf("RESPONSE ERROR: %v\n", err)
+	}
+	if err := utils.Fail(status, code, message, errs.FieldsOf(err), c); err != nil {
+		log.Printf("RESPONSE ERROR: %v\n", err)
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPErrorHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"not found", errs.NotFoundf("expense not found"), http.StatusNotFound},
+		{"conflict", errs.New(errs.Conflict, "duplicate key"), http.StatusConflict},
+		{"validation", errs.Validationf("invalid cursor"), http.StatusBadRequest},
+		{"unavailable", errs.New(errs.Unavailable, "the storage is unavailable"), http.StatusServiceUnavailable},
+		{"timeout", errs.New(errs.Timeout, "the storage did not answer in time"), http.StatusGatewayTimeout},
+		{"internal", errors.New("boom"), http.StatusInternalServerError},
+		{"echo", echo.NewHTTPError(http.StatusForbidden, "permission denied"), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(echo.GET, "/", nil), rec)
+			HTTPErrorHandler(tt.err, c)
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+}
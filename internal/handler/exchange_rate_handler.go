@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+)
+
+// ExchangeRateHandler godoc
+type ExchangeRateHandler struct {
+	rateModel models.ExchangeRateModeler
+}
+
+// NewExchangeRateHandler godoc
+func NewExchangeRateHandler(rm models.ExchangeRateModeler) ExchangeRateHandler {
+	return ExchangeRateHandler{rm}
+}
+
+// CreateExchangeRates godoc
+// load exchange rates from a JSON array or from a CSV body with the header `from,to,date,rate`
+// rates of the same pair & date are replaced
+// @Summary Load exchange rates.
+// @Description load exchange rates from JSON or CSV.
+// @Tags exchange-rates
+// @Accept json,text/csv
+// @Produce json
+// @Param rates body []models.ExchangeRateInput true "Exchange Rates"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/exchange-rates [post]
+func (h ExchangeRateHandler) CreateExchangeRates(c echo.Context) error {
+	var inputs []models.ExchangeRateInput
+	var err error
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), "text/csv") {
+		inputs, err = models.ParseExchangeRatesCSV(c.Request().Body)
+	} else {
+		inputs, err = models.ParseExchangeRatesJSON(c.Request().Body)
+	}
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	rates, err := ValidateExchangeRates(c.Echo().Validator, inputs)
+	if errs.Is(err, errs.Validation) {
+		return err
+	}
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	count, err := h.rateModel.Upsert(c.Request().Context(), rates)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusCreated, count, i18n.ExchangeRatesLoaded, c)
+}
+
+// GetExchangeRates godoc
+// @Summary Get exchange rates.
+// @Description get exchange rates, latest first
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Param from query string false "from currency code"
+// @Param to query string false "to currency code"
+// @Param limit query int false "page size, 50 by default and at most 500"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "comma separated sort fields, prefix with - for descending"
+// @Param fields query string false "comma separated fields to return"
+// @Param count query bool false "include the total count"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/exchange-rates [get]
+func (h ExchangeRateHandler) GetExchangeRates(c echo.Context) error {
+	opts, err := listOptions(c, models.ExchangeRateListFields, models.ExchangeRateDefaultSort)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	filter := models.ExchangeRateFilter{
+		From: strings.ToUpper(c.QueryParam("from")),
+		To:   strings.ToUpper(c.QueryParam("to")),
+	}
+
+	rates, page, err := h.rateModel.ReadAll(c.Request().Context(), filter, opts)
+	if err != nil {
+		return err
+	}
+	return listData(rates, page, opts, i18n.ExchangeRates, c)
+}
+
+// ValidateExchangeRates validate the inputs and convert them to the storage model
+func ValidateExchangeRates(v echo.Validator, inputs []models.ExchangeRateInput) ([]models.ExchangeRate, error) {
+	rates := make([]models.ExchangeRate, 0, len(inputs))
+	for idx, input := range inputs {
+		input.From = strings.ToUpper(input.From)
+		input.To = strings.ToUpper(input.To)
+		if err := v.Validate(input); err != nil {
+			return nil, rateFieldsError(err, idx)
+		}
+		rate, err := input.ToExchangeRate()
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
+
+// rateFieldsError prefix the path of the failed fields with the index of the rate in the request
+func rateFieldsError(err error, idx int) error {
+	fields := errs.FieldsOf(err)
+	if fields == nil {
+		return err
+	}
+	prefixed := make([]errs.FieldError, len(fields))
+	for i, field := range fields {
+		field.Field = fmt.Sprintf("[%d].%s", idx, field.Field)
+		prefixed[i] = field
+	}
+	return &errs.Error{Kind: errs.Validation, Message: errs.Message(err), Err: err, Fields: prefixed}
+}
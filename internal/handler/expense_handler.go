@@ -1,14 +1,17 @@
 package handler
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
 	"github.com/masihur1989/expense-tracker-api/internal/models"
 	"github.com/masihur1989/expense-tracker-api/internal/utils"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -17,14 +20,17 @@ type ExpenseHandler struct {
 	expenseModel  models.ExpenseModeler
 	userModel     models.UserModel
 	categoryModel models.CategoryModeler
+	projectModel  models.ProjectModeler
+	budgets       *models.BudgetTracker // nil when the budgets are not checked
 }
 
 // NewExpenseHandler godoc
-func NewExpenseHandler(em models.ExpenseModeler, um models.UserModel, cm models.CategoryModeler) ExpenseHandler {
-	return ExpenseHandler{em, um, cm}
+func NewExpenseHandler(em models.ExpenseModeler, um models.UserModel, cm models.CategoryModeler, pm models.ProjectModeler, bt *models.BudgetTracker) ExpenseHandler {
+	return ExpenseHandler{em, um, cm, pm, bt}
 }
 
 // CreateExpense godoc
+// the meta of the response flags the project budgets the expense pushed over the alert threshold
 // @Summary Create expense.
 // @Description create expense.
 // @Tags expenses
@@ -44,7 +50,7 @@ func (e ExpenseHandler) CreateExpense(c echo.Context) error {
 	}
 
 	if err := c.Validate(expInput); err != nil {
-		return utils.Error(http.StatusBadRequest, err.Error(), c)
+		return err
 	}
 
 	categoryID, err := objectIDFromStringID(expInput.CategoryID)
@@ -52,27 +58,36 @@ func (e ExpenseHandler) CreateExpense(c echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
-	category, err := e.categoryModel.ReadOne(bson.M{"_id": categoryID})
+	category, err := e.categoryModel.ReadOne(c.Request().Context(), categoryID)
 	if err != nil {
-		log.Printf("CATEGORY NOT FOUND ERROR: %v\n", err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return err
 	}
 
-	userID, err := objectIDFromStringID(expInput.InsertedBy)
+	// the author is always the authenticated user
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+
+	d, err := parseDateToFormat("2006-01-02", expInput.Date)
+	if err != nil {
+		log.Printf("Time Parsing Error %v", err)
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+	}
+
+	total, err := expInput.Money()
 	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
-	user, err := e.userModel.ReadOneUser(bson.M{"_id": userID})
+	projectID, err := projectToFileIn(c.Request().Context(), e.projectModel, user, expInput.ProjectID)
 	if err != nil {
-		log.Printf("USER NOT FOUND ERROR: %v\n", err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return err
 	}
 
-	d, err := parseDateToFormat("2006-01-02", expInput.Date)
+	paidBy, splits, err := e.shareExpense(c.Request().Context(), expInput, total, projectID, user.ID)
 	if err != nil {
-		log.Printf("Time Parsing Error %v", err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+		return err
 	}
 
 	exp := models.Expense{
@@ -82,81 +97,217 @@ func (e ExpenseHandler) CreateExpense(c echo.Context) error {
 		Title:       expInput.Title,
 		Description: expInput.Description,
 		Date:        d,
-		Category:    category,
+		Category:    category.Snapshot(),
 		Location:    expInput.Location,
-		Total:       expInput.Total,
-		Status:      expInput.Status,
-		InsertedBy:  user,
+		Tags:        expInput.NormalizedTags(),
+		Total:       total,
+		Status:      models.StatusDraft,
+		ProjectID:   projectID,
+		InsertedBy:  user.Snapshot(),
+		PaidBy:      paidBy,
+		SplitMethod: splitMethod(splits, expInput.SplitMethod),
+		Splits:      splits,
+		History:     []models.StatusTransition{},
+		Attachments: []models.Attachment{},
 	}
 
-	id, err := e.expenseModel.Insert(exp)
+	id, err := e.expenseModel.Insert(c.Request().Context(), exp)
 
 	if err != nil {
-		log.Printf("RESPONSE ERROR: %v\n", err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+		return err
 	}
 
-	return utils.Data(http.StatusCreated, id, "expense created", c)
+	return utils.DataMeta(http.StatusCreated, id, e.checkBudgets(c.Request().Context(), exp), i18n.ExpenseCreated, c)
+}
+
+// shareExpense the payer & the splits of the expense input, the author pays by default.
+// Only the expenses of a project are shared, between its active members.
+func (e ExpenseHandler) shareExpense(ctx context.Context, input *models.ExpenseInput, total models.Money, projectID, authorID primitive.ObjectID) (primitive.ObjectID, []models.Split, error) {
+	paidBy := authorID
+	if input.PaidBy != "" {
+		id, err := objectIDFromStringID(input.PaidBy)
+		if err != nil {
+			return paidBy, nil, err
+		}
+		paidBy = id
+	}
+	if paidBy == authorID && len(input.Splits) == 0 {
+		return paidBy, []models.Split{}, nil
+	}
+	if projectID.IsZero() {
+		return paidBy, nil, echo.NewHTTPError(http.StatusBadRequest, i18n.ExpenseNotShared)
+	}
+
+	splits, err := models.ResolveSplits(total, input.SplitMethod, input.Splits)
+	if err != nil {
+		return paidBy, nil, err
+	}
+	users := []primitive.ObjectID{paidBy}
+	for _, split := range splits {
+		users = append(users, split.UserID)
+	}
+	for _, id := range users {
+		if id == authorID {
+			// the author is checked when the expense is filed
+			continue
+		}
+		_, err := e.projectModel.ReadOneProjectUser(ctx, models.ProjectUserFilter{ProjectID: projectID, UserID: id, IsActive: models.Bool(true)})
+		if errs.Is(err, errs.NotFound) {
+			return paidBy, nil, echo.NewHTTPError(http.StatusBadRequest, i18n.SplitNotMember)
+		}
+		if err != nil {
+			return paidBy, nil, err
+		}
+	}
+	return paidBy, splits, nil
+}
+
+// splitMethod the split method stored with the splits, none without splits
+func splitMethod(splits []models.Split, method models.SplitMethod) models.SplitMethod {
+	if len(splits) == 0 {
+		return ""
+	}
+	return method
+}
+
+// checkBudgets the budgets of the project the new expense pushed over the alert threshold.
+// The expense is created anyway, a failed check is logged and answered without alert.
+func (e ExpenseHandler) checkBudgets(ctx context.Context, exp models.Expense) models.BudgetAlert {
+	if e.budgets == nil {
+		return models.BudgetAlert{Budgets: []models.BudgetLine{}}
+	}
+	alert, err := e.budgets.Check(ctx, exp)
+	if err != nil {
+		log.Printf("BUDGET CHECK ERROR: %v\n", err)
+		return models.BudgetAlert{Threshold: alert.Threshold, Budgets: []models.BudgetLine{}}
+	}
+	return alert
 }
 
 // GetExpenses godoc
-// get all the expenses
-// QueryParams accepted are name
-// and only single value is used. TODO needs to find a way to accept array of query pamra values
+// get the expenses the user can read: the ones of their projects and their own ones, all of them for the ADMIN
+// QueryParams can be combined and the multi value ones repeated, e.g. `?category=a&category=b`
 // @Summary Get Expenses.
 // @Description get expenses
 // @Tags expenses
 // @Accept json
 // @Produce json
-// @Param name query string false "date search by date"
+// @Param start query string false "start date, YYYY-MM-DD, inclusive"
+// @Param end query string false "end date, YYYY-MM-DD, exclusive"
+// @Param category query []string false "category ids" collectionFormat(multi)
+// @Param project query []string false "project ids" collectionFormat(multi)
+// @Param user query []string false "author ids" collectionFormat(multi)
+// @Param status query []string false "statuses" collectionFormat(multi)
+// @Param min_total query string false "minimum total, requires currency"
+// @Param max_total query string false "maximum total, requires currency"
+// @Param currency query string false "currency of the totals"
+// @Param location query string false "location contains"
+// @Param tag query []string false "tags, all of them must match" collectionFormat(multi)
+// @Param q query string false "title, description or location contains"
+// @Param subcategories query bool false "the categories match their subcategories too"
+// @Param limit query int false "page size, 50 by default and at most 500"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "comma separated sort fields, prefix with - for descending"
+// @Param fields query string false "comma separated fields to return"
+// @Param count query bool false "include the total count"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/expenses [get]
 func (e ExpenseHandler) GetExpenses(c echo.Context) error {
-	qs := c.QueryParams()
-	log.Printf("QS: %s\n", qs)
-	var filter interface{}
-	if len(c.QueryParams()) == 0 {
-		filter = bson.D{}
-	} else {
-		f := make(map[string]string)
-		if x, ok := qs["start"]; ok {
-			f["start"] = x[0]
-		}
-		if x, ok := qs["end"]; ok {
-			f["end"] = x[0]
-		}
-		log.Printf("f %v\n", f)
-		var startDate, endDate time.Time
-		var err error
+	opts, err := listOptions(c, models.ExpenseListFields, models.ExpenseDefaultSort)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
 
-		startDate, err = parseDateToFormat("2006-01-02", f["start"])
-		if err != nil {
-			log.Printf("ERROR PARSING STARTDATE: %v\n", err)
-			return utils.Error(http.StatusBadRequest, err.Error(), c)
-		}
+	var expFilter models.ExpenseFilter
+	if err := c.Bind(&expFilter); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := c.Validate(expFilter); err != nil {
+		return err
+	}
+	if err := e.expandCategories(c, &expFilter); err != nil {
+		return err
+	}
+	if expFilter.Scope, err = expenseScope(c, e.projectModel); err != nil {
+		return err
+	}
+	cats, page, err := e.expenseModel.ReadAll(c.Request().Context(), expFilter, opts)
+	if err != nil {
+		return err
+	}
+	return listData(cats, page, opts, i18n.ExpenseDetails, c)
+}
 
-		endDate, err = parseDateToFormat("2006-01-02", f["end"])
-		if err != nil {
-			log.Printf("ERROR PARSING ENDDATE: %v\n", err)
-			return utils.Error(http.StatusBadRequest, err.Error(), c)
-		}
+// GetCategoryTotals godoc
+// totals of the expenses by category, the totals of the subcategories are rolled up into their parents.
+// The filter & the scope are the ones of the expense list, the subcategories of the filtered categories are included.
+// @Summary Get the Expense totals by Category.
+// @Description get the expense totals by category as a tree
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param start query string false "start date, YYYY-MM-DD, inclusive"
+// @Param end query string false "end date, YYYY-MM-DD, exclusive"
+// @Param category query []string false "category ids" collectionFormat(multi)
+// @Param project query []string false "project ids" collectionFormat(multi)
+// @Param user query []string false "author ids" collectionFormat(multi)
+// @Param status query []string false "statuses" collectionFormat(multi)
+// @Param currency query string false "currency of the totals"
+// @Param location query string false "location contains"
+// @Param tag query []string false "tags, all of them must match" collectionFormat(multi)
+// @Param q query string false "title, description or location contains"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/expenses/category-totals [get]
+func (e ExpenseHandler) GetCategoryTotals(c echo.Context) error {
+	var expFilter models.ExpenseFilter
+	if err := c.Bind(&expFilter); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := c.Validate(expFilter); err != nil {
+		return err
+	}
+	scope, err := expenseScope(c, e.projectModel)
+	if err != nil {
+		return err
+	}
+	expFilter.Scope = scope
 
-		filter = bson.D{
-			{"date", bson.D{
-				{"$gte", startDate},
-				{"$lt", endDate}, // time.Now().Add(-1 * 24 * time.Hour)
-			}},
-		}
+	categories, err := e.categoryModel.ReadTree(c.Request().Context())
+	if err != nil {
+		return err
 	}
+	tree := models.NewCategoryTree(categories)
+	expFilter.Subcategories = true
+	expFilter.ExpandCategories(tree)
 
-	cats, err := e.expenseModel.ReadAll(filter)
+	totals, err := e.expenseModel.TotalsByCategory(c.Request().Context(), expFilter)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+		return err
+	}
+	nodes, err := tree.Rollup(totals)
+	if err != nil {
+		return err
 	}
-	return utils.Data(http.StatusOK, cats, "expense details", c)
+	return utils.Data(http.StatusOK, nodes, i18n.CategoryTotals, c)
+}
+
+// expandCategories add the subcategories to the categories of the filter when they are asked
+func (e ExpenseHandler) expandCategories(c echo.Context, f *models.ExpenseFilter) error {
+	if !f.Subcategories || len(f.Categories) == 0 {
+		return nil
+	}
+	categories, err := e.categoryModel.ReadTree(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	f.ExpandCategories(models.NewCategoryTree(categories))
+	return nil
 }
 
 // GetExpense godoc
@@ -176,12 +327,11 @@ func (e ExpenseHandler) GetExpense(c echo.Context) error {
 	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
-	expense, err := e.expenseModel.ReadOne(bson.M{"_id": expenseID})
+	expense, err := readableExpense(c, e.expenseModel, e.projectModel, expenseID)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return err
 	}
-	return utils.Data(http.StatusOK, expense, "expense detail", c)
+	return utils.Data(http.StatusOK, expense, i18n.ExpenseDetail, c)
 }
 
 // DeleteExpense godoc
@@ -194,6 +344,8 @@ func (e ExpenseHandler) GetExpense(c echo.Context) error {
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Router /api/v1/expenses/{id} [delete]
 func (e ExpenseHandler) DeleteExpense(c echo.Context) error {
 	expenseID, err := objectIDFromStringID(c.Param("id"))
@@ -201,12 +353,20 @@ func (e ExpenseHandler) DeleteExpense(c echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
-	count, err := e.expenseModel.Remove(bson.M{"_id": expenseID})
+	expense, err := e.authorizeExpense(c, expenseID)
+	if err != nil {
+		return err
+	}
+	// submitted expenses are part of the audit trail
+	if !expense.Status.IsEditable() {
+		return utils.Error(http.StatusConflict, i18n.ExpenseNotRemovable, c)
+	}
+
+	count, err := e.expenseModel.Remove(c.Request().Context(), expenseID)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return err
 	}
-	return utils.Data(http.StatusAccepted, count, "expense removed", c)
+	return utils.Data(http.StatusAccepted, count, i18n.ExpenseRemoved, c)
 }
 
 // UpdateExpense godoc
@@ -220,6 +380,8 @@ func (e ExpenseHandler) DeleteExpense(c echo.Context) error {
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Router /api/v1/expenses/{id} [put]
 func (e ExpenseHandler) UpdateExpense(c echo.Context) error {
 	expenseID, err := objectIDFromStringID(c.Param("id"))
@@ -227,6 +389,14 @@ func (e ExpenseHandler) UpdateExpense(c echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
+	expense, err := e.authorizeExpense(c, expenseID)
+	if err != nil {
+		return err
+	}
+	if !expense.Status.IsEditable() {
+		return utils.Error(http.StatusConflict, i18n.ExpenseNotEditable, c)
+	}
+
 	expInput := new(models.ExpenseInput)
 
 	if err := c.Bind(expInput); err != nil {
@@ -235,7 +405,7 @@ func (e ExpenseHandler) UpdateExpense(c echo.Context) error {
 	}
 
 	if err := c.Validate(expInput); err != nil {
-		return utils.Error(http.StatusBadRequest, err.Error(), c)
+		return err
 	}
 
 	categoryID, err := objectIDFromStringID(expInput.CategoryID)
@@ -243,40 +413,221 @@ func (e ExpenseHandler) UpdateExpense(c echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
-	category, err := e.categoryModel.ReadOne(bson.M{"_id": categoryID})
+	category, err := e.categoryModel.ReadOne(c.Request().Context(), categoryID)
 	if err != nil {
-		log.Printf("CATEGORY NOT FOUND ERROR: %v\n", err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return err
 	}
 
-	userID, err := objectIDFromStringID(expInput.InsertedBy)
+	d, err := parseDateToFormat(models.DateLayout, expInput.Date)
 	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
-	user, err := e.userModel.ReadOneUser(bson.M{"_id": userID})
+	total, err := expInput.Money()
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	paidBy, splits, err := e.shareExpense(c.Request().Context(), expInput, total, expense.ProjectID, expense.InsertedBy.ID)
+	if err != nil {
+		return err
+	}
+
+	// update fields - title. description, date, category, location, total, payer & splits
+	// the author, the project and the status of the expense are kept as they are
+	update := models.ExpenseUpdate{
+		Title:       expInput.Title,
+		Description: expInput.Description,
+		Date:        d,
+		Category:    category.Snapshot(),
+		Location:    expInput.Location,
+		Tags:        expInput.NormalizedTags(),
+		Total:       total,
+		PaidBy:      paidBy,
+		SplitMethod: splitMethod(splits, expInput.SplitMethod),
+		Splits:      splits,
+	}
+
+	count, err := e.expenseModel.UpdateOne(c.Request().Context(), expenseID, update)
 	if err != nil {
-		log.Printf("USER NOT FOUND ERROR: %v\n", err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return err
 	}
+	return utils.Data(http.StatusOK, count, i18n.ExpenseUpdated, c)
+}
 
-	// update fields - title. description, date, category, location, total, status, inserted_by
-	update := bson.M{
-		"title":       expInput.Title,
-		"description": expInput.Description,
-		"date":        expInput.Date,
-		"category":    category,
-		"location":    expInput.Location,
-		"total":       expInput.Total,
-		"status":      expInput.Status,
-		"inserted_by": user,
-		"updated_at":  time.Now(),
+// authorizeExpense allow the change only to the author of the expense
+// or to the roles which can manage the expenses of anyone they can read
+func (e ExpenseHandler) authorizeExpense(c echo.Context, expenseID primitive.ObjectID) (models.Expense, error) {
+	expense, err := readableExpense(c, e.expenseModel, e.projectModel, expenseID)
+	if err != nil {
+		return expense, err
+	}
+	if !canActOn(c, expense.InsertedBy.ID, auth.PermExpensesManage) {
+		return expense, echo.NewHTTPError(http.StatusForbidden, i18n.ExpenseAuthorOnly)
+	}
+	return expense, nil
+}
+
+// canApprove check if the authenticated user can approve the expense.
+// Expenses of a project are approved by its ADMIN/SUPERVISOR members,
+// the others by the users with the global approve permission.
+// Nobody approves their own expense.
+func (e ExpenseHandler) canApprove(ctx context.Context, user models.User, expense models.Expense) (bool, error) {
+	if expense.InsertedBy.ID == user.ID {
+		return false, nil
+	}
+	if expense.ProjectID.IsZero() || user.Role == models.RoleAdmin {
+		return auth.Can(user.Role, auth.PermExpensesApprove), nil
+	}
+	member, err := e.projectModel.ReadOneProjectUser(ctx, models.ProjectUserFilter{ProjectID: expense.ProjectID, UserID: user.ID, IsActive: models.Bool(true)})
+	if errs.Is(err, errs.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return auth.CanInProject(member.Role, auth.PermProjectApprove), nil
+}
+
+// transition apply the workflow action on the expense of the `:id` path param
+func (e ExpenseHandler) transition(c echo.Context, action models.ExpenseAction, reason string) error {
+	expenseID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+
+	// out of the scope of the user the expense is not found, its existence is not disclosed
+	expense, err := readableExpense(c, e.expenseModel, e.projectModel, expenseID)
+	if err != nil {
+		return err
+	}
+
+	if action == models.ActionSubmit {
+		if !canActOn(c, expense.InsertedBy.ID, auth.PermExpensesManage) {
+			return utils.Error(http.StatusForbidden, i18n.ExpenseSubmitOnly, c)
+		}
+	} else {
+		allowed, err := e.canApprove(c.Request().Context(), user, expense)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return utils.Error(http.StatusForbidden, i18n.T(c, i18n.PermissionDenied, string(auth.PermExpensesApprove)), c)
+		}
 	}
 
-	count, err := e.expenseModel.UpdateOne(update, bson.M{"_id": expenseID})
+	next, err := models.NextStatus(expense.Status, action)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return utils.Error(http.StatusConflict, err.Error(), c)
 	}
-	return utils.Data(http.StatusOK, count, "expense updated", c)
+
+	entry := models.StatusTransition{
+		Action: action,
+		From:   expense.Status,
+		To:     next,
+		ByID:   user.ID,
+		ByName: user.Name,
+		At:     time.Now(),
+		Reason: reason,
+	}
+	count, err := e.expenseModel.Transition(c.Request().Context(), expenseID, entry)
+	if err != nil {
+		return err
+	}
+	// the status changed in between the read and the update
+	if count == 0 {
+		return utils.Error(http.StatusConflict, i18n.ExpenseStatusChanged, c)
+	}
+	return utils.Data(http.StatusOK, entry, statusMessages[next], c)
+}
+
+// statusMessages response message of the transition to each status
+var statusMessages = map[models.ExpenseStatus]string{
+	models.StatusSubmitted:  i18n.ExpenseSubmitted,
+	models.StatusApproved:   i18n.ExpenseApproved,
+	models.StatusRejected:   i18n.ExpenseRejected,
+	models.StatusReimbursed: i18n.ExpenseReimbursed,
+}
+
+// SubmitExpense godoc
+// @Summary Submit an Expense.
+// @Description submit a draft or rejected expense for approval
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Expense ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/expenses/{id}/submit [post]
+func (e ExpenseHandler) SubmitExpense(c echo.Context) error {
+	return e.transition(c, models.ActionSubmit, "")
+}
+
+// ApproveExpense godoc
+// @Summary Approve an Expense.
+// @Description approve a submitted expense
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Expense ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/expenses/{id}/approve [post]
+func (e ExpenseHandler) ApproveExpense(c echo.Context) error {
+	return e.transition(c, models.ActionApprove, "")
+}
+
+// RejectExpense godoc
+// @Summary Reject an Expense.
+// @Description reject a submitted expense with a reason
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Expense ID"
+// @Param reject body models.RejectInput true "Reject Expense"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/expenses/{id}/reject [post]
+func (e ExpenseHandler) RejectExpense(c echo.Context) error {
+	input := new(models.RejectInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+	return e.transition(c, models.ActionReject, input.Reason)
+}
+
+// ReimburseExpense godoc
+// @Summary Reimburse an Expense.
+// @Description mark an approved expense as reimbursed
+// @Tags expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Expense ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/expenses/{id}/reimburse [post]
+func (e ExpenseHandler) ReimburseExpense(c echo.Context) error {
+	return e.transition(c, models.ActionReimburse, "")
 }
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetExpensesFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{"no filter", "", http.StatusOK},
+		{"start only", "?start=2021-01-01", http.StatusOK},
+		{"combined", "?category=6009be17d6a899ab8340eb79&category=6009be17d6a899ab8340eb7a&status=approved&tag=travel&q=hotel", http.StatusOK},
+		{"amount range", "?min_total=10&max_total=99.99&currency=USD", http.StatusOK},
+		{"invalid date", "?end=01-02-2021", http.StatusBadRequest},
+		{"invalid category", "?category=food", http.StatusBadRequest},
+		{"unknown status", "?status=pending", http.StatusBadRequest},
+		{"amount without currency", "?min_total=10", http.StatusBadRequest},
+		{"amount too precise", "?max_total=1.001&currency=USD", http.StatusBadRequest},
+	}
+	author, _ := UserModelStub{}.ReadOneUser(context.Background(), models.UserQuery{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewExpenseHandler(ExpenseModelStub{}, UserModelStub{}, CategoryModelStub{}, ProjectModelStub{}, nil)
+			e := newTestEcho()
+			e.GET("/expenses", h.GetExpenses, asUser(author))
+
+			req := httptest.NewRequest(echo.GET, "/expenses"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestGetExpensesProblem(t *testing.T) {
+	h := NewExpenseHandler(ExpenseModelStub{}, UserModelStub{}, CategoryModelStub{}, ProjectModelStub{}, nil)
+	e := newTestEcho()
+	e.Use(middleware.RequestID())
+	e.GET("/expenses", h.GetExpenses)
+
+	req := httptest.NewRequest(echo.GET, "/expenses?category=food&status=pending", nil)
+	req.Header.Set(echo.HeaderAccept, utils.MIMEApplicationProblemJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, utils.MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	var problem utils.Problem
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem)) {
+		assert.Equal(t, "validation_failed", problem.Code)
+		assert.Equal(t, "/expenses", problem.Instance)
+		assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), problem.RequestID)
+		assert.NotEmpty(t, problem.RequestID)
+		var fields, codes []string
+		for _, fe := range problem.Errors {
+			fields, codes = append(fields, fe.Field), append(codes, fe.Code)
+			assert.NotEmpty(t, fe.Message)
+		}
+		assert.Equal(t, []string{"category[0]", "status[0]"}, fields)
+		assert.Equal(t, []string{"objectid", "oneof"}, codes)
+	}
+}
+
+func TestGetExpensesLocalized(t *testing.T) {
+	h := NewExpenseHandler(ExpenseModelStub{}, UserModelStub{}, CategoryModelStub{}, ProjectModelStub{}, nil)
+	e := newTestEcho()
+	e.GET("/expenses", h.GetExpenses)
+
+	req := httptest.NewRequest(echo.GET, "/expenses?status=pending", nil)
+	req.Header.Set("Accept-Language", "de-DE,de;q=0.9,en;q=0.8")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "de", rec.Header().Get("Content-Language"))
+	var res utils.Response
+	if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res)) && assert.Len(t, res.Errors, 1) {
+		assert.Equal(t, "Statuses[0] muss einer der folgenden Werte sein: [draft submitted approved rejected reimbursed]", res.Errors[0].Message)
+		assert.Equal(t, res.Errors[0].Message, res.Message)
+	}
+}
+
+func TestExpenseReadScope(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	var current models.User
+	e := newTestEcho()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth.SetUser(c, current)
+			return next(c)
+		}
+	})
+	h := NewExpenseHandler(m.Expenses, m.Users, m.Categories, m.Projects, nil)
+	e.GET("/expenses", h.GetExpenses)
+	e.GET("/expenses/:id", h.GetExpense)
+
+	alice := models.User{ID: primitive.NewObjectID(), Name: "alice", Role: models.RoleStaff, IsActive: true}
+	bob := models.User{ID: primitive.NewObjectID(), Name: "bob", Role: models.RoleStaff, IsActive: true}
+	carol := models.User{ID: primitive.NewObjectID(), Name: "carol", Role: models.RoleSupervisor, IsActive: true}
+	admin := models.User{ID: primitive.NewObjectID(), Name: "admin", Role: models.RoleAdmin, IsActive: true}
+	project := primitive.NewObjectID()
+	_, err := m.Projects.InsertProjectUser(ctx, &models.ProjectUser{ID: primitive.NewObjectID(), ProjectID: project, UserID: alice.ID, Role: models.RoleStaff, IsActive: true})
+	assert.NoError(t, err)
+	// bob left the project
+	_, err = m.Projects.InsertProjectUser(ctx, &models.ProjectUser{ID: primitive.NewObjectID(), ProjectID: project, UserID: bob.ID, Role: models.RoleStaff, IsActive: false})
+	assert.NoError(t, err)
+
+	expense := func(title string, author models.User, projectID primitive.ObjectID) primitive.ObjectID {
+		id := primitive.NewObjectID()
+		_, err := m.Expenses.Insert(ctx, models.Expense{ID: id, Title: title, ProjectID: projectID, InsertedBy: models.UserSnapshot{ID: author.ID},
+			Date: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), Total: models.NewMoney(100, "EUR"), Status: models.StatusDraft})
+		assert.NoError(t, err)
+		return id
+	}
+	inProject := expense("in the project", alice, project)
+	expense("alice alone", alice, primitive.NilObjectID)
+	bobAlone := expense("bob alone", bob, primitive.NilObjectID)
+
+	titles := func(query string) []string {
+		req := httptest.NewRequest(echo.GET, "/expenses?sort=title"+query, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var res struct {
+			Data []models.Expense `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		out := []string{}
+		for _, expense := range res.Data {
+			out = append(out, expense.Title)
+		}
+		return out
+	}
+	get := func(id primitive.ObjectID) int {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(echo.GET, "/expenses/"+id.Hex(), nil))
+		return rec.Code
+	}
+
+	current = alice
+	assert.Equal(t, []string{"alice alone", "in the project"}, titles(""))
+	assert.Equal(t, http.StatusOK, get(inProject))
+	assert.Equal(t, http.StatusNotFound, get(bobAlone))
+
+	current = bob
+	assert.Equal(t, []string{"bob alone"}, titles(""))
+	assert.Equal(t, []string{}, titles("&project="+project.Hex()))
+	assert.Equal(t, http.StatusNotFound, get(inProject))
+
+	// the approvers of the expenses out of projects read all of them
+	current = carol
+	assert.Equal(t, []string{"alice alone", "bob alone"}, titles(""))
+	assert.Equal(t, http.StatusNotFound, get(inProject))
+
+	current = admin
+	assert.Equal(t, []string{"alice alone", "bob alone", "in the project"}, titles(""))
+	assert.Equal(t, http.StatusOK, get(inProject))
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/notify"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+)
+
+// NotificationHandler controller for the inbox & the notification preferences of the authenticated user
+type NotificationHandler struct {
+	notificationModel models.NotificationModeler
+}
+
+// NewNotificationHandler godoc
+func NewNotificationHandler(nm models.NotificationModeler) NotificationHandler {
+	return NotificationHandler{nm}
+}
+
+// notificationDefaultSort the latest notifications first
+var notificationDefaultSort = []models.SortField{{Key: "created_at", Desc: true}}
+
+// GetNotifications godoc
+// @Summary Get the Notifications.
+// @Description get the inbox of the authenticated user, latest first
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param unread query bool false "only the unread notifications"
+// @Param limit query int false "page size, 50 by default and at most 500"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "comma separated sort fields, prefix with - for descending"
+// @Param fields query string false "comma separated fields to return"
+// @Param count query bool false "include the total count"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/notifications [get]
+func (h NotificationHandler) GetNotifications(c echo.Context) error {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+	opts, err := listOptions(c, models.NotificationListFields, notificationDefaultSort)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	filter := models.NotificationFilter{UserID: user.ID}
+	if x := c.QueryParam("unread"); x != "" {
+		if filter.Unread, err = strconv.ParseBool(x); err != nil {
+			log.Printf("INVALID QUERY PARAM PASSED: %v\n", err)
+			return utils.Error(http.StatusBadRequest, err.Error(), c)
+		}
+	}
+
+	notifications, page, err := h.notificationModel.ReadAll(c.Request().Context(), filter, opts)
+	if err != nil {
+		return err
+	}
+	return listData(notifications, page, opts, i18n.NotificationDetails, c)
+}
+
+// ReadNotification godoc
+// @Summary Mark a Notification read.
+// @Description mark the notification of the authenticated user read
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/notifications/{id}/read [post]
+func (h NotificationHandler) ReadNotification(c echo.Context) error {
+	return h.markRead(c, true, i18n.NotificationRead)
+}
+
+// UnreadNotification godoc
+// @Summary Mark a Notification unread.
+// @Description mark the notification of the authenticated user unread
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/notifications/{id}/unread [post]
+func (h NotificationHandler) UnreadNotification(c echo.Context) error {
+	return h.markRead(c, false, i18n.NotificationUnread)
+}
+
+// ReadAllNotifications godoc
+// @Summary Mark every Notification read.
+// @Description mark every unread notification of the authenticated user read
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/notifications/read [post]
+func (h NotificationHandler) ReadAllNotifications(c echo.Context) error {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+	count, err := h.notificationModel.MarkAllRead(c.Request().Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, count, i18n.NotificationsRead, c)
+}
+
+// GetPreferences godoc
+// @Summary Get the Notification preferences.
+// @Description get the channels & the muted kinds of the authenticated user
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Success 200 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/notifications/preferences [get]
+func (h NotificationHandler) GetPreferences(c echo.Context) error {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+	prefs, err := h.notificationModel.ReadPreferences(c.Request().Context(), user.ID)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, prefs, i18n.NotificationPreferencesDetails, c)
+}
+
+// UpdatePreferences godoc
+// the preferences are replaced, a webhook needs an http(s) URL
+// @Summary Update the Notification preferences.
+// @Description change the channels & the muted kinds of the authenticated user
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Param preferences body models.NotificationPreferencesInput true "Update Preferences"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/notifications/preferences [put]
+func (h NotificationHandler) UpdatePreferences(c echo.Context) error {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+	input := new(models.NotificationPreferencesInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+	if err := webhookURL(c.Request().Context(), input.WebhookURL); err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+
+	prefs := models.NotificationPreferences{
+		UserID:     user.ID,
+		UpdatedAt:  time.Now(),
+		Inbox:      input.Inbox,
+		Email:      input.Email,
+		Webhook:    input.Webhook,
+		WebhookURL: input.WebhookURL,
+		Muted:      append([]models.NotificationKind{}, input.Muted...),
+	}
+	if err := h.notificationModel.UpdatePreferences(c.Request().Context(), prefs); err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, prefs, i18n.NotificationPreferencesUpdated, c)
+}
+
+// markRead mark the notification of the `:id` path param read or unread, only the recipient can mark it
+func (h NotificationHandler) markRead(c echo.Context, read bool, message string) error {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+	id, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return err
+	}
+	count, err := h.notificationModel.MarkRead(c.Request().Context(), user.ID, id, read)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errs.NotFoundf(i18n.NotificationNotFound)
+	}
+	return utils.Data(http.StatusOK, count, message, c)
+}
+
+// webhookURL check the webhook is posted over http or https to a public host,
+// the loopback, private & link-local addresses of the internal network are refused
+func webhookURL(ctx context.Context, value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New(i18n.NotificationWebhookURL)
+	}
+	if !notify.PublicHost(ctx, u.Hostname()) {
+		return errors.New(i18n.NotificationWebhookPublic)
+	}
+	return nil
+}
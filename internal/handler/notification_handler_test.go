@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNotifications(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	e := newTestEcho()
+	user := models.User{ID: primitive.NewObjectID(), Name: "jane", Role: models.RoleStaff, IsActive: true}
+	e.Use(asUser(user))
+	h := NewNotificationHandler(m.Notifications)
+	e.GET("/notifications", h.GetNotifications)
+	e.POST("/notifications/read", h.ReadAllNotifications)
+	e.POST("/notifications/:id/read", h.ReadNotification)
+	e.POST("/notifications/:id/unread", h.UnreadNotification)
+	e.GET("/notifications/preferences", h.GetPreferences)
+	e.PUT("/notifications/preferences", h.UpdatePreferences)
+
+	do := func(method, path, body string) (int, json.RawMessage) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		return rec.Code, res.Data
+	}
+	inbox := func(path string) []models.Notification {
+		code, data := do(echo.GET, path, "")
+		assert.Equal(t, http.StatusOK, code)
+		var notifications []models.Notification
+		assert.NoError(t, json.Unmarshal(data, &notifications))
+		return notifications
+	}
+
+	now := time.Now()
+	var ids []primitive.ObjectID
+	for i, userID := range []primitive.ObjectID{user.ID, user.ID, primitive.NewObjectID()} {
+		n := models.Notification{ID: primitive.NewObjectID(), CreatedAt: now.Add(time.Duration(i) * time.Minute), UserID: userID,
+			Kind: models.NotifyExpensePending, Title: "pending"}
+		_, err := m.Notifications.Insert(ctx, &n)
+		assert.NoError(t, err)
+		ids = append(ids, n.ID)
+	}
+
+	// only the inbox of the user, latest first
+	notifications := inbox("/notifications")
+	if assert.Len(t, notifications, 2) {
+		assert.Equal(t, ids[1], notifications[0].ID)
+		assert.Nil(t, notifications[0].ReadAt)
+	}
+
+	code, _ := do(echo.POST, "/notifications/"+ids[0].Hex()+"/read", "")
+	assert.Equal(t, http.StatusOK, code)
+	if unread := inbox("/notifications?unread=true"); assert.Len(t, unread, 1) {
+		assert.Equal(t, ids[1], unread[0].ID)
+	}
+	code, _ = do(echo.POST, "/notifications/"+ids[0].Hex()+"/unread", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, inbox("/notifications?unread=true"), 2)
+	// the notifications of the other users are not found
+	code, _ = do(echo.POST, "/notifications/"+ids[2].Hex()+"/read", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = do(echo.GET, "/notifications?unread=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, data := do(echo.POST, "/notifications/read", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `2`, string(data))
+	assert.Empty(t, inbox("/notifications?unread=true"))
+
+	// the defaults until the preferences are changed
+	code, data = do(echo.GET, "/notifications/preferences", "")
+	assert.Equal(t, http.StatusOK, code)
+	var prefs models.NotificationPreferences
+	assert.NoError(t, json.Unmarshal(data, &prefs))
+	assert.True(t, prefs.Inbox && prefs.Email && !prefs.Webhook)
+
+	for _, c := range []struct {
+		name string
+		body string
+		code int
+	}{
+		{"webhook without url", `{"inbox":true,"webhook":true}`, http.StatusBadRequest},
+		{"webhook not http", `{"webhook":true,"webhook_url":"ftp://example.com/hook"}`, http.StatusBadRequest},
+		{"webhook on the loopback", `{"webhook":true,"webhook_url":"http://127.0.0.1:8080/hook"}`, http.StatusBadRequest},
+		{"webhook on the metadata service", `{"webhook":true,"webhook_url":"http://169.254.169.254/latest/meta-data"}`, http.StatusBadRequest},
+		{"webhook on localhost", `{"webhook":true,"webhook_url":"http://localhost/hook"}`, http.StatusBadRequest},
+		{"webhook on a private network", `{"webhook":true,"webhook_url":"https://[fd00::1]/hook"}`, http.StatusBadRequest},
+		{"unknown kind", `{"inbox":true,"muted":["weekly_digest"]}`, http.StatusBadRequest},
+		{"webhook", `{"inbox":true,"webhook":true,"webhook_url":"https://example.com/hook","muted":["budget_threshold"]}`, http.StatusOK},
+	} {
+		code, _ := do(echo.PUT, "/notifications/preferences", c.body)
+		assert.Equal(t, c.code, code, c.name)
+	}
+	prefs, err := m.Notifications.ReadPreferences(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.True(t, prefs.Inbox && !prefs.Email && prefs.Webhook)
+	assert.Equal(t, "https://example.com/hook", prefs.WebhookURL)
+	assert.True(t, prefs.IsMuted(models.NotifyBudgetThreshold))
+}
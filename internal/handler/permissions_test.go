@@ -0,0 +1,353 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	customMiddleware "github.com/masihur1989/expense-tracker-api/internal/middleware"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CategoryModelStub struct{}
+
+func (c CategoryModelStub) Insert(ctx context.Context, category *models.Category) (interface{}, error) {
+	return category.ID, nil
+}
+
+func (c CategoryModelStub) ReadAll(ctx context.Context, f models.CategoryFilter, opts models.ListOptions) ([]models.Category, models.Page, error) {
+	return []models.Category{}, models.Page{}, nil
+}
+
+func (c CategoryModelStub) ReadOne(ctx context.Context, id primitive.ObjectID) (models.Category, error) {
+	return models.Category{ID: obzID, Name: "food"}, nil
+}
+
+func (c CategoryModelStub) UpdateOne(ctx context.Context, id primitive.ObjectID, update models.CategoryUpdateInput) (int64, error) {
+	return 1, nil
+}
+
+func (c CategoryModelStub) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	return 1, nil
+}
+
+func (c CategoryModelStub) ReadTree(ctx context.Context) ([]models.Category, error) {
+	return []models.Category{{ID: obzID, Name: "food"}}, nil
+}
+
+func (c CategoryModelStub) Move(ctx context.Context, id primitive.ObjectID, parentID primitive.ObjectID) (int64, error) {
+	return 1, nil
+}
+
+// ExpenseModelStub every expense is authored by the user of UserModelStub
+// and is in draft status unless another one is given
+type ExpenseModelStub struct {
+	status    models.ExpenseStatus
+	projectID primitive.ObjectID
+}
+
+func (e ExpenseModelStub) Insert(ctx context.Context, expense models.Expense) (interface{}, error) {
+	return expense.ID, nil
+}
+
+func (e ExpenseModelStub) ReadAll(ctx context.Context, f models.ExpenseFilter, opts models.ListOptions) ([]models.Expense, models.Page, error) {
+	return []models.Expense{}, models.Page{}, nil
+}
+
+func (e ExpenseModelStub) ReadOne(ctx context.Context, id primitive.ObjectID) (models.Expense, error) {
+	author, _ := UserModelStub{}.ReadOneUser(context.Background(), models.UserQuery{})
+	status := e.status
+	if status == "" {
+		status = models.StatusDraft
+	}
+	return models.Expense{ID: obzID, InsertedBy: author.Snapshot(), Status: status, ProjectID: e.projectID}, nil
+}
+
+func (e ExpenseModelStub) Remove(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	return 1, nil
+}
+
+func (e ExpenseModelStub) UpdateOne(ctx context.Context, id primitive.ObjectID, update models.ExpenseUpdate) (int64, error) {
+	return 1, nil
+}
+
+func (e ExpenseModelStub) Transition(ctx context.Context, id primitive.ObjectID, entry models.StatusTransition) (int64, error) {
+	return 1, nil
+}
+
+func (e ExpenseModelStub) AddAttachment(ctx context.Context, id primitive.ObjectID, attachment models.Attachment) (int64, error) {
+	return 1, nil
+}
+
+func (e ExpenseModelStub) RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) (int64, error) {
+	return 1, nil
+}
+
+func (e ExpenseModelStub) CountAttachmentRefs(ctx context.Context, sha256 string) (int64, error) {
+	return 0, nil
+}
+
+func (e ExpenseModelStub) ReassignCategory(ctx context.Context, from primitive.ObjectID, to models.CategorySnapshot) (int64, error) {
+	return 0, nil
+}
+
+func (e ExpenseModelStub) RefreshCategory(ctx context.Context, category models.CategorySnapshot) (int64, error) {
+	return 0, nil
+}
+
+func (e ExpenseModelStub) RefreshUser(ctx context.Context, user models.UserSnapshot) (int64, error) {
+	return 0, nil
+}
+
+func (e ExpenseModelStub) TotalsByCategory(ctx context.Context, f models.ExpenseFilter) ([]models.CategoryTotal, error) {
+	return []models.CategoryTotal{}, nil
+}
+
+// asUser authenticate every request as the given user
+func asUser(user models.User) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth.SetUser(c, user)
+			return next(c)
+		}
+	}
+}
+
+func TestPermissionMatrix(t *testing.T) {
+	author, _ := UserModelStub{}.ReadOneUser(context.Background(), models.UserQuery{})
+	other := primitive.NewObjectID()
+
+	userHandler := NewUserHandler(UserModelStub{}, nil)
+	categoryHandler := NewCategoryHandler(CategoryModelStub{}, ExpenseModelStub{}, nil, nil)
+	expenseHandler := NewExpenseHandler(ExpenseModelStub{}, UserModelStub{}, CategoryModelStub{}, ProjectModelStub{}, nil)
+
+	expenseBody := `{"date":"2021-01-01","title":"lunch","description":"team","total":10,"currency":"EUR","category_id":"6009be17d6a899ab8340eb79"}`
+
+	tests := []struct {
+		name   string
+		role   models.Role
+		userID primitive.ObjectID
+		method string
+		path   string
+		body   string
+		code   int
+	}{
+		{"admin creates category", models.RoleAdmin, other, echo.POST, "/categories", `{"name":"food"}`, http.StatusCreated},
+		{"supervisor can't create category", models.RoleSupervisor, other, echo.POST, "/categories", `{"name":"food"}`, http.StatusForbidden},
+		{"staff can't create category", models.RoleStaff, other, echo.POST, "/categories", `{"name":"food"}`, http.StatusForbidden},
+		{"user reads categories", models.RoleUser, other, echo.GET, "/categories", "", http.StatusOK},
+		{"admin deletes user", models.RoleAdmin, other, echo.DELETE, "/users/6009be17d6a899ab8340eb79", "", http.StatusAccepted},
+		{"supervisor can't delete user", models.RoleSupervisor, other, echo.DELETE, "/users/6009be17d6a899ab8340eb79", "", http.StatusForbidden},
+		{"user can't list users", models.RoleUser, other, echo.GET, "/users/", "", http.StatusForbidden},
+		{"staff edits own expense", models.RoleStaff, author.ID, echo.PUT, "/expenses/6009be17d6a899ab8340eb79", expenseBody, http.StatusOK},
+		{"staff can't edit others expense", models.RoleStaff, other, echo.PUT, "/expenses/6009be17d6a899ab8340eb79", expenseBody, http.StatusNotFound},
+		{"supervisor edits others expense", models.RoleSupervisor, other, echo.PUT, "/expenses/6009be17d6a899ab8340eb79", expenseBody, http.StatusOK},
+		{"over precise total is rejected", models.RoleStaff, author.ID, echo.PUT, "/expenses/6009be17d6a899ab8340eb79", strings.Replace(expenseBody, `"total":10`, `"total":10.005`, 1), http.StatusBadRequest},
+		{"staff can't delete others expense", models.RoleStaff, other, echo.DELETE, "/expenses/6009be17d6a899ab8340eb79", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newTestEcho()
+			g := e.Group("", asUser(models.User{ID: tt.userID, Role: tt.role, IsActive: true}))
+			g.GET("/users/", userHandler.GetUsers, customMiddleware.Authorize(auth.PermUsersRead))
+			g.DELETE("/users/:id", userHandler.DeleteUser, customMiddleware.Authorize(auth.PermUsersDelete))
+			g.GET("/categories", categoryHandler.GetCategories, customMiddleware.Authorize(auth.PermCategoriesRead))
+			g.POST("/categories", categoryHandler.CreateCategory, customMiddleware.Authorize(auth.PermCategoriesWrite))
+			g.PUT("/expenses/:id", expenseHandler.UpdateExpense, customMiddleware.Authorize(auth.PermExpensesWrite))
+			g.DELETE("/expenses/:id", expenseHandler.DeleteExpense, customMiddleware.Authorize(auth.PermExpensesWrite))
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+// ProjectModelStub project with a single member, the user of UserModelStub with the given role
+type ProjectModelStub struct {
+	memberRole models.Role
+}
+
+func (p ProjectModelStub) Insert(ctx context.Context, project *models.Project) (interface{}, error) {
+	return project.ID, nil
+}
+
+func (p ProjectModelStub) ReadAll(ctx context.Context, f models.ProjectFilter, opts models.ListOptions) ([]models.Project, models.Page, error) {
+	return []models.Project{}, models.Page{}, nil
+}
+
+func (p ProjectModelStub) ReadOne(ctx context.Context, id primitive.ObjectID) (models.Project, error) {
+	return models.Project{ID: obzID}, nil
+}
+
+func (p ProjectModelStub) UpdateOne(ctx context.Context, id primitive.ObjectID, update models.ProjectUpdate) (int64, error) {
+	return 1, nil
+}
+
+func (p ProjectModelStub) LookupProjectDetails(ctx context.Context, id primitive.ObjectID, expfilter models.ProjectDetailsQS) (models.ProjectDetails, error) {
+	return models.ProjectDetails{ID: obzID}, nil
+}
+
+func (p ProjectModelStub) InsertProjectUser(ctx context.Context, projectUser *models.ProjectUser) (interface{}, error) {
+	return projectUser.ID, nil
+}
+
+func (p ProjectModelStub) ReadAllProjectUser(ctx context.Context, f models.ProjectUserFilter) ([]models.ProjectUser, error) {
+	return []models.ProjectUser{}, nil
+}
+
+func (p ProjectModelStub) ReadAllProjectMembers(ctx context.Context, f models.ProjectUserFilter) ([]models.ProjectMember, error) {
+	return []models.ProjectMember{}, nil
+}
+
+func (p ProjectModelStub) ReadAllUserProjects(ctx context.Context, f models.ProjectUserFilter) ([]models.UserProject, error) {
+	return []models.UserProject{}, nil
+}
+
+func (p ProjectModelStub) ReadOneProjectUser(ctx context.Context, f models.ProjectUserFilter) (models.ProjectUser, error) {
+	if !f.UserID.IsZero() && f.UserID != obzID {
+		return models.ProjectUser{}, nil
+	}
+	return models.ProjectUser{ID: obzID, ProjectID: obzID, UserID: obzID, Role: p.memberRole, IsActive: true}, nil
+}
+
+func (p ProjectModelStub) UpdateOneProjectUser(ctx context.Context, f models.ProjectUserFilter, update models.ProjectUserUpdate) (int64, error) {
+	return 1, nil
+}
+
+func TestProjectPermissions(t *testing.T) {
+	member, _ := UserModelStub{}.ReadOneUser(context.Background(), models.UserQuery{})
+	outsider := models.User{ID: primitive.NewObjectID(), Email: "outsider@gmail.com", Role: models.RoleSupervisor, IsActive: true}
+	admin := models.User{ID: primitive.NewObjectID(), Email: "admin@gmail.com", Role: models.RoleAdmin, IsActive: true}
+
+	tests := []struct {
+		name       string
+		user       models.User
+		memberRole models.Role
+		method     string
+		path       string
+		code       int
+	}{
+		{"member sees project details", member, models.RoleStaff, echo.GET, "/projects/6009be17d6a899ab8340eb79/details", http.StatusOK},
+		{"outsider can't see project details", outsider, models.RoleStaff, echo.GET, "/projects/6009be17d6a899ab8340eb79/details", http.StatusForbidden},
+		{"outsider can't see members", outsider, models.RoleStaff, echo.GET, "/projects/6009be17d6a899ab8340eb79/users", http.StatusForbidden},
+		{"global admin sees project details", admin, models.RoleStaff, echo.GET, "/projects/6009be17d6a899ab8340eb79/details", http.StatusOK},
+		{"staff member can't remove members", member, models.RoleStaff, echo.DELETE, "/projects/6009be17d6a899ab8340eb79/users/6009be17d6a899ab8340eb79", http.StatusForbidden},
+		{"supervisor member removes members", member, models.RoleSupervisor, echo.DELETE, "/projects/6009be17d6a899ab8340eb79/users/6009be17d6a899ab8340eb79", http.StatusAccepted},
+		{"outsider can't remove the project", outsider, models.RoleAdmin, echo.DELETE, "/projects/6009be17d6a899ab8340eb79", http.StatusForbidden},
+		{"supervisor member can't remove the project", member, models.RoleSupervisor, echo.DELETE, "/projects/6009be17d6a899ab8340eb79", http.StatusForbidden},
+		{"admin member removes the project", member, models.RoleAdmin, echo.DELETE, "/projects/6009be17d6a899ab8340eb79", http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm := ProjectModelStub{memberRole: tt.memberRole}
+			h := NewProjectHandler(pm, UserModelStub{}, RateFinderStub{})
+			e := newTestEcho()
+			g := e.Group("", asUser(tt.user))
+			g.GET("/projects/:id/details", h.GetProjectExpenses, customMiddleware.ProjectAccess(pm, auth.PermProjectView))
+			g.GET("/projects/:id/users", h.GetProjectUsers, customMiddleware.ProjectAccess(pm, auth.PermProjectView))
+			g.DELETE("/projects/:id/users/:userId", h.DeleteProjectUser, customMiddleware.ProjectAccess(pm, auth.PermProjectMembers))
+			g.DELETE("/projects/:id", h.DeleteProject, customMiddleware.ProjectAccess(pm, auth.PermProjectManage))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestExpenseWorkflow(t *testing.T) {
+	author, _ := UserModelStub{}.ReadOneUser(context.Background(), models.UserQuery{})
+	supervisor := models.User{ID: primitive.NewObjectID(), Role: models.RoleSupervisor, IsActive: true}
+	staff := models.User{ID: primitive.NewObjectID(), Role: models.RoleStaff, IsActive: true}
+	project := primitive.NewObjectID()
+
+	tests := []struct {
+		name       string
+		user       models.User
+		status     models.ExpenseStatus
+		projectID  primitive.ObjectID
+		memberRole models.Role
+		path       string
+		body       string
+		code       int
+	}{
+		{"author submits draft", author, models.StatusDraft, primitive.NilObjectID, "", "/submit", "", http.StatusOK},
+		{"others can't submit", staff, models.StatusDraft, primitive.NilObjectID, "", "/submit", "", http.StatusNotFound},
+		{"project members can't submit others expense", staff, models.StatusDraft, project, models.RoleSupervisor, "/submit", "", http.StatusForbidden},
+		{"supervisor out of the project can't submit", supervisor, models.StatusDraft, project, "", "/submit", "", http.StatusNotFound},
+		{"supervisor approves submitted", supervisor, models.StatusSubmitted, primitive.NilObjectID, "", "/approve", "", http.StatusOK},
+		{"staff can't approve", staff, models.StatusSubmitted, primitive.NilObjectID, "", "/approve", "", http.StatusNotFound},
+		{"author can't approve own expense", models.User{ID: author.ID, Role: models.RoleAdmin}, models.StatusSubmitted, primitive.NilObjectID, "", "/approve", "", http.StatusForbidden},
+		{"draft can't be approved", supervisor, models.StatusDraft, primitive.NilObjectID, "", "/approve", "", http.StatusConflict},
+		{"reject requires a reason", supervisor, models.StatusSubmitted, primitive.NilObjectID, "", "/reject", `{}`, http.StatusBadRequest},
+		{"supervisor rejects with reason", supervisor, models.StatusSubmitted, primitive.NilObjectID, "", "/reject", `{"reason":"missing receipt"}`, http.StatusOK},
+		{"approved is reimbursed", supervisor, models.StatusApproved, primitive.NilObjectID, "", "/reimburse", "", http.StatusOK},
+		{"project supervisor approves", staff, models.StatusSubmitted, project, models.RoleSupervisor, "/approve", "", http.StatusOK},
+		{"project staff can't approve", supervisor, models.StatusSubmitted, project, models.RoleStaff, "/approve", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			em := ExpenseModelStub{status: tt.status, projectID: tt.projectID}
+			h := NewExpenseHandler(em, UserModelStub{}, CategoryModelStub{}, ProjectMemberStub{userID: tt.user.ID, role: tt.memberRole, projectID: tt.projectID}, nil)
+			e := newTestEcho()
+			g := e.Group("/expenses/:id", asUser(tt.user))
+			g.POST("/submit", h.SubmitExpense)
+			g.POST("/approve", h.ApproveExpense)
+			g.POST("/reject", h.RejectExpense)
+			g.POST("/reimburse", h.ReimburseExpense)
+
+			req := httptest.NewRequest(echo.POST, "/expenses/6009be17d6a899ab8340eb79"+tt.path, strings.NewReader(tt.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+// ProjectMemberStub project where only the given user is an active member
+type ProjectMemberStub struct {
+	ProjectModelStub
+	userID    primitive.ObjectID
+	role      models.Role
+	projectID primitive.ObjectID
+}
+
+func (p ProjectMemberStub) ReadAllProjectUser(ctx context.Context, f models.ProjectUserFilter) ([]models.ProjectUser, error) {
+	if p.role == "" || f.UserID != p.userID {
+		return []models.ProjectUser{}, nil
+	}
+	return []models.ProjectUser{{ID: primitive.NewObjectID(), ProjectID: p.projectID, UserID: p.userID, Role: p.role, IsActive: true}}, nil
+}
+
+func (p ProjectMemberStub) ReadOneProjectUser(ctx context.Context, f models.ProjectUserFilter) (models.ProjectUser, error) {
+	if p.role == "" || f.UserID != p.userID {
+		return models.ProjectUser{}, nil
+	}
+	return models.ProjectUser{ID: primitive.NewObjectID(), UserID: p.userID, Role: p.role, IsActive: true}, nil
+}
+
+// RateFinderStub every currency is worth the same
+type RateFinderStub struct{}
+
+func (r RateFinderStub) FindRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	return 1, nil
+}
+
+func (r RateFinderStub) FindRates(ctx context.Context, from, to string, start, end time.Time) (models.RateHistory, error) {
+	return models.RateHistory{From: from, To: to, Rates: []models.ExchangeRate{{From: from, To: to, Rate: 1}}}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
 	"github.com/masihur1989/expense-tracker-api/internal/utils"
 )
 
@@ -16,5 +17,5 @@ import (
 // @Success 200 {object} utils.Response
 // @Router / [get]
 func Ping(c echo.Context) error {
-	return utils.Data(http.StatusOK, nil, "Server is Up", c)
+	return utils.Data(http.StatusOK, nil, i18n.ServerUp, c)
 }
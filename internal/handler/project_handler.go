@@ -1,8 +1,7 @@
 package handler
 
 import (
-	"encoding/json"
-	"io/ioutil"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -10,20 +9,24 @@ import (
 
 	"github.com/jinzhu/now"
 	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
 	"github.com/masihur1989/expense-tracker-api/internal/models"
 	"github.com/masihur1989/expense-tracker-api/internal/utils"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ProjectHandler godoc
 type ProjectHandler struct {
 	projectModel models.ProjectModeler
+	userModel    models.UserModel
+	rateModel    models.RateFinder
 }
 
 // NewProjectHandler godoc
-func NewProjectHandler(pm models.ProjectModeler) ProjectHandler {
-	return ProjectHandler{pm}
+func NewProjectHandler(pm models.ProjectModeler, um models.UserModel, rm models.RateFinder) ProjectHandler {
+	return ProjectHandler{pm, um, rm}
 }
 
 // CreateProject godoc
@@ -45,7 +48,7 @@ func (c ProjectHandler) CreateProject(e echo.Context) error {
 	}
 
 	if err := e.Validate(p); err != nil {
-		return utils.Error(http.StatusBadRequest, err.Error(), e)
+		return err
 	}
 
 	// fill the nil values
@@ -53,14 +56,29 @@ func (c ProjectHandler) CreateProject(e echo.Context) error {
 	p.CreatedAt = time.Now()
 	p.UpdatedAt = time.Now()
 
-	id, err := c.projectModel.Insert(p)
+	id, err := c.projectModel.Insert(e.Request().Context(), p)
 
 	if err != nil {
-		log.Printf("RESPONSE ERROR: %v\n", err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), e)
+		return err
+	}
+
+	// the creator becomes the first admin of the project
+	if user, ok := auth.CurrentUser(e); ok {
+		member := &models.ProjectUser{
+			ID:        primitive.NewObjectID(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			ProjectID: p.ID,
+			UserID:    user.ID,
+			Role:      models.RoleAdmin,
+			IsActive:  true,
+		}
+		if _, err := c.projectModel.InsertProjectUser(e.Request().Context(), member); err != nil {
+			return err
+		}
 	}
 
-	return utils.Data(http.StatusCreated, id, "projects created", e)
+	return utils.Data(http.StatusCreated, id, i18n.ProjectCreated, e)
 }
 
 // GetProjects godoc
@@ -73,29 +91,27 @@ func (c ProjectHandler) CreateProject(e echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param name query string false "name search by name"
+// @Param limit query int false "page size, 50 by default and at most 500"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "comma separated sort fields, prefix with - for descending"
+// @Param fields query string false "comma separated fields to return"
+// @Param count query bool false "include the total count"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/projects [get]
 func (c ProjectHandler) GetProjects(e echo.Context) error {
-	qs := e.QueryParams()
-	var filter interface{}
-	if len(e.QueryParams()) == 0 {
-		filter = bson.D{}
-	} else {
-		f := bson.M{}
-		if x, ok := qs["name"]; ok {
-			f["name"] = x[0]
-		}
-		filter = f
+	opts, err := listOptions(e, models.ProjectListFields, nil)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
+	filter := models.ProjectFilter{Title: e.QueryParam("name")}
 
-	pats, err := c.projectModel.ReadAll(filter)
+	pats, page, err := c.projectModel.ReadAll(e.Request().Context(), filter, opts)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), e)
+		return err
 	}
-	return utils.Data(http.StatusOK, pats, "project details", e)
+	return listData(pats, page, opts, i18n.ProjectDetails, e)
 }
 
 // GetProject godoc
@@ -115,12 +131,11 @@ func (c ProjectHandler) GetProject(e echo.Context) error {
 	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
-	expense, err := c.projectModel.ReadOne(bson.M{"_id": projectID})
+	expense, err := c.projectModel.ReadOne(e.Request().Context(), projectID)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), e)
+		return err
 	}
-	return utils.Data(http.StatusOK, expense, "project detail", e)
+	return utils.Data(http.StatusOK, expense, i18n.ProjectDetail, e)
 }
 
 // DeleteProject godoc
@@ -132,6 +147,7 @@ func (c ProjectHandler) GetProject(e echo.Context) error {
 // @Param id path string true "Project ID"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Failure 404 {object} utils.Response
 // @Router /api/v1/projects/{id} [delete]
 func (c ProjectHandler) DeleteProject(e echo.Context) error {
@@ -140,12 +156,11 @@ func (c ProjectHandler) DeleteProject(e echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
 
-	count, err := c.projectModel.UpdateOne(bson.D{{"is_active", false}}, bson.M{"_id": ID})
+	count, err := c.projectModel.UpdateOne(e.Request().Context(), ID, models.ProjectUpdate{IsActive: models.Bool(false)})
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), e)
+		return err
 	}
-	return utils.Data(http.StatusAccepted, count, "project removed", e)
+	return utils.Data(http.StatusAccepted, count, i18n.ProjectRemoved, e)
 }
 
 // GetProjectExpenses godoc
@@ -162,12 +177,38 @@ func (c ProjectHandler) DeleteProject(e echo.Context) error {
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
 // @Failure 500 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 422 {object} utils.Response
 // @Router /api/v1/projects/{id}/details [get]
 func (c ProjectHandler) GetProjectExpenses(e echo.Context) error {
 	ID, err := objectIDFromStringID(e.Param("id"))
 	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
+	filter, err := projectDetailsQS(e)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	}
+
+	pats, err := c.projectModel.LookupProjectDetails(e.Request().Context(), ID, filter)
+	if err != nil {
+		return err
+	}
+
+	// the totals are reported in the base currency of the project
+	err = models.ConvertProjectDetails(e.Request().Context(), &pats, c.rateModel)
+	if errors.Is(err, models.ErrRateNotFound) {
+		return utils.Error(http.StatusUnprocessableEntity, err.Error(), e)
+	}
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, pats, i18n.ProjectReport, e)
+}
+
+// projectDetailsQS the period & membership filter of the project reports,
+// the current month by default. The error message is the one of the bad request.
+func projectDetailsQS(e echo.Context) (models.ProjectDetailsQS, error) {
 	qs := e.QueryParams()
 	filter := models.ProjectDetailsQS{
 		Start:    now.BeginningOfMonth(),
@@ -179,7 +220,7 @@ func (c ProjectHandler) GetProjectExpenses(e echo.Context) error {
 			startDate, err := parseDateToFormat("2006-01-02", x[0])
 			if err != nil {
 				log.Printf("ERROR PARSING STARTDATE: %v\n", err)
-				return utils.Error(http.StatusBadRequest, "Specify the Start period", e)
+				return filter, errors.New(i18n.ProjectStartRequired)
 			}
 			filter.Start = startDate
 		}
@@ -187,7 +228,7 @@ func (c ProjectHandler) GetProjectExpenses(e echo.Context) error {
 			endDate, err := parseDateToFormat("2006-01-02", x[0])
 			if err != nil {
 				log.Printf("ERROR PARSING ENDDATE: %v\n", err)
-				return utils.Error(http.StatusBadRequest, "Specify the End period", e)
+				return filter, errors.New(i18n.ProjectEndRequired)
 			}
 
 			filter.End = endDate
@@ -197,18 +238,12 @@ func (c ProjectHandler) GetProjectExpenses(e echo.Context) error {
 			b, err := strconv.ParseBool(x[0])
 			if err != nil {
 				log.Printf("INVALID QUERY PARAM PASSED: %v\n", err)
-				return utils.Error(http.StatusBadRequest, err.Error(), e)
+				return filter, err
 			}
 			filter.IsActive = b
 		}
 	}
-
-	pats, err := c.projectModel.LookupProjectDetails(bson.D{{"_id", ID}}, filter)
-	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), e)
-	}
-	return utils.Data(http.StatusOK, pats, "complete project details", e)
+	return filter, nil
 }
 
 // CreateProjectUser godoc
@@ -218,10 +253,13 @@ func (c ProjectHandler) GetProjectExpenses(e echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param id path string true "Project ID"
-// @Param projectUser body models.ProjectUser true "Add Project User"
+// @Param projectUser body models.ProjectUserInput true "Add Project User"
 // @Success 201 {object} utils.Response
 // @Failure 400 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Failure 500 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Router /api/v1/projects/{id}/users [post]
 func (c ProjectHandler) CreateProjectUser(e echo.Context) error {
 	ID, err := objectIDFromStringID(e.Param("id"))
@@ -229,33 +267,52 @@ func (c ProjectHandler) CreateProjectUser(e echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
 
-	b, err := ioutil.ReadAll(e.Request().Body)
-	if err != nil {
-		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	input := new(models.ProjectUserInput)
+	if err := e.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+
+	if err := e.Validate(input); err != nil {
+		return err
 	}
 
-	p := new(models.ProjectUser)
-	err = json.Unmarshal(b, &p)
+	userID, err := objectIDFromStringID(input.UserID)
 	if err != nil {
-		return utils.Error(http.StatusInternalServerError, err.Error(), e)
+		return err
 	}
 
-	if err := e.Validate(p); err != nil {
-		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	if _, err := c.userModel.ReadOneUser(e.Request().Context(), models.UserQuery{ID: userID}); err != nil {
+		return err
+	}
+	if input.Role.Outranks(projectRole(e)) {
+		return utils.Error(http.StatusForbidden, i18n.ProjectRoleAbove, e)
 	}
 
-	p.ID = primitive.NewObjectID()
-	p.ProjectID = ID
-	p.CreatedAt = time.Now()
-	p.UpdatedAt = time.Now()
+	existing, err := c.projectModel.ReadOneProjectUser(e.Request().Context(), models.ProjectUserFilter{ProjectID: ID, UserID: userID, IsActive: models.Bool(true)})
+	if err != nil && !errs.Is(err, errs.NotFound) {
+		return err
+	}
+	if !existing.ID.IsZero() {
+		return utils.Error(http.StatusConflict, i18n.ProjectMemberExists, e)
+	}
+
+	p := &models.ProjectUser{
+		ID:        primitive.NewObjectID(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		ProjectID: ID,
+		UserID:    userID,
+		Role:      input.Role,
+		IsActive:  true,
+	}
 
-	id, err := c.projectModel.InsertProjectUser(p)
+	id, err := c.projectModel.InsertProjectUser(e.Request().Context(), p)
 	if err != nil {
-		log.Printf("RESPONSE ERROR: %v\n", err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), e)
+		return err
 	}
 
-	return utils.Data(http.StatusCreated, id, "project user created", e)
+	return utils.Data(http.StatusCreated, id, i18n.ProjectUserCreated, e)
 }
 
 // GetProjectUsers godoc
@@ -268,6 +325,7 @@ func (c ProjectHandler) CreateProjectUser(e echo.Context) error {
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Router /api/v1/projects/{id}/users [get]
 func (c ProjectHandler) GetProjectUsers(e echo.Context) error {
 	ID, err := objectIDFromStringID(e.Param("id"))
@@ -275,31 +333,22 @@ func (c ProjectHandler) GetProjectUsers(e echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
 
-	qs := e.QueryParams()
-	filter := bson.D{
-		{"project_id", ID},
-	}
-	if len(e.QueryParams()) >= 0 {
-		if x, ok := qs["is_active"]; ok {
-			b, err := strconv.ParseBool(x[0])
-			if err != nil {
-				log.Printf("INVALID QUERY PARAM PASSED: %v\n", err)
-				return utils.Error(http.StatusBadRequest, err.Error(), e)
-			}
-			filter = bson.D{
-				{"project_id", ID},
-				{"is_active", b},
-			}
+	filter := models.ProjectUserFilter{ProjectID: ID}
+	if x, ok := e.QueryParams()["is_active"]; ok {
+		b, err := strconv.ParseBool(x[0])
+		if err != nil {
+			log.Printf("INVALID QUERY PARAM PASSED: %v\n", err)
+			return utils.Error(http.StatusBadRequest, err.Error(), e)
 		}
+		filter.IsActive = &b
 	}
 
-	user, err := c.projectModel.ReadAllProjectUser(filter)
+	user, err := c.projectModel.ReadAllProjectMembers(e.Request().Context(), filter)
 	if err != nil {
-		log.Printf("RESPONSE ERROR: %v\n", err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), e)
+		return err
 	}
 
-	return utils.Data(http.StatusOK, user, "project user details", e)
+	return utils.Data(http.StatusOK, user, i18n.ProjectUserDetails, e)
 }
 
 // GetProjectUser godoc
@@ -313,19 +362,27 @@ func (c ProjectHandler) GetProjectUsers(e echo.Context) error {
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Router /api/v1/projects/{id}/users/{userId} [get]
 func (c ProjectHandler) GetProjectUser(e echo.Context) error {
+	projectID, err := objectIDFromStringID(e.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	}
+
 	userID, err := objectIDFromStringID(e.Param("userId"))
 	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
 
-	count, err := c.projectModel.ReadOneProjectUser(bson.M{"_id": userID})
+	members, err := c.projectModel.ReadAllProjectMembers(e.Request().Context(), models.ProjectUserFilter{ID: userID, ProjectID: projectID})
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), e)
+		return err
 	}
-	return utils.Data(http.StatusAccepted, count, "project user removed", e)
+	if len(members) == 0 {
+		return utils.Error(http.StatusNotFound, i18n.ProjectUserNotFound, e)
+	}
+	return utils.Data(http.StatusOK, members[0], i18n.ProjectUserDetails, e)
 }
 
 // DeleteProjectUser godoc
@@ -339,18 +396,103 @@ func (c ProjectHandler) GetProjectUser(e echo.Context) error {
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Router /api/v1/projects/{id}/users/{userId} [delete]
 func (c ProjectHandler) DeleteProjectUser(e echo.Context) error {
+	projectID, err := objectIDFromStringID(e.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	}
+
 	userID, err := objectIDFromStringID(e.Param("userId"))
 	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), e)
 	}
 
+	member, err := c.projectModel.ReadOneProjectUser(e.Request().Context(), models.ProjectUserFilter{ID: userID, ProjectID: projectID})
+	if err != nil {
+		return err
+	}
+	if member.Role.Outranks(projectRole(e)) {
+		return utils.Error(http.StatusForbidden, i18n.ProjectRoleAbove, e)
+	}
+	// the project is never left without an admin to manage it
+	if member.IsActive && member.Role == models.RoleAdmin {
+		admins, err := c.projectModel.ReadAllProjectUser(e.Request().Context(), models.ProjectUserFilter{ProjectID: projectID, IsActive: models.Bool(true)})
+		if err != nil {
+			return err
+		}
+		if countRole(admins, models.RoleAdmin) <= 1 {
+			return utils.Error(http.StatusConflict, i18n.ProjectLastAdmin, e)
+		}
+	}
+
 	// soft delete
-	count, err := c.projectModel.UpdateOneProjectUser(bson.M{"is_active": false}, bson.M{"_id": userID})
+	count, err := c.projectModel.UpdateOneProjectUser(e.Request().Context(), models.ProjectUserFilter{ID: userID, ProjectID: projectID}, models.ProjectUserUpdate{IsActive: models.Bool(false)})
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), e)
+		return err
+	}
+	return utils.Data(http.StatusAccepted, count, i18n.ProjectUserRemoved, e)
+}
+
+// GetUserProjects godoc
+// @Summary Get the Projects of an User.
+// @Description get the project memberships of the user by ID
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param is_active query string false "is_active to check if the membership is active or not"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/users/{id}/projects [get]
+func (c ProjectHandler) GetUserProjects(e echo.Context) error {
+	userID, err := objectIDFromStringID(e.Param("id"))
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), e)
+	}
+
+	if !canActOn(e, userID, auth.PermUsersRead) {
+		return utils.Error(http.StatusForbidden, i18n.T(e, i18n.PermissionDenied, string(auth.PermUsersRead)), e)
+	}
+
+	filter := models.ProjectUserFilter{UserID: userID}
+	if x, ok := e.QueryParams()["is_active"]; ok {
+		b, err := strconv.ParseBool(x[0])
+		if err != nil {
+			log.Printf("INVALID QUERY PARAM PASSED: %v\n", err)
+			return utils.Error(http.StatusBadRequest, err.Error(), e)
+		}
+		filter.IsActive = &b
+	}
+
+	projects, err := c.projectModel.ReadAllUserProjects(e.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, projects, i18n.UserProjects, e)
+}
+
+// projectRole the role of the authenticated user in the project of the request, set by the ProjectAccess middleware,
+// the ADMIN acts as a project admin
+func projectRole(e echo.Context) models.Role {
+	if user, ok := auth.CurrentUser(e); ok && user.Role == models.RoleAdmin {
+		return models.RoleAdmin
+	}
+	member, _ := auth.CurrentProjectMember(e)
+	return member.Role
+}
+
+// countRole count the memberships with the role
+func countRole(members []models.ProjectUser, role models.Role) int {
+	count := 0
+	for _, m := range members {
+		if m.Role == role {
+			count++
+		}
 	}
-	return utils.Data(http.StatusAccepted, count, "project user removed", e)
+	return count
 }
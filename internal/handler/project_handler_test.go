@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	customMiddleware "github.com/masihur1989/expense-tracker-api/internal/middleware"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGetProjectUser(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	h := NewProjectHandler(m.Projects, m.Users, RateFinderStub{})
+	e := newTestEcho()
+	e.GET("/projects/:id/users/:userId", h.GetProjectUser)
+
+	user := models.User{ID: primitive.NewObjectID(), Name: "alice", Email: "alice@example.com", Role: models.RoleStaff, IsActive: true}
+	_, err := m.Users.InsertNewUser(ctx, &user)
+	assert.NoError(t, err)
+	project := models.Project{ID: primitive.NewObjectID(), Title: "office", BaseCurrency: "EUR"}
+	_, err = m.Projects.Insert(ctx, &project)
+	assert.NoError(t, err)
+	member := models.ProjectUser{ID: primitive.NewObjectID(), ProjectID: project.ID, UserID: user.ID, Role: models.RoleStaff, IsActive: true}
+	_, err = m.Projects.InsertProjectUser(ctx, &member)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(echo.GET, "/projects/"+project.ID.Hex()+"/users/"+member.ID.Hex(), nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var res struct {
+		Message string `json:"message"`
+		Data    struct {
+			ID primitive.ObjectID `json:"id"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, i18n.ProjectUserDetails, res.Message)
+	assert.Equal(t, member.ID, res.Data.ID)
+
+	req = httptest.NewRequest(echo.GET, "/projects/"+project.ID.Hex()+"/users/"+primitive.NewObjectID().Hex(), nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestProjectUserRoles(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	h := NewProjectHandler(m.Projects, m.Users, RateFinderStub{})
+	var current models.User
+	e := newTestEcho()
+	g := e.Group("", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			auth.SetUser(c, current)
+			return next(c)
+		}
+	})
+	g.POST("/projects/:id/users", h.CreateProjectUser, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectMembers))
+	g.DELETE("/projects/:id/users/:userId", h.DeleteProjectUser, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectMembers))
+
+	project := primitive.NewObjectID()
+	user := func(name string) models.User {
+		u := models.User{ID: primitive.NewObjectID(), Name: name, Email: name + "@example.com", Role: models.RoleStaff, IsActive: true}
+		_, err := m.Users.InsertNewUser(ctx, &u)
+		assert.NoError(t, err)
+		return u
+	}
+	member := func(u models.User, role models.Role) string {
+		pu := models.ProjectUser{ID: primitive.NewObjectID(), ProjectID: project, UserID: u.ID, Role: role, IsActive: true}
+		_, err := m.Projects.InsertProjectUser(ctx, &pu)
+		assert.NoError(t, err)
+		return pu.ID.Hex()
+	}
+	do := func(method, path, body string) int {
+		req := httptest.NewRequest(method, "/projects/"+project.Hex()+"/users"+path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	alice, sue, joe, bob := user("alice"), user("sue"), user("joe"), user("bob")
+	aliceMember := member(alice, models.RoleAdmin)
+	member(sue, models.RoleSupervisor)
+
+	// a supervisor manages the roles up to their own
+	current = sue
+	assert.Equal(t, http.StatusForbidden, do(echo.POST, "", `{"user_id":"`+joe.ID.Hex()+`","role":"ADMIN"}`))
+	assert.Equal(t, http.StatusCreated, do(echo.POST, "", `{"user_id":"`+joe.ID.Hex()+`","role":"SUPERVISOR"}`))
+	assert.Equal(t, http.StatusForbidden, do(echo.DELETE, "/"+aliceMember, ""))
+
+	// the last admin stays
+	current = alice
+	assert.Equal(t, http.StatusConflict, do(echo.DELETE, "/"+aliceMember, ""))
+	assert.Equal(t, http.StatusCreated, do(echo.POST, "", `{"user_id":"`+bob.ID.Hex()+`","role":"ADMIN"}`))
+	assert.Equal(t, http.StatusAccepted, do(echo.DELETE, "/"+aliceMember, ""))
+}
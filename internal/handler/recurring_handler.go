@@ -0,0 +1,338 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecurringHandler controller for the recurring expenses, their expenses are created by the models.RecurringJob
+type RecurringHandler struct {
+	recurringModel models.RecurringExpenseModeler
+	categoryModel  models.CategoryModeler
+	projectModel   models.ProjectModeler
+}
+
+// NewRecurringHandler godoc
+func NewRecurringHandler(rm models.RecurringExpenseModeler, cm models.CategoryModeler, pm models.ProjectModeler) RecurringHandler {
+	return RecurringHandler{rm, cm, pm}
+}
+
+// recurringDefaultSort the latest series first
+var recurringDefaultSort = []models.SortField{{Key: "created_at", Desc: true}}
+
+// recurringUpcoming number of the upcoming occurrences of the series details
+const recurringUpcoming = 5
+
+// recurringDetails the series with its next occurrences
+type recurringDetails struct {
+	models.RecurringExpense
+	Upcoming []time.Time `json:"upcoming"`
+}
+
+// CreateRecurring godoc
+// the occurrences since the start are created at once, the next ones when they are due
+// @Summary Create a recurring expense.
+// @Description create the template of the expenses created on each date of the recurrence rule
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param recurring body models.RecurringExpenseInput true "Create Recurring Expense"
+// @Success 201 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/recurring-expenses [post]
+func (h RecurringHandler) CreateRecurring(c echo.Context) error {
+	input := new(models.RecurringExpenseInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+	// the author is always the authenticated user
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+
+	categoryID, err := objectIDFromStringID(input.CategoryID)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	category, err := h.categoryModel.ReadOne(c.Request().Context(), categoryID)
+	if err != nil {
+		return err
+	}
+	start, err := parseDateToFormat(models.DateLayout, input.Start)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	total, err := input.Money()
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	rule, err := models.ParseRRule(input.RRule)
+	if err != nil {
+		return err
+	}
+	projectID, err := projectToFileIn(c.Request().Context(), h.projectModel, user, input.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	series := models.RecurringExpense{
+		ID:          primitive.NewObjectID(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Title:       input.Title,
+		Description: input.Description,
+		Location:    input.Location,
+		Tags:        input.NormalizedTags(),
+		Total:       total,
+		ProjectID:   projectID,
+		Category:    category.Snapshot(),
+		InsertedBy:  user.Snapshot(),
+		RRule:       rule.String(),
+		Start:       start,
+		Status:      models.RecurringActive,
+		Skipped:     []time.Time{},
+	}
+	if _, err := h.recurringModel.Insert(c.Request().Context(), &series); err != nil {
+		return err
+	}
+	return utils.Data(http.StatusCreated, series, i18n.RecurringCreated, c)
+}
+
+// GetRecurringExpenses godoc
+// the users who manage the expenses of anyone see every series, the others their own
+// @Summary Get the recurring expenses.
+// @Description get the recurring expenses, latest first
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param limit query int false "page size, 50 by default and at most 500"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "comma separated sort fields, prefix with - for descending"
+// @Param fields query string false "comma separated fields to return"
+// @Param count query bool false "include the total count"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 500 {object} utils.Response
+// @Router /api/v1/recurring-expenses [get]
+func (h RecurringHandler) GetRecurringExpenses(c echo.Context) error {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+	opts, err := listOptions(c, models.RecurringListFields, recurringDefaultSort)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	filter := models.RecurringFilter{}
+	if !auth.Can(user.Role, auth.PermExpensesManage) {
+		filter.UserID = user.ID
+	}
+	series, page, err := h.recurringModel.ReadAll(c.Request().Context(), filter, opts)
+	if err != nil {
+		return err
+	}
+	return listData(series, page, opts, i18n.RecurringList, c)
+}
+
+// GetRecurring godoc
+// @Summary Get a recurring expense.
+// @Description get the recurring expense by ID with its next occurrences
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Recurring Expense ID"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Router /api/v1/recurring-expenses/{id} [get]
+func (h RecurringHandler) GetRecurring(c echo.Context) error {
+	series, err := h.authorizeRecurring(c)
+	if err != nil {
+		return err
+	}
+	details := recurringDetails{series, []time.Time{}}
+	if series.Status != models.RecurringEnded {
+		details.Upcoming = series.Next(time.Now().AddDate(0, 0, -1), recurringUpcoming)
+	}
+	return utils.Data(http.StatusOK, details, i18n.RecurringDetails, c)
+}
+
+// PauseRecurring godoc
+// no expense is created while the series is paused
+// @Summary Pause a recurring expense.
+// @Description pause the active recurring expense
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Recurring Expense ID"
+// @Success 200 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/recurring-expenses/{id}/pause [post]
+func (h RecurringHandler) PauseRecurring(c echo.Context) error {
+	series, err := h.authorizeRecurring(c)
+	if err != nil {
+		return err
+	}
+	if series.Status != models.RecurringActive {
+		return utils.Error(http.StatusConflict, i18n.RecurringNotActive, c)
+	}
+	status := models.RecurringPaused
+	return h.update(c, series.ID, models.RecurringUpdate{Status: &status}, i18n.RecurringPaused)
+}
+
+// ResumeRecurring godoc
+// the occurrences missed while paused are not created
+// @Summary Resume a recurring expense.
+// @Description resume the paused recurring expense from today
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Recurring Expense ID"
+// @Success 200 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/recurring-expenses/{id}/resume [post]
+func (h RecurringHandler) ResumeRecurring(c echo.Context) error {
+	series, err := h.authorizeRecurring(c)
+	if err != nil {
+		return err
+	}
+	if series.Status != models.RecurringPaused {
+		return utils.Error(http.StatusConflict, i18n.RecurringNotPaused, c)
+	}
+	now := time.Now().UTC()
+	yesterday := time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, time.UTC)
+	if series.ScheduledUntil != nil && series.ScheduledUntil.After(yesterday) {
+		yesterday = *series.ScheduledUntil
+	}
+	status := models.RecurringActive
+	return h.update(c, series.ID, models.RecurringUpdate{Status: &status, ScheduledUntil: &yesterday}, i18n.RecurringResumed)
+}
+
+// SkipRecurring godoc
+// @Summary Skip an occurrence of a recurring expense.
+// @Description the expense of the upcoming occurrence is not created
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Recurring Expense ID"
+// @Param skip body models.RecurringSkipInput true "Occurrence to skip"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/recurring-expenses/{id}/skip [post]
+func (h RecurringHandler) SkipRecurring(c echo.Context) error {
+	series, err := h.authorizeRecurring(c)
+	if err != nil {
+		return err
+	}
+	if series.Status == models.RecurringEnded {
+		return utils.Error(http.StatusConflict, i18n.RecurringAlreadyEnded, c)
+	}
+	input := new(models.RecurringSkipInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+	date, err := parseDateToFormat(models.DateLayout, input.Date)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
+	// only an occurrence not created yet can be skipped
+	next := series.Next(date.AddDate(0, 0, -1), 1)
+	if len(next) == 0 || !next[0].Equal(date) {
+		return utils.Error(http.StatusBadRequest, i18n.RecurringNotOccurrence, c)
+	}
+	skipped := append(series.Skipped, date)
+	return h.update(c, series.ID, models.RecurringUpdate{Skipped: skipped}, i18n.RecurringSkipped)
+}
+
+// EndRecurring godoc
+// the expenses already created are kept
+// @Summary End a recurring expense.
+// @Description end the recurring expense for good
+// @Tags recurring-expenses
+// @Accept json
+// @Produce json
+// @Param id path string true "Recurring Expense ID"
+// @Success 200 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Failure 404 {object} utils.Response
+// @Failure 409 {object} utils.Response
+// @Router /api/v1/recurring-expenses/{id}/end [post]
+func (h RecurringHandler) EndRecurring(c echo.Context) error {
+	series, err := h.authorizeRecurring(c)
+	if err != nil {
+		return err
+	}
+	if series.Status == models.RecurringEnded {
+		return utils.Error(http.StatusConflict, i18n.RecurringAlreadyEnded, c)
+	}
+	status := models.RecurringEnded
+	return h.update(c, series.ID, models.RecurringUpdate{Status: &status}, i18n.RecurringEnded)
+}
+
+// authorizeRecurring read the series of the `:id` path param, only its author
+// or the roles which can manage the expenses of anyone can see and change it,
+// when its expenses are in the scope of the user, not found otherwise
+func (h RecurringHandler) authorizeRecurring(c echo.Context) (models.RecurringExpense, error) {
+	id, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
+		return models.RecurringExpense{}, err
+	}
+	series, err := h.recurringModel.ReadOne(c.Request().Context(), id)
+	if err != nil {
+		return series, err
+	}
+	scope, err := expenseScope(c, h.projectModel)
+	if err != nil {
+		return models.RecurringExpense{}, err
+	}
+	if scope != nil && !scope.Includes(series.ProjectID, series.InsertedBy.ID) {
+		return models.RecurringExpense{}, errs.NotFoundf("recurring expense not found")
+	}
+	if !canActOn(c, series.InsertedBy.ID, auth.PermExpensesManage) {
+		return series, echo.NewHTTPError(http.StatusForbidden, i18n.RecurringAuthorOnly)
+	}
+	return series, nil
+}
+
+// update apply the update to the series and respond with the updated series
+func (h RecurringHandler) update(c echo.Context, id primitive.ObjectID, update models.RecurringUpdate, message string) error {
+	if _, err := h.recurringModel.UpdateOne(c.Request().Context(), id, update); err != nil {
+		return err
+	}
+	series, err := h.recurringModel.ReadOne(c.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, series, message, c)
+}
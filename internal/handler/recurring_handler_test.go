@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecurringExpenses(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	e := newTestEcho()
+	user := models.User{ID: primitive.NewObjectID(), Name: "jane", Role: models.RoleStaff, IsActive: true}
+	e.Use(asUser(user))
+	h := NewRecurringHandler(m.Recurring, m.Categories, m.Projects)
+	e.GET("/recurring-expenses", h.GetRecurringExpenses)
+	e.GET("/recurring-expenses/:id", h.GetRecurring)
+	e.POST("/recurring-expenses", h.CreateRecurring)
+	e.POST("/recurring-expenses/:id/pause", h.PauseRecurring)
+	e.POST("/recurring-expenses/:id/resume", h.ResumeRecurring)
+	e.POST("/recurring-expenses/:id/skip", h.SkipRecurring)
+	e.POST("/recurring-expenses/:id/end", h.EndRecurring)
+
+	do := func(method, path, body string) (int, json.RawMessage) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		var res struct {
+			Data json.RawMessage `json:"data"`
+		}
+		json.Unmarshal(rec.Body.Bytes(), &res)
+		return rec.Code, res.Data
+	}
+	details := func(id string) recurringDetails {
+		code, data := do(echo.GET, "/recurring-expenses/"+id, "")
+		assert.Equal(t, http.StatusOK, code)
+		var series recurringDetails
+		assert.NoError(t, json.Unmarshal(data, &series))
+		return series
+	}
+
+	rent := models.Category{ID: primitive.NewObjectID(), Name: "Rent", Slug: "rent"}
+	_, err := m.Categories.Insert(ctx, &rent)
+	assert.NoError(t, err)
+	next := time.Now().Year() + 1
+	start := time.Date(next, 1, 1, 0, 0, 0, 0, time.UTC)
+	body := func(rule string) string {
+		return `{"title":"office rent","description":"monthly rent","total":"1500.00","currency":"EUR","category_id":"` + rent.ID.Hex() +
+			`","rrule":"` + rule + `","start":"` + start.Format(models.DateLayout) + `"}`
+	}
+
+	for _, rule := range []string{"", "FREQ=HOURLY", "FREQ=MONTHLY;BYMONTHDAY=1;COUNT=2;UNTIL=20300101"} {
+		code, _ := do(echo.POST, "/recurring-expenses", body(rule))
+		assert.Equal(t, http.StatusBadRequest, code, rule)
+	}
+	code, data := do(echo.POST, "/recurring-expenses", body("rrule:freq=monthly;bymonthday=1"))
+	assert.Equal(t, http.StatusCreated, code)
+	var created models.RecurringExpense
+	assert.NoError(t, json.Unmarshal(data, &created))
+	assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=1", created.RRule)
+	assert.Equal(t, models.RecurringActive, created.Status)
+	assert.Equal(t, user.ID, created.InsertedBy.ID)
+	id := created.ID.Hex()
+
+	// the upcoming occurrences start on the start date
+	series := details(id)
+	if assert.Len(t, series.Upcoming, recurringUpcoming) {
+		assert.Equal(t, start, series.Upcoming[0])
+		assert.Equal(t, start.AddDate(0, 1, 0), series.Upcoming[1])
+	}
+
+	// only an upcoming occurrence can be skipped
+	code, _ = do(echo.POST, "/recurring-expenses/"+id+"/skip", `{"date":"`+start.AddDate(0, 0, 1).Format(models.DateLayout)+`"}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = do(echo.POST, "/recurring-expenses/"+id+"/skip", `{"date":"`+start.Format(models.DateLayout)+`"}`)
+	assert.Equal(t, http.StatusOK, code)
+	series = details(id)
+	assert.Equal(t, []time.Time{start}, series.Skipped)
+	assert.Equal(t, start.AddDate(0, 1, 0), series.Upcoming[0])
+
+	for _, c := range []struct {
+		action string
+		code   int
+		status models.RecurringStatus
+	}{
+		{"pause", http.StatusOK, models.RecurringPaused},
+		{"pause", http.StatusConflict, models.RecurringPaused},
+		{"resume", http.StatusOK, models.RecurringActive},
+		{"resume", http.StatusConflict, models.RecurringActive},
+		{"end", http.StatusOK, models.RecurringEnded},
+		{"end", http.StatusConflict, models.RecurringEnded},
+		{"pause", http.StatusConflict, models.RecurringEnded},
+	} {
+		code, _ := do(echo.POST, "/recurring-expenses/"+id+"/"+c.action, "")
+		assert.Equal(t, c.code, code, c.action)
+		assert.Equal(t, c.status, details(id).Status, c.action)
+	}
+	assert.Empty(t, details(id).Upcoming)
+	code, _ = do(echo.POST, "/recurring-expenses/"+id+"/skip", `{"date":"`+start.AddDate(0, 1, 0).Format(models.DateLayout)+`"}`)
+	assert.Equal(t, http.StatusConflict, code)
+
+	// the series of the other users are neither listed nor changed
+	other := models.RecurringExpense{ID: primitive.NewObjectID(), Title: "salary", RRule: "FREQ=MONTHLY", Start: start,
+		Status: models.RecurringActive, InsertedBy: models.UserSnapshot{ID: primitive.NewObjectID(), Name: "joe"}}
+	_, err = m.Recurring.Insert(ctx, &other)
+	assert.NoError(t, err)
+	code, data = do(echo.GET, "/recurring-expenses", "")
+	assert.Equal(t, http.StatusOK, code)
+	var list []models.RecurringExpense
+	assert.NoError(t, json.Unmarshal(data, &list))
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, created.ID, list[0].ID)
+	}
+	code, _ = do(echo.POST, "/recurring-expenses/"+other.ID.Hex()+"/pause", "")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = do(echo.GET, "/recurring-expenses/"+primitive.NewObjectID().Hex(), "")
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestRecurringScope(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	e := newTestEcho()
+	supervisor := models.User{ID: primitive.NewObjectID(), Name: "sue", Role: models.RoleSupervisor, IsActive: true}
+	e.Use(asUser(supervisor))
+	h := NewRecurringHandler(m.Recurring, m.Categories, m.Projects)
+	e.GET("/recurring-expenses/:id", h.GetRecurring)
+	e.POST("/recurring-expenses/:id/pause", h.PauseRecurring)
+
+	project := primitive.NewObjectID()
+	series := models.RecurringExpense{ID: primitive.NewObjectID(), Title: "rent", RRule: "FREQ=MONTHLY", Start: time.Now(), ProjectID: project,
+		Status: models.RecurringActive, InsertedBy: models.UserSnapshot{ID: primitive.NewObjectID(), Name: "joe"}}
+	_, err := m.Recurring.Insert(ctx, &series)
+	assert.NoError(t, err)
+	do := func(method, path string) int {
+		req := httptest.NewRequest(method, path, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// the series of a project are managed by its members only, the others don't see them
+	assert.Equal(t, http.StatusNotFound, do(echo.GET, "/recurring-expenses/"+series.ID.Hex()))
+	assert.Equal(t, http.StatusNotFound, do(echo.POST, "/recurring-expenses/"+series.ID.Hex()+"/pause"))
+	_, err = m.Projects.InsertProjectUser(ctx, &models.ProjectUser{ID: primitive.NewObjectID(), ProjectID: project, UserID: supervisor.ID, Role: models.RoleSupervisor, IsActive: true})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, do(echo.POST, "/recurring-expenses/"+series.ID.Hex()+"/pause"))
+}
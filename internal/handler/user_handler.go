@@ -7,20 +7,22 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
 	"github.com/masihur1989/expense-tracker-api/internal/models"
 	"github.com/masihur1989/expense-tracker-api/internal/utils"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // UserHandler controller for users
 type UserHandler struct {
-	userModel models.UserModel
+	userModel  models.UserModel
+	resetModel models.PasswordResetModeler
 }
 
 // NewUserHandler echo.Echo handler function
-func NewUserHandler(um models.UserModel) UserHandler {
-	return UserHandler{um}
+func NewUserHandler(um models.UserModel, rm models.PasswordResetModeler) UserHandler {
+	return UserHandler{um, rm}
 }
 
 // CreateUser godoc
@@ -32,6 +34,7 @@ func NewUserHandler(um models.UserModel) UserHandler {
 // @Param user body models.User true "Create User"
 // @Success 201 {object} utils.Response
 // @Failure 400 {object} utils.Response
+// @Failure 409 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/users [post]
 func (u UserHandler) CreateUser(c echo.Context) error {
@@ -42,22 +45,29 @@ func (u UserHandler) CreateUser(c echo.Context) error {
 	}
 
 	if err := c.Validate(user); err != nil {
-		return utils.Error(http.StatusBadRequest, err.Error(), c)
+		return err
+	}
+
+	hash, err := auth.HashPassword(user.Password)
+	if err != nil {
+		log.Printf("PASSWORD HASHING ERROR: %v\n", err)
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
 	}
 
 	// fill the nil values
 	user.ID = primitive.NewObjectID()
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
+	user.PasswordHash = hash
+	user.Password = ""
 
-	id, err := u.userModel.InsertNewUser(user)
+	id, err := u.userModel.InsertNewUser(c.Request().Context(), user)
 
 	if err != nil {
-		log.Printf("RESPONSE ERROR: %v\n", err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+		return err
 	}
 
-	return utils.Data(http.StatusCreated, id, "user created", c)
+	return utils.Data(http.StatusCreated, id, i18n.UserCreated, c)
 }
 
 // GetUsers godoc
@@ -70,38 +80,36 @@ func (u UserHandler) CreateUser(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param name query string false "name search by name"
+// @Param limit query int false "page size, 50 by default and at most 500"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "comma separated sort fields, prefix with - for descending"
+// @Param fields query string false "comma separated fields to return"
+// @Param count query bool false "include the total count"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 500 {object} utils.Response
 // @Router /api/v1/users [get]
 func (u UserHandler) GetUsers(c echo.Context) error {
+	opts, err := listOptions(c, models.UserListFields, nil)
+	if err != nil {
+		return utils.Error(http.StatusBadRequest, err.Error(), c)
+	}
 	qs := c.QueryParams()
-	var filter interface{}
-	if len(c.QueryParams()) == 0 {
-		filter = bson.D{}
-	} else {
-		f := bson.M{}
-		if x, ok := qs["is_active"]; ok {
-			b, err := strconv.ParseBool(x[0])
-			if err != nil {
-				log.Printf("INVALID QUERY PARAM PASSED: %v\n", err)
-				return utils.Error(http.StatusBadRequest, err.Error(), c)
-			}
-			f["is_active"] = b
+	filter := models.UserFilter{Role: models.Role(qs.Get("role"))}
+	if x, ok := qs["is_active"]; ok {
+		b, err := strconv.ParseBool(x[0])
+		if err != nil {
+			log.Printf("INVALID QUERY PARAM PASSED: %v\n", err)
+			return utils.Error(http.StatusBadRequest, err.Error(), c)
 		}
-		if x, ok := qs["role"]; ok {
-			f["role"] = x[0]
-		}
-
-		filter = f
+		filter.IsActive = &b
 	}
 
-	users, err := u.userModel.ReadAllUsers(filter)
+	users, page, err := u.userModel.ReadAllUsers(c.Request().Context(), filter, opts)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+		return err
 	}
-	return utils.Data(http.StatusOK, users, "user details", c)
+	return listData(users, page, opts, i18n.UserDetails, c)
 }
 
 // GetUser godoc
@@ -121,12 +129,11 @@ func (u UserHandler) GetUser(c echo.Context) error {
 	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
-	user, err := u.userModel.ReadOneUser(bson.M{"_id": userID})
+	user, err := u.userModel.ReadOneUser(c.Request().Context(), models.UserQuery{ID: userID})
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return err
 	}
-	return utils.Data(http.StatusOK, user, "user detail", c)
+	return utils.Data(http.StatusOK, user, i18n.UserDetail, c)
 }
 
 // DeleteUser godoc
@@ -146,12 +153,11 @@ func (u UserHandler) DeleteUser(c echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
-	count, err := u.userModel.RemoveOneUser(bson.M{"_id": userID})
+	count, err := u.userModel.RemoveOneUser(c.Request().Context(), userID)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		return err
 	}
-	return utils.Data(http.StatusAccepted, count, "user removed", c)
+	return utils.Data(http.StatusAccepted, count, i18n.UserRemoved, c)
 }
 
 // UpdateUser godoc
@@ -165,6 +171,7 @@ func (u UserHandler) DeleteUser(c echo.Context) error {
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Failure 404 {object} utils.Response
+// @Failure 403 {object} utils.Response
 // @Router /api/v1/users/{id} [put]
 func (u UserHandler) UpdateUser(c echo.Context) error {
 	userID, err := objectIDFromStringID(c.Param("id"))
@@ -172,6 +179,10 @@ func (u UserHandler) UpdateUser(c echo.Context) error {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
+	if !canActOn(c, userID, auth.PermUsersWrite) {
+		return utils.Error(http.StatusForbidden, i18n.T(c, i18n.PermissionDenied, string(auth.PermUsersWrite)), c)
+	}
+
 	userInput := new(models.UserUpdateInput)
 
 	if err := c.Bind(userInput); err != nil {
@@ -180,20 +191,79 @@ func (u UserHandler) UpdateUser(c echo.Context) error {
 	}
 
 	if err := c.Validate(userInput); err != nil {
+		return err
+	}
+
+	// update fields - name, is_active, locale, updated_at
+	update := models.UserUpdate{
+		Name:     &userInput.Name,
+		IsActive: &userInput.IsActive,
+	}
+	if userInput.Locale != "" {
+		update.Locale = &userInput.Locale
+	}
+
+	count, err := u.userModel.UpdateOneUser(c.Request().Context(), userID, update)
+	if err != nil {
+		return err
+	}
+	return utils.Data(http.StatusOK, count, i18n.UserUpdated, c)
+}
+
+// ChangePassword godoc
+// @Summary Change the password of an User.
+// @Description change the password of the authenticated user
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param password body models.ChangePasswordInput true "Change Password"
+// @Success 200 {object} utils.Response
+// @Failure 400 {object} utils.Response
+// @Failure 401 {object} utils.Response
+// @Failure 403 {object} utils.Response
+// @Router /api/v1/users/{id}/password [put]
+func (u UserHandler) ChangePassword(c echo.Context) error {
+	userID, err := objectIDFromStringID(c.Param("id"))
+	if err != nil {
 		return utils.Error(http.StatusBadRequest, err.Error(), c)
 	}
 
-	// update fields - name, is_active, updated_at
-	update := bson.M{
-		"name":       userInput.Name,
-		"is_active":  userInput.IsActive,
-		"updated_at": time.Now(),
+	current, ok := auth.CurrentUser(c)
+	if !ok {
+		return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+	}
+	if current.ID != userID {
+		return utils.Error(http.StatusForbidden, i18n.PasswordOwnerOnly, c)
 	}
 
-	count, err := u.userModel.UpdateOneUser(update, bson.M{"_id": userID})
+	input := new(models.ChangePasswordInput)
+	if err := c.Bind(input); err != nil {
+		log.Printf("ECHO BINDING ERROR: %v\n", err)
+		return err
+	}
+
+	if err := c.Validate(input); err != nil {
+		return err
+	}
+
+	if err := auth.ComparePassword(current.PasswordHash, input.CurrentPassword); err != nil {
+		return utils.Error(http.StatusUnauthorized, i18n.PasswordMismatch, c)
+	}
+
+	hash, err := auth.HashPassword(input.NewPassword)
 	if err != nil {
-		log.Println(err)
-		return utils.Error(http.StatusNotFound, err.Error(), c)
+		log.Printf("PASSWORD HASHING ERROR: %v\n", err)
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+	}
+
+	count, err := u.userModel.UpdateOneUser(c.Request().Context(), userID, models.UserUpdate{PasswordHash: &hash})
+	if err != nil {
+		return err
+	}
+	// the reset tokens issued for the old password are revoked with it
+	if _, err := u.resetModel.MarkUserUsed(c.Request().Context(), userID); err != nil {
+		return err
 	}
-	return utils.Data(http.StatusOK, count, "user updated", c)
+	return utils.Data(http.StatusOK, count, i18n.PasswordChanged, c)
 }
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
 	"github.com/masihur1989/expense-tracker-api/internal/models"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -30,7 +32,7 @@ func init() {
 	}
 }
 
-func (u UserModelStub) ReadOneUser(filter interface{}) (models.User, error) {
+func (u UserModelStub) ReadOneUser(ctx context.Context, q models.UserQuery) (models.User, error) {
 	return models.User{
 		ID:          obzID,
 		CreatedAt:   time.Now(),
@@ -43,11 +45,11 @@ func (u UserModelStub) ReadOneUser(filter interface{}) (models.User, error) {
 	}, nil
 }
 
-func (u UserModelStub) InsertNewUser(user *models.User) (interface{}, error) {
+func (u UserModelStub) InsertNewUser(ctx context.Context, user *models.User) (interface{}, error) {
 	return 0, nil
 }
 
-func (u UserModelStub) ReadAllUsers(filter interface{}) ([]*models.User, error) {
+func (u UserModelStub) ReadAllUsers(ctx context.Context, f models.UserFilter, opts models.ListOptions) ([]*models.User, models.Page, error) {
 	var users []*models.User
 	users = append(users, &models.User{
 		ID:          obzID,
@@ -59,14 +61,14 @@ func (u UserModelStub) ReadAllUsers(filter interface{}) ([]*models.User, error)
 		Role:        "USER",
 		IsActive:    true,
 	})
-	return users, nil
+	return users, models.Page{Limit: opts.Limit}, nil
 }
 
-func (u UserModelStub) RemoveOneUser(filter interface{}) (int64, error) {
+func (u UserModelStub) RemoveOneUser(ctx context.Context, id primitive.ObjectID) (int64, error) {
 	return 0, nil
 }
 
-func (u UserModelStub) UpdateOneUser(updatedData interface{}, filter interface{}) (int64, error) {
+func (u UserModelStub) UpdateOneUser(ctx context.Context, id primitive.ObjectID, update models.UserUpdate) (int64, error) {
 	return 0, nil
 }
 
@@ -80,7 +82,7 @@ func TestReadOneUser(t *testing.T) {
 	c.SetParamValues("6009be17d6a899ab8340eb79")
 
 	u := UserModelStub{}
-	h := NewUserHandler(u)
+	h := NewUserHandler(u, nil)
 
 	if assert.NoError(t, h.GetUser(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
@@ -95,13 +97,46 @@ func TestReadAllUsers(t *testing.T) {
 	c.SetPath("/api/v1/users/:id")
 
 	u := UserModelStub{}
-	h := NewUserHandler(u)
+	h := NewUserHandler(u, nil)
 
 	if assert.NoError(t, h.GetUsers(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 	}
 }
 
+func TestReadAllUsersFields(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/?fields=id,name&limit=10", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := NewUserHandler(UserModelStub{}, nil)
+
+	if assert.NoError(t, h.GetUsers(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res struct {
+			Data []map[string]interface{} `json:"data"`
+			Meta models.Page              `json:"meta"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, map[string]interface{}{"id": obzID.Hex(), "name": "test"}, res.Data[0])
+		assert.Equal(t, int64(10), res.Meta.Limit)
+	}
+}
+
+func TestReadAllUsersInvalidSort(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/?sort=password_hash", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := NewUserHandler(UserModelStub{}, nil)
+
+	if assert.NoError(t, h.GetUsers(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
+
 func TestInsertNewUser(t *testing.T) {
 	e := echo.New()
 	reqByte, err := json.Marshal(models.User{
@@ -125,9 +160,107 @@ func TestInsertNewUser(t *testing.T) {
 	c.SetPath("/api/v1/users/")
 
 	u := UserModelStub{}
-	h := NewUserHandler(u)
+	h := NewUserHandler(u, nil)
 
 	if assert.NoError(t, h.GetUsers(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 	}
 }
+
+func TestReadOneUserOmitsPasswordHash(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/api/v1/users/:id")
+	c.SetParamNames("id")
+	c.SetParamValues("6009be17d6a899ab8340eb79")
+
+	h := NewUserHandler(AuthUserModelStub{hash: "$2a$10$secret"}, nil)
+
+	if assert.NoError(t, h.GetUser(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "$2a$10$secret")
+		assert.NotContains(t, rec.Body.String(), "password")
+	}
+}
+
+func TestUsersMemoryStore(t *testing.T) {
+	e := newTestEcho()
+	h := NewUserHandler(models.NewMemoryModels(models.NewMemoryStore()).Users, nil)
+
+	for _, name := range []string{"alice", "bob", "carol"} {
+		body := `{"email":"` + name + `@example.com","phone_number":"0123456789","name":"` + name + `","role":"USER","is_active":true,"password":"S3cretpass"}`
+		req := httptest.NewRequest(echo.POST, "/", bytes.NewReader([]byte(body)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		if assert.NoError(t, h.CreateUser(e.NewContext(req, rec))) {
+			assert.Equal(t, http.StatusCreated, rec.Code)
+		}
+	}
+
+	var names []string
+	cursor := ""
+	for {
+		req := httptest.NewRequest(echo.GET, "/?sort=name&limit=2&cursor="+cursor, nil)
+		rec := httptest.NewRecorder()
+		assert.NoError(t, h.GetUsers(e.NewContext(req, rec)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var res struct {
+			Data []models.User `json:"data"`
+			Meta models.Page   `json:"meta"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		for _, u := range res.Data {
+			names = append(names, u.Name)
+		}
+		if cursor = res.Meta.NextCursor; cursor == "" {
+			break
+		}
+	}
+	assert.Equal(t, []string{"alice", "bob", "carol"}, names)
+}
+
+func TestCreateUserDuplicateEmail(t *testing.T) {
+	e := newTestEcho()
+	h := NewUserHandler(models.NewMemoryModels(models.NewMemoryStore()).Users, nil)
+
+	body := `{"email":"alice@example.com","phone_number":"0123456789","name":"alice","role":"USER","is_active":true,"password":"S3cretpass"}`
+	for _, code := range []int{http.StatusCreated, http.StatusConflict} {
+		req := httptest.NewRequest(echo.POST, "/", bytes.NewReader([]byte(body)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if err := h.CreateUser(c); err != nil {
+			HTTPErrorHandler(err, c)
+		}
+		assert.Equal(t, code, rec.Code)
+	}
+}
+
+// SlowUserModelStub never answers before the deadline of the request
+type SlowUserModelStub struct {
+	UserModelStub
+}
+
+func (u SlowUserModelStub) ReadAllUsers(ctx context.Context, f models.UserFilter, opts models.ListOptions) ([]*models.User, models.Page, error) {
+	<-ctx.Done()
+	return nil, models.Page{}, errs.Wrap(errs.Timeout, models.ErrTimeout.Message, ctx.Err())
+}
+
+func TestReadAllUsersTimeout(t *testing.T) {
+	e := echo.New()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(echo.GET, "/", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := NewUserHandler(SlowUserModelStub{}, nil)
+
+	err := h.GetUsers(c)
+	if assert.Error(t, err) {
+		HTTPErrorHandler(err, c)
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	}
+}
@@ -1,9 +1,17 @@
 package handler
 
 import (
+	"context"
 	"log"
+	"net/http"
 	"time"
 
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -13,7 +21,7 @@ func objectIDFromStringID(param string) (primitive.ObjectID, error) {
 	id, err := primitive.ObjectIDFromHex(param)
 	if err != nil {
 		log.Printf("INVALID ID PASSED: %v\n", err)
-		return primitive.NilObjectID, err
+		return primitive.NilObjectID, errs.Wrap(errs.Validation, i18n.InvalidID, err)
 	}
 	return id, nil
 }
@@ -22,3 +30,93 @@ func objectIDFromStringID(param string) (primitive.ObjectID, error) {
 func parseDateToFormat(layout, date string) (time.Time, error) {
 	return time.Parse(layout, date)
 }
+
+// canActOn check if the authenticated user owns the resource
+// or has the permission to act on the resources of anyone
+func canActOn(c echo.Context, ownerID primitive.ObjectID, perm auth.Permission) bool {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return false
+	}
+	return user.ID == ownerID || auth.Can(user.Role, perm)
+}
+
+// projectToFileIn the project of the hex id an expense is filed in, the zero id without project.
+// An expense can only be filed in a project the author is an active member of, or by an ADMIN.
+func projectToFileIn(ctx context.Context, pm models.ProjectModeler, user models.User, hex string) (primitive.ObjectID, error) {
+	if hex == "" {
+		return primitive.NilObjectID, nil
+	}
+	projectID, err := objectIDFromStringID(hex)
+	if err != nil {
+		return projectID, err
+	}
+	member, err := pm.ReadOneProjectUser(ctx, models.ProjectUserFilter{ProjectID: projectID, UserID: user.ID, IsActive: models.Bool(true)})
+	if err != nil && !errs.Is(err, errs.NotFound) {
+		return projectID, err
+	}
+	if member.ID.IsZero() && user.Role != models.RoleAdmin {
+		return projectID, echo.NewHTTPError(http.StatusForbidden, i18n.NotProjectMember)
+	}
+	return projectID, nil
+}
+
+// expenseScope the expenses the authenticated user can read, nil for the ADMIN reading all of them.
+// The others read the expenses of the projects they are active members of and their own expenses
+// out of projects, or all the expenses out of projects when they approve them.
+func expenseScope(c echo.Context, pm models.ProjectModeler) (*models.ExpenseScope, error) {
+	user, ok := auth.CurrentUser(c)
+	if !ok {
+		return nil, echo.NewHTTPError(http.StatusUnauthorized, i18n.Unauthenticated)
+	}
+	if user.Role == models.RoleAdmin {
+		return nil, nil
+	}
+	memberships, err := pm.ReadAllProjectUser(c.Request().Context(), models.ProjectUserFilter{UserID: user.ID, IsActive: models.Bool(true)})
+	if err != nil {
+		return nil, err
+	}
+	scope := &models.ExpenseScope{Projects: make([]primitive.ObjectID, 0, len(memberships))}
+	for _, membership := range memberships {
+		scope.Projects = append(scope.Projects, membership.ProjectID)
+	}
+	if !auth.Can(user.Role, auth.PermExpensesApprove) {
+		scope.Author = user.ID
+	}
+	return scope, nil
+}
+
+// readableExpense the expense of the id when the authenticated user can read it,
+// not found when it is out of their scope so its existence is not disclosed
+func readableExpense(c echo.Context, em models.ExpenseModeler, pm models.ProjectModeler, id primitive.ObjectID) (models.Expense, error) {
+	expense, err := em.ReadOne(c.Request().Context(), id)
+	if err != nil {
+		return expense, err
+	}
+	scope, err := expenseScope(c, pm)
+	if err != nil {
+		return expense, err
+	}
+	if scope != nil && !scope.Allows(expense) {
+		return models.Expense{}, errs.NotFoundf("expense not found")
+	}
+	return expense, nil
+}
+
+// listOptions parse the pagination, sort and fields query params of a list endpoint
+func listOptions(c echo.Context, fields models.ListFields, defaultSort []models.SortField) (models.ListOptions, error) {
+	opts, err := models.ParseListOptions(c.QueryParams(), fields, defaultSort)
+	if err != nil {
+		log.Printf("INVALID QUERY PARAM PASSED: %v\n", err)
+	}
+	return opts, err
+}
+
+// listData respond with the page of items reduced to the requested fields
+func listData(items interface{}, page models.Page, opts models.ListOptions, message string, c echo.Context) error {
+	data, err := models.SelectFields(items, opts.Fields)
+	if err != nil {
+		return utils.Error(http.StatusInternalServerError, err.Error(), c)
+	}
+	return utils.DataMeta(http.StatusOK, data, page, message, c)
+}
@@ -0,0 +1,186 @@
+package i18n
+
+// bnMessages bengali catalog of the response messages
+var bnMessages = map[string]string{
+	ServerUp: "সার্ভার চালু আছে",
+
+	Unauthenticated:        "অপ্রমাণিত অনুরোধ",
+	MissingBearerToken:     "বেয়ারার টোকেন নেই",
+	InvalidToken:           "টোকেনটি অবৈধ অথবা মেয়াদোত্তীর্ণ",
+	UserNotFoundOrInactive: "ব্যবহারকারী পাওয়া যায়নি অথবা নিষ্ক্রিয়",
+	PermissionDenied:       "অনুমতি নেই: {0}",
+	NotProjectMember:       "প্রকল্পের সদস্য নন",
+	InvalidCredentials:     "ভুল লগইন তথ্য",
+	UserNotActive:          "ব্যবহারকারী সক্রিয় নন",
+	LoginSuccessful:        "লগইন সফল হয়েছে",
+	TokenRefreshed:         "টোকেন নবায়ন করা হয়েছে",
+	PasswordReset:          "পাসওয়ার্ড রিসেট করা হয়েছে",
+	PasswordChanged:        "পাসওয়ার্ড পরিবর্তন করা হয়েছে",
+	PasswordOwnerOnly:      "শুধু মালিক নিজের পাসওয়ার্ড পরিবর্তন করতে পারেন",
+	PasswordMismatch:       "বর্তমান পাসওয়ার্ড মেলেনি",
+	PasswordResetTitle:     "আপনার পাসওয়ার্ড রিসেট করুন",
+	PasswordResetBody:      "পাসওয়ার্ড রিসেট করতে টোকেন {0} ব্যবহার করুন, এটির মেয়াদ {1} এ শেষ হবে",
+	PasswordResetIssued:    "ইমেইলটি থাকলে একটি রিসেট টোকেন দেওয়া হয়েছে",
+	InvalidResetToken:      "রিসেট টোকেনটি অবৈধ অথবা মেয়াদোত্তীর্ণ",
+
+	UserCreated:  "ব্যবহারকারী তৈরি করা হয়েছে",
+	UserDetails:  "ব্যবহারকারীদের বিবরণ",
+	UserDetail:   "ব্যবহারকারীর বিবরণ",
+	UserRemoved:  "ব্যবহারকারী মুছে ফেলা হয়েছে",
+	UserUpdated:  "ব্যবহারকারী হালনাগাদ করা হয়েছে",
+	UserProjects: "ব্যবহারকারীর প্রকল্পসমূহ",
+
+	CategoryCreated:         "বিভাগ তৈরি করা হয়েছে",
+	CategoryDetails:         "বিভাগের বিবরণ",
+	CategoryRemoved:         "বিভাগ মুছে ফেলা হয়েছে",
+	CategoryUpdated:         "বিভাগ হালনাগাদ করা হয়েছে",
+	CategoryMoved:           "বিভাগ সরানো হয়েছে",
+	CategoryMerged:          "বিভাগ একীভূত করা হয়েছে",
+	CategoryTotals:          "বিভাগের মোট হিসাব",
+	CategoryHasChildren:     "বিভাগটির উপবিভাগ আছে, আগে সেগুলো সরান অথবা একীভূত করুন",
+	CategoryCycle:           "একটি বিভাগকে তার নিজের নিচে সরানো যায় না",
+	CategoryMergeCycle:      "একটি বিভাগকে তার নিজের অথবা তার উপবিভাগের সাথে একীভূত করা যায় না",
+	CategorySlugEmpty:       "নামে স্লাগের জন্য কোনো অক্ষর বা সংখ্যা নেই, স্লাগ উল্লেখ করুন",
+	CategoryInUse:           "বিভাগটি খরচে ব্যবহৃত হচ্ছে, একটি বিকল্প বিভাগ উল্লেখ করুন",
+	CategoryReplacementSelf: "একটি বিভাগকে তার নিজের দ্বারা প্রতিস্থাপন করা যায় না",
+	CategoryHasBudgets:      "বিভাগটি বাজেটে ব্যবহৃত হচ্ছে, একটি বিকল্প বিভাগ উল্লেখ করুন",
+	CategoryBudgetsOverlap:  "একটি প্রকল্পের দুটি বিভাগেই একই সময়কালের বাজেট আছে, আগে একটি মুছে ফেলুন",
+
+	ExchangeRatesLoaded: "বিনিময় হার লোড করা হয়েছে",
+	ExchangeRates:       "বিনিময় হার",
+
+	ExpenseCreated:       "খরচ তৈরি করা হয়েছে",
+	ExpenseDetails:       "খরচের বিবরণ",
+	ExpenseDetail:        "খরচের বিস্তারিত",
+	ExpenseRemoved:       "খরচ মুছে ফেলা হয়েছে",
+	ExpenseUpdated:       "খরচ হালনাগাদ করা হয়েছে",
+	ExpenseNotRemovable:  "শুধু খসড়া অথবা প্রত্যাখ্যাত খরচ মুছে ফেলা যায়",
+	ExpenseNotEditable:   "শুধু খসড়া অথবা প্রত্যাখ্যাত খরচ পরিবর্তন করা যায়",
+	ExpenseAuthorOnly:    "শুধু লেখক এই খরচ পরিবর্তন করতে পারেন",
+	ExpenseSubmitOnly:    "শুধু লেখক এই খরচ জমা দিতে পারেন",
+	ExpenseStatusChanged: "খরচের অবস্থা পরিবর্তিত হয়েছে, আবার চেষ্টা করুন",
+	ExpenseSubmitted:     "খরচ জমা দেওয়া হয়েছে",
+	ExpenseApproved:      "খরচ অনুমোদিত হয়েছে",
+	ExpenseRejected:      "খরচ প্রত্যাখ্যাত হয়েছে",
+	ExpenseReimbursed:    "খরচ পরিশোধ করা হয়েছে",
+
+	AttachmentAuthorOnly:  "শুধু লেখক রসিদ সংযুক্ত করতে পারেন",
+	AttachmentRemoverOnly: "শুধু লেখক রসিদ মুছে ফেলতে পারেন",
+	FileTooLarge:          "ফাইলটি {0} বাইটের বেশি",
+	UnsupportedFileType:   "অসমর্থিত ফাইলের ধরন {0}",
+	AttachmentExists:      "সংযুক্তি আগে থেকেই আছে",
+	AttachmentCreated:     "সংযুক্তি তৈরি করা হয়েছে",
+	AttachmentDetails:     "সংযুক্তির বিবরণ",
+	AttachmentRemoved:     "সংযুক্তি মুছে ফেলা হয়েছে",
+	AttachmentNotFound:    "সংযুক্তি পাওয়া যায়নি",
+
+	ProjectCreated:       "প্রকল্প তৈরি করা হয়েছে",
+	ProjectDetails:       "প্রকল্পের বিবরণ",
+	ProjectDetail:        "প্রকল্পের বিস্তারিত",
+	ProjectRemoved:       "প্রকল্প মুছে ফেলা হয়েছে",
+	ProjectStartRequired: "সময়কালের শুরু উল্লেখ করুন",
+	ProjectEndRequired:   "সময়কালের শেষ উল্লেখ করুন",
+	ProjectReport:        "প্রকল্পের সম্পূর্ণ বিবরণ",
+	ProjectMemberExists:  "ব্যবহারকারী ইতিমধ্যে প্রকল্পের সদস্য",
+	ProjectUserCreated:   "প্রকল্পের সদস্য যোগ করা হয়েছে",
+	ProjectUserDetails:   "প্রকল্পের সদস্যদের বিবরণ",
+	ProjectUserNotFound:  "প্রকল্পের সদস্য পাওয়া যায়নি",
+	ProjectUserRemoved:   "প্রকল্পের সদস্য সরানো হয়েছে",
+	ProjectRoleAbove:     "প্রকল্পে আপনার নিজের চেয়ে উচ্চতর ভূমিকা দেওয়া বা সরানো যায় না",
+	ProjectLastAdmin:     "প্রকল্পের শেষ অ্যাডমিনকে সরানো যায় না",
+	InvalidID:            "অবৈধ আইডি",
+
+	BudgetCreated:    "বাজেট তৈরি করা হয়েছে",
+	BudgetDetails:    "বাজেটের বিবরণ",
+	BudgetUpdated:    "বাজেট হালনাগাদ করা হয়েছে",
+	BudgetRemoved:    "বাজেট মুছে ফেলা হয়েছে",
+	BudgetReport:     "বাজেটের ব্যবহার",
+	BudgetNotFound:   "বাজেট পাওয়া যায়নি",
+	BudgetAmountZero: "বাজেটের পরিমাণ অবশ্যই শূন্যের বেশি হতে হবে",
+
+	NotificationDetails:            "বিজ্ঞপ্তি",
+	NotificationRead:               "বিজ্ঞপ্তি পড়া হিসেবে চিহ্নিত",
+	NotificationUnread:             "বিজ্ঞপ্তি না-পড়া হিসেবে চিহ্নিত",
+	NotificationsRead:              "বিজ্ঞপ্তিগুলো পড়া হিসেবে চিহ্নিত",
+	NotificationNotFound:           "বিজ্ঞপ্তি পাওয়া যায়নি",
+	NotificationPreferencesDetails: "বিজ্ঞপ্তির পছন্দসমূহ",
+	NotificationPreferencesUpdated: "বিজ্ঞপ্তির পছন্দসমূহ হালনাগাদ করা হয়েছে",
+	NotificationWebhookURL:         "ওয়েবহুক ইউআরএল অবশ্যই একটি http বা https ইউআরএল হতে হবে",
+	NotificationWebhookPublic:      "ওয়েবহুক ইউআরএল অবশ্যই একটি সর্বজনীন ঠিকানার দিকে নির্দেশ করতে হবে",
+	NotifyBudgetTitle:              "বাজেটের {0}% পূর্ণ হয়েছে",
+	NotifyBudgetBody:               "প্রকল্প {3} এর {2} বাজেটের {1} এর মধ্যে {0} খরচ হয়েছে",
+	NotifyPendingTitle:             "খরচ অনুমোদনের অপেক্ষায়",
+	NotifyPendingBody:              "{1} পরিমাণের খরচ {0} {2} থেকে অনুমোদনের অপেক্ষায় আছে",
+
+	RecurringCreated:       "পুনরাবৃত্ত খরচ তৈরি করা হয়েছে",
+	RecurringDetails:       "পুনরাবৃত্ত খরচের বিবরণ",
+	RecurringList:          "পুনরাবৃত্ত খরচসমূহ",
+	RecurringNotFound:      "পুনরাবৃত্ত খরচ পাওয়া যায়নি",
+	RecurringPaused:        "পুনরাবৃত্ত খরচ স্থগিত করা হয়েছে",
+	RecurringResumed:       "পুনরাবৃত্ত খরচ আবার চালু করা হয়েছে",
+	RecurringSkipped:       "এই তারিখটি বাদ দেওয়া হয়েছে",
+	RecurringEnded:         "পুনরাবৃত্ত খরচ শেষ করা হয়েছে",
+	RecurringAuthorOnly:    "শুধু লেখক পুনরাবৃত্ত খরচ পরিবর্তন করতে পারেন",
+	RecurringNotActive:     "পুনরাবৃত্ত খরচটি সক্রিয় নয়",
+	RecurringNotPaused:     "পুনরাবৃত্ত খরচটি স্থগিত নয়",
+	RecurringAlreadyEnded:  "পুনরাবৃত্ত খরচটি ইতিমধ্যে শেষ হয়েছে",
+	RecurringNotOccurrence: "তারিখটি পুনরাবৃত্ত খরচের কোনো আসন্ন তারিখ নয়",
+
+	ExpenseNotShared:      "শুধু প্রকল্পের খরচ অন্য সদস্য পরিশোধ করতে বা ভাগ করা যায়",
+	SplitNotMember:        "পরিশোধকারী এবং ভাগের ব্যবহারকারীদের অবশ্যই প্রকল্পের সক্রিয় সদস্য হতে হবে",
+	ProjectBalances:       "প্রকল্পের ব্যালেন্স",
+	SettlementCreated:     "নিষ্পত্তি লিপিবদ্ধ করা হয়েছে",
+	SettlementList:        "নিষ্পত্তিসমূহ",
+	SettlementRemoved:     "নিষ্পত্তি মুছে ফেলা হয়েছে",
+	SettlementNotFound:    "নিষ্পত্তি পাওয়া যায়নি",
+	SettlementNotMember:   "নিষ্পত্তির পরিশোধকারী ও গ্রহীতাকে অবশ্যই প্রকল্পের সদস্য হতে হবে",
+	SettlementPartiesOnly: "শুধু পরিশোধকারী, গ্রহীতা অথবা প্রকল্পের অনুমোদনকারী নিষ্পত্তি পরিবর্তন করতে পারেন",
+	SettlementAmountZero:  "নিষ্পত্তির পরিমাণ অবশ্যই শূন্যের বেশি হতে হবে",
+
+	// errors of the storage
+	"user not found":                                    "ব্যবহারকারী পাওয়া যায়নি",
+	"category not found":                                "বিভাগ পাওয়া যায়নি",
+	"expense not found":                                 "খরচ পাওয়া যায়নি",
+	"project not found":                                 "প্রকল্প পাওয়া যায়নি",
+	"project member not found":                          "প্রকল্পের সদস্য পাওয়া যায়নি",
+	"password reset not found":                          "পাসওয়ার্ড রিসেট পাওয়া যায়নি",
+	"exchange rate not found":                           "বিনিময় হার পাওয়া যায়নি",
+	"invalid cursor":                                    "অবৈধ কার্সর",
+	"duplicate key: the resource already exists":        "ডুপ্লিকেট কী: রিসোর্সটি আগে থেকেই আছে",
+	"the storage is unavailable":                        "স্টোরেজ পাওয়া যাচ্ছে না",
+	"the storage did not answer in time":                "স্টোরেজ সময়মতো সাড়া দেয়নি",
+	"amount can not be negative":                        "পরিমাণ ঋণাত্মক হতে পারে না",
+	"amount has more decimals than the currency allows": "পরিমাণে মুদ্রার অনুমোদিত দশমিকের চেয়ে বেশি ঘর আছে",
+	"invalid amount":                                    "অবৈধ পরিমাণ",
+	"currencies do not match":                           "মুদ্রা মেলেনি",
+	"invalid recurrence rule":                           "অবৈধ পুনরাবৃত্তি নিয়ম",
+	"a user can only be once in the splits":             "একজন ব্যবহারকারী ভাগে শুধু একবার থাকতে পারেন",
+	"invalid split value":                               "ভাগের অবৈধ মান",
+	"the split amounts must add up to the total":        "ভাগের পরিমাণগুলোর যোগফল অবশ্যই মোট পরিমাণের সমান হতে হবে",
+	"the split percentages must add up to 100":          "ভাগের শতাংশগুলোর যোগফল অবশ্যই ১০০ হতে হবে",
+	"the split shares must add up to more than zero":    "ভাগের অংশগুলোর যোগফল অবশ্যই শূন্যের বেশি হতে হবে",
+}
+
+// bnValidation bengali messages of the validation tags, `{0}` is the field and `{1}` the param of the tag
+var bnValidation = map[string]string{
+	"required":      "{0} একটি আবশ্যিক ঘর",
+	"required_with": "সংশ্লিষ্ট ঘরগুলোর সাথে {0} আবশ্যিক",
+	"email":         "{0} অবশ্যই একটি বৈধ ইমেইল ঠিকানা হতে হবে",
+	"numeric":       "{0} অবশ্যই একটি বৈধ সংখ্যা হতে হবে",
+	"alpha":         "{0} শুধু অক্ষর ধারণ করতে পারে",
+	"oneof":         "{0} অবশ্যই [{1}] এর একটি হতে হবে",
+	"max":           "{0} সর্বোচ্চ {1} হতে পারে",
+	"min":           "{0} অন্তত {1} হতে হবে",
+	"gt":            "{0} অবশ্যই {1} এর বেশি হতে হবে",
+	"nefield":       "{0} অবশ্যই {1} এর সমান হতে পারবে না",
+	"password":      "{0} অবশ্যই ৮ থেকে ৭২ অক্ষরের হতে হবে এবং বড় হাতের, ছোট হাতের অক্ষর ও সংখ্যা থাকতে হবে",
+	"currency":      "{0} অবশ্যই একটি সমর্থিত ISO 4217 মুদ্রা কোড হতে হবে",
+	"money":         "{0} অবশ্যই মুদ্রার অনুমোদিত দশমিকের মধ্যে একটি ধনাত্মক পরিমাণ হতে হবে",
+	"date":          "{0} অবশ্যই YYYY-MM-DD ফরম্যাটের তারিখ হতে হবে",
+	"objectid":      "{0} অবশ্যই একটি বৈধ আইডি হতে হবে",
+	"locale":        "{0} অবশ্যই একটি সমর্থিত ভাষা হতে হবে",
+	"slug":          "{0} শুধু ছোট হাতের অক্ষর ও সংখ্যা ধারণ করতে পারে, একক ড্যাশ দিয়ে আলাদা",
+	"hexcolor":      "{0} অবশ্যই একটি বৈধ HEX রঙ হতে হবে",
+	"rrule":         "{0} অবশ্যই একটি সমর্থিত পুনরাবৃত্তি নিয়ম হতে হবে, যেমন FREQ=MONTHLY;BYMONTHDAY=1",
+	"url":           "{0} অবশ্যই একটি বৈধ URL হতে হবে",
+}
@@ -0,0 +1,186 @@
+package i18n
+
+// deMessages german catalog of the response messages
+var deMessages = map[string]string{
+	ServerUp: "Der Server läuft",
+
+	Unauthenticated:        "nicht authentifizierte Anfrage",
+	MissingBearerToken:     "Bearer-Token fehlt",
+	InvalidToken:           "ungültiges oder abgelaufenes Token",
+	UserNotFoundOrInactive: "Benutzer nicht gefunden oder inaktiv",
+	PermissionDenied:       "Berechtigung verweigert: {0}",
+	NotProjectMember:       "kein Mitglied des Projekts",
+	InvalidCredentials:     "ungültige Anmeldedaten",
+	UserNotActive:          "Benutzer ist nicht aktiv",
+	LoginSuccessful:        "Anmeldung erfolgreich",
+	TokenRefreshed:         "Token erneuert",
+	PasswordReset:          "Passwort zurückgesetzt",
+	PasswordChanged:        "Passwort geändert",
+	PasswordOwnerOnly:      "das Passwort kann nur von seinem Inhaber geändert werden",
+	PasswordMismatch:       "das aktuelle Passwort stimmt nicht überein",
+	PasswordResetTitle:     "Passwort zurücksetzen",
+	PasswordResetBody:      "verwende das Token {0}, um dein Passwort zurückzusetzen, es läuft um {1} ab",
+	PasswordResetIssued:    "falls die E-Mail-Adresse existiert, wurde ein Token zum Zurücksetzen ausgestellt",
+	InvalidResetToken:      "ungültiges oder abgelaufenes Token zum Zurücksetzen",
+
+	UserCreated:  "Benutzer erstellt",
+	UserDetails:  "Benutzerdetails",
+	UserDetail:   "Benutzerdetail",
+	UserRemoved:  "Benutzer entfernt",
+	UserUpdated:  "Benutzer aktualisiert",
+	UserProjects: "Projekte des Benutzers",
+
+	CategoryCreated:         "Kategorie erstellt",
+	CategoryDetails:         "Kategoriedetails",
+	CategoryRemoved:         "Kategorie entfernt",
+	CategoryUpdated:         "Kategorie aktualisiert",
+	CategoryMoved:           "Kategorie verschoben",
+	CategoryMerged:          "Kategorie zusammengeführt",
+	CategoryTotals:          "Summen der Kategorien",
+	CategoryHasChildren:     "die Kategorie hat Unterkategorien, verschieben oder zusammenführen Sie diese zuerst",
+	CategoryCycle:           "eine Kategorie kann nicht unter sich selbst verschoben werden",
+	CategoryMergeCycle:      "eine Kategorie kann nicht mit sich selbst oder ihren Unterkategorien zusammengeführt werden",
+	CategorySlugEmpty:       "der Name enthält keine Buchstaben oder Ziffern für den Slug, geben Sie den Slug an",
+	CategoryInUse:           "die Kategorie wird von Ausgaben verwendet, geben Sie eine Ersatzkategorie an",
+	CategoryReplacementSelf: "eine Kategorie kann nicht durch sich selbst ersetzt werden",
+	CategoryHasBudgets:      "die Kategorie wird von Budgets verwendet, geben Sie eine Ersatzkategorie an",
+	CategoryBudgetsOverlap:  "ein Projekt hat Budgets auf beiden Kategorien für denselben Zeitraum, entfernen Sie zuerst eines davon",
+
+	ExchangeRatesLoaded: "Wechselkurse geladen",
+	ExchangeRates:       "Wechselkurse",
+
+	ExpenseCreated:       "Ausgabe erstellt",
+	ExpenseDetails:       "Ausgabendetails",
+	ExpenseDetail:        "Ausgabendetail",
+	ExpenseRemoved:       "Ausgabe entfernt",
+	ExpenseUpdated:       "Ausgabe aktualisiert",
+	ExpenseNotRemovable:  "nur Entwürfe oder abgelehnte Ausgaben können entfernt werden",
+	ExpenseNotEditable:   "nur Entwürfe oder abgelehnte Ausgaben können geändert werden",
+	ExpenseAuthorOnly:    "nur der Autor kann diese Ausgabe ändern",
+	ExpenseSubmitOnly:    "nur der Autor kann diese Ausgabe einreichen",
+	ExpenseStatusChanged: "der Status der Ausgabe hat sich geändert, bitte erneut versuchen",
+	ExpenseSubmitted:     "Ausgabe eingereicht",
+	ExpenseApproved:      "Ausgabe genehmigt",
+	ExpenseRejected:      "Ausgabe abgelehnt",
+	ExpenseReimbursed:    "Ausgabe erstattet",
+
+	AttachmentAuthorOnly:  "nur der Autor kann Belege anhängen",
+	AttachmentRemoverOnly: "nur der Autor kann Belege entfernen",
+	FileTooLarge:          "die Datei überschreitet {0} Bytes",
+	UnsupportedFileType:   "nicht unterstützter Dateityp {0}",
+	AttachmentExists:      "der Anhang existiert bereits",
+	AttachmentCreated:     "Anhang erstellt",
+	AttachmentDetails:     "Anhangdetails",
+	AttachmentRemoved:     "Anhang entfernt",
+	AttachmentNotFound:    "Anhang nicht gefunden",
+
+	ProjectCreated:       "Projekt erstellt",
+	ProjectDetails:       "Projektdetails",
+	ProjectDetail:        "Projektdetail",
+	ProjectRemoved:       "Projekt entfernt",
+	ProjectStartRequired: "Geben Sie den Beginn des Zeitraums an",
+	ProjectEndRequired:   "Geben Sie das Ende des Zeitraums an",
+	ProjectReport:        "vollständige Projektdetails",
+	ProjectMemberExists:  "der Benutzer ist bereits Mitglied des Projekts",
+	ProjectUserCreated:   "Projektmitglied erstellt",
+	ProjectUserDetails:   "Details der Projektmitglieder",
+	ProjectUserNotFound:  "Projektmitglied nicht gefunden",
+	ProjectUserRemoved:   "Projektmitglied entfernt",
+	ProjectRoleAbove:     "eine Rolle über Ihrer eigenen im Projekt kann nicht vergeben oder entzogen werden",
+	ProjectLastAdmin:     "der letzte Administrator des Projekts kann nicht entfernt werden",
+	InvalidID:            "ungültige ID",
+
+	BudgetCreated:    "Budget erstellt",
+	BudgetDetails:    "Budgetdetails",
+	BudgetUpdated:    "Budget aktualisiert",
+	BudgetRemoved:    "Budget entfernt",
+	BudgetReport:     "Budgetauslastung",
+	BudgetNotFound:   "Budget nicht gefunden",
+	BudgetAmountZero: "der Budgetbetrag muss größer als null sein",
+
+	NotificationDetails:            "Benachrichtigungen",
+	NotificationRead:               "Benachrichtigung als gelesen markiert",
+	NotificationUnread:             "Benachrichtigung als ungelesen markiert",
+	NotificationsRead:              "Benachrichtigungen als gelesen markiert",
+	NotificationNotFound:           "Benachrichtigung nicht gefunden",
+	NotificationPreferencesDetails: "Benachrichtigungseinstellungen",
+	NotificationPreferencesUpdated: "Benachrichtigungseinstellungen aktualisiert",
+	NotificationWebhookURL:         "die Webhook-URL muss eine http- oder https-URL sein",
+	NotificationWebhookPublic:      "die Webhook-URL muss auf eine öffentliche Adresse zeigen",
+	NotifyBudgetTitle:              "{0}% des Budgets erreicht",
+	NotifyBudgetBody:               "{0} von {1} des Budgets {2} des Projekts {3} ausgegeben",
+	NotifyPendingTitle:             "Ausgabe wartet auf Genehmigung",
+	NotifyPendingBody:              "die Ausgabe {0} über {1} wartet seit {2} auf Genehmigung",
+
+	RecurringCreated:       "wiederkehrende Ausgabe erstellt",
+	RecurringDetails:       "Details der wiederkehrenden Ausgabe",
+	RecurringList:          "wiederkehrende Ausgaben",
+	RecurringNotFound:      "wiederkehrende Ausgabe nicht gefunden",
+	RecurringPaused:        "wiederkehrende Ausgabe pausiert",
+	RecurringResumed:       "wiederkehrende Ausgabe fortgesetzt",
+	RecurringSkipped:       "Termin übersprungen",
+	RecurringEnded:         "wiederkehrende Ausgabe beendet",
+	RecurringAuthorOnly:    "nur der Autor kann die wiederkehrende Ausgabe ändern",
+	RecurringNotActive:     "die wiederkehrende Ausgabe ist nicht aktiv",
+	RecurringNotPaused:     "die wiederkehrende Ausgabe ist nicht pausiert",
+	RecurringAlreadyEnded:  "die wiederkehrende Ausgabe ist bereits beendet",
+	RecurringNotOccurrence: "das Datum ist kein anstehender Termin der wiederkehrenden Ausgabe",
+
+	ExpenseNotShared:      "nur Ausgaben eines Projekts können von einem anderen Mitglied bezahlt oder aufgeteilt werden",
+	SplitNotMember:        "der Zahler und die Benutzer der Aufteilung müssen aktive Mitglieder des Projekts sein",
+	ProjectBalances:       "Salden des Projekts",
+	SettlementCreated:     "Ausgleichszahlung erfasst",
+	SettlementList:        "Ausgleichszahlungen",
+	SettlementRemoved:     "Ausgleichszahlung entfernt",
+	SettlementNotFound:    "Ausgleichszahlung nicht gefunden",
+	SettlementNotMember:   "Zahler und Empfänger einer Ausgleichszahlung müssen Mitglieder des Projekts sein",
+	SettlementPartiesOnly: "nur der Zahler, der Empfänger oder ein Genehmiger des Projekts kann eine Ausgleichszahlung ändern",
+	SettlementAmountZero:  "der Betrag der Ausgleichszahlung muss größer als null sein",
+
+	// errors of the storage
+	"user not found":                                    "Benutzer nicht gefunden",
+	"category not found":                                "Kategorie nicht gefunden",
+	"expense not found":                                 "Ausgabe nicht gefunden",
+	"project not found":                                 "Projekt nicht gefunden",
+	"project member not found":                          "Projektmitglied nicht gefunden",
+	"password reset not found":                          "Passwortzurücksetzung nicht gefunden",
+	"exchange rate not found":                           "Wechselkurs nicht gefunden",
+	"invalid cursor":                                    "ungültiger Cursor",
+	"duplicate key: the resource already exists":        "doppelter Schlüssel: die Ressource existiert bereits",
+	"the storage is unavailable":                        "der Speicher ist nicht erreichbar",
+	"the storage did not answer in time":                "der Speicher hat nicht rechtzeitig geantwortet",
+	"amount can not be negative":                        "der Betrag darf nicht negativ sein",
+	"amount has more decimals than the currency allows": "der Betrag hat mehr Nachkommastellen als die Währung erlaubt",
+	"invalid amount":                                    "ungültiger Betrag",
+	"currencies do not match":                           "die Währungen stimmen nicht überein",
+	"invalid recurrence rule":                           "ungültige Wiederholungsregel",
+	"a user can only be once in the splits":             "ein Benutzer darf nur einmal in der Aufteilung vorkommen",
+	"invalid split value":                               "ungültiger Wert der Aufteilung",
+	"the split amounts must add up to the total":        "die Beträge der Aufteilung müssen den Gesamtbetrag ergeben",
+	"the split percentages must add up to 100":          "die Prozentsätze der Aufteilung müssen 100 ergeben",
+	"the split shares must add up to more than zero":    "die Anteile der Aufteilung müssen mehr als null ergeben",
+}
+
+// deValidation german messages of the validation tags, `{0}` is the field and `{1}` the param of the tag
+var deValidation = map[string]string{
+	"required":      "{0} ist ein Pflichtfeld",
+	"required_with": "{0} ist zusammen mit den zugehörigen Feldern erforderlich",
+	"email":         "{0} muss eine gültige E-Mail-Adresse sein",
+	"numeric":       "{0} muss ein gültiger numerischer Wert sein",
+	"alpha":         "{0} darf nur Buchstaben enthalten",
+	"oneof":         "{0} muss einer der folgenden Werte sein: [{1}]",
+	"max":           "{0} darf höchstens {1} sein",
+	"min":           "{0} muss mindestens {1} sein",
+	"gt":            "{0} muss größer als {1} sein",
+	"nefield":       "{0} darf nicht gleich {1} sein",
+	"password":      "{0} muss 8 bis 72 Zeichen lang sein und Groß-, Kleinbuchstaben und Ziffern enthalten",
+	"currency":      "{0} muss ein unterstützter ISO-4217-Währungscode sein",
+	"money":         "{0} muss ein positiver Betrag mit nicht mehr Nachkommastellen sein, als die Währung erlaubt",
+	"date":          "{0} muss ein Datum im Format JJJJ-MM-TT sein",
+	"objectid":      "{0} muss eine gültige ID sein",
+	"locale":        "{0} muss eine unterstützte Sprache sein",
+	"slug":          "{0} darf nur Kleinbuchstaben und Ziffern enthalten, getrennt durch einzelne Bindestriche",
+	"hexcolor":      "{0} muss eine gültige HEX-Farbe sein",
+	"rrule":         "{0} muss eine unterstützte Wiederholungsregel sein, z. B. FREQ=MONTHLY;BYMONTHDAY=1",
+	"url":           "{0} muss eine gültige URL sein",
+}
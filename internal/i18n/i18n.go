@@ -0,0 +1,185 @@
+// Package i18n the message catalogs of the supported locales and the negotiation of the locale of a request
+package i18n
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/bn"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultLocale locale of the messages when the client asks for none of the supported locales
+const DefaultLocale = "en"
+
+// Locales the supported locales
+var Locales = []string{"en", "de", "bn"}
+
+// catalogs the translations of the response messages, keyed by the english message.
+// English needs no catalog, the messages are written in english.
+var catalogs = map[string]map[string]string{
+	"de": deMessages,
+	"bn": bnMessages,
+}
+
+// ValidationMessages the messages of the validation tags of the locales with no default validator translations
+var ValidationMessages = map[string]map[string]string{
+	"de": deValidation,
+	"bn": bnValidation,
+}
+
+// CustomValidationMessages english messages of the custom validation tags,
+// the default validator translations cover the other tags
+var CustomValidationMessages = map[string]string{
+	"password":      "{0} must be 8 to 72 characters long and contain upper case, lower case and numeric characters",
+	"currency":      "{0} must be a supported ISO 4217 currency code",
+	"money":         "{0} must be a positive amount with no more decimals than the currency allows",
+	"date":          "{0} must be a date formatted as YYYY-MM-DD",
+	"objectid":      "{0} must be a valid id",
+	"locale":        "{0} must be a supported locale",
+	"slug":          "{0} must be lower case letters and digits separated by single dashes",
+	"rrule":         "{0} must be a supported recurrence rule, e.g. FREQ=MONTHLY;BYMONTHDAY=1",
+	"required_with": "{0} is required with the related fields",
+}
+
+// context keys of the translators of the request
+const (
+	universalKey  = "i18n.universal"
+	translatorKey = "i18n.translator"
+)
+
+// IsSupported check the locale is one of the supported locales
+func IsSupported(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// New the translators of the supported locales with their message catalogs
+func New() *ut.UniversalTranslator {
+	uni := ut.New(en.New(), en.New(), de.New(), bn.New())
+	for locale, messages := range catalogs {
+		trans, _ := uni.GetTranslator(locale)
+		for key, text := range messages {
+			if err := trans.Add(key, text, false); err != nil {
+				log.Fatalf("TRANSLATION ERROR: %s %q: %v", locale, key, err)
+			}
+		}
+	}
+	return uni
+}
+
+// Negotiate the translator of the preferred locale when it is supported,
+// the best match of the Accept-Language header otherwise
+func Negotiate(uni *ut.UniversalTranslator, preferred, acceptLanguage string) ut.Translator {
+	locales := AcceptedLocales(acceptLanguage)
+	if preferred != "" {
+		locales = append([]string{preferred}, locales...)
+	}
+	trans, _ := uni.FindTranslator(locales...)
+	return trans
+}
+
+// AcceptedLocales the locales of the Accept-Language header, by decreasing quality.
+// A regional locale like `de-AT` is followed by its language `de`.
+func AcceptedLocales(acceptLanguage string) []string {
+	type accepted struct {
+		tag     string
+		quality float64
+	}
+	var tags []accepted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			if q := strings.TrimSpace(param); strings.HasPrefix(q, "q=") {
+				if v, err := strconv.ParseFloat(q[2:], 64); err == nil {
+					quality = v
+				}
+			}
+		}
+		if quality > 0 {
+			tags = append(tags, accepted{tag, quality})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+	locales := make([]string, 0, len(tags))
+	for _, t := range tags {
+		locale := strings.ReplaceAll(t.tag, "-", "_")
+		locales = append(locales, locale)
+		if i := strings.IndexByte(locale, '_'); i > 0 {
+			locales = append(locales, locale[:i])
+		}
+	}
+	return locales
+}
+
+// SetTranslator keep the translators in the context, the translator of the request is negotiated from its Accept-Language header
+func SetTranslator(c echo.Context, uni *ut.UniversalTranslator) {
+	c.Set(universalKey, uni)
+	setTranslator(c, Negotiate(uni, "", c.Request().Header.Get("Accept-Language")))
+}
+
+// Prefer switch the translator of the request to the preferred locale of the user, when it is supported
+func Prefer(c echo.Context, locale string) {
+	uni, ok := c.Get(universalKey).(*ut.UniversalTranslator)
+	if !ok || locale == "" {
+		return
+	}
+	if trans, found := uni.GetTranslator(locale); found && IsSupported(locale) {
+		setTranslator(c, trans)
+	}
+}
+
+// setTranslator use the translator for the messages of the request and tell the client their locale
+func setTranslator(c echo.Context, trans ut.Translator) {
+	c.Set(translatorKey, trans)
+	c.Response().Header().Set("Content-Language", trans.Locale())
+}
+
+// Translator the translator of the request, nil when the translators are not set
+func Translator(c echo.Context) ut.Translator {
+	trans, _ := c.Get(translatorKey).(ut.Translator)
+	return trans
+}
+
+// T translate the message into the locale of the request, the params replace the `{0}`, `{1}`... placeholders.
+// Messages missing from the catalog are returned in english.
+func T(c echo.Context, message string, params ...string) string {
+	return translate(Translator(c), message, params...)
+}
+
+// Localize translate the message into the locale, out of a request like the background notifications.
+// Unsupported locales and messages missing from the catalog are returned in english.
+func Localize(uni *ut.UniversalTranslator, locale, message string, params ...string) string {
+	var trans ut.Translator
+	if uni != nil && IsSupported(locale) {
+		trans, _ = uni.GetTranslator(locale)
+	}
+	return translate(trans, message, params...)
+}
+
+// translate the message with the translator, in english when the translator is nil
+func translate(trans ut.Translator, message string, params ...string) string {
+	if trans != nil && trans.Locale() != DefaultLocale {
+		if text, err := trans.T(message, params...); err == nil {
+			return text
+		}
+	}
+	for i, param := range params {
+		message = strings.ReplaceAll(message, "{"+strconv.Itoa(i)+"}", param)
+	}
+	return message
+}
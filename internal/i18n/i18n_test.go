@@ -0,0 +1,146 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/go-playground/validator.v9"
+	en_translations "gopkg.in/go-playground/validator.v9/translations/en"
+)
+
+func TestAcceptedLocales(t *testing.T) {
+	assert.Equal(t, []string{"de_at", "de", "en", "fr"}, AcceptedLocales("fr;q=0.5, de-AT, en;q=0.8"))
+	assert.Equal(t, []string{"bn"}, AcceptedLocales("*, bn, ja;q=0"))
+	assert.Empty(t, AcceptedLocales(""))
+}
+
+func TestNegotiate(t *testing.T) {
+	uni := New()
+	tests := []struct {
+		name           string
+		preferred      string
+		acceptLanguage string
+		want           string
+	}{
+		{"accept language", "", "de-DE,de;q=0.9", "de"},
+		{"best supported", "", "fr, bn;q=0.7, de;q=0.5", "bn"},
+		{"preference first", "bn", "de", "bn"},
+		{"unsupported", "", "fr", DefaultLocale},
+		{"none", "", "", DefaultLocale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Negotiate(uni, tt.preferred, tt.acceptLanguage).Locale())
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	uni := New()
+	req := httptest.NewRequest(echo.GET, "/", nil)
+	req.Header.Set("Accept-Language", "de")
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	assert.Equal(t, "permission denied: users:read", T(c, PermissionDenied, "users:read"))
+
+	SetTranslator(c, uni)
+	assert.Equal(t, "de", rec.Header().Get("Content-Language"))
+	assert.Equal(t, "Berechtigung verweigert: users:read", T(c, PermissionDenied, "users:read"))
+	assert.Equal(t, "not in the catalog", T(c, "not in the catalog"))
+
+	Prefer(c, "bn")
+	assert.Equal(t, "খরচ তৈরি করা হয়েছে", T(c, ExpenseCreated))
+	Prefer(c, "fr")
+	assert.Equal(t, "bn", Translator(c).Locale())
+}
+
+func TestCatalogs(t *testing.T) {
+	// every locale translates the same messages
+	for locale, messages := range catalogs {
+		for key := range deMessages {
+			assert.Contains(t, messages, key, locale)
+		}
+		assert.Len(t, messages, len(deMessages), locale)
+	}
+	assert.Len(t, bnValidation, len(deValidation))
+
+	// every validation tag of the models has a message in every locale
+	uni := New()
+	en, _ := uni.GetTranslator(DefaultLocale)
+	assert.NoError(t, en_translations.RegisterDefaultTranslations(validator.New(), en))
+	for _, tag := range modelValidationTags(t) {
+		_, custom := CustomValidationMessages[tag]
+		_, plain := en.T(tag, "{0}", "{1}")
+		_, sized := en.T(tag+"-number", "{0}", "{1}")
+		assert.True(t, custom || plain == nil || sized == nil, "en %s", tag)
+		for locale, messages := range ValidationMessages {
+			assert.Contains(t, messages, tag, locale)
+		}
+	}
+}
+
+// modelValidationTags the validation tags of the models: the tags of the struct fields,
+// the registered custom tags and the tags reported by the struct validations
+func modelValidationTags(t *testing.T) []string {
+	pkgs, err := parser.ParseDir(token.NewFileSet(), "../models", nil, 0)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	seen := map[string]bool{}
+	var tags []string
+	add := func(tag string) {
+		tag = strings.SplitN(tag, "=", 2)[0]
+		switch tag {
+		case "", "omitempty", "dive", "keys", "endkeys":
+			return
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	literal := func(e ast.Expr) string {
+		if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			s, _ := strconv.Unquote(lit.Value)
+			return s
+		}
+		return ""
+	}
+	for _, pkg := range pkgs {
+		for name, file := range pkg.Files {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			ast.Inspect(file, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.Field:
+					if n.Tag != nil {
+						for _, rule := range strings.FieldsFunc(reflect.StructTag(literal(n.Tag)).Get("validate"), func(r rune) bool { return r == ',' || r == '|' }) {
+							add(rule)
+						}
+					}
+				case *ast.CallExpr:
+					if sel, ok := n.Fun.(*ast.SelectorExpr); ok {
+						switch {
+						case sel.Sel.Name == "RegisterValidation" && len(n.Args) > 0:
+							add(literal(n.Args[0]))
+						case sel.Sel.Name == "ReportError" && len(n.Args) > 3:
+							add(literal(n.Args[3]))
+						}
+					}
+				}
+				return true
+			})
+		}
+	}
+	return tags
+}
@@ -0,0 +1,150 @@
+package i18n
+
+// Messages of the responses, the english message is the key of the catalogs
+const (
+	ServerUp = "Server is Up"
+
+	// authentication and authorization
+	Unauthenticated        = "unauthenticated request"
+	MissingBearerToken     = "missing bearer token"
+	InvalidToken           = "invalid or expired token"
+	UserNotFoundOrInactive = "user not found or inactive"
+	PermissionDenied       = "permission denied: {0}"
+	NotProjectMember       = "not a member of the project"
+	InvalidCredentials     = "invalid credentials"
+	UserNotActive          = "user is not active"
+	LoginSuccessful        = "login successful"
+	TokenRefreshed         = "token refreshed"
+	PasswordReset          = "password reset"
+	PasswordChanged        = "password changed"
+	PasswordOwnerOnly      = "password can only be changed by its owner"
+	PasswordMismatch       = "current password does not match"
+	PasswordResetTitle     = "reset your password"
+	PasswordResetBody      = "use the token {0} to reset your password, it expires at {1}"
+	PasswordResetIssued    = "if the email exists a reset token has been issued"
+	InvalidResetToken      = "invalid or expired reset token"
+
+	// users
+	UserCreated  = "user created"
+	UserDetails  = "user details"
+	UserDetail   = "user detail"
+	UserRemoved  = "user removed"
+	UserUpdated  = "user updated"
+	UserProjects = "user projects"
+
+	// categories
+	CategoryCreated         = "category created"
+	CategoryDetails         = "category details"
+	CategoryRemoved         = "category removed"
+	CategoryUpdated         = "category updated"
+	CategoryMoved           = "category moved"
+	CategoryMerged          = "category merged"
+	CategoryTotals          = "category totals"
+	CategoryHasChildren     = "category has subcategories, move or merge them first"
+	CategoryCycle           = "a category can not be moved below itself"
+	CategoryMergeCycle      = "a category can not be merged into itself or its subcategories"
+	CategorySlugEmpty       = "the name has no letters or digits for the slug, specify the slug"
+	CategoryInUse           = "category is used by expenses, specify a replacement category"
+	CategoryReplacementSelf = "a category can not be replaced by itself"
+	CategoryHasBudgets      = "category is used by budgets, specify a replacement category"
+	CategoryBudgetsOverlap  = "a project has budgets on both categories for the same period, remove one of them first"
+
+	// exchange rates
+	ExchangeRatesLoaded = "exchange rates loaded"
+	ExchangeRates       = "exchange rates"
+
+	// expenses
+	ExpenseCreated       = "expense created"
+	ExpenseDetails       = "expense details"
+	ExpenseDetail        = "expense detail"
+	ExpenseRemoved       = "expense removed"
+	ExpenseUpdated       = "expense updated"
+	ExpenseNotRemovable  = "only draft or rejected expenses can be removed"
+	ExpenseNotEditable   = "only draft or rejected expenses can be changed"
+	ExpenseAuthorOnly    = "only the author can change this expense"
+	ExpenseSubmitOnly    = "only the author can submit this expense"
+	ExpenseStatusChanged = "expense status changed, try again"
+	ExpenseSubmitted     = "expense submitted"
+	ExpenseApproved      = "expense approved"
+	ExpenseRejected      = "expense rejected"
+	ExpenseReimbursed    = "expense reimbursed"
+
+	// attachments
+	AttachmentAuthorOnly  = "only the author can attach receipts"
+	AttachmentRemoverOnly = "only the author can remove receipts"
+	FileTooLarge          = "file exceeds {0} bytes"
+	UnsupportedFileType   = "unsupported file type {0}"
+	AttachmentExists      = "attachment already exists"
+	AttachmentCreated     = "attachment created"
+	AttachmentDetails     = "attachment details"
+	AttachmentRemoved     = "attachment removed"
+	AttachmentNotFound    = "attachment not found"
+
+	// projects
+	ProjectCreated       = "projects created"
+	ProjectDetails       = "project details"
+	ProjectDetail        = "project detail"
+	ProjectRemoved       = "project removed"
+	ProjectStartRequired = "Specify the Start period"
+	ProjectEndRequired   = "Specify the End period"
+	ProjectReport        = "complete project details"
+	ProjectMemberExists  = "user is already a member of the project"
+	ProjectUserCreated   = "project user created"
+	ProjectUserDetails   = "project user details"
+	ProjectUserNotFound  = "project user not found"
+	ProjectUserRemoved   = "project user removed"
+	ProjectRoleAbove     = "a role above your own in the project can't be granted or removed"
+	ProjectLastAdmin     = "the last admin of the project can't be removed"
+	InvalidID            = "invalid id"
+
+	// budgets
+	BudgetCreated    = "budget created"
+	BudgetDetails    = "budget details"
+	BudgetUpdated    = "budget updated"
+	BudgetRemoved    = "budget removed"
+	BudgetReport     = "budget utilization"
+	BudgetNotFound   = "budget not found"
+	BudgetAmountZero = "the budget amount must be greater than zero"
+
+	// notifications
+	NotificationDetails            = "notifications"
+	NotificationRead               = "notification marked read"
+	NotificationUnread             = "notification marked unread"
+	NotificationsRead              = "notifications marked read"
+	NotificationNotFound           = "notification not found"
+	NotificationPreferencesDetails = "notification preferences"
+	NotificationPreferencesUpdated = "notification preferences updated"
+	NotificationWebhookURL         = "the webhook url must be an http or https url"
+	NotificationWebhookPublic      = "the webhook url must point to a public address"
+	NotifyBudgetTitle              = "{0}% of the budget reached"
+	NotifyBudgetBody               = "{0} of {1} spent on the {2} budget of the project {3}"
+	NotifyPendingTitle             = "expense waiting for approval"
+	NotifyPendingBody              = "the expense {0} of {1} is waiting for approval since {2}"
+
+	// recurring expenses
+	RecurringCreated       = "recurring expense created"
+	RecurringDetails       = "recurring expense details"
+	RecurringList          = "recurring expenses"
+	RecurringNotFound      = "recurring expense not found"
+	RecurringPaused        = "recurring expense paused"
+	RecurringResumed       = "recurring expense resumed"
+	RecurringSkipped       = "occurrence skipped"
+	RecurringEnded         = "recurring expense ended"
+	RecurringAuthorOnly    = "only the author can change the recurring expense"
+	RecurringNotActive     = "the recurring expense is not active"
+	RecurringNotPaused     = "the recurring expense is not paused"
+	RecurringAlreadyEnded  = "the recurring expense already ended"
+	RecurringNotOccurrence = "the date is not an upcoming occurrence of the recurring expense"
+
+	// shared expenses, balances & settlements
+	ExpenseNotShared      = "only the expenses of a project can be paid by another member or split"
+	SplitNotMember        = "the payer and the users of the splits must be active members of the project"
+	ProjectBalances       = "project balances"
+	SettlementCreated     = "settlement recorded"
+	SettlementList        = "settlements"
+	SettlementRemoved     = "settlement removed"
+	SettlementNotFound    = "settlement not found"
+	SettlementNotMember   = "the payer and the receiver of a settlement must be members of the project"
+	SettlementPartiesOnly = "only the payer, the receiver or an approver of the project can change a settlement"
+	SettlementAmountZero  = "the settlement amount must be greater than zero"
+)
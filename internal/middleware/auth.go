@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JWT validate the bearer access token of the request
+// and put the calling user into the echo.Context
+func JWT(tokens *auth.TokenManager, um models.UserModel) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get(echo.HeaderAuthorization)
+			token := strings.TrimPrefix(header, "Bearer ")
+			if header == "" || token == header {
+				return utils.Error(http.StatusUnauthorized, i18n.MissingBearerToken, c)
+			}
+
+			claims, err := tokens.Parse(token, auth.AccessToken)
+			if err != nil {
+				log.Printf("TOKEN VALIDATION ERROR: %v\n", err)
+				return utils.Error(http.StatusUnauthorized, i18n.InvalidToken, c)
+			}
+
+			userID, err := primitive.ObjectIDFromHex(claims.Subject)
+			if err != nil {
+				return utils.Error(http.StatusUnauthorized, i18n.InvalidToken, c)
+			}
+
+			// load the user on every request so deactivated users lose access immediately
+			user, err := um.ReadOneUser(c.Request().Context(), models.UserQuery{ID: userID})
+			if err != nil && !errs.Is(err, errs.NotFound) {
+				return err
+			}
+			if user.ID.IsZero() || !user.IsActive {
+				return utils.Error(http.StatusUnauthorized, i18n.UserNotFoundOrInactive, c)
+			}
+			if !claims.Current(user) {
+				return utils.Error(http.StatusUnauthorized, i18n.InvalidToken, c)
+			}
+
+			auth.SetUser(c, user)
+			i18n.Prefer(c, user.Locale)
+			return next(c)
+		}
+	}
+}
+
+// Authorize allow the request only when the role of the authenticated user
+// is granted every one of the permissions. Must run after JWT.
+func Authorize(perms ...auth.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, ok := auth.CurrentUser(c)
+			if !ok {
+				return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+			}
+			for _, p := range perms {
+				if !auth.Can(user.Role, p) {
+					return utils.Error(http.StatusForbidden, i18n.T(c, i18n.PermissionDenied, string(p)), c)
+				}
+			}
+			return next(c)
+		}
+	}
+}
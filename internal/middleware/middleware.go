@@ -5,7 +5,9 @@ import (
 	"net/http/httputil"
 	"strings"
 
+	ut "github.com/go-playground/universal-translator"
 	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
 )
 
 // RequestHeaders log the all the request header
@@ -26,3 +28,14 @@ func RequestHeaders() echo.MiddlewareFunc {
 		}
 	}
 }
+
+// Locale negotiate the locale of the messages from the Accept-Language header,
+// JWT switches it to the preferred locale of the authenticated user
+func Locale(uni *ut.UniversalTranslator) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			i18n.SetTranslator(c, uni)
+			return next(c)
+		}
+	}
+}
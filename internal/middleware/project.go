@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProjectAccess allow the request only to the active members of the project
+// in the `:id` path param whose project role is granted the permission.
+// Global admins are allowed on every project. Must run after JWT.
+func ProjectAccess(pm models.ProjectModeler, perm auth.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			user, ok := auth.CurrentUser(c)
+			if !ok {
+				return utils.Error(http.StatusUnauthorized, i18n.Unauthenticated, c)
+			}
+
+			projectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+			if err != nil {
+				return utils.Error(http.StatusBadRequest, err.Error(), c)
+			}
+
+			member, err := pm.ReadOneProjectUser(c.Request().Context(), models.ProjectUserFilter{
+				ProjectID: projectID,
+				UserID:    user.ID,
+				IsActive:  models.Bool(true),
+			})
+			if err != nil && !errs.Is(err, errs.NotFound) {
+				return err
+			}
+			isMember := !member.ID.IsZero()
+			if isMember {
+				auth.SetProjectMember(c, member)
+			}
+
+			if user.Role == models.RoleAdmin {
+				return next(c)
+			}
+			if !isMember {
+				return utils.Error(http.StatusForbidden, i18n.NotProjectMember, c)
+			}
+			if !auth.CanInProject(member.Role, perm) {
+				return utils.Error(http.StatusForbidden, i18n.T(c, i18n.PermissionDenied, string(perm)), c)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Attachment metadata of a receipt embedded in the expense document
+// the content is kept in the storage under the SHA256 key
+type Attachment struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id"`
+	FileName    string             `json:"file_name" bson:"file_name"`
+	ContentType string             `json:"content_type" bson:"content_type"`
+	Size        int64              `json:"size" bson:"size"`
+	SHA256      string             `json:"sha256" bson:"sha256"`
+	UploadedBy  primitive.ObjectID `json:"uploaded_by" bson:"uploaded_by"`
+	UploadedAt  time.Time          `json:"uploaded_at" bson:"uploaded_at"`
+}
+
+// AllowedAttachmentTypes content types accepted for the receipts, sniffed from the content
+var AllowedAttachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
@@ -0,0 +1,269 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// settleUpExactMembers the most members with a balance whose settle up plan is searched exhaustively,
+// the plans of the larger projects are greedy
+const settleUpExactMembers = 16
+
+// Balance what a project member paid for the shared expenses and what they owe, in the base currency of the project
+type Balance struct {
+	UserID   primitive.ObjectID `json:"user_id"`
+	Name     string             `json:"name"`
+	Paid     Money              `json:"paid"`     // the shared expenses the member paid
+	Owed     Money              `json:"owed"`     // the splits of the member
+	Sent     Money              `json:"sent"`     // the settlements the member paid
+	Received Money              `json:"received"` // the settlements the member received
+	Net      Money              `json:"net"`      // positive when the member gets money back, negative when they owe
+}
+
+// Transfer payment of the settle up plan
+type Transfer struct {
+	FromID   primitive.ObjectID `json:"from_id"`
+	FromName string             `json:"from_name"`
+	ToID     primitive.ObjectID `json:"to_id"`
+	ToName   string             `json:"to_name"`
+	Amount   Money              `json:"amount"`
+}
+
+// BalanceSheet the balances of the members of a project and the transfers settling them
+type BalanceSheet struct {
+	BaseCurrency string     `json:"base_currency"`
+	Balances     []Balance  `json:"balances"`
+	SettleUp     []Transfer `json:"settle_up"`
+}
+
+// ProjectBalances the balances of every member of the project with the settle up plan.
+// The shared expenses not rejected & the settlements are converted into the base currency
+// of the project with the rate on their date.
+func ProjectBalances(ctx context.Context, m Models, projectID primitive.ObjectID) (BalanceSheet, error) {
+	project, err := m.Projects.ReadOne(ctx, projectID)
+	if err != nil {
+		return BalanceSheet{}, err
+	}
+	base := project.BaseCurrency
+	if base == "" {
+		base = DefaultCurrency
+	}
+	expenses, _, err := m.Expenses.ReadAll(ctx, ExpenseFilter{Projects: []string{projectID.Hex()}}, ListOptions{})
+	if err != nil {
+		return BalanceSheet{}, err
+	}
+	settlements, err := m.Settlements.ReadAll(ctx, projectID)
+	if err != nil {
+		return BalanceSheet{}, err
+	}
+	members, err := m.Projects.ReadAllProjectMembers(ctx, ProjectUserFilter{ProjectID: projectID})
+	if err != nil {
+		return BalanceSheet{}, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(members))
+	names := make(map[primitive.ObjectID]string, len(members))
+	for _, member := range members {
+		ids = append(ids, member.UserID)
+		names[member.UserID] = member.User.Name
+	}
+	balances, err := ComputeBalances(ctx, m.ExchangeRates, base, ids, expenses, settlements)
+	if err != nil {
+		return BalanceSheet{}, err
+	}
+	for i := range balances {
+		balances[i].Name = names[balances[i].UserID]
+	}
+	sort.SliceStable(balances, func(i, j int) bool {
+		return balances[i].Name < balances[j].Name
+	})
+	return BalanceSheet{BaseCurrency: base, Balances: balances, SettleUp: SettleUp(balances)}, nil
+}
+
+// ComputeBalances the balances of the members & of the users of the expenses and settlements, in the base currency.
+// The converted total of an expense is divided in proportion to its splits, so the splits still add up to it.
+func ComputeBalances(ctx context.Context, rates RateFinder, base string, members []primitive.ObjectID, expenses []Expense, settlements []Settlement) ([]Balance, error) {
+	var balances []Balance
+	index := map[primitive.ObjectID]int{}
+	balance := func(id primitive.ObjectID) *Balance {
+		if i, ok := index[id]; ok {
+			return &balances[i]
+		}
+		index[id] = len(balances)
+		zero := NewMoney(0, base)
+		balances = append(balances, Balance{UserID: id, Paid: zero, Owed: zero, Sent: zero, Received: zero, Net: zero})
+		return &balances[len(balances)-1]
+	}
+	convert := func(amount Money, on time.Time) (Money, error) {
+		rate, err := rates.FindRate(ctx, amount.Currency, base, on)
+		if err != nil {
+			return Money{}, fmt.Errorf("%w: %s to %s on %s", err, amount.Currency, base, on.Format(DateLayout))
+		}
+		return amount.Convert(rate, base), nil
+	}
+	for _, id := range members {
+		balance(id)
+	}
+
+	for _, expense := range expenses {
+		if len(expense.Splits) == 0 || expense.Status == StatusRejected {
+			continue
+		}
+		total, err := convert(expense.Total, expense.Date)
+		if err != nil {
+			return nil, err
+		}
+		weights := make([]*big.Rat, len(expense.Splits))
+		for i, split := range expense.Splits {
+			weights[i] = big.NewRat(split.Amount.Amount, 1)
+		}
+		balance(expense.Payer()).Paid.Amount += total.Amount
+		for i, amount := range allocate(total.Amount, weights) {
+			balance(expense.Splits[i].UserID).Owed.Amount += amount
+		}
+	}
+	for _, settlement := range settlements {
+		amount, err := convert(settlement.Amount, settlement.Date)
+		if err != nil {
+			return nil, err
+		}
+		balance(settlement.FromID).Sent.Amount += amount.Amount
+		balance(settlement.ToID).Received.Amount += amount.Amount
+	}
+
+	for i := range balances {
+		b := &balances[i]
+		b.Net.Amount = b.Paid.Amount - b.Owed.Amount + b.Sent.Amount - b.Received.Amount
+	}
+	return balances, nil
+}
+
+// SettleUp the transfers settling the net balances with as few transfers as possible.
+// The members are split into the most groups whose balances add up to zero, each group
+// settles with one transfer less than its members, the largest debtor paying the largest
+// creditor first. Above settleUpExactMembers the members are settled as one group.
+func SettleUp(balances []Balance) []Transfer {
+	var open []Balance
+	for _, b := range balances {
+		if !b.Net.IsZero() {
+			open = append(open, b)
+		}
+	}
+	sort.SliceStable(open, func(i, j int) bool {
+		return open[i].Net.Amount < open[j].Net.Amount
+	})
+
+	transfers := []Transfer{}
+	for _, group := range zeroSumGroups(open) {
+		transfers = append(transfers, settleGroup(group)...)
+	}
+	return transfers
+}
+
+// zeroSumGroups partition the balances into the most groups adding up to zero
+func zeroSumGroups(balances []Balance) [][]Balance {
+	n := len(balances)
+	if n == 0 {
+		return nil
+	}
+	if n > settleUpExactMembers {
+		return [][]Balance{balances}
+	}
+
+	// groups[mask] the most zero sum groups the members of the mask are partitioned into,
+	// counted along the best order of the members: a group closes whenever the sum is back to zero
+	size := 1 << uint(n)
+	sums := make([]int64, size)
+	groups := make([]int, size)
+	for mask := 1; mask < size; mask++ {
+		low := 0
+		for mask&(1<<uint(low)) == 0 {
+			low++
+		}
+		sums[mask] = sums[mask&^(1<<uint(low))] + balances[low].Net.Amount
+		best := 0
+		for i := 0; i < n; i++ {
+			if mask&(1<<uint(i)) != 0 && groups[mask&^(1<<uint(i))] > best {
+				best = groups[mask&^(1<<uint(i))]
+			}
+		}
+		if sums[mask] == 0 {
+			best++
+		}
+		groups[mask] = best
+	}
+
+	// the best order, built from its end
+	order := make([]int, 0, n)
+	for mask := size - 1; mask > 0; {
+		want := groups[mask]
+		if sums[mask] == 0 {
+			want--
+		}
+		for i := 0; i < n; i++ {
+			if mask&(1<<uint(i)) != 0 && groups[mask&^(1<<uint(i))] == want {
+				order = append(order, i)
+				mask &^= 1 << uint(i)
+				break
+			}
+		}
+	}
+
+	var partition [][]Balance
+	var group []Balance
+	var sum int64
+	for k := len(order) - 1; k >= 0; k-- {
+		group = append(group, balances[order[k]])
+		sum += balances[order[k]].Net.Amount
+		if sum == 0 {
+			partition = append(partition, group)
+			group = nil
+		}
+	}
+	if len(group) > 0 {
+		partition = append(partition, group)
+	}
+	return partition
+}
+
+// settleGroup the transfers settling the group, the largest debtor pays the largest creditor first
+func settleGroup(group []Balance) []Transfer {
+	var debtors, creditors []Balance
+	for _, b := range group {
+		if b.Net.Amount < 0 {
+			debtors = append(debtors, b)
+		} else {
+			creditors = append(creditors, b)
+		}
+	}
+	sort.SliceStable(debtors, func(i, j int) bool { return debtors[i].Net.Amount < debtors[j].Net.Amount })
+	sort.SliceStable(creditors, func(i, j int) bool { return creditors[i].Net.Amount > creditors[j].Net.Amount })
+
+	var transfers []Transfer
+	for d, c := 0, 0; d < len(debtors) && c < len(creditors); {
+		debtor, creditor := &debtors[d], &creditors[c]
+		amount := -debtor.Net.Amount
+		if creditor.Net.Amount < amount {
+			amount = creditor.Net.Amount
+		}
+		transfers = append(transfers, Transfer{
+			FromID: debtor.UserID, FromName: debtor.Name,
+			ToID: creditor.UserID, ToName: creditor.Name,
+			Amount: NewMoney(amount, creditor.Net.Currency),
+		})
+		debtor.Net.Amount += amount
+		creditor.Net.Amount -= amount
+		if debtor.Net.Amount == 0 {
+			d++
+		}
+		if creditor.Net.Amount == 0 {
+			c++
+		}
+	}
+	return transfers
+}
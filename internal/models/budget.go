@@ -0,0 +1,179 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BudgetPeriod period a budget amount is granted for
+type BudgetPeriod string
+
+// the periods of the budgets
+const (
+	BudgetMonthly   BudgetPeriod = "monthly"
+	BudgetQuarterly BudgetPeriod = "quarterly"
+	BudgetProject   BudgetPeriod = "project" // the whole life of the project
+)
+
+// Budget amount a project can spend per period, on one category & its subcategories
+// or on the whole project when the category is zero. The amount is in the base currency of the project.
+type Budget struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+	ProjectID  primitive.ObjectID `json:"project_id" bson:"project_id"`
+	CategoryID primitive.ObjectID `json:"category_id" bson:"category_id,omitempty"`
+	Period     BudgetPeriod       `json:"period" bson:"period"`
+	Amount     Money              `json:"amount" bson:"amount"`
+}
+
+// BudgetInput budget create input model, one budget per category & period of the project
+type BudgetInput struct {
+	CategoryID string       `json:"category_id" validate:"omitempty,objectid"`
+	Period     BudgetPeriod `json:"period" validate:"required,oneof=monthly quarterly project"`
+	Amount     json.Number  `json:"amount" validate:"required"` // decimal amount in the base currency of the project
+}
+
+// BudgetUpdateInput model for the budget update endpoint, the scope of a budget is not changed
+type BudgetUpdateInput struct {
+	Amount json.Number `json:"amount" validate:"required"`
+}
+
+// Bounds the period of the budget containing the date, zero bounds for the whole project.
+// The periods follow the calendar in UTC like the dates of the expenses.
+func (p BudgetPeriod) Bounds(on time.Time) (time.Time, time.Time) {
+	switch p {
+	case BudgetMonthly:
+		start := time.Date(on.Year(), on.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	case BudgetQuarterly:
+		month := (on.Month()-1)/3*3 + 1
+		start := time.Date(on.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 3, 0)
+	}
+	return time.Time{}, time.Time{}
+}
+
+// BudgetModeler godoc
+type BudgetModeler interface {
+	Insert(ctx context.Context, budget *Budget) (interface{}, error)
+	ReadAll(ctx context.Context, projectID primitive.ObjectID) ([]Budget, error)
+	ReadOne(ctx context.Context, id primitive.ObjectID) (Budget, error)
+	UpdateOne(ctx context.Context, id primitive.ObjectID, amount Money) (int64, error)
+	RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error)
+	CountCategory(ctx context.Context, categoryID primitive.ObjectID) (int64, error)
+	ReassignCategory(ctx context.Context, from, to primitive.ObjectID) (int64, error)
+}
+
+// BudgetModel godoc
+type BudgetModel struct {
+	db db.MongoDBClient
+}
+
+// NewBudgetModel godoc
+func NewBudgetModel(db db.MongoDBClient) *BudgetModel {
+	return &BudgetModel{db}
+}
+
+// budgetIndexes one budget per category & period of a project
+var budgetIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "category_id", Value: 1}, {Key: "period", Value: 1}},
+		Options: options.Index().SetName("scope_unique").SetUnique(true)},
+}
+
+// Insert insert a record at budgets collection
+func (b *BudgetModel) Insert(ctx context.Context, budget *Budget) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := b.db.Client.Database(b.db.DBName).Collection("budgets")
+	insertResult, err := collection.InsertOne(ctx, budget)
+	if err != nil {
+		log.Printf("Error on inserting new budget: %v\n", err)
+		return nil, dbError(err)
+	}
+	return insertResult.InsertedID, nil
+}
+
+// ReadAll read every budget of the project
+func (b *BudgetModel) ReadAll(ctx context.Context, projectID primitive.ObjectID) ([]Budget, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	budgets := []Budget{}
+	collection := b.db.Client.Database(b.db.DBName).Collection("budgets")
+	opts := options.Find().SetSort(bson.D{{Key: "period", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := collection.Find(ctx, bson.M{"project_id": projectID}, opts)
+	if err != nil {
+		return budgets, dbError(err)
+	}
+	err = cur.All(ctx, &budgets)
+	return budgets, dbError(err)
+}
+
+// ReadOne read a single budget
+func (b *BudgetModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Budget, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var budget Budget
+	collection := b.db.Client.Database(b.db.DBName).Collection("budgets")
+	err := findOne(ctx, collection, bson.M{"_id": id}, "budget", &budget)
+	return budget, err
+}
+
+// UpdateOne change the amount of one budget
+func (b *BudgetModel) UpdateOne(ctx context.Context, id primitive.ObjectID, amount Money) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := b.db.Client.Database(b.db.DBName).Collection("budgets")
+	update := bson.M{"$set": bson.M{"amount": amount, "updated_at": time.Now()}}
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		log.Printf("Error on updating one budget: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// RemoveOne remove one budget from collections
+func (b *BudgetModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := b.db.Client.Database(b.db.DBName).Collection("budgets")
+	deleteResult, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		log.Printf("Error on deleting one budget: %v\n", err)
+		return 0, dbError(err)
+	}
+	return deleteResult.DeletedCount, nil
+}
+
+// CountCategory count the budgets on the category
+func (b *BudgetModel) CountCategory(ctx context.Context, categoryID primitive.ObjectID) (int64, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	collection := b.db.Client.Database(b.db.DBName).Collection("budgets")
+	count, err := collection.CountDocuments(ctx, bson.M{"category_id": categoryID})
+	return count, dbError(err)
+}
+
+// ReassignCategory move the budgets of the category to another one,
+// a conflict when a project already has a budget on the other category for the same period
+func (b *BudgetModel) ReassignCategory(ctx context.Context, from, to primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := b.db.Client.Database(b.db.DBName).Collection("budgets")
+	update := bson.M{"$set": bson.M{"category_id": to, "updated_at": time.Now()}}
+	updatedResult, err := collection.UpdateMany(ctx, bson.M{"category_id": from}, update)
+	if err != nil {
+		log.Printf("Error on reassigning the category of budgets: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
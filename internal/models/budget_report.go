@@ -0,0 +1,221 @@
+package models
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// budgetEndOfTime end of the period of the whole project budgets
+var budgetEndOfTime = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// BudgetLine utilization of a budget in one of its periods.
+// The expenses are counted in the base currency of the project, the rejected ones are not spent.
+type BudgetLine struct {
+	BudgetID    primitive.ObjectID `json:"budget_id"`
+	CategoryID  primitive.ObjectID `json:"category_id"`
+	Period      BudgetPeriod       `json:"period"`
+	Start       *time.Time         `json:"start,omitempty"` // nil for the whole project
+	End         *time.Time         `json:"end,omitempty"`   // exclusive, nil for the whole project
+	Budgeted    Money              `json:"budgeted"`
+	Spent       Money              `json:"spent"`
+	Remaining   Money              `json:"remaining"`   // negative when the budget is overspent
+	Utilization float64            `json:"utilization"` // percent of the budget spent
+	Count       int64              `json:"count"`       // expenses of the period
+}
+
+// BudgetAlert the budgets an expense pushed over the alert threshold, the percent of the budgets
+type BudgetAlert struct {
+	Alert     bool         `json:"budget_alert"`
+	Threshold int64        `json:"threshold"`
+	Budgets   []BudgetLine `json:"budgets"`
+}
+
+// Reached check the spent amount is at least the percent of the budget
+func (l BudgetLine) Reached(percent int64) bool {
+	return l.Budgeted.Amount > 0 && l.Spent.Amount*100 >= percent*l.Budgeted.Amount
+}
+
+// budgetPeriods the periods of the budget overlapping the window [start, end)
+func budgetPeriods(p BudgetPeriod, start, end time.Time) [][2]time.Time {
+	if p == BudgetProject {
+		return [][2]time.Time{{}}
+	}
+	var periods [][2]time.Time
+	if !start.Before(end) {
+		return periods
+	}
+	for from, to := p.Bounds(start); from.Before(end); from, to = p.Bounds(to) {
+		periods = append(periods, [2]time.Time{from, to})
+	}
+	return periods
+}
+
+// budgetSpan the dates covered by the periods of the budgets overlapping the window
+func budgetSpan(budgets []Budget, start, end time.Time) (time.Time, time.Time) {
+	from, to := start, end
+	for _, budget := range budgets {
+		for _, period := range budgetPeriods(budget.Period, start, end) {
+			if period[0].IsZero() {
+				return time.Time{}, budgetEndOfTime
+			}
+			if period[0].Before(from) {
+				from = period[0]
+			}
+			if period[1].After(to) {
+				to = period[1]
+			}
+		}
+	}
+	return from, to
+}
+
+// BudgetUtilization the utilization of the budgets in each of their periods overlapping the window [start, end).
+// The expenses are spent in a budget when their category is the category of the budget or one of its
+// descendants in the tree, they are counted with their base total when they are converted.
+func BudgetUtilization(budgets []Budget, expenses []Expense, tree CategoryTree, start, end time.Time) ([]BudgetLine, error) {
+	lines := []BudgetLine{}
+	for _, budget := range budgets {
+		var categories map[primitive.ObjectID]bool
+		if !budget.CategoryID.IsZero() {
+			categories = map[primitive.ObjectID]bool{}
+			for _, id := range tree.Descendants(budget.CategoryID) {
+				categories[id] = true
+			}
+		}
+		for _, period := range budgetPeriods(budget.Period, start, end) {
+			line := BudgetLine{
+				BudgetID:   budget.ID,
+				CategoryID: budget.CategoryID,
+				Period:     budget.Period,
+				Budgeted:   budget.Amount,
+				Spent:      NewMoney(0, budget.Amount.Currency),
+			}
+			if from, to := period[0], period[1]; !from.IsZero() {
+				line.Start, line.End = &from, &to
+			}
+			for _, expense := range expenses {
+				if expense.Status == StatusRejected || (categories != nil && !categories[expense.Category.ID]) {
+					continue
+				}
+				if line.Start != nil && (expense.Date.Before(*line.Start) || !expense.Date.Before(*line.End)) {
+					continue
+				}
+				amount := expense.Total
+				if expense.BaseTotal != nil {
+					amount = *expense.BaseTotal
+				}
+				var err error
+				if line.Spent, err = line.Spent.Add(amount); err != nil {
+					return nil, err
+				}
+				line.Count++
+			}
+			line.Remaining = NewMoney(line.Budgeted.Amount-line.Spent.Amount, line.Budgeted.Currency)
+			if line.Budgeted.Amount > 0 {
+				line.Utilization = math.Round(float64(line.Spent.Amount)*10000/float64(line.Budgeted.Amount)) / 100
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// BudgetTracker report the utilization of the project budgets and detect the expenses
+// pushing a budget over the alert threshold
+type BudgetTracker struct {
+	models    Models
+	threshold int64
+}
+
+// NewBudgetTracker the tracker of the budgets of the models, alerting at the threshold percent of a budget
+func NewBudgetTracker(m Models, threshold int64) *BudgetTracker {
+	return &BudgetTracker{models: m, threshold: threshold}
+}
+
+// Report the utilization of every budget of the project in its periods overlapping the window of the query
+func (t *BudgetTracker) Report(ctx context.Context, projectID primitive.ObjectID, qs ProjectDetailsQS) ([]BudgetLine, error) {
+	budgets, err := t.models.Budgets.ReadAll(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := t.models.Categories.ReadTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tree := NewCategoryTree(categories)
+	details, err := t.spending(ctx, projectID, budgets, qs.Start, qs.End)
+	if err != nil {
+		return nil, err
+	}
+	return BudgetUtilization(budgets, details.Expenses, tree, qs.Start, qs.End)
+}
+
+// Check the budgets of the expense whose utilization reached the threshold with the expense.
+// The expense is already stored, a budget already over the threshold without it is not reported again.
+func (t *BudgetTracker) Check(ctx context.Context, expense Expense) (BudgetAlert, error) {
+	alert := BudgetAlert{Threshold: t.threshold, Budgets: []BudgetLine{}}
+	if expense.ProjectID.IsZero() || expense.Status == StatusRejected {
+		return alert, nil
+	}
+	budgets, err := t.models.Budgets.ReadAll(ctx, expense.ProjectID)
+	if err != nil || len(budgets) == 0 {
+		return alert, err
+	}
+	categories, err := t.models.Categories.ReadTree(ctx)
+	if err != nil {
+		return alert, err
+	}
+	tree := NewCategoryTree(categories)
+	var covering []Budget
+	for _, budget := range budgets {
+		if budget.CategoryID.IsZero() || tree.IsDescendant(expense.Category.ID, budget.CategoryID) {
+			covering = append(covering, budget)
+		}
+	}
+	if len(covering) == 0 {
+		return alert, nil
+	}
+
+	// the periods of the budgets containing the day of the expense
+	start, end := expense.Date, expense.Date.AddDate(0, 0, 1)
+	details, err := t.spending(ctx, expense.ProjectID, covering, start, end)
+	if err != nil {
+		return alert, err
+	}
+	after, err := BudgetUtilization(covering, details.Expenses, tree, start, end)
+	if err != nil {
+		return alert, err
+	}
+	others := make([]Expense, 0, len(details.Expenses))
+	for _, e := range details.Expenses {
+		if e.ID != expense.ID {
+			others = append(others, e)
+		}
+	}
+	before, err := BudgetUtilization(covering, others, tree, start, end)
+	if err != nil {
+		return alert, err
+	}
+	for i := range after {
+		if after[i].Reached(t.threshold) && !before[i].Reached(t.threshold) {
+			alert.Budgets = append(alert.Budgets, after[i])
+		}
+	}
+	alert.Alert = len(alert.Budgets) > 0
+	return alert, nil
+}
+
+// spending the project with its expenses of the periods of the budgets overlapping the window,
+// converted into the base currency of the project
+func (t *BudgetTracker) spending(ctx context.Context, projectID primitive.ObjectID, budgets []Budget, start, end time.Time) (ProjectDetails, error) {
+	from, to := budgetSpan(budgets, start, end)
+	details, err := t.models.Projects.LookupProjectDetails(ctx, projectID, ProjectDetailsQS{Start: from, End: to, IsActive: true})
+	if err != nil {
+		return details, err
+	}
+	err = ConvertProjectDetails(ctx, &details, t.models.ExchangeRates)
+	return details, err
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBudgetPeriods(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2021, month, d, 0, 0, 0, 0, time.UTC) }
+
+	start, end := BudgetQuarterly.Bounds(day(5, 17))
+	assert.Equal(t, day(4, 1), start)
+	assert.Equal(t, day(7, 1), end)
+	start, end = BudgetProject.Bounds(day(5, 17))
+	assert.True(t, start.IsZero() && end.IsZero())
+
+	monthly := budgetPeriods(BudgetMonthly, day(1, 15), day(3, 10))
+	assert.Equal(t, [][2]time.Time{{day(1, 1), day(2, 1)}, {day(2, 1), day(3, 1)}, {day(3, 1), day(4, 1)}}, monthly)
+	assert.Len(t, budgetPeriods(BudgetQuarterly, day(1, 15), day(3, 10)), 1)
+	assert.Empty(t, budgetPeriods(BudgetMonthly, day(1, 15), day(1, 15)))
+
+	from, to := budgetSpan([]Budget{{Period: BudgetQuarterly}}, day(2, 10), day(2, 20))
+	assert.Equal(t, day(1, 1), from)
+	assert.Equal(t, day(4, 1), to)
+}
+
+func TestBudgetTracker(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	tracker := NewBudgetTracker(m, 90)
+	day := func(month time.Month, d int) time.Time { return time.Date(2021, month, d, 0, 0, 0, 0, time.UTC) }
+
+	project := Project{ID: primitive.NewObjectID(), Title: "trip", BaseCurrency: "EUR", IsActive: true}
+	_, err := m.Projects.Insert(ctx, &project)
+	assert.NoError(t, err)
+	travel := Category{ID: primitive.NewObjectID(), Name: "Travel", Slug: "travel"}
+	hotel := Category{ID: primitive.NewObjectID(), Name: "Hotel", Slug: "hotel", ParentID: travel.ID}
+	food := Category{ID: primitive.NewObjectID(), Name: "Food", Slug: "food"}
+	for _, c := range []*Category{&travel, &hotel, &food} {
+		_, err := m.Categories.Insert(ctx, c)
+		assert.NoError(t, err)
+	}
+	monthly := Budget{ID: primitive.NewObjectID(), ProjectID: project.ID, CategoryID: travel.ID, Period: BudgetMonthly, Amount: NewMoney(10000, "EUR")}
+	whole := Budget{ID: primitive.NewObjectID(), ProjectID: project.ID, Period: BudgetProject, Amount: NewMoney(50000, "EUR")}
+	for _, b := range []*Budget{&monthly, &whole} {
+		_, err := m.Budgets.Insert(ctx, b)
+		assert.NoError(t, err)
+	}
+	_, err = m.Budgets.Insert(ctx, &Budget{ID: primitive.NewObjectID(), ProjectID: project.ID, CategoryID: travel.ID, Period: BudgetMonthly})
+	assert.Equal(t, ErrDuplicateKey, err)
+
+	insert := func(category Category, date time.Time, amount int64, status ExpenseStatus) Expense {
+		expense := newMemoryExpense(category.Name, date, amount)
+		expense.Category, expense.ProjectID, expense.Status = category.Snapshot(), project.ID, status
+		_, err := m.Expenses.Insert(ctx, expense)
+		assert.NoError(t, err)
+		return expense
+	}
+	insert(hotel, day(1, 5), 6000, StatusApproved)
+	insert(food, day(1, 6), 3000, StatusDraft)
+	insert(hotel, day(1, 7), 5000, StatusRejected)
+	insert(hotel, day(2, 3), 7000, StatusSubmitted)
+
+	lines, err := tracker.Report(ctx, project.ID, ProjectDetailsQS{Start: day(1, 1), End: day(2, 1)})
+	assert.NoError(t, err)
+	if assert.Len(t, lines, 2) {
+		// the subcategories are spent in the budget of their parent, the rejected expenses are not spent
+		assert.Equal(t, monthly.ID, lines[0].BudgetID)
+		assert.Equal(t, day(1, 1), *lines[0].Start)
+		assert.Equal(t, NewMoney(6000, "EUR"), lines[0].Spent)
+		assert.Equal(t, NewMoney(4000, "EUR"), lines[0].Remaining)
+		assert.Equal(t, 60.0, lines[0].Utilization)
+		// the whole project budget counts every period
+		assert.Equal(t, whole.ID, lines[1].BudgetID)
+		assert.Nil(t, lines[1].Start)
+		assert.Equal(t, NewMoney(16000, "EUR"), lines[1].Spent)
+		assert.Equal(t, int64(3), lines[1].Count)
+	}
+
+	// 60 + 30 reaches 90% of the monthly travel budget
+	alert, err := tracker.Check(ctx, insert(hotel, day(1, 20), 3000, StatusDraft))
+	assert.NoError(t, err)
+	assert.True(t, alert.Alert)
+	if assert.Len(t, alert.Budgets, 1) {
+		assert.Equal(t, monthly.ID, alert.Budgets[0].BudgetID)
+		assert.Equal(t, 90.0, alert.Budgets[0].Utilization)
+	}
+	// the budget was already over the threshold
+	alert, err = tracker.Check(ctx, insert(hotel, day(1, 21), 2000, StatusDraft))
+	assert.NoError(t, err)
+	assert.False(t, alert.Alert)
+	// the food is not in the travel budget
+	alert, err = tracker.Check(ctx, insert(food, day(2, 21), 2000, StatusDraft))
+	assert.NoError(t, err)
+	assert.False(t, alert.Alert)
+
+	lines, err = tracker.Report(ctx, project.ID, ProjectDetailsQS{Start: day(1, 1), End: day(2, 1)})
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(-1000, "EUR"), lines[0].Remaining)
+}
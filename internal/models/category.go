@@ -3,33 +3,92 @@ package models
 import (
 	"context"
 	"log"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/masihur1989/expense-tracker-api/internal/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-// Category model for category collection
+// Category model for category collection, the categories form a tree through their parent
 type Category struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
-	Name      string             `json:"name" bson:"name" validate:"required,alpha"`
+	Name      string             `json:"name" bson:"name"`
+	Slug      string             `json:"slug" bson:"slug,omitempty"`
+	ParentID  primitive.ObjectID `json:"parent_id" bson:"parent_id,omitempty"` // zero for the root categories
+	Color     string             `json:"color,omitempty" bson:"color,omitempty"`
+	Icon      string             `json:"icon,omitempty" bson:"icon,omitempty"`
 }
 
-// CategoryUpdateInput model for update endpoint
+// CategoryInput category create input model, the slug is derived from the name when empty
+type CategoryInput struct {
+	Name     string `json:"name" validate:"required,max=64"`
+	Slug     string `json:"slug" validate:"omitempty,max=64,slug"`
+	ParentID string `json:"parent_id" validate:"omitempty,objectid"`
+	Color    string `json:"color" validate:"omitempty,hexcolor"`
+	Icon     string `json:"icon" validate:"omitempty,max=32"`
+}
+
+// CategoryUpdateInput model for update endpoint, the parent is changed by moving the category
 type CategoryUpdateInput struct {
-	Name string `json:"name" bson:"name" validate:"required,alpha"`
+	Name  string `json:"name" bson:"name" validate:"required,max=64"`
+	Slug  string `json:"slug" bson:"slug" validate:"omitempty,max=64,slug"`
+	Color string `json:"color" bson:"color" validate:"omitempty,hexcolor"`
+	Icon  string `json:"icon" bson:"icon" validate:"omitempty,max=32"`
+}
+
+// CategoryMoveInput new parent of the category, empty to make it a root category
+type CategoryMoveInput struct {
+	ParentID string `json:"parent_id" validate:"omitempty,objectid"`
+}
+
+// CategoryMergeInput category receiving the expenses & the children of the merged category
+type CategoryMergeInput struct {
+	IntoID string `json:"into_id" validate:"required,objectid"`
+}
+
+// Slugify the url friendly form of the name, lower cased letters & digits of any script
+// separated by single dashes, e.g. `Travel > Hotel` is `travel-hotel`
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || (unicode.IsMark(r) && b.Len() > 0 && !dash):
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// isSlug check the text is its own slug
+func isSlug(text string) bool {
+	return text != "" && Slugify(text) == text
+}
+
+// CategoryFilter filter of the category list, zero fields match every category
+type CategoryFilter struct {
+	Name string
 }
 
 // CategoryModeler godoc
 type CategoryModeler interface {
-	Insert(catergory *Category) (interface{}, error)
-	ReadAll(filter interface{}) ([]Category, error)
-	ReadOne(filter interface{}) (Category, error)
-	UpdateOne(updatedData interface{}, filter interface{}) (int64, error)
-	RemoveOne(filter interface{}) (int64, error)
+	Insert(ctx context.Context, catergory *Category) (interface{}, error)
+	ReadAll(ctx context.Context, f CategoryFilter, opts ListOptions) ([]Category, Page, error)
+	ReadOne(ctx context.Context, id primitive.ObjectID) (Category, error)
+	UpdateOne(ctx context.Context, id primitive.ObjectID, update CategoryUpdateInput) (int64, error)
+	RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error)
+	ReadTree(ctx context.Context) ([]Category, error)
+	Move(ctx context.Context, id primitive.ObjectID, parentID primitive.ObjectID) (int64, error)
 }
 
 // CategoryModel godoc
@@ -43,65 +102,147 @@ func NewCategoryModel(db db.MongoDBClient) *CategoryModel {
 }
 
 // Insert insert a record at categories collection
-func (c *CategoryModel) Insert(catergory *Category) (interface{}, error) {
+func (c *CategoryModel) Insert(ctx context.Context, catergory *Category) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("categories")
-	insertResult, err := collection.InsertOne(context.TODO(), catergory)
+	insertResult, err := collection.InsertOne(ctx, catergory)
 	if err != nil {
-		log.Fatalf("Error on inserting new category: %v\n", err)
-		return nil, err
+		log.Printf("Error on inserting new category: %v\n", err)
+		return nil, dbError(err)
 	}
 	return insertResult.InsertedID, nil
 }
 
-// ReadAll read all the categories
-func (c *CategoryModel) ReadAll(filter interface{}) ([]Category, error) {
-	var categories []Category
+// ReadAll read a page of the categories
+func (c *CategoryModel) ReadAll(ctx context.Context, f CategoryFilter, opts ListOptions) ([]Category, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	categories := []Category{}
 	collection := c.db.Client.Database(c.db.DBName).Collection("categories")
-	cur, err := collection.Find(context.TODO(), filter)
-	if err != nil {
-		log.Printf("ERROR FINDING DATA: %v\n", err)
-		return categories, err
+	filter := bson.D{}
+	if f.Name != "" {
+		filter = append(filter, bson.E{Key: "name", Value: f.Name})
 	}
-	for cur.Next(context.TODO()) {
+	page, err := findPage(ctx, collection, filter, opts, CategoryListFields, func(cur *mongo.Cursor) error {
 		var category Category
-		err = cur.Decode(&category)
-		if err != nil {
-			log.Printf("Error on Decoding the document: %v\n", err)
+		if err := cur.Decode(&category); err != nil {
+			return err
 		}
 		categories = append(categories, category)
-	}
-	log.Printf("documentReturned: %v\n", categories)
-	return categories, nil
+		return nil
+	})
+	return categories, page, dbError(err)
 }
 
 // ReadOne read a single category
-func (c *CategoryModel) ReadOne(filter interface{}) (Category, error) {
+func (c *CategoryModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Category, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
 	var category Category
 	collection := c.db.Client.Database(c.db.DBName).Collection("categories")
-	documentReturned := collection.FindOne(context.TODO(), filter)
-	documentReturned.Decode(&category)
-	return category, nil
+	err := findOne(ctx, collection, bson.M{"_id": id}, "category", &category)
+	return category, err
 }
 
 // UpdateOne update one category from collections
-func (c *CategoryModel) UpdateOne(updatedData interface{}, filter interface{}) (int64, error) {
+func (c *CategoryModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update CategoryUpdateInput) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("categories")
+	updatedData := bson.M{
+		"name":       update.Name,
+		"slug":       update.Slug,
+		"color":      update.Color,
+		"icon":       update.Icon,
+		"updated_at": time.Now(),
+	}
 	atualizacao := bson.D{{Key: "$set", Value: updatedData}}
-	updatedResult, err := collection.UpdateOne(context.TODO(), filter, atualizacao)
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id}, atualizacao)
 	if err != nil {
-		log.Fatal("Error on updating one Hero", err)
-		return 0, err
+		log.Printf("Error on updating one Category: %v\n", err)
+		return 0, dbError(err)
 	}
 	return updatedResult.ModifiedCount, nil
 }
 
 // RemoveOne remove one category from collections
-func (c *CategoryModel) RemoveOne(filter interface{}) (int64, error) {
+func (c *CategoryModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("categories")
-	deleteResult, err := collection.DeleteOne(context.TODO(), filter)
+	deleteResult, err := collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
-		log.Fatal("Error on deleting one Category", err)
-		return 0, err
+		log.Printf("Error on deleting one Category: %v\n", err)
+		return 0, dbError(err)
 	}
 	return deleteResult.DeletedCount, nil
 }
+
+// ReadTree read every category, the tree is built by the callers with NewCategoryTree
+func (c *CategoryModel) ReadTree(ctx context.Context) ([]Category, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	categories := []Category{}
+	collection := c.db.Client.Database(c.db.DBName).Collection("categories")
+	cur, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return categories, dbError(err)
+	}
+	err = cur.All(ctx, &categories)
+	return categories, dbError(err)
+}
+
+// Move change the parent of one category, a zero parent makes it a root category.
+// The caller checks the new parent is not in the subtree of the category.
+func (c *CategoryModel) Move(ctx context.Context, id primitive.ObjectID, parentID primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := c.db.Client.Database(c.db.DBName).Collection("categories")
+	update := bson.M{"$set": bson.M{"updated_at": time.Now()}}
+	if parentID.IsZero() {
+		update["$unset"] = bson.M{"parent_id": ""}
+	} else {
+		update["$set"].(bson.M)["parent_id"] = parentID
+	}
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		log.Printf("Error on moving one Category: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// MigrateSlugs set the slug of the categories created before the slugs,
+// a slug already taken gets the id of the category as suffix
+func (c *CategoryModel) MigrateSlugs() (int64, error) {
+	collection := c.db.Client.Database(c.db.DBName).Collection("categories")
+	var categories []Category
+	cur, err := collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	if err := cur.All(context.TODO(), &categories); err != nil {
+		return 0, err
+	}
+	taken := map[string]bool{}
+	for _, category := range categories {
+		taken[category.Slug] = category.Slug != ""
+	}
+	var migrated int64
+	for _, category := range categories {
+		if category.Slug != "" {
+			continue
+		}
+		slug := Slugify(category.Name)
+		if slug == "" || taken[slug] {
+			slug = strings.TrimPrefix(slug+"-"+category.ID.Hex(), "-")
+		}
+		taken[slug] = true
+		if _, err := collection.UpdateOne(context.TODO(), bson.M{"_id": category.ID}, bson.M{"$set": bson.M{"slug": slug}}); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CategoryTree the categories indexed by id and by parent
+type CategoryTree struct {
+	byID     map[primitive.ObjectID]Category
+	children map[primitive.ObjectID][]primitive.ObjectID
+}
+
+// CategoryTotal total of the expenses of one category in one currency
+type CategoryTotal struct {
+	CategoryID primitive.ObjectID `json:"category_id" bson:"category_id"`
+	Total      Money              `json:"total" bson:"total"`
+	Count      int64              `json:"count" bson:"count"`
+}
+
+// CategoryNode category of the totals report, the totals of its subtree are rolled up into it
+type CategoryNode struct {
+	Category Category       `json:"category"`
+	Own      []Money        `json:"own"`   // totals of the expenses of the category itself, one per currency
+	Total    []Money        `json:"total"` // totals of the category & its descendants, one per currency
+	Count    int64          `json:"count"` // expenses of the category & its descendants
+	Children []CategoryNode `json:"children"`
+}
+
+// NewCategoryTree index the categories, a category whose parent is missing is a root
+func NewCategoryTree(categories []Category) CategoryTree {
+	t := CategoryTree{
+		byID:     make(map[primitive.ObjectID]Category, len(categories)),
+		children: map[primitive.ObjectID][]primitive.ObjectID{},
+	}
+	for _, c := range categories {
+		t.byID[c.ID] = c
+	}
+	for _, c := range categories {
+		parent := c.ParentID
+		if _, ok := t.byID[parent]; !ok {
+			parent = primitive.NilObjectID
+		}
+		t.children[parent] = append(t.children[parent], c.ID)
+	}
+	for _, ids := range t.children {
+		sort.Slice(ids, func(i, j int) bool { return t.byID[ids[i]].Name < t.byID[ids[j]].Name })
+	}
+	return t
+}
+
+// Has check the category is in the tree
+func (t CategoryTree) Has(id primitive.ObjectID) bool {
+	_, ok := t.byID[id]
+	return ok
+}
+
+// Children the direct children of the category, the roots for the zero id
+func (t CategoryTree) Children(id primitive.ObjectID) []Category {
+	children := make([]Category, 0, len(t.children[id]))
+	for _, child := range t.children[id] {
+		children = append(children, t.byID[child])
+	}
+	return children
+}
+
+// Descendants the ids of the category and of every category below it
+func (t CategoryTree) Descendants(id primitive.ObjectID) []primitive.ObjectID {
+	ids := []primitive.ObjectID{id}
+	seen := map[primitive.ObjectID]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// IsDescendant check the category is the ancestor or one of its descendants
+func (t CategoryTree) IsDescendant(id, ancestor primitive.ObjectID) bool {
+	for _, d := range t.Descendants(ancestor) {
+		if d == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Rollup the tree of the categories with the totals of each category added to every ancestor.
+// The totals of unknown categories are reported under roots with only their id.
+func (t CategoryTree) Rollup(totals []CategoryTotal) ([]CategoryNode, error) {
+	own := map[primitive.ObjectID][]CategoryTotal{}
+	for _, total := range totals {
+		own[total.CategoryID] = append(own[total.CategoryID], total)
+	}
+	var build func(c Category) (CategoryNode, error)
+	build = func(c Category) (CategoryNode, error) {
+		node := CategoryNode{Category: c, Own: []Money{}, Children: []CategoryNode{}}
+		var err error
+		for _, total := range own[c.ID] {
+			if node.Own, err = addMoney(node.Own, []Money{total.Total}); err != nil {
+				return node, err
+			}
+			node.Count += total.Count
+		}
+		node.Total = append([]Money{}, node.Own...)
+		if !t.Has(c.ID) {
+			return node, nil
+		}
+		for _, child := range t.Children(c.ID) {
+			childNode, err := build(child)
+			if err != nil {
+				return node, err
+			}
+			if node.Total, err = addMoney(node.Total, childNode.Total); err != nil {
+				return node, err
+			}
+			node.Count += childNode.Count
+			node.Children = append(node.Children, childNode)
+		}
+		return node, nil
+	}
+
+	nodes := []CategoryNode{}
+	for _, root := range t.Children(primitive.NilObjectID) {
+		node, err := build(root)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	var orphans []primitive.ObjectID
+	for id := range own {
+		if _, ok := t.byID[id]; !ok {
+			orphans = append(orphans, id)
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool { return orphans[i].Hex() < orphans[j].Hex() })
+	for _, id := range orphans {
+		node, err := build(Category{ID: id})
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// addMoney add the amounts to the totals of the same currency, the totals stay sorted by currency
+func addMoney(totals []Money, amounts []Money) ([]Money, error) {
+	for _, amount := range amounts {
+		found := false
+		for i := range totals {
+			if totals[i].Currency == amount.Currency {
+				sum, err := totals[i].Add(amount)
+				if err != nil {
+					return nil, err
+				}
+				totals[i], found = sum, true
+				break
+			}
+		}
+		if !found {
+			totals = append(totals, amount)
+		}
+	}
+	sortMoney(totals)
+	return totals, nil
+}
+
+// sortMoney sort the amounts by currency
+func sortMoney(amounts []Money) {
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i].Currency < amounts[j].Currency })
+}
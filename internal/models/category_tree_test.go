@@ -0,0 +1,127 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Food":            "food",
+		"Office Supplies": "office-supplies",
+		"Travel > Hotel":  "travel-hotel",
+		"  Café & Bar!  ": "café-bar",
+		"Straße":          "straße",
+		"যাতায়াত":        "যাতায়াত",
+		"2021 Q1":         "2021-q1",
+		"!!!":             "",
+	}
+	for name, slug := range cases {
+		assert.Equal(t, slug, Slugify(name), name)
+		if slug != "" {
+			assert.True(t, isSlug(slug), slug)
+		}
+	}
+	assert.False(t, isSlug("Office Supplies"))
+	assert.False(t, isSlug("office--supplies"))
+}
+
+func TestCategoryTree(t *testing.T) {
+	travel := Category{ID: primitive.NewObjectID(), Name: "Travel"}
+	hotel := Category{ID: primitive.NewObjectID(), Name: "Hotel", ParentID: travel.ID}
+	taxi := Category{ID: primitive.NewObjectID(), Name: "Taxi", ParentID: travel.ID}
+	suite := Category{ID: primitive.NewObjectID(), Name: "Suite", ParentID: hotel.ID}
+	food := Category{ID: primitive.NewObjectID(), Name: "Food"}
+	tree := NewCategoryTree([]Category{suite, taxi, food, hotel, travel})
+
+	assert.Equal(t, []Category{food, travel}, tree.Children(primitive.NilObjectID))
+	assert.Equal(t, []Category{hotel, taxi}, tree.Children(travel.ID))
+	assert.ElementsMatch(t, []primitive.ObjectID{travel.ID, hotel.ID, taxi.ID, suite.ID}, tree.Descendants(travel.ID))
+	assert.True(t, tree.IsDescendant(suite.ID, travel.ID))
+	assert.True(t, tree.IsDescendant(travel.ID, travel.ID))
+	assert.False(t, tree.IsDescendant(travel.ID, suite.ID))
+	assert.False(t, tree.IsDescendant(food.ID, travel.ID))
+
+	unknown := primitive.NewObjectID()
+	nodes, err := tree.Rollup([]CategoryTotal{
+		{CategoryID: travel.ID, Total: NewMoney(500, "EUR"), Count: 1},
+		{CategoryID: suite.ID, Total: NewMoney(10000, "EUR"), Count: 2},
+		{CategoryID: taxi.ID, Total: NewMoney(2000, "USD"), Count: 1},
+		{CategoryID: unknown, Total: NewMoney(100, "EUR"), Count: 1},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 3)
+	assert.Equal(t, food.ID, nodes[0].Category.ID)
+	assert.Equal(t, int64(0), nodes[0].Count)
+	assert.Equal(t, []Money{}, nodes[0].Total)
+
+	root := nodes[1]
+	assert.Equal(t, travel.ID, root.Category.ID)
+	assert.Equal(t, []Money{NewMoney(500, "EUR")}, root.Own)
+	assert.Equal(t, []Money{NewMoney(10500, "EUR"), NewMoney(2000, "USD")}, root.Total)
+	assert.Equal(t, int64(4), root.Count)
+	assert.Equal(t, []Money{NewMoney(10000, "EUR")}, root.Children[0].Total)
+	assert.Equal(t, []Money{}, root.Children[0].Own)
+	assert.Equal(t, suite.ID, root.Children[0].Children[0].Category.ID)
+
+	// totals of removed categories are not lost
+	assert.Equal(t, unknown, nodes[2].Category.ID)
+	assert.Equal(t, []Money{NewMoney(100, "EUR")}, nodes[2].Total)
+}
+
+func TestExpandCategories(t *testing.T) {
+	travel := Category{ID: primitive.NewObjectID(), Name: "Travel"}
+	hotel := Category{ID: primitive.NewObjectID(), Name: "Hotel", ParentID: travel.ID}
+	tree := NewCategoryTree([]Category{travel, hotel})
+
+	f := ExpenseFilter{Categories: []string{travel.ID.Hex()}}
+	f.ExpandCategories(tree)
+	assert.Equal(t, []string{travel.ID.Hex()}, f.Categories)
+
+	f.Subcategories = true
+	f.ExpandCategories(tree)
+	assert.Equal(t, []string{travel.ID.Hex(), hotel.ID.Hex()}, f.Categories)
+}
+
+func TestMemoryCategoryTotals(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	travel := Category{ID: primitive.NewObjectID(), Name: "Travel", Slug: "travel"}
+	hotel := Category{ID: primitive.NewObjectID(), Name: "Hotel", Slug: "hotel", ParentID: travel.ID}
+	for _, c := range []Category{travel, hotel} {
+		c := c
+		_, err := m.Categories.Insert(ctx, &c)
+		assert.NoError(t, err)
+	}
+	_, err := m.Categories.Insert(ctx, &Category{ID: primitive.NewObjectID(), Name: "hotel", Slug: "hotel"})
+	assert.Equal(t, ErrDuplicateKey, err)
+
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, e := range []struct {
+		category Category
+		amount   int64
+	}{{travel, 1000}, {hotel, 12000}, {hotel, 8000}} {
+		expense := newMemoryExpense("night", day, e.amount)
+		expense.Category = e.category.Snapshot()
+		_, err := m.Expenses.Insert(ctx, expense)
+		assert.NoError(t, err)
+	}
+
+	totals, err := m.Expenses.TotalsByCategory(ctx, ExpenseFilter{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []CategoryTotal{
+		{CategoryID: travel.ID, Total: NewMoney(1000, "EUR"), Count: 1},
+		{CategoryID: hotel.ID, Total: NewMoney(20000, "EUR"), Count: 2},
+	}, totals)
+
+	count, err := m.Expenses.ReassignCategory(ctx, hotel.ID, travel.Snapshot())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	totals, err = m.Expenses.TotalsByCategory(ctx, ExpenseFilter{Categories: []string{travel.ID.Hex()}})
+	assert.NoError(t, err)
+	assert.Equal(t, []CategoryTotal{{CategoryID: travel.ID, Total: NewMoney(21000, "EUR"), Count: 3}}, totals)
+}
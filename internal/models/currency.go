@@ -0,0 +1,27 @@
+package models
+
+// DefaultCurrency currency of the records created before the multi currency support
+const DefaultCurrency = "USD"
+
+// currencies the supported ISO 4217 currency codes with their number of minor units
+var currencies = map[string]int{
+	"AUD": 2,
+	"BDT": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KWD": 3,
+	"SEK": 2,
+	"SGD": 2,
+	"USD": 2,
+}
+
+// IsCurrency check if the code is a supported ISO 4217 currency code
+func IsCurrency(code string) bool {
+	_, ok := currencies[code]
+	return ok
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+
+	"github.com/lib/pq"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrDuplicateKey returned when a write breaks a unique index
+var ErrDuplicateKey = errs.New(errs.Conflict, "duplicate key: the resource already exists")
+
+// ErrUnavailable returned when the storage can not be reached
+var ErrUnavailable = errs.New(errs.Unavailable, "the storage is unavailable")
+
+// mongoDuplicateKeyCode error code of mongo for a unique index violation
+const mongoDuplicateKeyCode = 11000
+
+// postgresUniqueViolation SQLSTATE of postgres for a unique index violation
+const postgresUniqueViolation = "23505"
+
+// mongoNetworkError label of mongo for the network errors
+const mongoNetworkError = "NetworkError"
+
+// duplicateKey map the unique index violations of the drivers to ErrDuplicateKey,
+// other errors are returned unchanged
+func duplicateKey(err error) error {
+	switch e := err.(type) {
+	case mongo.WriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == mongoDuplicateKeyCode {
+				return ErrDuplicateKey
+			}
+		}
+	case mongo.BulkWriteException:
+		for _, we := range e.WriteErrors {
+			if we.Code == mongoDuplicateKeyCode {
+				return ErrDuplicateKey
+			}
+		}
+	case mongo.CommandError:
+		if e.Code == mongoDuplicateKeyCode {
+			return ErrDuplicateKey
+		}
+	case *pq.Error:
+		if e.Code == postgresUniqueViolation {
+			return ErrDuplicateKey
+		}
+	}
+	return err
+}
+
+// dbError map the errors of the drivers to the typed errors of the domain,
+// typed and unknown errors are returned unchanged
+func dbError(err error) error {
+	var typed *errs.Error
+	switch {
+	case err == nil, errors.As(err, &typed):
+		return err
+	case IsTimeout(err):
+		return errs.Wrap(errs.Timeout, ErrTimeout.Message, err)
+	case unavailable(err):
+		return errs.Wrap(errs.Unavailable, ErrUnavailable.Message, err)
+	}
+	return duplicateKey(err)
+}
+
+// unavailable check if the storage could not be reached
+func unavailable(err error) bool {
+	var netErr net.Error
+	var cmdErr mongo.CommandError
+	return errors.Is(err, mongo.ErrClientDisconnected) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, sql.ErrConnDone) ||
+		errors.As(err, &netErr) ||
+		(errors.As(err, &cmdErr) && cmdErr.HasErrorLabel(mongoNetworkError))
+}
+
+// notFound the error of a missing resource
+func notFound(resource string) error {
+	return errs.NotFoundf("%s not found", resource)
+}
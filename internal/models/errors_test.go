@@ -0,0 +1,57 @@
+package models
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestDuplicateKey(t *testing.T) {
+	other := errors.New("boom")
+	foreignKey := &pq.Error{Code: "23503"}
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"mongo write", mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}, ErrDuplicateKey},
+		{"mongo bulk write", mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Code: 11000}}}}, ErrDuplicateKey},
+		{"mongo command", mongo.CommandError{Code: 11000}, ErrDuplicateKey},
+		{"postgres", &pq.Error{Code: "23505"}, ErrDuplicateKey},
+		{"postgres foreign key", foreignKey, foreignKey},
+		{"other", other, other},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, duplicateKey(tt.err))
+		})
+	}
+}
+
+func TestDBError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errs.Kind
+	}{
+		{"timeout", context.DeadlineExceeded, errs.Timeout},
+		{"disconnected", mongo.ErrClientDisconnected, errs.Unavailable},
+		{"bad connection", driver.ErrBadConn, errs.Unavailable},
+		{"duplicate key", &pq.Error{Code: "23505"}, errs.Conflict},
+		{"not found", notFound("expense"), errs.NotFound},
+		{"other", errors.New("boom"), errs.Internal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, errs.KindOf(dbError(tt.err)))
+		})
+	}
+	assert.NoError(t, dbError(nil))
+}
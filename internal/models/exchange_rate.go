@@ -0,0 +1,364 @@
+package models
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrRateNotFound returned when no exchange rate is known for a currency pair
+var ErrRateNotFound = errs.New(errs.NotFound, "exchange rate not found")
+
+// ExchangeRate model for exchangeRates collection
+// Rate is the amount of `To` currency for one unit of `From` currency on Date
+type ExchangeRate struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	From      string             `json:"from" bson:"from"`
+	To        string             `json:"to" bson:"to"`
+	Date      time.Time          `json:"date" bson:"date"`
+	Rate      float64            `json:"rate" bson:"rate"`
+}
+
+// ExchangeRateInput exchange rate input model for the JSON & CSV imports
+type ExchangeRateInput struct {
+	From string  `json:"from" validate:"required,currency"`
+	To   string  `json:"to" validate:"required,currency,nefield=From"`
+	Date string  `json:"date" validate:"required"`
+	Rate float64 `json:"rate" validate:"required,gt=0"`
+}
+
+// RateFinder find the exchange rate of a currency pair on a date
+type RateFinder interface {
+	FindRate(ctx context.Context, from, to string, on time.Time) (float64, error)
+	// FindRates load the rates of the pair known from start to end at once, to convert many dates
+	FindRates(ctx context.Context, from, to string, start, end time.Time) (RateHistory, error)
+}
+
+// RateHistory the rates of a currency pair known over a date range,
+// the first rate of each direction is the latest one known on the start of the range
+type RateHistory struct {
+	From    string
+	To      string
+	Rates   []ExchangeRate // the rates of the pair, by ascending date
+	Inverse []ExchangeRate // the rates of the inverse pair, by ascending date
+}
+
+// Rate the latest rate of the pair known on the date, like FindRate.
+// The inverse pair is used when only that one is known.
+func (h RateHistory) Rate(on time.Time) (float64, error) {
+	if h.From == h.To {
+		return 1, nil
+	}
+	if rate, ok := latestKnown(h.Rates, on); ok {
+		return rate, nil
+	}
+	if inverse, ok := latestKnown(h.Inverse, on); ok {
+		return 1 / inverse, nil
+	}
+	return 0, ErrRateNotFound
+}
+
+// latestKnown the latest of the rates sorted by date known on the date
+func latestKnown(rates []ExchangeRate, on time.Time) (float64, bool) {
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].Date.After(on) })
+	if i == 0 {
+		return 0, false
+	}
+	return rates[i-1].Rate, true
+}
+
+// ExchangeRateFilter filter of the exchange rate list, zero fields match every rate
+type ExchangeRateFilter struct {
+	From string
+	To   string
+}
+
+// ExchangeRateModeler godoc
+type ExchangeRateModeler interface {
+	RateFinder
+	Upsert(ctx context.Context, rates []ExchangeRate) (int64, error)
+	ReadAll(ctx context.Context, f ExchangeRateFilter, opts ListOptions) ([]ExchangeRate, Page, error)
+}
+
+// ExchangeRateModel godoc
+type ExchangeRateModel struct {
+	db db.MongoDBClient
+}
+
+// NewExchangeRateModel godoc
+func NewExchangeRateModel(db db.MongoDBClient) *ExchangeRateModel {
+	return &ExchangeRateModel{db}
+}
+
+// Upsert insert or replace the rates of the same pair & date
+func (r *ExchangeRateModel) Upsert(ctx context.Context, rates []ExchangeRate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	if len(rates) == 0 {
+		return 0, nil
+	}
+	collection := r.db.Client.Database(r.db.DBName).Collection("exchangeRates")
+	writes := make([]mongo.WriteModel, 0, len(rates))
+	for _, rate := range rates {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"from": rate.From, "to": rate.To, "date": rate.Date}).
+			SetUpdate(bson.M{"$set": bson.M{"rate": rate.Rate, "updated_at": time.Now()}}).
+			SetUpsert(true))
+	}
+	res, err := collection.BulkWrite(ctx, writes)
+	if err != nil {
+		log.Printf("Error on upserting exchange rates: %v\n", err)
+		return 0, dbError(err)
+	}
+	return res.UpsertedCount + res.ModifiedCount, nil
+}
+
+// ExchangeRateDefaultSort exchange rates are listed latest first
+var ExchangeRateDefaultSort = []SortField{{Key: "date", Desc: true}}
+
+// ReadAll read a page of the exchange rates
+func (r *ExchangeRateModel) ReadAll(ctx context.Context, f ExchangeRateFilter, opts ListOptions) ([]ExchangeRate, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	rates := []ExchangeRate{}
+	collection := r.db.Client.Database(r.db.DBName).Collection("exchangeRates")
+	filter := bson.D{}
+	if f.From != "" {
+		filter = append(filter, bson.E{Key: "from", Value: f.From})
+	}
+	if f.To != "" {
+		filter = append(filter, bson.E{Key: "to", Value: f.To})
+	}
+	if len(opts.Sort) == 0 {
+		opts.Sort = ExchangeRateDefaultSort
+	}
+	page, err := findPage(ctx, collection, filter, opts, ExchangeRateListFields, func(cur *mongo.Cursor) error {
+		var rate ExchangeRate
+		if err := cur.Decode(&rate); err != nil {
+			return err
+		}
+		rates = append(rates, rate)
+		return nil
+	})
+	return rates, page, dbError(err)
+}
+
+// FindRate find the latest rate of the pair known on the date.
+// The inverse pair is used when only that one is stored.
+func (r *ExchangeRateModel) FindRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	return findRate(ctx, r.latestRate, from, to, on)
+}
+
+// findRate resolve the rate of the pair with the latest rate lookup of a backend,
+// falling back to the inverse pair
+func findRate(ctx context.Context, latestRate func(ctx context.Context, from, to string, on time.Time) (float64, error), from, to string, on time.Time) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	rate, err := latestRate(ctx, from, to, on)
+	if err == nil {
+		return rate, nil
+	}
+	if err != ErrRateNotFound {
+		return 0, err
+	}
+	inverse, err := latestRate(ctx, to, from, on)
+	if err != nil {
+		return 0, err
+	}
+	return 1 / inverse, nil
+}
+
+func (r *ExchangeRateModel) latestRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	var rate ExchangeRate
+	collection := r.db.Client.Database(r.db.DBName).Collection("exchangeRates")
+	opts := options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})
+	filter := bson.D{{Key: "from", Value: from}, {Key: "to", Value: to}, {Key: "date", Value: bson.D{{Key: "$lte", Value: on}}}}
+	err := collection.FindOne(ctx, filter, opts).Decode(&rate)
+	if err == mongo.ErrNoDocuments {
+		return 0, ErrRateNotFound
+	}
+	if err != nil {
+		log.Printf("Error on finding the exchange rate: %v\n", err)
+		return 0, dbError(err)
+	}
+	return rate.Rate, nil
+}
+
+// FindRates load the rates of the pair & of its inverse known from start to end
+func (r *ExchangeRateModel) FindRates(ctx context.Context, from, to string, start, end time.Time) (RateHistory, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	return findRates(ctx, r.latestRate, r.ratesBetween, from, to, start, end)
+}
+
+func (r *ExchangeRateModel) ratesBetween(ctx context.Context, from, to string, start, end time.Time) ([]ExchangeRate, error) {
+	rates := []ExchangeRate{}
+	collection := r.db.Client.Database(r.db.DBName).Collection("exchangeRates")
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}})
+	filter := bson.D{{Key: "from", Value: from}, {Key: "to", Value: to}, {Key: "date", Value: bson.D{{Key: "$gt", Value: start}, {Key: "$lte", Value: end}}}}
+	cur, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Printf("Error on finding the exchange rates: %v\n", err)
+		return nil, dbError(err)
+	}
+	if err := cur.All(ctx, &rates); err != nil {
+		log.Printf("Error on decoding the exchange rates: %v\n", err)
+		return nil, dbError(err)
+	}
+	return rates, nil
+}
+
+// findRates load the history of the pair with the lookups of a backend:
+// the latest rate known on start, then the rates after it up to end, for each direction
+func findRates(ctx context.Context,
+	latestRate func(ctx context.Context, from, to string, on time.Time) (float64, error),
+	ratesBetween func(ctx context.Context, from, to string, start, end time.Time) ([]ExchangeRate, error),
+	from, to string, start, end time.Time) (RateHistory, error) {
+	history := RateHistory{From: from, To: to}
+	if from == to {
+		return history, nil
+	}
+	for _, pair := range []struct {
+		from, to string
+		rates    *[]ExchangeRate
+	}{{from, to, &history.Rates}, {to, from, &history.Inverse}} {
+		rate, err := latestRate(ctx, pair.from, pair.to, start)
+		if err == nil {
+			*pair.rates = append(*pair.rates, ExchangeRate{From: pair.from, To: pair.to, Date: start, Rate: rate})
+		} else if err != ErrRateNotFound {
+			return history, err
+		}
+		after, err := ratesBetween(ctx, pair.from, pair.to, start, end)
+		if err != nil {
+			return history, err
+		}
+		*pair.rates = append(*pair.rates, after...)
+	}
+	return history, nil
+}
+
+// ToExchangeRate convert the input to the storage model
+func (i ExchangeRateInput) ToExchangeRate() (ExchangeRate, error) {
+	date, err := time.Parse("2006-01-02", i.Date)
+	if err != nil {
+		return ExchangeRate{}, err
+	}
+	return ExchangeRate{
+		From: strings.ToUpper(i.From),
+		To:   strings.ToUpper(i.To),
+		Date: date,
+		Rate: i.Rate,
+	}, nil
+}
+
+// ParseExchangeRatesJSON parse a JSON array of exchange rates
+func ParseExchangeRatesJSON(r io.Reader) ([]ExchangeRateInput, error) {
+	var inputs []ExchangeRateInput
+	if err := json.NewDecoder(r).Decode(&inputs); err != nil {
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// ParseExchangeRatesCSV parse exchange rates from a CSV with the header `from,to,date,rate`
+func ParseExchangeRatesCSV(r io.Reader) ([]ExchangeRateInput, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := map[string]int{}
+	for idx, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = idx
+	}
+	for _, name := range []string{"from", "to", "date", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing csv column %q", name)
+		}
+	}
+
+	inputs := make([]ExchangeRateInput, 0, len(records)-1)
+	for line, record := range records[1:] {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[columns["rate"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line+2, err)
+		}
+		inputs = append(inputs, ExchangeRateInput{
+			From: strings.TrimSpace(record[columns["from"]]),
+			To:   strings.TrimSpace(record[columns["to"]]),
+			Date: strings.TrimSpace(record[columns["date"]]),
+			Rate: rate,
+		})
+	}
+	return inputs, nil
+}
+
+// ConvertProjectDetails convert every expense into the base currency of the project
+// with the rate on the expense date and sum them up into the project total.
+// The rates of each currency are loaded once, for the dates of its expenses.
+func ConvertProjectDetails(ctx context.Context, details *ProjectDetails, rates RateFinder) error {
+	base := details.BaseCurrency
+	if base == "" {
+		base = DefaultCurrency
+	}
+	details.BaseCurrency = base
+	details.Total = NewMoney(0, base)
+
+	type dateRange struct{ start, end time.Time }
+	ranges := map[string]*dateRange{}
+	for _, expense := range details.Expenses {
+		r, ok := ranges[expense.Total.Currency]
+		if !ok {
+			ranges[expense.Total.Currency] = &dateRange{expense.Date, expense.Date}
+			continue
+		}
+		if expense.Date.Before(r.start) {
+			r.start = expense.Date
+		}
+		if expense.Date.After(r.end) {
+			r.end = expense.Date
+		}
+	}
+	histories := make(map[string]RateHistory, len(ranges))
+	for currency, r := range ranges {
+		history, err := rates.FindRates(ctx, currency, base, r.start, r.end)
+		if err != nil {
+			return err
+		}
+		histories[currency] = history
+	}
+
+	for idx := range details.Expenses {
+		expense := &details.Expenses[idx]
+		rate, err := histories[expense.Total.Currency].Rate(expense.Date)
+		if err != nil {
+			return fmt.Errorf("%w: %s to %s on %s", err, expense.Total.Currency, base, expense.Date.Format("2006-01-02"))
+		}
+		converted := expense.Total.Convert(rate, base)
+		expense.BaseTotal = &converted
+		if details.Total, err = details.Total.Add(converted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
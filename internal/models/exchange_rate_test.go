@@ -0,0 +1,59 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ratesStub known rates into EUR, starting on 2021-01-01
+type ratesStub map[string]float64
+
+func (r ratesStub) FindRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	history, _ := r.FindRates(ctx, from, to, on, on)
+	return history.Rate(on)
+}
+
+func (r ratesStub) FindRates(ctx context.Context, from, to string, start, end time.Time) (RateHistory, error) {
+	history := RateHistory{From: from, To: to}
+	if rate, ok := r[from+to]; ok {
+		history.Rates = []ExchangeRate{{From: from, To: to, Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Rate: rate}}
+	}
+	return history, nil
+}
+
+func TestConvertProjectDetails(t *testing.T) {
+	day := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	details := ProjectDetails{
+		BaseCurrency: "EUR",
+		Expenses: []Expense{
+			{Total: NewMoney(1000, "EUR"), Date: day},
+			{Total: NewMoney(1000, "USD"), Date: day},
+			{Total: NewMoney(100000, "BDT"), Date: day},
+		},
+	}
+	rates := ratesStub{"USDEUR": 0.8, "BDTEUR": 0.01}
+
+	assert.NoError(t, ConvertProjectDetails(context.Background(), &details, rates))
+	assert.Equal(t, "28.00", details.Total.String())
+	assert.Equal(t, "8.00", details.Expenses[1].BaseTotal.String())
+
+	details.Expenses = append(details.Expenses, Expense{Total: NewMoney(100, "USD"), Date: day.AddDate(-1, 0, 0)})
+	assert.Error(t, ConvertProjectDetails(context.Background(), &details, rates))
+}
+
+func TestParseExchangeRatesCSV(t *testing.T) {
+	csv := "date,from,to,rate\n2021-01-01,USD,EUR,0.82\n2021-01-02,BDT,EUR,0.0097\n"
+	inputs, err := ParseExchangeRatesCSV(strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.Equal(t, []ExchangeRateInput{
+		{From: "USD", To: "EUR", Date: "2021-01-01", Rate: 0.82},
+		{From: "BDT", To: "EUR", Date: "2021-01-02", Rate: 0.0097},
+	}, inputs)
+
+	_, err = ParseExchangeRatesCSV(strings.NewReader("from,to,rate\nUSD,EUR,1\n"))
+	assert.Error(t, err)
+}
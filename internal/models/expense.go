@@ -2,13 +2,14 @@ package models
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"time"
 
 	"github.com/masihur1989/expense-tracker-api/internal/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Expense expesne model
@@ -20,32 +21,83 @@ type Expense struct {
 	Title       string             `json:"title" bson:"title"`
 	Description string             `json:"description" bson:"description"`
 	Location    string             `json:"location" bson:"location"`
-	Total       float64            `json:"total" bson:"total"`
-	Status      string             `json:"status" bson:"status"`
+	Tags        []string           `json:"tags" bson:"tags"`
+	Total       Money              `json:"total" bson:"total"`
+	BaseTotal   *Money             `json:"base_total,omitempty" bson:"-"` // total converted into the project base currency, only in reports
+	Status      ExpenseStatus      `json:"status" bson:"status"`
 	ProjectID   primitive.ObjectID `json:"project_id" bson:"project_id"`
-	Category    Category           `json:"category" bson:"category"`
-	InsertedBy  User               `json:"user" bson:"user"`
+	Category    CategorySnapshot   `json:"category" bson:"category"` // reference to the category, see CategorySnapshot
+	InsertedBy  UserSnapshot       `json:"user" bson:"user"`         // reference to the author, see UserSnapshot
+	PaidBy      primitive.ObjectID `json:"paid_by" bson:"paid_by"`   // the member who paid, the author when zero
+	SplitMethod SplitMethod        `json:"split_method,omitempty" bson:"split_method,omitempty"`
+	Splits      []Split            `json:"splits" bson:"splits"` // the shares of the project members, none when not shared
+	History     []StatusTransition `json:"history" bson:"history"`
+	Attachments []Attachment       `json:"attachments" bson:"attachments"`
 }
 
 // ExpenseInput expense create input model
 type ExpenseInput struct {
-	Date        string  `json:"date" bson:"date" validate:"required"` // string date give more controll to parse it in any form for storage
-	Title       string  `json:"title" bson:"title" validate:"required"`
-	Description string  `json:"description" bson:"description" validate:"required"`
-	Location    string  `json:"location" bson:"location"`
-	Total       float64 `json:"total" bson:"total" validate:"required"`
-	Status      string  `json:"status" bson:"status" validate:"required,oneof=pending confirmed"`
-	CategoryID  string  `json:"category_id" bson:"category_id" validate:"required"`
-	InsertedBy  string  `json:"inserted_by" bson:"inserted_by" validate:"required"`
+	Date        string       `json:"date" bson:"date" validate:"required"` // string date give more controll to parse it in any form for storage
+	Title       string       `json:"title" bson:"title" validate:"required"`
+	Description string       `json:"description" bson:"description" validate:"required"`
+	Location    string       `json:"location" bson:"location"`
+	Tags        []string     `json:"tags" bson:"tags" validate:"max=20,dive,required,max=32"`
+	Total       json.Number  `json:"total" bson:"total" validate:"required"` // decimal amount, validated against the currency
+	Currency    string       `json:"currency" bson:"currency" validate:"required,currency"`
+	CategoryID  string       `json:"category_id" bson:"category_id" validate:"required"`
+	ProjectID   string       `json:"project_id" bson:"project_id"`
+	PaidBy      string       `json:"paid_by" bson:"paid_by" validate:"omitempty,objectid"` // the author by default
+	SplitMethod SplitMethod  `json:"split_method" bson:"split_method" validate:"required_with=Splits,omitempty,oneof=equal exact percent shares"`
+	Splits      []SplitInput `json:"splits" bson:"splits" validate:"required_with=SplitMethod,max=100,dive"`
+}
+
+// ExpenseUpdate editable fields of an expense, the author and the status are kept
+type ExpenseUpdate struct {
+	Title       string
+	Description string
+	Date        time.Time
+	Category    CategorySnapshot
+	Location    string
+	Tags        []string
+	Total       Money
+	PaidBy      primitive.ObjectID
+	SplitMethod SplitMethod
+	Splits      []Split
+}
+
+// Payer the member who paid the expense
+func (e Expense) Payer() primitive.ObjectID {
+	if e.PaidBy.IsZero() {
+		return e.InsertedBy.ID
+	}
+	return e.PaidBy
+}
+
+// NormalizedTags the tags of the input trimmed, lower cased and deduplicated
+func (i ExpenseInput) NormalizedTags() []string {
+	return normalizeTags(i.Tags)
+}
+
+// Money the exact total of the input
+func (i ExpenseInput) Money() (Money, error) {
+	return ParseMoney(i.Total.String(), i.Currency)
 }
 
 // ExpenseModeler godoc
 type ExpenseModeler interface {
-	Insert(expense Expense) (interface{}, error)
-	ReadAll(filter interface{}) ([]Expense, error)
-	ReadOne(filter interface{}) (Expense, error)
-	Remove(filter interface{}) (int64, error)
-	UpdateOne(updatedData interface{}, filter interface{}) (int64, error)
+	Insert(ctx context.Context, expense Expense) (interface{}, error)
+	ReadAll(ctx context.Context, f ExpenseFilter, opts ListOptions) ([]Expense, Page, error)
+	ReadOne(ctx context.Context, id primitive.ObjectID) (Expense, error)
+	Remove(ctx context.Context, id primitive.ObjectID) (int64, error)
+	UpdateOne(ctx context.Context, id primitive.ObjectID, update ExpenseUpdate) (int64, error)
+	Transition(ctx context.Context, id primitive.ObjectID, entry StatusTransition) (int64, error)
+	AddAttachment(ctx context.Context, id primitive.ObjectID, attachment Attachment) (int64, error)
+	RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) (int64, error)
+	CountAttachmentRefs(ctx context.Context, sha256 string) (int64, error)
+	ReassignCategory(ctx context.Context, from primitive.ObjectID, to CategorySnapshot) (int64, error)
+	TotalsByCategory(ctx context.Context, f ExpenseFilter) ([]CategoryTotal, error)
+	RefreshCategory(ctx context.Context, category CategorySnapshot) (int64, error)
+	RefreshUser(ctx context.Context, user UserSnapshot) (int64, error)
 }
 
 // ExpenseModel godoc
@@ -59,69 +111,345 @@ func NewExpenseModel(db db.MongoDBClient) *ExpenseModel {
 }
 
 // Insert insert a record at expenses collection
-func (e *ExpenseModel) Insert(expense Expense) (interface{}, error) {
+func (e *ExpenseModel) Insert(ctx context.Context, expense Expense) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
-	insertResult, err := collection.InsertOne(context.TODO(), expense)
+	insertResult, err := collection.InsertOne(ctx, expense)
 	if err != nil {
-		log.Fatalf("Error on inserting new expense: %v\n", err)
-		return nil, err
+		log.Printf("Error on inserting new expense: %v\n", err)
+		return nil, dbError(err)
 	}
 	return insertResult.InsertedID, nil
 }
 
-// ReadAll read all the expenses
-func (e *ExpenseModel) ReadAll(filter interface{}) ([]Expense, error) {
-	var expenses []Expense
+// ExpenseDefaultSort expenses are listed latest first
+var ExpenseDefaultSort = []SortField{{Key: "date", Desc: true}}
+
+// ReadAll read a page of the expenses
+func (e *ExpenseModel) ReadAll(ctx context.Context, f ExpenseFilter, opts ListOptions) ([]Expense, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	expenses := []Expense{}
 	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
-	log.Printf("filter: %v\n", filter)
-	// sort the entries based on the `date` field
-	opts := options.FindOptions{}
-	opts.SetSort(bson.D{{"date", -1}})
-	cur, err := collection.Find(context.TODO(), filter, &opts)
+	filter, err := f.toBSON()
 	if err != nil {
-		log.Printf("ERROR FINDING DATA: %v\n", err)
-		return expenses, err
+		return expenses, Page{}, dbError(err)
 	}
-	for cur.Next(context.TODO()) {
+	log.Printf("filter: %v\n", filter)
+	if len(opts.Sort) == 0 {
+		opts.Sort = ExpenseDefaultSort
+	}
+	page, err := findPage(ctx, collection, filter, opts, ExpenseListFields, func(cur *mongo.Cursor) error {
 		var expense Expense
-		err = cur.Decode(&expense)
-		if err != nil {
-			log.Printf("Error on Decoding the document: %v\n", err)
+		if err := cur.Decode(&expense); err != nil {
+			return err
 		}
 		expenses = append(expenses, expense)
-	}
-	log.Printf("documentReturned: %v\n", expenses)
-	return expenses, nil
+		return nil
+	})
+	return expenses, page, dbError(err)
 }
 
 // ReadOne read a single expense
-func (e *ExpenseModel) ReadOne(filter interface{}) (Expense, error) {
+func (e *ExpenseModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Expense, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
 	var expense Expense
 	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
-	documentReturned := collection.FindOne(context.TODO(), filter)
-	documentReturned.Decode(&expense)
-	return expense, nil
+	err := findOne(ctx, collection, bson.M{"_id": id}, "expense", &expense)
+	return expense, err
 }
 
 // Remove remove one expense from collctions
-func (e *ExpenseModel) Remove(filter interface{}) (int64, error) {
+func (e *ExpenseModel) Remove(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
-	deleteResult, err := collection.DeleteOne(context.TODO(), filter)
+	deleteResult, err := collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
-		log.Fatal("Error on deleting one expense", err)
-		return 0, err
+		log.Printf("Error on deleting one expense: %v\n", err)
+		return 0, dbError(err)
 	}
 	return deleteResult.DeletedCount, nil
 }
 
 // UpdateOne update one expense from collections
-func (e *ExpenseModel) UpdateOne(updatedData interface{}, filter interface{}) (int64, error) {
+func (e *ExpenseModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update ExpenseUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	updatedData := bson.M{
+		"title":        update.Title,
+		"description":  update.Description,
+		"date":         update.Date,
+		"category":     update.Category,
+		"location":     update.Location,
+		"tags":         update.Tags,
+		"total":        update.Total,
+		"paid_by":      update.PaidBy,
+		"split_method": update.SplitMethod,
+		"splits":       update.Splits,
+		"updated_at":   time.Now(),
+	}
 	atualizacao := bson.D{{Key: "$set", Value: updatedData}}
-	updatedResult, err := collection.UpdateOne(context.TODO(), filter, atualizacao)
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id}, atualizacao)
 	if err != nil {
-		log.Fatal("Error on updating one expense", err)
-		return 0, err
+		log.Printf("Error on updating one expense: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// Transition apply a workflow transition and record it in the history
+// the filter on the `from` status makes concurrent transitions safe
+func (e *ExpenseModel) Transition(ctx context.Context, id primitive.ObjectID, entry StatusTransition) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	filter := bson.M{"_id": id, "status": entry.From}
+	update := bson.M{
+		"$set":  bson.M{"status": entry.To, "updated_at": entry.At},
+		"$push": bson.M{"history": entry},
+	}
+	updatedResult, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		log.Printf("Error on transition of expense: %v\n", err)
+		return 0, dbError(err)
 	}
 	return updatedResult.ModifiedCount, nil
 }
+
+// AddAttachment embed the attachment metadata in the expense
+func (e *ExpenseModel) AddAttachment(ctx context.Context, id primitive.ObjectID, attachment Attachment) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	update := bson.M{
+		"$push": bson.M{"attachments": attachment},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		log.Printf("Error on adding attachment: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// RemoveAttachment remove the attachment metadata from the expense,
+// the pull is conditional so only one of the concurrent removals counts it
+func (e *ExpenseModel) RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	update := bson.M{
+		"$pull": bson.M{"attachments": bson.M{"_id": attachmentID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id, "attachments._id": attachmentID}, update)
+	if err != nil {
+		log.Printf("Error on removing attachment: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// CountAttachmentRefs count the attachments sharing the same content
+func (e *ExpenseModel) CountAttachmentRefs(ctx context.Context, sha256 string) (int64, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"attachments.sha256": sha256}}},
+		{{Key: "$unwind", Value: "$attachments"}},
+		{{Key: "$match", Value: bson.M{"attachments.sha256": sha256}}},
+		{{Key: "$count", Value: "refs"}},
+	}
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	var result []struct {
+		Refs int64 `bson:"refs"`
+	}
+	if err := cur.All(ctx, &result); err != nil || len(result) == 0 {
+		return 0, dbError(err)
+	}
+	return result[0].Refs, nil
+}
+
+// ReassignCategory move the expenses of the category to another one
+func (e *ExpenseModel) ReassignCategory(ctx context.Context, from primitive.ObjectID, to CategorySnapshot) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	update := bson.M{"$set": bson.M{"category": to, "updated_at": time.Now()}}
+	updatedResult, err := collection.UpdateMany(ctx, bson.M{"category._id": from}, update)
+	if err != nil {
+		log.Printf("Error on reassigning the category of expenses: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// RefreshCategory copy the category into the snapshots of its expenses, the expenses are not marked as updated
+func (e *ExpenseModel) RefreshCategory(ctx context.Context, category CategorySnapshot) (int64, error) {
+	return e.refreshSnapshots(ctx, "category", category.ID, category)
+}
+
+// RefreshUser copy the user into the snapshots of its expenses, the expenses are not marked as updated
+func (e *ExpenseModel) RefreshUser(ctx context.Context, user UserSnapshot) (int64, error) {
+	return e.refreshSnapshots(ctx, "user", user.ID, user)
+}
+
+// refreshSnapshots replace the stale snapshot of the field of the expenses referencing the id
+func (e *ExpenseModel) refreshSnapshots(ctx context.Context, field string, id primitive.ObjectID, snapshot interface{}) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	filter := bson.D{{Key: field + "._id", Value: id}, {Key: field, Value: bson.D{{Key: "$ne", Value: snapshot}}}}
+	updatedResult, err := collection.UpdateMany(ctx, filter, bson.D{{Key: "$set", Value: bson.D{{Key: field, Value: snapshot}}}})
+	if err != nil {
+		log.Printf("Error on refreshing the %s of expenses: %v\n", field, err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// TotalsByCategory sum the expenses matching the filter by category & currency
+func (e *ExpenseModel) TotalsByCategory(ctx context.Context, f ExpenseFilter) ([]CategoryTotal, error) {
+	ctx, cancel := reportContext(ctx)
+	defer cancel()
+	totals := []CategoryTotal{}
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	filter, err := f.toBSON()
+	if err != nil {
+		return totals, dbError(err)
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "category", Value: "$category._id"}, {Key: "currency", Value: "$total.currency"}}},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$total.amount"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+	}
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return totals, dbError(err)
+	}
+	var groups []struct {
+		ID struct {
+			Category primitive.ObjectID `bson:"category"`
+			Currency string             `bson:"currency"`
+		} `bson:"_id"`
+		Amount int64 `bson:"amount"`
+		Count  int64 `bson:"count"`
+	}
+	if err := cur.All(ctx, &groups); err != nil {
+		return totals, dbError(err)
+	}
+	for _, g := range groups {
+		totals = append(totals, CategoryTotal{CategoryID: g.ID.Category, Total: NewMoney(g.Amount, g.ID.Currency), Count: g.Count})
+	}
+	return totals, nil
+}
+
+// MigrateSnapshots replace the full copies of the categories & users embedded in the expenses by their snapshots.
+// The copies of removed categories & users lose their other fields, the password hashes among them.
+func (e *ExpenseModel) MigrateSnapshots() (int64, error) {
+	database := e.db.Client.Database(e.db.DBName)
+	var migrated int64
+	var categories []Category
+	cur, err := database.Collection("categories").Find(context.TODO(), bson.M{})
+	if err != nil {
+		return migrated, err
+	}
+	if err := cur.All(context.TODO(), &categories); err != nil {
+		return migrated, err
+	}
+	for _, category := range categories {
+		count, err := e.RefreshCategory(context.TODO(), category.Snapshot())
+		if err != nil {
+			return migrated, err
+		}
+		migrated += count
+	}
+	var users []User
+	cur, err = database.Collection("users").Find(context.TODO(), bson.M{})
+	if err != nil {
+		return migrated, err
+	}
+	if err := cur.All(context.TODO(), &users); err != nil {
+		return migrated, err
+	}
+	for _, user := range users {
+		count, err := e.RefreshUser(context.TODO(), user.Snapshot())
+		if err != nil {
+			return migrated, err
+		}
+		migrated += count
+	}
+	unset := bson.M{}
+	for _, field := range []string{"created_at", "updated_at", "parent_id"} {
+		unset["category."+field] = ""
+	}
+	for _, field := range []string{"created_at", "updated_at", "phone_number", "role", "locale", "password_hash"} {
+		unset["user."+field] = ""
+	}
+	res, err := database.Collection("expenses").UpdateMany(context.TODO(), bson.M{}, bson.M{"$unset": unset})
+	if err != nil {
+		return migrated, err
+	}
+	return migrated + res.ModifiedCount, nil
+}
+
+// MigrateLegacyStatuses map the legacy `pending` & `confirmed` statuses to the approval workflow
+func (e *ExpenseModel) MigrateLegacyStatuses() (int64, error) {
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	var migrated int64
+	for legacy, status := range map[string]ExpenseStatus{"pending": StatusSubmitted, "confirmed": StatusApproved} {
+		res, err := collection.UpdateMany(context.TODO(), bson.M{"status": legacy}, bson.M{"$set": bson.M{"status": status}})
+		if err != nil {
+			return migrated, err
+		}
+		migrated += res.ModifiedCount
+	}
+	return migrated, nil
+}
+
+// MigrateMoney convert the legacy float totals into exact money in minor units.
+// Expenses without a currency get the default one.
+func (e *ExpenseModel) MigrateMoney(defaultCurrency string) (int64, error) {
+	collection := e.db.Client.Database(e.db.DBName).Collection("expenses")
+	cur, err := collection.Find(context.TODO(), bson.M{"total": bson.M{"$type": "number"}})
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(context.TODO())
+
+	var migrated int64
+	for cur.Next(context.TODO()) {
+		var legacy struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Total    float64            `bson:"total"`
+			Currency string             `bson:"currency"`
+		}
+		if err := cur.Decode(&legacy); err != nil {
+			return migrated, err
+		}
+		if legacy.Currency == "" {
+			legacy.Currency = defaultCurrency
+		}
+		update := bson.M{
+			"$set":   bson.M{"total": FromFloat(legacy.Total, legacy.Currency)},
+			"$unset": bson.M{"currency": ""},
+		}
+		if _, err := collection.UpdateOne(context.TODO(), bson.M{"_id": legacy.ID}, update); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, cur.Err()
+}
@@ -0,0 +1,245 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// DateLayout layout of the dates accepted in the query params
+const DateLayout = "2006-01-02"
+
+// ExpenseFilter filter of the expense list, bound from the query params.
+// The params can be combined and the multi value ones repeated, e.g. `?category=a&category=b`
+type ExpenseFilter struct {
+	Start         string   `query:"start" validate:"omitempty,date"` // inclusive
+	End           string   `query:"end" validate:"omitempty,date"`   // exclusive
+	Categories    []string `query:"category" validate:"dive,objectid"`
+	Projects      []string `query:"project" validate:"dive,objectid"`
+	Users         []string `query:"user" validate:"dive,objectid"`
+	Statuses      []string `query:"status" validate:"dive,oneof=draft submitted approved rejected reimbursed"`
+	MinTotal      string   `query:"min_total"`
+	MaxTotal      string   `query:"max_total"`
+	Currency      string   `query:"currency" validate:"required_with=MinTotal MaxTotal,omitempty,currency"`
+	Location      string   `query:"location"`
+	Tags          []string `query:"tag" validate:"dive,required"`
+	Text          string   `query:"q"`
+	Subcategories bool     `query:"subcategories"` // the categories match their descendants too, see ExpandCategories
+	// Scope the expenses the user can read, set by the handlers & never bound from the query params, nil for all of them
+	Scope *ExpenseScope `query:"-"`
+}
+
+// ExpenseScope the expenses readable by a user: the ones of the projects
+// and the ones out of projects, only filed by the author when set
+type ExpenseScope struct {
+	Projects []primitive.ObjectID
+	Author   primitive.ObjectID // zero for the expenses out of projects of anyone
+}
+
+// Allows check if the expense is in the scope
+func (s ExpenseScope) Allows(e Expense) bool {
+	return s.Includes(e.ProjectID, e.InsertedBy.ID)
+}
+
+// Includes check if the expenses filed by the author in the project,
+// or out of projects with the zero project id, are in the scope
+func (s ExpenseScope) Includes(projectID, author primitive.ObjectID) bool {
+	if projectID.IsZero() {
+		return s.Author.IsZero() || author == s.Author
+	}
+	for _, id := range s.Projects {
+		if id == projectID {
+			return true
+		}
+	}
+	return false
+}
+
+// toBSON the mongo condition of the scope, the expenses out of projects have the nil project id or none
+func (s ExpenseScope) toBSON() bson.E {
+	projects := append([]primitive.ObjectID{}, s.Projects...)
+	outside := bson.D{{Key: "project_id", Value: bson.D{{Key: "$in", Value: bson.A{primitive.NilObjectID, nil}}}}}
+	if !s.Author.IsZero() {
+		outside = append(outside, bson.E{Key: "user._id", Value: s.Author})
+	}
+	// wrapped in $and so it does not clash with the $or of the text search
+	return bson.E{Key: "$and", Value: bson.A{bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "project_id", Value: bson.D{{Key: "$in", Value: projects}}}},
+		outside,
+	}}}}}
+}
+
+// toBSON build the mongo filter, the filter is expected to be validated
+func (f ExpenseFilter) toBSON() (bson.D, error) {
+	filter := bson.D{}
+
+	date := bson.D{}
+	if f.Start != "" {
+		start, err := time.Parse(DateLayout, f.Start)
+		if err != nil {
+			return nil, err
+		}
+		date = append(date, bson.E{Key: "$gte", Value: start})
+	}
+	if f.End != "" {
+		end, err := time.Parse(DateLayout, f.End)
+		if err != nil {
+			return nil, err
+		}
+		date = append(date, bson.E{Key: "$lt", Value: end})
+	}
+	if len(date) > 0 {
+		filter = append(filter, bson.E{Key: "date", Value: date})
+	}
+
+	for _, in := range []struct {
+		key string
+		ids []string
+	}{
+		{"category._id", f.Categories},
+		{"project_id", f.Projects},
+		{"user._id", f.Users},
+	} {
+		if len(in.ids) == 0 {
+			continue
+		}
+		ids, err := objectIDs(in.ids)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, bson.E{Key: in.key, Value: bson.D{{Key: "$in", Value: ids}}})
+	}
+
+	if len(f.Statuses) > 0 {
+		filter = append(filter, bson.E{Key: "status", Value: bson.D{{Key: "$in", Value: f.Statuses}}})
+	}
+
+	// amounts are stored in minor units, so a range only makes sense within one currency
+	if f.Currency != "" {
+		filter = append(filter, bson.E{Key: "total.currency", Value: f.Currency})
+		total := bson.D{}
+		if f.MinTotal != "" {
+			min, err := ParseMoney(f.MinTotal, f.Currency)
+			if err != nil {
+				return nil, err
+			}
+			total = append(total, bson.E{Key: "$gte", Value: min.Amount})
+		}
+		if f.MaxTotal != "" {
+			max, err := ParseMoney(f.MaxTotal, f.Currency)
+			if err != nil {
+				return nil, err
+			}
+			total = append(total, bson.E{Key: "$lte", Value: max.Amount})
+		}
+		if len(total) > 0 {
+			filter = append(filter, bson.E{Key: "total.amount", Value: total})
+		}
+	}
+
+	if f.Location != "" {
+		filter = append(filter, bson.E{Key: "location", Value: containsRegex(f.Location)})
+	}
+	if len(f.Tags) > 0 {
+		filter = append(filter, bson.E{Key: "tags", Value: bson.D{{Key: "$all", Value: normalizeTags(f.Tags)}}})
+	}
+	if f.Text != "" {
+		text := containsRegex(f.Text)
+		filter = append(filter, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "title", Value: text}},
+			bson.D{{Key: "description", Value: text}},
+			bson.D{{Key: "location", Value: text}},
+		}})
+	}
+	if f.Scope != nil {
+		filter = append(filter, f.Scope.toBSON())
+	}
+	return filter, nil
+}
+
+// ExpandCategories add the descendants of the categories of the filter when the subcategories are asked,
+// the filter is expected to be validated
+func (f *ExpenseFilter) ExpandCategories(tree CategoryTree) {
+	if !f.Subcategories || len(f.Categories) == 0 {
+		return
+	}
+	expanded := []string{}
+	seen := map[primitive.ObjectID]bool{}
+	ids, _ := objectIDs(f.Categories)
+	for _, id := range ids {
+		for _, d := range tree.Descendants(id) {
+			if !seen[d] {
+				seen[d] = true
+				expanded = append(expanded, d.Hex())
+			}
+		}
+	}
+	f.Categories = expanded
+}
+
+// containsRegex case insensitive match of the literal text
+func containsRegex(text string) primitive.Regex {
+	return primitive.Regex{Pattern: regexp.QuoteMeta(text), Options: "i"}
+}
+
+// objectIDs convert the hex ids
+func objectIDs(hexes []string) ([]primitive.ObjectID, error) {
+	ids := make([]primitive.ObjectID, 0, len(hexes))
+	for _, h := range hexes {
+		id, err := primitive.ObjectIDFromHex(h)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// normalizeTags tags are stored trimmed and lower cased
+func normalizeTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// validateExpenseFilter check the amount range is exact in the currency
+func validateExpenseFilter(sl validator.StructLevel) {
+	filter := sl.Current().Interface().(ExpenseFilter)
+	if !IsCurrency(filter.Currency) {
+		// reported by the field validations
+		return
+	}
+	if filter.MinTotal != "" {
+		if _, err := ParseMoney(filter.MinTotal, filter.Currency); err != nil {
+			sl.ReportError(filter.MinTotal, "min_total", "MinTotal", "money", "")
+		}
+	}
+	if filter.MaxTotal != "" {
+		if _, err := ParseMoney(filter.MaxTotal, filter.Currency); err != nil {
+			sl.ReportError(filter.MaxTotal, "max_total", "MaxTotal", "money", "")
+		}
+	}
+}
+
+// validateDate check the field is a YYYY-MM-DD date
+func validateDate(fl validator.FieldLevel) bool {
+	_, err := time.Parse(DateLayout, fl.Field().String())
+	return err == nil
+}
+
+// validateObjectID check the field is a hex object id
+func validateObjectID(fl validator.FieldLevel) bool {
+	_, err := primitive.ObjectIDFromHex(fl.Field().String())
+	return err == nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExpenseFilterBSON(t *testing.T) {
+	category := primitive.NewObjectID()
+	f := ExpenseFilter{
+		Start:      "2021-01-01",
+		Categories: []string{category.Hex()},
+		Statuses:   []string{"submitted", "approved"},
+		MinTotal:   "10.5",
+		Currency:   "EUR",
+		Tags:       []string{" Travel", "travel", "hotel"},
+		Text:       "a.b",
+	}
+	filter, err := f.toBSON()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "date", Value: bson.D{{Key: "$gte", Value: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}}},
+		{Key: "category._id", Value: bson.D{{Key: "$in", Value: []primitive.ObjectID{category}}}},
+		{Key: "status", Value: bson.D{{Key: "$in", Value: []string{"submitted", "approved"}}}},
+		{Key: "total.currency", Value: "EUR"},
+		{Key: "total.amount", Value: bson.D{{Key: "$gte", Value: int64(1050)}}},
+		{Key: "tags", Value: bson.D{{Key: "$all", Value: []string{"travel", "hotel"}}}},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "title", Value: primitive.Regex{Pattern: `a\.b`, Options: "i"}}},
+			bson.D{{Key: "description", Value: primitive.Regex{Pattern: `a\.b`, Options: "i"}}},
+			bson.D{{Key: "location", Value: primitive.Regex{Pattern: `a\.b`, Options: "i"}}},
+		}},
+	}, filter)
+
+	empty, err := ExpenseFilter{}.toBSON()
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+
+	project, author := primitive.NewObjectID(), primitive.NewObjectID()
+	scoped, err := ExpenseFilter{Scope: &ExpenseScope{Projects: []primitive.ObjectID{project}, Author: author}}.toBSON()
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "project_id", Value: bson.D{{Key: "$in", Value: []primitive.ObjectID{project}}}}},
+		bson.D{{Key: "project_id", Value: bson.D{{Key: "$in", Value: bson.A{primitive.NilObjectID, nil}}}}, {Key: "user._id", Value: author}},
+	}}}}}}, scoped)
+}
+
+func TestExpenseScopeAllows(t *testing.T) {
+	project, author := primitive.NewObjectID(), primitive.NewObjectID()
+	expense := func(projectID, authorID primitive.ObjectID) Expense {
+		return Expense{ProjectID: projectID, InsertedBy: UserSnapshot{ID: authorID}}
+	}
+	scope := ExpenseScope{Projects: []primitive.ObjectID{project}, Author: author}
+	assert.True(t, scope.Allows(expense(project, primitive.NewObjectID())))
+	assert.False(t, scope.Allows(expense(primitive.NewObjectID(), author)))
+	assert.True(t, scope.Allows(expense(primitive.NilObjectID, author)))
+	assert.False(t, scope.Allows(expense(primitive.NilObjectID, primitive.NewObjectID())))
+	assert.True(t, ExpenseScope{}.Allows(expense(primitive.NilObjectID, primitive.NewObjectID())))
+}
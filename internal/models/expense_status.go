@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExpenseStatus state of an expense in the approval workflow
+type ExpenseStatus string
+
+// all the states of the approval workflow
+const (
+	StatusDraft      ExpenseStatus = "draft"
+	StatusSubmitted  ExpenseStatus = "submitted"
+	StatusApproved   ExpenseStatus = "approved"
+	StatusRejected   ExpenseStatus = "rejected"
+	StatusReimbursed ExpenseStatus = "reimbursed"
+)
+
+// ExpenseAction transition of the approval workflow
+type ExpenseAction string
+
+// all the actions of the approval workflow
+const (
+	ActionSubmit    ExpenseAction = "submit"
+	ActionApprove   ExpenseAction = "approve"
+	ActionReject    ExpenseAction = "reject"
+	ActionReimburse ExpenseAction = "reimburse"
+)
+
+// expenseTransitions the state machine of the approval workflow
+// draft → submitted → approved/rejected → reimbursed, a rejected expense can be submitted again
+var expenseTransitions = map[ExpenseAction]struct {
+	from []ExpenseStatus
+	to   ExpenseStatus
+}{
+	ActionSubmit:    {[]ExpenseStatus{StatusDraft, StatusRejected}, StatusSubmitted},
+	ActionApprove:   {[]ExpenseStatus{StatusSubmitted}, StatusApproved},
+	ActionReject:    {[]ExpenseStatus{StatusSubmitted}, StatusRejected},
+	ActionReimburse: {[]ExpenseStatus{StatusApproved}, StatusReimbursed},
+}
+
+// StatusTransition audit entry of a transition, stored in the expense history
+type StatusTransition struct {
+	Action ExpenseAction      `json:"action" bson:"action"`
+	From   ExpenseStatus      `json:"from" bson:"from"`
+	To     ExpenseStatus      `json:"to" bson:"to"`
+	ByID   primitive.ObjectID `json:"by_id" bson:"by_id"`
+	ByName string             `json:"by_name" bson:"by_name"`
+	At     time.Time          `json:"at" bson:"at"`
+	Reason string             `json:"reason,omitempty" bson:"reason,omitempty"`
+}
+
+// RejectInput input model for the reject endpoint
+type RejectInput struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// NextStatus return the status reached by applying the action on the current status
+func NextStatus(current ExpenseStatus, action ExpenseAction) (ExpenseStatus, error) {
+	t, ok := expenseTransitions[action]
+	if !ok {
+		return "", fmt.Errorf("unknown action %q", action)
+	}
+	for _, from := range t.from {
+		if from == current {
+			return t.to, nil
+		}
+	}
+	return "", fmt.Errorf("can not %s an expense in %q status", action, current)
+}
+
+// IsEditable check if the expense can still be changed by its author
+func (s ExpenseStatus) IsEditable() bool {
+	return s == StatusDraft || s == StatusRejected
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextStatus(t *testing.T) {
+	tests := []struct {
+		current ExpenseStatus
+		action  ExpenseAction
+		next    ExpenseStatus
+		valid   bool
+	}{
+		{StatusDraft, ActionSubmit, StatusSubmitted, true},
+		{StatusRejected, ActionSubmit, StatusSubmitted, true},
+		{StatusSubmitted, ActionApprove, StatusApproved, true},
+		{StatusSubmitted, ActionReject, StatusRejected, true},
+		{StatusApproved, ActionReimburse, StatusReimbursed, true},
+		{StatusDraft, ActionApprove, "", false},
+		{StatusApproved, ActionReject, "", false},
+		{StatusReimbursed, ActionSubmit, "", false},
+		{StatusSubmitted, ActionReimburse, "", false},
+	}
+	for _, tt := range tests {
+		next, err := NextStatus(tt.current, tt.action)
+		if tt.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, tt.next, next)
+		} else {
+			assert.Error(t, err, "%s from %s", tt.action, tt.current)
+		}
+	}
+}
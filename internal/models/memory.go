@@ -0,0 +1,213 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore thread safe in-memory storage of every model, for local development and tests.
+// The data are lost when the process exits. Every operation answers immediately, so the
+// contexts given to the memory models are never checked.
+type MemoryStore struct {
+	mu                      sync.RWMutex
+	users                   map[primitive.ObjectID]User
+	categories              map[primitive.ObjectID]Category
+	projects                map[primitive.ObjectID]Project
+	projectUsers            map[primitive.ObjectID]ProjectUser
+	expenses                map[primitive.ObjectID]Expense
+	passwordResets          map[primitive.ObjectID]PasswordReset
+	exchangeRates           map[primitive.ObjectID]ExchangeRate
+	budgets                 map[primitive.ObjectID]Budget
+	notifications           map[primitive.ObjectID]Notification
+	notificationAlerts      map[string]time.Time // the claimed alert keys
+	notificationPreferences map[primitive.ObjectID]NotificationPreferences
+	recurringExpenses       map[primitive.ObjectID]RecurringExpense
+	settlements             map[primitive.ObjectID]Settlement
+}
+
+// NewMemoryStore an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:                   map[primitive.ObjectID]User{},
+		categories:              map[primitive.ObjectID]Category{},
+		projects:                map[primitive.ObjectID]Project{},
+		projectUsers:            map[primitive.ObjectID]ProjectUser{},
+		expenses:                map[primitive.ObjectID]Expense{},
+		passwordResets:          map[primitive.ObjectID]PasswordReset{},
+		exchangeRates:           map[primitive.ObjectID]ExchangeRate{},
+		budgets:                 map[primitive.ObjectID]Budget{},
+		notifications:           map[primitive.ObjectID]Notification{},
+		notificationAlerts:      map[string]time.Time{},
+		notificationPreferences: map[primitive.ObjectID]NotificationPreferences{},
+		recurringExpenses:       map[primitive.ObjectID]RecurringExpense{},
+		settlements:             map[primitive.ObjectID]Settlement{},
+	}
+}
+
+// NewMemoryModels the models kept in the memory store
+func NewMemoryModels(store *MemoryStore) Models {
+	return Models{
+		Users:          &MemoryUserModel{store},
+		Categories:     &MemoryCategoryModel{store},
+		Expenses:       &MemoryExpenseModel{store},
+		Projects:       &MemoryProjectModel{store},
+		PasswordResets: &MemoryPasswordResetModel{store},
+		ExchangeRates:  &MemoryExchangeRateModel{store},
+		Budgets:        &MemoryBudgetModel{store},
+		Notifications:  &MemoryNotificationModel{store},
+		Recurring:      &MemoryRecurringExpenseModel{store},
+		Settlements:    &MemorySettlementModel{store},
+	}
+}
+
+// memoryPage sort the n records on the sort fields and cut the page after the cursor.
+// value returns the value of a json field of the record i, the "id" breaks the ties.
+// The values are strings, int64, float64, bool or time.Time.
+func memoryPage(n int, value func(i int, field string) interface{}, opts ListOptions) ([]int, Page, error) {
+	page := Page{Limit: opts.Limit}
+	keys := make([]string, 0, len(opts.Sort)+1)
+	desc := make([]bool, 0, len(opts.Sort)+1)
+	for _, sf := range opts.Sort {
+		if sf.Key == "id" {
+			continue
+		}
+		keys = append(keys, sf.Key)
+		desc = append(desc, sf.Desc)
+	}
+	keys = append(keys, "id")
+	desc = append(desc, false)
+
+	values := make([][]interface{}, n)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+		values[i] = make([]interface{}, len(keys))
+		for k, key := range keys {
+			values[i][k] = value(i, key)
+		}
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return compareKeys(values[order[a]], values[order[b]], desc) < 0
+	})
+	if opts.Count {
+		total := int64(n)
+		page.Total = &total
+	}
+
+	start := 0
+	if opts.Cursor != "" {
+		after, err := decodeSQLCursor(opts.Cursor, len(keys))
+		if err != nil {
+			return nil, page, err
+		}
+		if n > 0 {
+			if after, err = typedCursor(after, values[0]); err != nil {
+				return nil, page, err
+			}
+		}
+		start = sort.Search(n, func(i int) bool {
+			return compareKeys(values[order[i]], after, desc) > 0
+		})
+	}
+	end := n
+	if opts.Limit > 0 && int64(n-start) > opts.Limit {
+		end = start + int(opts.Limit)
+		cursor, err := encodeSQLCursor(values[order[end-1]])
+		if err != nil {
+			return nil, page, err
+		}
+		page.NextCursor = cursor
+	}
+	return order[start:end], page, nil
+}
+
+// typedCursor convert the decoded cursor values to the types of the record values
+func typedCursor(after []interface{}, sample []interface{}) ([]interface{}, error) {
+	typed := make([]interface{}, len(after))
+	for i, v := range after {
+		s, ok := v.(string)
+		if !ok {
+			typed[i] = v
+			continue
+		}
+		var err error
+		switch sample[i].(type) {
+		case time.Time:
+			typed[i], err = time.Parse(time.RFC3339Nano, s)
+		case int64:
+			typed[i], err = strconv.ParseInt(s, 10, 64)
+		case float64:
+			typed[i], err = strconv.ParseFloat(s, 64)
+		default:
+			typed[i] = s
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return typed, nil
+}
+
+// compareKeys compare the sort key values of two records
+func compareKeys(a, b []interface{}, desc []bool) int {
+	for i := range a {
+		c := compareValues(a[i], b[i])
+		if desc[i] {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareValues compare two values of the same type, nil sorts first
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch x := a.(type) {
+	case string:
+		y, _ := b.(string)
+		return strings.Compare(x, y)
+	case int64:
+		y, _ := b.(int64)
+		return compareOrdered(x < y, x > y)
+	case float64:
+		y, _ := b.(float64)
+		return compareOrdered(x < y, x > y)
+	case bool:
+		y, _ := b.(bool)
+		return compareOrdered(!x && y, x && !y)
+	case time.Time:
+		y, _ := b.(time.Time)
+		return compareOrdered(x.Before(y), x.After(y))
+	}
+	return 0
+}
+
+func compareOrdered(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
+
+// containsFold case insensitive match of the literal text
+func containsFold(s, text string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(text))
+}
@@ -0,0 +1,121 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryBudgetModel BudgetModeler of the memory store
+type MemoryBudgetModel struct {
+	store *MemoryStore
+}
+
+// Insert add the budget to the store, one budget per category & period of a project
+func (b *MemoryBudgetModel) Insert(ctx context.Context, budget *Budget) (interface{}, error) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	for _, stored := range b.store.budgets {
+		if stored.ProjectID == budget.ProjectID && stored.CategoryID == budget.CategoryID && stored.Period == budget.Period {
+			return nil, ErrDuplicateKey
+		}
+	}
+	b.store.budgets[budget.ID] = *budget
+	return budget.ID, nil
+}
+
+// ReadAll read every budget of the project
+func (b *MemoryBudgetModel) ReadAll(ctx context.Context, projectID primitive.ObjectID) ([]Budget, error) {
+	b.store.mu.RLock()
+	defer b.store.mu.RUnlock()
+	budgets := []Budget{}
+	for _, budget := range b.store.budgets {
+		if budget.ProjectID == projectID {
+			budgets = append(budgets, budget)
+		}
+	}
+	sort.Slice(budgets, func(i, j int) bool {
+		if budgets[i].Period != budgets[j].Period {
+			return budgets[i].Period < budgets[j].Period
+		}
+		return budgets[i].ID.Hex() < budgets[j].ID.Hex()
+	})
+	return budgets, nil
+}
+
+// ReadOne read a single budget
+func (b *MemoryBudgetModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Budget, error) {
+	b.store.mu.RLock()
+	defer b.store.mu.RUnlock()
+	budget, ok := b.store.budgets[id]
+	if !ok {
+		return Budget{}, notFound("budget")
+	}
+	return budget, nil
+}
+
+// UpdateOne change the amount of one budget
+func (b *MemoryBudgetModel) UpdateOne(ctx context.Context, id primitive.ObjectID, amount Money) (int64, error) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	budget, ok := b.store.budgets[id]
+	if !ok {
+		return 0, nil
+	}
+	budget.Amount = amount
+	budget.UpdatedAt = time.Now()
+	b.store.budgets[id] = budget
+	return 1, nil
+}
+
+// RemoveOne remove one budget from the store
+func (b *MemoryBudgetModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	if _, ok := b.store.budgets[id]; !ok {
+		return 0, nil
+	}
+	delete(b.store.budgets, id)
+	return 1, nil
+}
+
+// CountCategory count the budgets on the category
+func (b *MemoryBudgetModel) CountCategory(ctx context.Context, categoryID primitive.ObjectID) (int64, error) {
+	b.store.mu.RLock()
+	defer b.store.mu.RUnlock()
+	var count int64
+	for _, budget := range b.store.budgets {
+		if budget.CategoryID == categoryID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ReassignCategory move the budgets of the category to another one,
+// none is moved when a project already has a budget on the other category for the same period
+func (b *MemoryBudgetModel) ReassignCategory(ctx context.Context, from, to primitive.ObjectID) (int64, error) {
+	b.store.mu.Lock()
+	defer b.store.mu.Unlock()
+	moved := []Budget{}
+	for _, budget := range b.store.budgets {
+		if budget.CategoryID == from {
+			moved = append(moved, budget)
+		}
+	}
+	for _, budget := range moved {
+		for _, stored := range b.store.budgets {
+			if stored.ProjectID == budget.ProjectID && stored.CategoryID == to && stored.Period == budget.Period {
+				return 0, ErrDuplicateKey
+			}
+		}
+	}
+	for _, budget := range moved {
+		budget.CategoryID = to
+		budget.UpdatedAt = time.Now()
+		b.store.budgets[budget.ID] = budget
+	}
+	return int64(len(moved)), nil
+}
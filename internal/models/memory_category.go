@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// categoryValue value of a list field of the category
+func categoryValue(c Category, field string) interface{} {
+	switch field {
+	case "id":
+		return c.ID.Hex()
+	case "created_at":
+		return c.CreatedAt
+	case "updated_at":
+		return c.UpdatedAt
+	case "name":
+		return c.Name
+	case "slug":
+		return c.Slug
+	}
+	return nil
+}
+
+// MemoryCategoryModel CategoryModeler of the memory store
+type MemoryCategoryModel struct {
+	store *MemoryStore
+}
+
+// Insert add the category to the store
+func (c *MemoryCategoryModel) Insert(ctx context.Context, category *Category) (interface{}, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	if c.store.slugTaken(category.Slug, category.ID) {
+		return nil, ErrDuplicateKey
+	}
+	c.store.categories[category.ID] = *category
+	return category.ID, nil
+}
+
+// ReadAll read a page of the categories
+func (c *MemoryCategoryModel) ReadAll(ctx context.Context, f CategoryFilter, opts ListOptions) ([]Category, Page, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	var matched []Category
+	for _, category := range c.store.categories {
+		if f.Name == "" || category.Name == f.Name {
+			matched = append(matched, category)
+		}
+	}
+	order, page, err := memoryPage(len(matched), func(i int, field string) interface{} {
+		return categoryValue(matched[i], field)
+	}, opts)
+	categories := make([]Category, 0, len(order))
+	for _, i := range order {
+		categories = append(categories, matched[i])
+	}
+	return categories, page, err
+}
+
+// ReadOne read a single category
+func (c *MemoryCategoryModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Category, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	category, ok := c.store.categories[id]
+	if !ok {
+		return Category{}, notFound("category")
+	}
+	return category, nil
+}
+
+// UpdateOne update one category of the store
+func (c *MemoryCategoryModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update CategoryUpdateInput) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	category, ok := c.store.categories[id]
+	if !ok {
+		return 0, nil
+	}
+	if c.store.slugTaken(update.Slug, id) {
+		return 0, ErrDuplicateKey
+	}
+	category.Name = update.Name
+	category.Slug = update.Slug
+	category.Color = update.Color
+	category.Icon = update.Icon
+	category.UpdatedAt = time.Now()
+	c.store.categories[id] = category
+	return 1, nil
+}
+
+// RemoveOne remove one category from the store
+func (c *MemoryCategoryModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	if _, ok := c.store.categories[id]; !ok {
+		return 0, nil
+	}
+	delete(c.store.categories, id)
+	return 1, nil
+}
+
+// ReadTree read every category, the tree is built by the callers with NewCategoryTree
+func (c *MemoryCategoryModel) ReadTree(ctx context.Context) ([]Category, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	categories := make([]Category, 0, len(c.store.categories))
+	for _, category := range c.store.categories {
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// Move change the parent of one category, a zero parent makes it a root category.
+// The caller checks the new parent is not in the subtree of the category.
+func (c *MemoryCategoryModel) Move(ctx context.Context, id primitive.ObjectID, parentID primitive.ObjectID) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	category, ok := c.store.categories[id]
+	if !ok {
+		return 0, nil
+	}
+	category.ParentID = parentID
+	category.UpdatedAt = time.Now()
+	c.store.categories[id] = category
+	return 1, nil
+}
+
+// slugTaken check another category has the slug, the lock is held by the caller
+func (s *MemoryStore) slugTaken(slug string, id primitive.ObjectID) bool {
+	for _, category := range s.categories {
+		if category.Slug == slug && category.ID != id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// exchangeRateValue value of a list field of the exchange rate
+func exchangeRateValue(r ExchangeRate, field string) interface{} {
+	switch field {
+	case "id":
+		return r.ID.Hex()
+	case "updated_at":
+		return r.UpdatedAt
+	case "from":
+		return r.From
+	case "to":
+		return r.To
+	case "date":
+		return r.Date
+	case "rate":
+		return r.Rate
+	}
+	return nil
+}
+
+// MemoryExchangeRateModel ExchangeRateModeler of the memory store
+type MemoryExchangeRateModel struct {
+	store *MemoryStore
+}
+
+// Upsert insert or replace the rates of the same pair & date
+func (r *MemoryExchangeRateModel) Upsert(ctx context.Context, rates []ExchangeRate) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var count int64
+	for _, rate := range rates {
+		rate.ID = primitive.NewObjectID()
+		for id, stored := range r.store.exchangeRates {
+			if stored.From == rate.From && stored.To == rate.To && stored.Date.Equal(rate.Date) {
+				rate.ID = id
+				break
+			}
+		}
+		rate.UpdatedAt = time.Now()
+		r.store.exchangeRates[rate.ID] = rate
+		count++
+	}
+	return count, nil
+}
+
+// ReadAll read a page of the exchange rates
+func (r *MemoryExchangeRateModel) ReadAll(ctx context.Context, f ExchangeRateFilter, opts ListOptions) ([]ExchangeRate, Page, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var matched []ExchangeRate
+	for _, rate := range r.store.exchangeRates {
+		if (f.From == "" || rate.From == f.From) && (f.To == "" || rate.To == f.To) {
+			matched = append(matched, rate)
+		}
+	}
+	if len(opts.Sort) == 0 {
+		opts.Sort = ExchangeRateDefaultSort
+	}
+	order, page, err := memoryPage(len(matched), func(i int, field string) interface{} {
+		return exchangeRateValue(matched[i], field)
+	}, opts)
+	rates := make([]ExchangeRate, 0, len(order))
+	for _, i := range order {
+		rates = append(rates, matched[i])
+	}
+	return rates, page, err
+}
+
+// FindRate find the latest rate of the pair known on the date.
+// The inverse pair is used when only that one is stored.
+func (r *MemoryExchangeRateModel) FindRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	return findRate(ctx, r.latestRate, from, to, on)
+}
+
+func (r *MemoryExchangeRateModel) latestRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var latest *ExchangeRate
+	for _, rate := range r.store.exchangeRates {
+		if rate.From != from || rate.To != to || rate.Date.After(on) {
+			continue
+		}
+		if latest == nil || rate.Date.After(latest.Date) {
+			rate := rate
+			latest = &rate
+		}
+	}
+	if latest == nil {
+		return 0, ErrRateNotFound
+	}
+	return latest.Rate, nil
+}
+
+// FindRates load the rates of the pair & of its inverse known from start to end
+func (r *MemoryExchangeRateModel) FindRates(ctx context.Context, from, to string, start, end time.Time) (RateHistory, error) {
+	return findRates(ctx, r.latestRate, r.ratesBetween, from, to, start, end)
+}
+
+func (r *MemoryExchangeRateModel) ratesBetween(ctx context.Context, from, to string, start, end time.Time) ([]ExchangeRate, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	rates := []ExchangeRate{}
+	for _, rate := range r.store.exchangeRates {
+		if rate.From == from && rate.To == to && rate.Date.After(start) && !rate.Date.After(end) {
+			rates = append(rates, rate)
+		}
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Date.Before(rates[j].Date) })
+	return rates, nil
+}
@@ -0,0 +1,379 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// expenseValue value of a list field of the expense
+func expenseValue(e Expense, field string) interface{} {
+	switch field {
+	case "id":
+		return e.ID.Hex()
+	case "created_at":
+		return e.CreatedAt
+	case "updated_at":
+		return e.UpdatedAt
+	case "date":
+		return e.Date
+	case "title":
+		return e.Title
+	case "description":
+		return e.Description
+	case "location":
+		return e.Location
+	case "total":
+		return e.Total.Amount
+	case "status":
+		return string(e.Status)
+	case "project_id":
+		return e.ProjectID.Hex()
+	case "category":
+		return e.Category.Name
+	case "user":
+		return e.InsertedBy.Name
+	case "paid_by":
+		return e.PaidBy.Hex()
+	}
+	return nil
+}
+
+// cloneExpense copy the slices of the expense, so the store never shares them with the callers
+func cloneExpense(e Expense) Expense {
+	e.Tags = append([]string{}, e.Tags...)
+	e.History = append([]StatusTransition{}, e.History...)
+	e.Attachments = append([]Attachment{}, e.Attachments...)
+	e.Splits = append([]Split{}, e.Splits...)
+	return e
+}
+
+// toMatcher build the predicate of the filter, the filter is expected to be validated
+func (f ExpenseFilter) toMatcher() (func(Expense) bool, error) {
+	var start, end time.Time
+	var err error
+	if f.Start != "" {
+		if start, err = time.Parse(DateLayout, f.Start); err != nil {
+			return nil, err
+		}
+	}
+	if f.End != "" {
+		if end, err = time.Parse(DateLayout, f.End); err != nil {
+			return nil, err
+		}
+	}
+	categories, err := idSet(f.Categories)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := idSet(f.Projects)
+	if err != nil {
+		return nil, err
+	}
+	users, err := idSet(f.Users)
+	if err != nil {
+		return nil, err
+	}
+	statuses := map[string]bool{}
+	for _, s := range f.Statuses {
+		statuses[s] = true
+	}
+
+	// amounts are stored in minor units, so a range only makes sense within one currency
+	var min, max *Money
+	if f.Currency != "" && f.MinTotal != "" {
+		m, err := ParseMoney(f.MinTotal, f.Currency)
+		if err != nil {
+			return nil, err
+		}
+		min = &m
+	}
+	if f.Currency != "" && f.MaxTotal != "" {
+		m, err := ParseMoney(f.MaxTotal, f.Currency)
+		if err != nil {
+			return nil, err
+		}
+		max = &m
+	}
+	tags := normalizeTags(f.Tags)
+
+	return func(e Expense) bool {
+		switch {
+		case f.Start != "" && e.Date.Before(start),
+			f.End != "" && !e.Date.Before(end),
+			len(categories) > 0 && !categories[e.Category.ID],
+			len(projects) > 0 && !projects[e.ProjectID],
+			len(users) > 0 && !users[e.InsertedBy.ID],
+			len(statuses) > 0 && !statuses[string(e.Status)],
+			f.Currency != "" && e.Total.Currency != f.Currency,
+			min != nil && e.Total.Amount < min.Amount,
+			max != nil && e.Total.Amount > max.Amount,
+			f.Location != "" && !containsFold(e.Location, f.Location),
+			f.Text != "" && !containsFold(e.Title, f.Text) && !containsFold(e.Description, f.Text) && !containsFold(e.Location, f.Text),
+			f.Scope != nil && !f.Scope.Allows(e):
+			return false
+		}
+		for _, tag := range tags {
+			if !hasTag(e.Tags, tag) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// idSet the set of the hex ids
+func idSet(hexes []string) (map[primitive.ObjectID]bool, error) {
+	ids, err := objectIDs(hexes)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[primitive.ObjectID]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryExpenseModel ExpenseModeler of the memory store
+type MemoryExpenseModel struct {
+	store *MemoryStore
+}
+
+// Insert add the expense to the store
+func (e *MemoryExpenseModel) Insert(ctx context.Context, expense Expense) (interface{}, error) {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	if _, ok := e.store.expenses[expense.ID]; ok {
+		return nil, ErrDuplicateKey
+	}
+	e.store.expenses[expense.ID] = cloneExpense(expense)
+	return expense.ID, nil
+}
+
+// ReadAll read a page of the expenses
+func (e *MemoryExpenseModel) ReadAll(ctx context.Context, f ExpenseFilter, opts ListOptions) ([]Expense, Page, error) {
+	match, err := f.toMatcher()
+	if err != nil {
+		return []Expense{}, Page{}, err
+	}
+	if len(opts.Sort) == 0 {
+		opts.Sort = ExpenseDefaultSort
+	}
+	e.store.mu.RLock()
+	defer e.store.mu.RUnlock()
+	return e.store.selectExpenses(match, opts)
+}
+
+// selectExpenses read a page of the expenses matching the predicate, the lock is held by the caller
+func (s *MemoryStore) selectExpenses(match func(Expense) bool, opts ListOptions) ([]Expense, Page, error) {
+	var matched []Expense
+	for _, expense := range s.expenses {
+		if match(expense) {
+			matched = append(matched, expense)
+		}
+	}
+	order, page, err := memoryPage(len(matched), func(i int, field string) interface{} {
+		return expenseValue(matched[i], field)
+	}, opts)
+	expenses := make([]Expense, 0, len(order))
+	for _, i := range order {
+		expenses = append(expenses, cloneExpense(matched[i]))
+	}
+	return expenses, page, err
+}
+
+// ReadOne read a single expense
+func (e *MemoryExpenseModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Expense, error) {
+	e.store.mu.RLock()
+	defer e.store.mu.RUnlock()
+	expense, ok := e.store.expenses[id]
+	if !ok {
+		return Expense{}, notFound("expense")
+	}
+	return cloneExpense(expense), nil
+}
+
+// Remove remove one expense from the store
+func (e *MemoryExpenseModel) Remove(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	if _, ok := e.store.expenses[id]; !ok {
+		return 0, nil
+	}
+	delete(e.store.expenses, id)
+	return 1, nil
+}
+
+// UpdateOne update one expense of the store
+func (e *MemoryExpenseModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update ExpenseUpdate) (int64, error) {
+	return e.update(id, func(expense *Expense) bool {
+		expense.Title = update.Title
+		expense.Description = update.Description
+		expense.Date = update.Date
+		expense.Category = update.Category
+		expense.Location = update.Location
+		expense.Tags = append([]string{}, update.Tags...)
+		expense.Total = update.Total
+		expense.PaidBy = update.PaidBy
+		expense.SplitMethod = update.SplitMethod
+		expense.Splits = append([]Split{}, update.Splits...)
+		expense.UpdatedAt = time.Now()
+		return true
+	})
+}
+
+// Transition apply a workflow transition and record it in the history
+// the check of the `from` status under the lock makes concurrent transitions safe
+func (e *MemoryExpenseModel) Transition(ctx context.Context, id primitive.ObjectID, entry StatusTransition) (int64, error) {
+	return e.update(id, func(expense *Expense) bool {
+		if expense.Status != entry.From {
+			return false
+		}
+		expense.Status = entry.To
+		expense.UpdatedAt = entry.At
+		expense.History = append(expense.History, entry)
+		return true
+	})
+}
+
+// AddAttachment add the attachment metadata to the expense
+func (e *MemoryExpenseModel) AddAttachment(ctx context.Context, id primitive.ObjectID, attachment Attachment) (int64, error) {
+	return e.update(id, func(expense *Expense) bool {
+		expense.Attachments = append(expense.Attachments, attachment)
+		expense.UpdatedAt = time.Now()
+		return true
+	})
+}
+
+// RemoveAttachment remove the attachment metadata from the expense
+func (e *MemoryExpenseModel) RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) (int64, error) {
+	return e.update(id, func(expense *Expense) bool {
+		for i, attachment := range expense.Attachments {
+			if attachment.ID == attachmentID {
+				expense.Attachments = append(expense.Attachments[:i], expense.Attachments[i+1:]...)
+				expense.UpdatedAt = time.Now()
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// CountAttachmentRefs count the attachments sharing the same content
+func (e *MemoryExpenseModel) CountAttachmentRefs(ctx context.Context, sha256 string) (int64, error) {
+	e.store.mu.RLock()
+	defer e.store.mu.RUnlock()
+	var refs int64
+	for _, expense := range e.store.expenses {
+		for _, attachment := range expense.Attachments {
+			if attachment.SHA256 == sha256 {
+				refs++
+			}
+		}
+	}
+	return refs, nil
+}
+
+// ReassignCategory move the expenses of the category to another one
+func (e *MemoryExpenseModel) ReassignCategory(ctx context.Context, from primitive.ObjectID, to CategorySnapshot) (int64, error) {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	var count int64
+	for id, expense := range e.store.expenses {
+		if expense.Category.ID == from {
+			expense.Category = to
+			expense.UpdatedAt = time.Now()
+			e.store.expenses[id] = expense
+			count++
+		}
+	}
+	return count, nil
+}
+
+// RefreshCategory copy the category into the snapshots of its expenses, the expenses are not marked as updated
+func (e *MemoryExpenseModel) RefreshCategory(ctx context.Context, category CategorySnapshot) (int64, error) {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	var count int64
+	for id, expense := range e.store.expenses {
+		if expense.Category.ID == category.ID && expense.Category != category {
+			expense.Category = category
+			e.store.expenses[id] = expense
+			count++
+		}
+	}
+	return count, nil
+}
+
+// RefreshUser copy the user into the snapshots of its expenses, the expenses are not marked as updated
+func (e *MemoryExpenseModel) RefreshUser(ctx context.Context, user UserSnapshot) (int64, error) {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	var count int64
+	for id, expense := range e.store.expenses {
+		if expense.InsertedBy.ID == user.ID && expense.InsertedBy != user {
+			expense.InsertedBy = user
+			e.store.expenses[id] = expense
+			count++
+		}
+	}
+	return count, nil
+}
+
+// TotalsByCategory sum the expenses matching the filter by category & currency
+func (e *MemoryExpenseModel) TotalsByCategory(ctx context.Context, f ExpenseFilter) ([]CategoryTotal, error) {
+	match, err := f.toMatcher()
+	if err != nil {
+		return []CategoryTotal{}, err
+	}
+	e.store.mu.RLock()
+	defer e.store.mu.RUnlock()
+	type group struct {
+		category primitive.ObjectID
+		currency string
+	}
+	index := map[group]int{}
+	totals := []CategoryTotal{}
+	for _, expense := range e.store.expenses {
+		if !match(expense) {
+			continue
+		}
+		g := group{expense.Category.ID, expense.Total.Currency}
+		i, ok := index[g]
+		if !ok {
+			i = len(totals)
+			index[g] = i
+			totals = append(totals, CategoryTotal{CategoryID: g.category, Total: NewMoney(0, g.currency)})
+		}
+		totals[i].Total.Amount += expense.Total.Amount
+		totals[i].Count++
+	}
+	return totals, nil
+}
+
+// update apply the change to a copy of the expense and store it when the change reports it was applied
+func (e *MemoryExpenseModel) update(id primitive.ObjectID, change func(*Expense) bool) (int64, error) {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	expense, ok := e.store.expenses[id]
+	if !ok {
+		return 0, nil
+	}
+	expense = cloneExpense(expense)
+	if !change(&expense) {
+		return 0, nil
+	}
+	e.store.expenses[id] = expense
+	return 1, nil
+}
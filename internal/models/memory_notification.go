@@ -0,0 +1,123 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// notificationValue the value of a list field of the notification
+func notificationValue(n Notification, field string) interface{} {
+	switch field {
+	case "id":
+		return n.ID.Hex()
+	case "created_at":
+		return n.CreatedAt
+	case "kind":
+		return string(n.Kind)
+	}
+	return nil
+}
+
+// MemoryNotificationModel NotificationModeler of the memory store
+type MemoryNotificationModel struct {
+	store *MemoryStore
+}
+
+// Insert add the notification to the store
+func (n *MemoryNotificationModel) Insert(ctx context.Context, notification *Notification) (interface{}, error) {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+	if _, ok := n.store.notifications[notification.ID]; ok {
+		return nil, ErrDuplicateKey
+	}
+	n.store.notifications[notification.ID] = *notification
+	return notification.ID, nil
+}
+
+// ReadAll read a page of the inbox of the user
+func (n *MemoryNotificationModel) ReadAll(ctx context.Context, f NotificationFilter, opts ListOptions) ([]Notification, Page, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+	var matched []Notification
+	for _, notification := range n.store.notifications {
+		if notification.UserID != f.UserID || (f.Unread && notification.ReadAt != nil) {
+			continue
+		}
+		matched = append(matched, notification)
+	}
+	order, page, err := memoryPage(len(matched), func(i int, field string) interface{} {
+		return notificationValue(matched[i], field)
+	}, opts)
+	notifications := make([]Notification, 0, len(order))
+	for _, i := range order {
+		notifications = append(notifications, matched[i])
+	}
+	return notifications, page, err
+}
+
+// MarkRead mark one notification of the user read or unread
+func (n *MemoryNotificationModel) MarkRead(ctx context.Context, userID, id primitive.ObjectID, read bool) (int64, error) {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+	notification, ok := n.store.notifications[id]
+	if !ok || notification.UserID != userID {
+		return 0, nil
+	}
+	notification.ReadAt = nil
+	if read {
+		now := time.Now()
+		notification.ReadAt = &now
+	}
+	n.store.notifications[id] = notification
+	return 1, nil
+}
+
+// MarkAllRead mark every unread notification of the user read
+func (n *MemoryNotificationModel) MarkAllRead(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+	now := time.Now()
+	var count int64
+	for id, notification := range n.store.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			notification.ReadAt = &now
+			n.store.notifications[id] = notification
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Claim record the alert key, false when it was already claimed
+func (n *MemoryNotificationModel) Claim(ctx context.Context, key string) (bool, error) {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+	if _, ok := n.store.notificationAlerts[key]; ok {
+		return false, nil
+	}
+	n.store.notificationAlerts[key] = time.Now()
+	return true, nil
+}
+
+// ReadPreferences read the preferences of the user, the defaults when the user never changed them
+func (n *MemoryNotificationModel) ReadPreferences(ctx context.Context, userID primitive.ObjectID) (NotificationPreferences, error) {
+	n.store.mu.RLock()
+	defer n.store.mu.RUnlock()
+	prefs, ok := n.store.notificationPreferences[userID]
+	if !ok {
+		return DefaultNotificationPreferences(userID), nil
+	}
+	prefs.Muted = append([]NotificationKind{}, prefs.Muted...)
+	return prefs, nil
+}
+
+// UpdatePreferences replace the preferences of the user
+func (n *MemoryNotificationModel) UpdatePreferences(ctx context.Context, prefs NotificationPreferences) error {
+	n.store.mu.Lock()
+	defer n.store.mu.Unlock()
+	prefs.Muted = append([]NotificationKind{}, prefs.Muted...)
+	n.store.notificationPreferences[prefs.UserID] = prefs
+	return nil
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryPasswordResetModel PasswordResetModeler of the memory store
+type MemoryPasswordResetModel struct {
+	store *MemoryStore
+}
+
+// Insert add the password reset to the store
+func (p *MemoryPasswordResetModel) Insert(ctx context.Context, reset *PasswordReset) (interface{}, error) {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	p.store.passwordResets[reset.ID] = *reset
+	return reset.ID, nil
+}
+
+// ReadOneByTokenHash read the password reset of the token
+func (p *MemoryPasswordResetModel) ReadOneByTokenHash(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	for _, reset := range p.store.passwordResets {
+		if reset.TokenHash == tokenHash {
+			return reset, nil
+		}
+	}
+	return PasswordReset{}, notFound("password reset")
+}
+
+// MarkUsed consume the reset token, the check of `used_at` under the lock makes sure
+// a token can only be used once even with concurrent requests
+func (p *MemoryPasswordResetModel) MarkUsed(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	reset, ok := p.store.passwordResets[id]
+	if !ok || reset.UsedAt != nil {
+		return 0, nil
+	}
+	now := time.Now()
+	reset.UsedAt = &now
+	p.store.passwordResets[id] = reset
+	return 1, nil
+}
+
+// MarkUserUsed consume every unused reset token of the user, once the password changed
+func (p *MemoryPasswordResetModel) MarkUserUsed(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	now := time.Now()
+	var count int64
+	for id, reset := range p.store.passwordResets {
+		if reset.UserID == userID && reset.UsedAt == nil {
+			reset.UsedAt = &now
+			p.store.passwordResets[id] = reset
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// projectValue value of a list field of the project
+func projectValue(p Project, field string) interface{} {
+	switch field {
+	case "id":
+		return p.ID.Hex()
+	case "created_at":
+		return p.CreatedAt
+	case "updated_at":
+		return p.UpdatedAt
+	case "title":
+		return p.Title
+	case "description":
+		return p.Description
+	case "base_currency":
+		return p.BaseCurrency
+	case "is_active":
+		return p.IsActive
+	}
+	return nil
+}
+
+// matches check the membership against the filter
+func (f ProjectUserFilter) matches(pu ProjectUser) bool {
+	return (f.ID.IsZero() || pu.ID == f.ID) &&
+		(f.ProjectID.IsZero() || pu.ProjectID == f.ProjectID) &&
+		(f.UserID.IsZero() || pu.UserID == f.UserID) &&
+		(f.IsActive == nil || pu.IsActive == *f.IsActive)
+}
+
+// MemoryProjectModel ProjectModeler of the memory store
+type MemoryProjectModel struct {
+	store *MemoryStore
+}
+
+// Insert add the project to the store
+func (c *MemoryProjectModel) Insert(ctx context.Context, project *Project) (interface{}, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	c.store.projects[project.ID] = *project
+	return project.ID, nil
+}
+
+// ReadAll read a page of the projects
+func (c *MemoryProjectModel) ReadAll(ctx context.Context, f ProjectFilter, opts ListOptions) ([]Project, Page, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	var matched []Project
+	for _, project := range c.store.projects {
+		if f.Title == "" || project.Title == f.Title {
+			matched = append(matched, project)
+		}
+	}
+	order, page, err := memoryPage(len(matched), func(i int, field string) interface{} {
+		return projectValue(matched[i], field)
+	}, opts)
+	projects := make([]Project, 0, len(order))
+	for _, i := range order {
+		projects = append(projects, matched[i])
+	}
+	return projects, page, err
+}
+
+// ReadOne read a single project
+func (c *MemoryProjectModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Project, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	project, ok := c.store.projects[id]
+	if !ok {
+		return Project{}, notFound("project")
+	}
+	return project, nil
+}
+
+// UpdateOne update one project of the store
+func (c *MemoryProjectModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update ProjectUpdate) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	project, ok := c.store.projects[id]
+	if !ok {
+		return 0, nil
+	}
+	if update.IsActive != nil {
+		project.IsActive = *update.IsActive
+	}
+	project.UpdatedAt = time.Now()
+	c.store.projects[id] = project
+	return 1, nil
+}
+
+// LookupProjectDetails read the project with its expenses of the period, latest first,
+// and its memberships joined with their user account
+func (c *MemoryProjectModel) LookupProjectDetails(ctx context.Context, id primitive.ObjectID, qsFilter ProjectDetailsQS) (ProjectDetails, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	project, ok := c.store.projects[id]
+	if !ok {
+		return ProjectDetails{}, notFound("project")
+	}
+	details := ProjectDetails{
+		ID:           project.ID,
+		CreatedAt:    project.CreatedAt,
+		UpdatedAt:    project.UpdatedAt,
+		Title:        project.Title,
+		Description:  project.Description,
+		BaseCurrency: project.BaseCurrency,
+	}
+	var err error
+	details.Expenses, _, err = c.store.selectExpenses(func(e Expense) bool {
+		return e.ProjectID == id && !e.Date.Before(qsFilter.Start) && e.Date.Before(qsFilter.End)
+	}, ListOptions{Sort: ExpenseDefaultSort})
+	if err != nil {
+		return details, err
+	}
+	details.Users = c.store.projectMembers(ProjectUserFilter{ProjectID: id, IsActive: Bool(qsFilter.IsActive)})
+	if details.Users == nil {
+		details.Users = []ProjectMember{}
+	}
+	return details, nil
+}
+
+// InsertProjectUser add the membership to the store
+func (c *MemoryProjectModel) InsertProjectUser(ctx context.Context, user *ProjectUser) (interface{}, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	c.store.projectUsers[user.ID] = *user
+	return user.ID, nil
+}
+
+// ReadAllProjectUser read all the memberships
+func (c *MemoryProjectModel) ReadAllProjectUser(ctx context.Context, f ProjectUserFilter) ([]ProjectUser, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	return c.store.projectUsersOf(f), nil
+}
+
+// ReadAllProjectMembers read all the memberships joined with their user account
+func (c *MemoryProjectModel) ReadAllProjectMembers(ctx context.Context, f ProjectUserFilter) ([]ProjectMember, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	return c.store.projectMembers(f), nil
+}
+
+// ReadAllUserProjects read all the memberships joined with their project
+func (c *MemoryProjectModel) ReadAllUserProjects(ctx context.Context, f ProjectUserFilter) ([]UserProject, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	var projects []UserProject
+	for _, pu := range c.store.projectUsersOf(f) {
+		if project, ok := c.store.projects[pu.ProjectID]; ok {
+			projects = append(projects, UserProject{ProjectUser: pu, Project: project})
+		}
+	}
+	return projects, nil
+}
+
+// ReadOneProjectUser read a single membership
+func (c *MemoryProjectModel) ReadOneProjectUser(ctx context.Context, f ProjectUserFilter) (ProjectUser, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	users := c.store.projectUsersOf(f)
+	if len(users) == 0 {
+		return ProjectUser{}, notFound("project member")
+	}
+	return users[0], nil
+}
+
+// UpdateOneProjectUser update the first membership matching the filter
+func (c *MemoryProjectModel) UpdateOneProjectUser(ctx context.Context, f ProjectUserFilter, update ProjectUserUpdate) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	users := c.store.projectUsersOf(f)
+	if len(users) == 0 {
+		return 0, nil
+	}
+	pu := users[0]
+	if update.IsActive != nil {
+		pu.IsActive = *update.IsActive
+	}
+	pu.UpdatedAt = time.Now()
+	c.store.projectUsers[pu.ID] = pu
+	return 1, nil
+}
+
+// projectUsersOf the memberships matching the filter in id order, the lock is held by the caller
+func (s *MemoryStore) projectUsersOf(f ProjectUserFilter) []ProjectUser {
+	var users []ProjectUser
+	for _, pu := range s.projectUsers {
+		if f.matches(pu) {
+			users = append(users, pu)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID.Hex() < users[j].ID.Hex() })
+	return users
+}
+
+// projectMembers the memberships matching the filter joined with their user account, the lock is held by the caller
+func (s *MemoryStore) projectMembers(f ProjectUserFilter) []ProjectMember {
+	var members []ProjectMember
+	for _, pu := range s.projectUsersOf(f) {
+		if user, ok := s.users[pu.UserID]; ok {
+			members = append(members, ProjectMember{ProjectUser: pu, User: user})
+		}
+	}
+	return members
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recurringValue the value of a list field of the recurring expense
+func recurringValue(r RecurringExpense, field string) interface{} {
+	switch field {
+	case "id":
+		return r.ID.Hex()
+	case "created_at":
+		return r.CreatedAt
+	case "updated_at":
+		return r.UpdatedAt
+	case "title":
+		return r.Title
+	case "start":
+		return r.Start
+	case "status":
+		return string(r.Status)
+	}
+	return nil
+}
+
+// cloneRecurring copy the slices of the recurring expense, so the store never shares them with the callers
+func cloneRecurring(r RecurringExpense) RecurringExpense {
+	r.Tags = append([]string{}, r.Tags...)
+	r.Skipped = append([]time.Time{}, r.Skipped...)
+	if r.ScheduledUntil != nil {
+		until := *r.ScheduledUntil
+		r.ScheduledUntil = &until
+	}
+	return r
+}
+
+// MemoryRecurringExpenseModel RecurringExpenseModeler of the memory store
+type MemoryRecurringExpenseModel struct {
+	store *MemoryStore
+}
+
+// Insert add the recurring expense to the store
+func (r *MemoryRecurringExpenseModel) Insert(ctx context.Context, recurring *RecurringExpense) (interface{}, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.recurringExpenses[recurring.ID]; ok {
+		return nil, ErrDuplicateKey
+	}
+	r.store.recurringExpenses[recurring.ID] = cloneRecurring(*recurring)
+	return recurring.ID, nil
+}
+
+// ReadAll read a page of the recurring expenses
+func (r *MemoryRecurringExpenseModel) ReadAll(ctx context.Context, f RecurringFilter, opts ListOptions) ([]RecurringExpense, Page, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var matched []RecurringExpense
+	for _, series := range r.store.recurringExpenses {
+		if !f.UserID.IsZero() && series.InsertedBy.ID != f.UserID {
+			continue
+		}
+		if len(f.Statuses) > 0 && !hasRecurringStatus(f.Statuses, series.Status) {
+			continue
+		}
+		matched = append(matched, series)
+	}
+	order, page, err := memoryPage(len(matched), func(i int, field string) interface{} {
+		return recurringValue(matched[i], field)
+	}, opts)
+	recurring := make([]RecurringExpense, 0, len(order))
+	for _, i := range order {
+		recurring = append(recurring, cloneRecurring(matched[i]))
+	}
+	return recurring, page, err
+}
+
+// hasRecurringStatus check the status is one of the statuses
+func hasRecurringStatus(statuses []RecurringStatus, status RecurringStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadOne read a single recurring expense
+func (r *MemoryRecurringExpenseModel) ReadOne(ctx context.Context, id primitive.ObjectID) (RecurringExpense, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	series, ok := r.store.recurringExpenses[id]
+	if !ok {
+		return RecurringExpense{}, notFound("recurring expense")
+	}
+	return cloneRecurring(series), nil
+}
+
+// UpdateOne change the status, the schedule or the skipped occurrences of one recurring expense
+func (r *MemoryRecurringExpenseModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update RecurringUpdate) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	series, ok := r.store.recurringExpenses[id]
+	if !ok {
+		return 0, nil
+	}
+	series.UpdatedAt = time.Now()
+	if update.Status != nil {
+		series.Status = *update.Status
+	}
+	if update.ScheduledUntil != nil {
+		until := *update.ScheduledUntil
+		series.ScheduledUntil = &until
+	}
+	if update.Skipped != nil {
+		series.Skipped = append([]time.Time{}, update.Skipped...)
+	}
+	r.store.recurringExpenses[id] = series
+	return 1, nil
+}
+
+// ReassignCategory move the recurring expenses of the category to another one
+func (r *MemoryRecurringExpenseModel) ReassignCategory(ctx context.Context, from primitive.ObjectID, to CategorySnapshot) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var count int64
+	for id, series := range r.store.recurringExpenses {
+		if series.Category.ID == from {
+			series.Category = to
+			series.UpdatedAt = time.Now()
+			r.store.recurringExpenses[id] = series
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountCategory count the recurring expenses of the category which are not ended
+func (r *MemoryRecurringExpenseModel) CountCategory(ctx context.Context, categoryID primitive.ObjectID) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	var count int64
+	for _, series := range r.store.recurringExpenses {
+		if series.Category.ID == categoryID && series.Status != RecurringEnded {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"context"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemorySettlementModel SettlementModeler of the memory store
+type MemorySettlementModel struct {
+	store *MemoryStore
+}
+
+// Insert add the settlement to the store
+func (s *MemorySettlementModel) Insert(ctx context.Context, settlement *Settlement) (interface{}, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if _, ok := s.store.settlements[settlement.ID]; ok {
+		return nil, ErrDuplicateKey
+	}
+	s.store.settlements[settlement.ID] = *settlement
+	return settlement.ID, nil
+}
+
+// ReadAll read every settlement of the project, oldest first
+func (s *MemorySettlementModel) ReadAll(ctx context.Context, projectID primitive.ObjectID) ([]Settlement, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	settlements := []Settlement{}
+	for _, settlement := range s.store.settlements {
+		if settlement.ProjectID == projectID {
+			settlements = append(settlements, settlement)
+		}
+	}
+	sort.Slice(settlements, func(i, j int) bool {
+		if !settlements[i].Date.Equal(settlements[j].Date) {
+			return settlements[i].Date.Before(settlements[j].Date)
+		}
+		return settlements[i].ID.Hex() < settlements[j].ID.Hex()
+	})
+	return settlements, nil
+}
+
+// ReadOne read a single settlement
+func (s *MemorySettlementModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Settlement, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	settlement, ok := s.store.settlements[id]
+	if !ok {
+		return Settlement{}, notFound("settlement")
+	}
+	return settlement, nil
+}
+
+// RemoveOne remove one settlement from the store
+func (s *MemorySettlementModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if _, ok := s.store.settlements[id]; !ok {
+		return 0, nil
+	}
+	delete(s.store.settlements, id)
+	return 1, nil
+}
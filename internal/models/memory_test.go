@@ -0,0 +1,152 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newMemoryExpense(title string, date time.Time, amount int64, tags ...string) Expense {
+	return Expense{
+		ID:     primitive.NewObjectID(),
+		Date:   date,
+		Title:  title,
+		Tags:   tags,
+		Total:  NewMoney(amount, "EUR"),
+		Status: StatusDraft,
+	}
+}
+
+func TestMemoryExpensePages(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, title := range []string{"a", "b", "c", "d", "e"} {
+		_, err := m.Expenses.Insert(ctx, newMemoryExpense(title, day.AddDate(0, 0, i), int64(100*i)))
+		assert.NoError(t, err)
+	}
+
+	var titles []string
+	opts := ListOptions{Limit: 2, Count: true}
+	for {
+		expenses, page, err := m.Expenses.ReadAll(ctx, ExpenseFilter{}, opts)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), *page.Total)
+		for _, e := range expenses {
+			titles = append(titles, e.Title)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	// latest first by default
+	assert.Equal(t, []string{"e", "d", "c", "b", "a"}, titles)
+
+	_, _, err := m.Expenses.ReadAll(ctx, ExpenseFilter{}, ListOptions{Cursor: "bogus"})
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestMemoryExpenseFilter(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.Expenses.Insert(ctx, newMemoryExpense("Hotel night", day, 12000, "travel", "hotel"))
+	m.Expenses.Insert(ctx, newMemoryExpense("Taxi", day.AddDate(0, 0, 1), 2500, "travel"))
+	m.Expenses.Insert(ctx, newMemoryExpense("Lunch", day.AddDate(0, 1, 0), 1500))
+
+	tests := []struct {
+		name   string
+		filter ExpenseFilter
+		titles []string
+	}{
+		{"tags", ExpenseFilter{Tags: []string{"Travel"}}, []string{"Taxi", "Hotel night"}},
+		{"all tags", ExpenseFilter{Tags: []string{"travel", "hotel"}}, []string{"Hotel night"}},
+		{"period", ExpenseFilter{Start: "2021-01-02", End: "2021-02-01"}, []string{"Taxi"}},
+		{"amount range", ExpenseFilter{MinTotal: "20", MaxTotal: "100", Currency: "EUR"}, []string{"Taxi"}},
+		{"text", ExpenseFilter{Text: "NIGHT"}, []string{"Hotel night"}},
+		{"other currency", ExpenseFilter{Currency: "USD"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expenses, _, err := m.Expenses.ReadAll(ctx, tt.filter, ListOptions{})
+			assert.NoError(t, err)
+			titles := []string{}
+			for _, e := range expenses {
+				titles = append(titles, e.Title)
+			}
+			assert.Equal(t, tt.titles, titles)
+		})
+	}
+}
+
+func TestMemoryExpenseTransition(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	expense := newMemoryExpense("Taxi", time.Now(), 2500)
+	m.Expenses.Insert(ctx, expense)
+
+	entry := StatusTransition{Action: ActionSubmit, From: StatusDraft, To: StatusSubmitted, At: time.Now()}
+	count, err := m.Expenses.Transition(ctx, expense.ID, entry)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// the expense is no longer a draft
+	count, err = m.Expenses.Transition(ctx, expense.ID, entry)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	stored, _ := m.Expenses.ReadOne(ctx, expense.ID)
+	assert.Equal(t, StatusSubmitted, stored.Status)
+	assert.Len(t, stored.History, 1)
+}
+
+func TestMemoryFindRate(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.ExchangeRates.Upsert(ctx, []ExchangeRate{{From: "EUR", To: "USD", Date: day, Rate: 1.25}})
+	m.ExchangeRates.Upsert(ctx, []ExchangeRate{{From: "EUR", To: "USD", Date: day, Rate: 2}})
+
+	rate, err := m.ExchangeRates.FindRate(ctx, "USD", "EUR", day.AddDate(0, 0, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, rate)
+
+	_, err = m.ExchangeRates.FindRate(ctx, "EUR", "USD", day.AddDate(0, 0, -1))
+	assert.Equal(t, ErrRateNotFound, err)
+}
+
+func TestMemoryFindRates(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.ExchangeRates.Upsert(ctx, []ExchangeRate{
+		{From: "USD", To: "EUR", Date: day, Rate: 0.8},
+		{From: "USD", To: "EUR", Date: day.AddDate(0, 0, 10), Rate: 0.9},
+		{From: "USD", To: "EUR", Date: day.AddDate(0, 0, 30), Rate: 1},
+		{From: "EUR", To: "BDT", Date: day, Rate: 100},
+	})
+
+	history, err := m.ExchangeRates.FindRates(ctx, "USD", "EUR", day.AddDate(0, 0, 5), day.AddDate(0, 0, 20))
+	assert.NoError(t, err)
+	assert.Len(t, history.Rates, 2)
+	for _, on := range []time.Time{day.AddDate(0, 0, 5), day.AddDate(0, 0, 10), day.AddDate(0, 0, 20)} {
+		want, err := m.ExchangeRates.FindRate(ctx, "USD", "EUR", on)
+		assert.NoError(t, err)
+		rate, err := history.Rate(on)
+		assert.NoError(t, err)
+		assert.Equal(t, want, rate, on.Format(DateLayout))
+	}
+
+	// the inverse pair is used when only that one is known
+	history, err = m.ExchangeRates.FindRates(ctx, "BDT", "EUR", day, day)
+	assert.NoError(t, err)
+	rate, err := history.Rate(day)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.01, rate)
+	_, err = history.Rate(day.AddDate(0, 0, -1))
+	assert.Equal(t, ErrRateNotFound, err)
+}
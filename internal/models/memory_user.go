@@ -0,0 +1,126 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// userValue value of a list field of the user
+func userValue(u User, field string) interface{} {
+	switch field {
+	case "id":
+		return u.ID.Hex()
+	case "created_at":
+		return u.CreatedAt
+	case "updated_at":
+		return u.UpdatedAt
+	case "email":
+		return u.Email
+	case "phone_number":
+		return u.PhoneNumber
+	case "name":
+		return u.Name
+	case "role":
+		return string(u.Role)
+	case "is_active":
+		return u.IsActive
+	}
+	return nil
+}
+
+// MemoryUserModel UserModel of the memory store
+type MemoryUserModel struct {
+	store *MemoryStore
+}
+
+// InsertNewUser add the user to the store
+func (c *MemoryUserModel) InsertNewUser(ctx context.Context, user *User) (interface{}, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	for _, stored := range c.store.users {
+		if stored.Email == user.Email {
+			return nil, ErrDuplicateKey
+		}
+	}
+	c.store.users[user.ID] = *user
+	return user.ID, nil
+}
+
+// ReadOneUser read a single user
+func (c *MemoryUserModel) ReadOneUser(ctx context.Context, q UserQuery) (User, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	if q.ID.IsZero() && q.Email == "" {
+		// an empty query must not match the first user
+		return User{}, notFound("user")
+	}
+	for _, user := range c.store.users {
+		if (q.ID.IsZero() || user.ID == q.ID) && (q.Email == "" || user.Email == q.Email) {
+			return user, nil
+		}
+	}
+	return User{}, notFound("user")
+}
+
+// ReadAllUsers read a page of the users
+func (c *MemoryUserModel) ReadAllUsers(ctx context.Context, f UserFilter, opts ListOptions) ([]*User, Page, error) {
+	c.store.mu.RLock()
+	defer c.store.mu.RUnlock()
+	var matched []User
+	for _, user := range c.store.users {
+		if f.IsActive != nil && user.IsActive != *f.IsActive {
+			continue
+		}
+		if f.Role != "" && user.Role != f.Role {
+			continue
+		}
+		matched = append(matched, user)
+	}
+	order, page, err := memoryPage(len(matched), func(i int, field string) interface{} {
+		return userValue(matched[i], field)
+	}, opts)
+	users := make([]*User, 0, len(order))
+	for _, i := range order {
+		users = append(users, &matched[i])
+	}
+	return users, page, err
+}
+
+// RemoveOneUser remove one user from the store
+func (c *MemoryUserModel) RemoveOneUser(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	if _, ok := c.store.users[id]; !ok {
+		return 0, nil
+	}
+	delete(c.store.users, id)
+	return 1, nil
+}
+
+// UpdateOneUser update one user of the store
+func (c *MemoryUserModel) UpdateOneUser(ctx context.Context, id primitive.ObjectID, update UserUpdate) (int64, error) {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+	user, ok := c.store.users[id]
+	if !ok {
+		return 0, nil
+	}
+	if update.Name != nil {
+		user.Name = *update.Name
+	}
+	if update.IsActive != nil {
+		user.IsActive = *update.IsActive
+	}
+	user.UpdatedAt = time.Now()
+	if update.PasswordHash != nil {
+		user.PasswordHash = *update.PasswordHash
+		user.PasswordChangedAt = user.UpdatedAt
+	}
+	if update.Locale != nil {
+		user.Locale = *update.Locale
+	}
+	c.store.users[id] = user
+	return 1, nil
+}
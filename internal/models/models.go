@@ -0,0 +1,53 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+)
+
+// Models the models of one storage backend
+type Models struct {
+	Users          UserModel
+	Categories     CategoryModeler
+	Expenses       ExpenseModeler
+	Projects       ProjectModeler
+	PasswordResets PasswordResetModeler
+	ExchangeRates  ExchangeRateModeler
+	Budgets        BudgetModeler
+	Notifications  NotificationModeler
+	Recurring      RecurringExpenseModeler
+	Settlements    SettlementModeler
+}
+
+// NewMongoModels the models stored in MongoDB
+func NewMongoModels(client db.MongoDBClient) Models {
+	return Models{
+		Users:          NewUserModelImpl(client),
+		Categories:     NewCategoryModel(client),
+		Expenses:       NewExpenseModel(client),
+		Projects:       NewProjectModel(client),
+		PasswordResets: NewPasswordResetModel(client),
+		ExchangeRates:  NewExchangeRateModel(client),
+		Budgets:        NewBudgetModel(client),
+		Notifications:  NewNotificationModel(client),
+		Recurring:      NewRecurringExpenseModel(client),
+		Settlements:    NewSettlementModel(client),
+	}
+}
+
+// NewPostgresModels the models stored in PostgreSQL, the schema is created by MigratePostgres
+func NewPostgresModels(db *sql.DB) Models {
+	return Models{
+		Users:          NewPostgresUserModel(db),
+		Categories:     NewPostgresCategoryModel(db),
+		Expenses:       NewPostgresExpenseModel(db),
+		Projects:       NewPostgresProjectModel(db),
+		PasswordResets: NewPostgresPasswordResetModel(db),
+		ExchangeRates:  NewPostgresExchangeRateModel(db),
+		Budgets:        NewPostgresBudgetModel(db),
+		Notifications:  NewPostgresNotificationModel(db),
+		Recurring:      NewPostgresRecurringExpenseModel(db),
+		Settlements:    NewPostgresSettlementModel(db),
+	}
+}
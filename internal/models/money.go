@@ -0,0 +1,178 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+)
+
+// money errors
+var (
+	ErrNegativeAmount   = errs.New(errs.Validation, "amount can not be negative")
+	ErrTooPrecise       = errs.New(errs.Validation, "amount has more decimals than the currency allows")
+	ErrInvalidAmount    = errs.New(errs.Validation, "invalid amount")
+	ErrCurrencyMismatch = errs.New(errs.Validation, "currencies do not match")
+)
+
+// Money exact amount stored as integer minor units of its currency, e.g. cents for EUR.
+// In JSON the amount is written as a decimal string to not lose precision.
+type Money struct {
+	Amount   int64  `json:"-" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+// NewMoney build the money from the minor units amount
+func NewMoney(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: currency}
+}
+
+// MinorUnits number of decimals of the currency
+func MinorUnits(currency string) int {
+	if units, ok := currencies[currency]; ok {
+		return units
+	}
+	return 2
+}
+
+// ParseMoney parse a decimal string like "12.34" into money of the currency.
+// Negative amounts and amounts with more decimals than the currency allows are rejected.
+func ParseMoney(value, currency string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Money{}, ErrInvalidAmount
+	}
+	if strings.HasPrefix(value, "-") {
+		return Money{}, ErrNegativeAmount
+	}
+	value = strings.TrimPrefix(value, "+")
+
+	parts := strings.Split(value, ".")
+	if len(parts) > 2 || (parts[0] == "" && (len(parts) == 1 || parts[1] == "")) {
+		return Money{}, ErrInvalidAmount
+	}
+	whole, fraction := parts[0], ""
+	if len(parts) == 2 {
+		fraction = strings.TrimRight(parts[1], "0")
+	}
+
+	scale := MinorUnits(currency)
+	if len(fraction) > scale {
+		return Money{}, ErrTooPrecise
+	}
+	digits := whole + fraction + strings.Repeat("0", scale-len(fraction))
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return Money{}, ErrInvalidAmount
+		}
+	}
+	minor, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// FromFloat convert a legacy float amount, rounded to the minor units of the currency
+func FromFloat(amount float64, currency string) Money {
+	r := new(big.Rat).SetFloat64(amount)
+	if r == nil {
+		return Money{Currency: currency}
+	}
+	return Money{Amount: roundRat(r, MinorUnits(currency)), Currency: currency}
+}
+
+// String the decimal representation of the amount, e.g. "12.34"
+func (m Money) String() string {
+	scale := MinorUnits(m.Currency)
+	sign, minor := "", m.Amount
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// IsZero check if the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Add sum two amounts of the same currency
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Convert the amount into another currency with the exchange rate,
+// rounded half away from zero to the minor units of that currency
+func (m Money) Convert(rate float64, currency string) Money {
+	if m.Currency == currency {
+		return m
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	if !ok {
+		return Money{Currency: currency}
+	}
+	// amount / 10^fromScale * rate
+	value := new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10(MinorUnits(m.Currency)))
+	value.Mul(value, r)
+	return Money{Amount: roundRat(value, MinorUnits(currency)), Currency: currency}
+}
+
+// MarshalJSON write the money as {"amount": "12.34", "currency": "EUR"}
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.String(), m.Currency})
+}
+
+// UnmarshalJSON read the money from {"amount": "12.34", "currency": "EUR"},
+// the amount can be a string or a number literal
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Amount   json.Number `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	parsed, err := ParseMoney(raw.Amount.String(), raw.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// roundRat round the value half away from zero to the given decimals, as minor units
+func roundRat(value *big.Rat, scale int) int64 {
+	scaled := new(big.Rat).Mul(value, new(big.Rat).SetInt(pow10(scale)))
+	num, den := scaled.Num(), scaled.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	// |rem| * 2 >= den means the fraction is at least .5
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		if num.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		} else {
+			quo.Add(quo, big.NewInt(1))
+		}
+	}
+	return quo.Int64()
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency string
+		minor    int64
+		err      error
+	}{
+		{"12.34", "EUR", 1234, nil},
+		{"12.3", "EUR", 1230, nil},
+		{"12", "EUR", 1200, nil},
+		{".5", "USD", 50, nil},
+		{"12.340", "EUR", 1234, nil},
+		{"1500", "JPY", 1500, nil},
+		{"1.234", "KWD", 1234, nil},
+		{"12.345", "EUR", 0, ErrTooPrecise},
+		{"12.5", "JPY", 0, ErrTooPrecise},
+		{"-1.00", "EUR", 0, ErrNegativeAmount},
+		{"1e3", "EUR", 0, ErrInvalidAmount},
+		{"", "EUR", 0, ErrInvalidAmount},
+		{".", "EUR", 0, ErrInvalidAmount},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.value, tt.currency)
+		assert.Equal(t, tt.err, err, tt.value)
+		assert.Equal(t, tt.minor, m.Amount, tt.value)
+	}
+}
+
+func TestMoneySumIsExact(t *testing.T) {
+	// 0.1 summed a thousand times drifts with float64
+	total := NewMoney(0, "EUR")
+	cent, _ := ParseMoney("0.10", "EUR")
+	for i := 0; i < 1000; i++ {
+		total, _ = total.Add(cent)
+	}
+	assert.Equal(t, "100.00", total.String())
+
+	_, err := total.Add(NewMoney(1, "USD"))
+	assert.Error(t, err)
+}
+
+func TestMoneyConvert(t *testing.T) {
+	assert.Equal(t, "8.23", NewMoney(1000, "USD").Convert(0.8225, "EUR").String())
+	assert.Equal(t, "1318", NewMoney(1000, "EUR").Convert(131.75, "JPY").String())
+	assert.Equal(t, "0.12", NewMoney(1250, "BDT").Convert(0.0098, "EUR").String())
+}
+
+func TestMoneyJSON(t *testing.T) {
+	b, err := json.Marshal(NewMoney(5, "EUR"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"0.05","currency":"EUR"}`, string(b))
+
+	var m Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":19.99,"currency":"USD"}`), &m))
+	assert.Equal(t, NewMoney(1999, "USD"), m)
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":"19.999","currency":"USD"}`), &m))
+}
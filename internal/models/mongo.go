@@ -0,0 +1,21 @@
+package models
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// findOne decode the first document matching the filter into v,
+// no matching document is the not found error of the resource
+func findOne(ctx context.Context, collection *mongo.Collection, filter interface{}, resource string, v interface{}) error {
+	err := collection.FindOne(ctx, filter).Decode(v)
+	if err == mongo.ErrNoDocuments {
+		return notFound(resource)
+	}
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+	}
+	return dbError(err)
+}
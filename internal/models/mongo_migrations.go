@@ -0,0 +1,272 @@
+package models
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoMigration versioned change of the mongo collections.
+// Up must be safe to run again, a failed run is retried on the next start.
+type MongoMigration struct {
+	Version int
+	Name    string
+	Up      func(client db.MongoDBClient) error
+}
+
+// MongoMigrations the migration history, applied in order.
+// Applied migrations must never be edited, add a new version instead.
+// Projects & expenses missing a currency get the default currency.
+func MongoMigrations(defaultCurrency string) []MongoMigration {
+	return []MongoMigration{
+		{1, "link project users to users", func(client db.MongoDBClient) error {
+			linked, unmatched, err := NewProjectModel(client).LinkProjectUsersToUsers()
+			log.Printf("projectUsers linked to users: %d, unmatched: %d\n", linked, unmatched)
+			return err
+		}},
+		{2, "approval workflow statuses", func(client db.MongoDBClient) error {
+			migrated, err := NewExpenseModel(client).MigrateLegacyStatuses()
+			log.Printf("expenses moved to the approval workflow: %d\n", migrated)
+			return err
+		}},
+		{3, "project base currency", func(client db.MongoDBClient) error {
+			projects, err := NewProjectModel(client).MigrateMissingBaseCurrency(defaultCurrency)
+			log.Printf("base currency %s set on projects: %d\n", defaultCurrency, projects)
+			return err
+		}},
+		{4, "expense money", func(client db.MongoDBClient) error {
+			expenses, err := NewExpenseModel(client).MigrateMoney(defaultCurrency)
+			log.Printf("expense totals converted to money: %d\n", expenses)
+			return err
+		}},
+		{5, "indexes", createMongoIndexes},
+		{6, "category tree", func(client db.MongoDBClient) error {
+			categories, err := NewCategoryModel(client).MigrateSlugs()
+			log.Printf("slugs set on categories: %d\n", categories)
+			if err != nil {
+				return err
+			}
+			names, err := client.Client.Database(client.DBName).Collection("categories").Indexes().CreateMany(context.TODO(), categoryIndexes)
+			log.Printf("indexes of categories: %v\n", names)
+			return err
+		}},
+		{7, "expense snapshots", func(client db.MongoDBClient) error {
+			expenses, err := NewExpenseModel(client).MigrateSnapshots()
+			log.Printf("expense snapshots of categories & users migrated: %d\n", expenses)
+			return err
+		}},
+		{8, "budgets", func(client db.MongoDBClient) error {
+			names, err := client.Client.Database(client.DBName).Collection("budgets").Indexes().CreateMany(context.TODO(), budgetIndexes)
+			log.Printf("indexes of budgets: %v\n", names)
+			return err
+		}},
+		{9, "notifications", func(client db.MongoDBClient) error {
+			names, err := client.Client.Database(client.DBName).Collection("notifications").Indexes().CreateMany(context.TODO(), notificationIndexes)
+			log.Printf("indexes of notifications: %v\n", names)
+			return err
+		}},
+		{10, "recurring expenses", func(client db.MongoDBClient) error {
+			names, err := client.Client.Database(client.DBName).Collection("recurringExpenses").Indexes().CreateMany(context.TODO(), recurringIndexes)
+			log.Printf("indexes of recurring expenses: %v\n", names)
+			return err
+		}},
+		{11, "settlements", func(client db.MongoDBClient) error {
+			names, err := client.Client.Database(client.DBName).Collection("settlements").Indexes().CreateMany(context.TODO(), settlementIndexes)
+			log.Printf("indexes of settlements: %v\n", names)
+			return err
+		}},
+	}
+}
+
+// categoryIndexes the indexes of the category tree, the slugs are unique
+var categoryIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "slug", Value: 1}}, Options: options.Index().SetName("slug_unique").SetUnique(true)},
+	{Keys: bson.D{{Key: "parent_id", Value: 1}}, Options: options.Index().SetName("parent_id")},
+}
+
+// mongoIndexes the indexes of each collection
+var mongoIndexes = map[string][]mongo.IndexModel{
+	"users": {
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetName("email_unique").SetUnique(true)},
+	},
+	"expenses": {
+		{Keys: bson.D{{Key: "date", Value: -1}}, Options: options.Index().SetName("date")},
+		{Keys: bson.D{{Key: "project_id", Value: 1}}, Options: options.Index().SetName("project_id")},
+	},
+	"projectUsers": {
+		{Keys: bson.D{{Key: "project_id", Value: 1}}, Options: options.Index().SetName("project_id")},
+	},
+}
+
+// createMongoIndexes create the indexes, existing indexes with the same definition are kept.
+// The unique email index fails while duplicated emails are stored, they must be fixed first.
+func createMongoIndexes(client db.MongoDBClient) error {
+	database := client.Client.Database(client.DBName)
+	for collection, indexes := range mongoIndexes {
+		names, err := database.Collection(collection).Indexes().CreateMany(context.TODO(), indexes)
+		if err != nil {
+			return err
+		}
+		log.Printf("indexes of %s: %v\n", collection, names)
+	}
+	return nil
+}
+
+// migrationRecord applied migration, stored in the schemaMigrations collection.
+// The runner applying it is the owner, it renews the heartbeat while the migration runs.
+type migrationRecord struct {
+	Version     int        `bson:"_id"`
+	Name        string     `bson:"name"`
+	Owner       string     `bson:"owner"`
+	StartedAt   time.Time  `bson:"started_at"`
+	HeartbeatAt time.Time  `bson:"heartbeat_at"`
+	AppliedAt   *time.Time `bson:"applied_at"`
+}
+
+// migrationHeartbeat the interval the claim of a running migration is renewed at,
+// migrationClaimTimeout the time without a heartbeat after which the claim is taken as left
+// by a runner that crashed, and migrationClaimPoll the interval of the checks of the waiting runners
+var (
+	migrationHeartbeat    = 10 * time.Second
+	migrationClaimTimeout = time.Minute
+	migrationClaimPoll    = 5 * time.Second
+)
+
+// MigrateMongo apply the pending migrations and return the applied versions.
+// A version is claimed before it runs, so concurrent runners wait for it, and released when it fails.
+func MigrateMongo(client db.MongoDBClient, migrations []MongoMigration) ([]int, error) {
+	collection := client.Client.Database(client.DBName).Collection("schemaMigrations")
+	owner := primitive.NewObjectID().Hex()
+	var applied []int
+	for _, m := range migrations {
+		claimed, err := claimMigration(collection, m, owner)
+		if err != nil {
+			return applied, err
+		}
+		if !claimed {
+			continue
+		}
+
+		stop := heartbeatMigration(collection, m, owner)
+		err = m.Up(client)
+		stop()
+		if err != nil {
+			log.Printf("Error on migration %d %s: %v\n", m.Version, m.Name, err)
+			filter := bson.D{{Key: "_id", Value: m.Version}, {Key: "owner", Value: owner}}
+			if _, derr := collection.DeleteOne(context.TODO(), filter); derr != nil {
+				log.Printf("Error on releasing migration %d: %v\n", m.Version, derr)
+			}
+			return applied, err
+		}
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "applied_at", Value: time.Now()}}}}
+		if _, err := collection.UpdateOne(context.TODO(), bson.D{{Key: "_id", Value: m.Version}}, update); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m.Version)
+	}
+	return applied, nil
+}
+
+// heartbeatMigration renew the claim of the owner every migrationHeartbeat until stop is called
+func heartbeatMigration(collection *mongo.Collection, m MongoMigration, owner string) (stop func()) {
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(migrationHeartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			filter := bson.D{{Key: "_id", Value: m.Version}, {Key: "owner", Value: owner}}
+			update := bson.D{{Key: "$set", Value: bson.D{{Key: "heartbeat_at", Value: time.Now()}}}}
+			result, err := collection.UpdateOne(context.TODO(), filter, update)
+			if err != nil {
+				log.Printf("Error on renewing the claim of migration %d: %v\n", m.Version, err)
+			} else if result.MatchedCount == 0 {
+				log.Printf("migration %d %s was taken over by another runner\n", m.Version, m.Name)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// claimMigration claim the version of the migration for the owner, false when it is already applied.
+// The claim of another runner is waited for until it is applied or released,
+// and taken over when its heartbeat stopped for migrationClaimTimeout.
+func claimMigration(collection *mongo.Collection, m MongoMigration, owner string) (bool, error) {
+	for {
+		now := time.Now()
+		record := migrationRecord{Version: m.Version, Name: m.Name, Owner: owner, StartedAt: now, HeartbeatAt: now}
+		_, err := collection.InsertOne(context.TODO(), record)
+		if err == nil {
+			return true, nil
+		}
+		if duplicateKey(err) != ErrDuplicateKey {
+			return false, err
+		}
+
+		var claim migrationRecord
+		err = collection.FindOne(context.TODO(), bson.D{{Key: "_id", Value: m.Version}}).Decode(&claim)
+		if err == mongo.ErrNoDocuments {
+			// released by a failed run in the meantime
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if claim.AppliedAt != nil {
+			return false, nil
+		}
+		// the claims of the runners before the heartbeat have none
+		heartbeat := claim.HeartbeatAt
+		if heartbeat.IsZero() {
+			heartbeat = claim.StartedAt
+		}
+		if time.Since(heartbeat) < migrationClaimTimeout {
+			log.Printf("migration %d %s is applied by another runner since %v, waiting\n", m.Version, m.Name, claim.StartedAt)
+			time.Sleep(migrationClaimPoll)
+			continue
+		}
+
+		// a single runner takes over the stale claim, the others see it renewed
+		filter := bson.D{
+			{Key: "_id", Value: m.Version},
+			{Key: "applied_at", Value: nil},
+			{Key: "started_at", Value: claim.StartedAt},
+			{Key: "heartbeat_at", Value: nullable(claim.HeartbeatAt)},
+		}
+		update := bson.D{{Key: "$set", Value: bson.D{
+			{Key: "owner", Value: owner},
+			{Key: "started_at", Value: now},
+			{Key: "heartbeat_at", Value: now},
+		}}}
+		result, err := collection.UpdateOne(context.TODO(), filter, update)
+		if err != nil {
+			return false, err
+		}
+		if result.ModifiedCount == 1 {
+			log.Printf("migration %d %s claimed at %v lost its runner at %v, running it again\n", m.Version, m.Name, claim.StartedAt, heartbeat)
+			return true, nil
+		}
+	}
+}
+
+// nullable the time, nil when zero to match a missing field
+func nullable(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NotificationKind event a user is notified of
+type NotificationKind string
+
+// the kinds of the notifications
+const (
+	NotifyBudgetThreshold NotificationKind = "budget_threshold" // the spending of a project crossed 50, 80 or 100% of a budget
+	NotifyExpensePending  NotificationKind = "expense_pending"  // a submitted expense waits too long for its approval
+)
+
+// Notification message of the in-app inbox of a user
+type Notification struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Kind      NotificationKind   `json:"kind" bson:"kind"`
+	Title     string             `json:"title" bson:"title"`
+	Body      string             `json:"body" bson:"body"`
+	ProjectID primitive.ObjectID `json:"project_id,omitempty" bson:"project_id,omitempty"`
+	ExpenseID primitive.ObjectID `json:"expense_id,omitempty" bson:"expense_id,omitempty"`
+	BudgetID  primitive.ObjectID `json:"budget_id,omitempty" bson:"budget_id,omitempty"`
+	ReadAt    *time.Time         `json:"read_at" bson:"read_at"` // nil while unread
+}
+
+// NotificationFilter filter of the inbox of a user
+type NotificationFilter struct {
+	UserID primitive.ObjectID
+	Unread bool // only the notifications not read yet
+}
+
+// NotificationPreferences channels a user is notified on, and the kinds the user muted.
+// The users without stored preferences get DefaultNotificationPreferences.
+type NotificationPreferences struct {
+	UserID     primitive.ObjectID `json:"user_id" bson:"_id"`
+	UpdatedAt  time.Time          `json:"updated_at" bson:"updated_at"`
+	Inbox      bool               `json:"inbox" bson:"inbox"`
+	Email      bool               `json:"email" bson:"email"`
+	Webhook    bool               `json:"webhook" bson:"webhook"`
+	WebhookURL string             `json:"webhook_url" bson:"webhook_url"`
+	Muted      []NotificationKind `json:"muted" bson:"muted"`
+}
+
+// NotificationPreferencesInput model for the preferences update endpoint, the preferences are replaced
+type NotificationPreferencesInput struct {
+	Inbox      bool               `json:"inbox"`
+	Email      bool               `json:"email"`
+	Webhook    bool               `json:"webhook"`
+	WebhookURL string             `json:"webhook_url" validate:"required_with=Webhook,omitempty,url"`
+	Muted      []NotificationKind `json:"muted" validate:"dive,oneof=budget_threshold expense_pending"`
+}
+
+// DefaultNotificationPreferences the preferences of a user who never changed them: the inbox & the email
+func DefaultNotificationPreferences(userID primitive.ObjectID) NotificationPreferences {
+	return NotificationPreferences{UserID: userID, Inbox: true, Email: true, Muted: []NotificationKind{}}
+}
+
+// IsMuted check the user muted the kind of notifications
+func (p NotificationPreferences) IsMuted(kind NotificationKind) bool {
+	for _, muted := range p.Muted {
+		if muted == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationListFields list fields of the notifications
+var NotificationListFields = ListFields{Sort: map[string]string{
+	"id":         "_id",
+	"created_at": "created_at",
+	"kind":       "kind",
+}}
+
+// NotificationModeler godoc
+type NotificationModeler interface {
+	Insert(ctx context.Context, notification *Notification) (interface{}, error)
+	ReadAll(ctx context.Context, f NotificationFilter, opts ListOptions) ([]Notification, Page, error)
+	// MarkRead mark the notification of the user read or unread, 0 when the user has no such notification
+	MarkRead(ctx context.Context, userID, id primitive.ObjectID, read bool) (int64, error)
+	MarkAllRead(ctx context.Context, userID primitive.ObjectID) (int64, error)
+	// Claim record the alert key, false when it was already claimed so the alert is sent once
+	Claim(ctx context.Context, key string) (bool, error)
+	ReadPreferences(ctx context.Context, userID primitive.ObjectID) (NotificationPreferences, error)
+	UpdatePreferences(ctx context.Context, prefs NotificationPreferences) error
+}
+
+// NotificationModel godoc
+type NotificationModel struct {
+	db db.MongoDBClient
+}
+
+// NewNotificationModel godoc
+func NewNotificationModel(db db.MongoDBClient) *NotificationModel {
+	return &NotificationModel{db}
+}
+
+// notificationIndexes the inbox of a user, latest first
+var notificationIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetName("user_id_created_at")},
+}
+
+// alertRecord claimed alert key, stored in the notificationAlerts collection
+type alertRecord struct {
+	Key       string    `bson:"_id"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+// Insert insert a record at notifications collection
+func (n *NotificationModel) Insert(ctx context.Context, notification *Notification) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := n.db.Client.Database(n.db.DBName).Collection("notifications")
+	insertResult, err := collection.InsertOne(ctx, notification)
+	if err != nil {
+		log.Printf("Error on inserting new notification: %v\n", err)
+		return nil, dbError(err)
+	}
+	return insertResult.InsertedID, nil
+}
+
+// ReadAll read a page of the inbox of the user
+func (n *NotificationModel) ReadAll(ctx context.Context, f NotificationFilter, opts ListOptions) ([]Notification, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	notifications := []Notification{}
+	collection := n.db.Client.Database(n.db.DBName).Collection("notifications")
+	filter := bson.D{{Key: "user_id", Value: f.UserID}}
+	if f.Unread {
+		filter = append(filter, bson.E{Key: "read_at", Value: nil})
+	}
+	page, err := findPage(ctx, collection, filter, opts, NotificationListFields, func(cur *mongo.Cursor) error {
+		var notification Notification
+		if err := cur.Decode(&notification); err != nil {
+			return err
+		}
+		notifications = append(notifications, notification)
+		return nil
+	})
+	return notifications, page, dbError(err)
+}
+
+// MarkRead mark one notification of the user read or unread
+func (n *NotificationModel) MarkRead(ctx context.Context, userID, id primitive.ObjectID, read bool) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := n.db.Client.Database(n.db.DBName).Collection("notifications")
+	var readAt *time.Time
+	if read {
+		now := time.Now()
+		readAt = &now
+	}
+	update := bson.M{"$set": bson.M{"read_at": readAt}}
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id, "user_id": userID}, update)
+	if err != nil {
+		log.Printf("Error on updating one notification: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.MatchedCount, nil
+}
+
+// MarkAllRead mark every unread notification of the user read
+func (n *NotificationModel) MarkAllRead(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := n.db.Client.Database(n.db.DBName).Collection("notifications")
+	update := bson.M{"$set": bson.M{"read_at": time.Now()}}
+	updatedResult, err := collection.UpdateMany(ctx, bson.M{"user_id": userID, "read_at": nil}, update)
+	if err != nil {
+		log.Printf("Error on updating the notifications: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// Claim insert the key in the notificationAlerts collection, the duplicated key is already claimed
+func (n *NotificationModel) Claim(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := n.db.Client.Database(n.db.DBName).Collection("notificationAlerts")
+	_, err := collection.InsertOne(ctx, alertRecord{Key: key, CreatedAt: time.Now()})
+	if duplicateKey(err) == ErrDuplicateKey {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("Error on claiming the alert %s: %v\n", key, err)
+		return false, dbError(err)
+	}
+	return true, nil
+}
+
+// ReadPreferences read the preferences of the user, the defaults when the user never changed them
+func (n *NotificationModel) ReadPreferences(ctx context.Context, userID primitive.ObjectID) (NotificationPreferences, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	prefs := DefaultNotificationPreferences(userID)
+	collection := n.db.Client.Database(n.db.DBName).Collection("notificationPreferences")
+	err := collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&prefs)
+	if err == mongo.ErrNoDocuments {
+		return prefs, nil
+	}
+	return prefs, dbError(err)
+}
+
+// UpdatePreferences replace the preferences of the user
+func (n *NotificationModel) UpdatePreferences(ctx context.Context, prefs NotificationPreferences) error {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := n.db.Client.Database(n.db.DBName).Collection("notificationPreferences")
+	_, err := collection.ReplaceOne(ctx, bson.M{"_id": prefs.UserID}, prefs, options.Replace().SetUpsert(true))
+	if err != nil {
+		log.Printf("Error on updating the notification preferences: %v\n", err)
+	}
+	return dbError(err)
+}
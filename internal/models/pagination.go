@@ -0,0 +1,389 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultPageLimit page size used when the limit is not requested
+	DefaultPageLimit int64 = 50
+	// MaxPageLimit biggest page size a client can request
+	MaxPageLimit int64 = 500
+)
+
+// ErrInvalidCursor returned when the cursor was not issued for the query
+var ErrInvalidCursor = errs.New(errs.Validation, "invalid cursor")
+
+// SortField sort key of a list query
+type SortField struct {
+	Key  string
+	Desc bool
+}
+
+// ListOptions pagination, sorting and projection of a list query.
+// A zero Limit reads every document, it is meant for the internal callers.
+type ListOptions struct {
+	Limit  int64
+	Cursor string
+	Sort   []SortField
+	Fields []string
+	Count  bool
+}
+
+// Page pagination metadata of a list query
+type Page struct {
+	Limit      int64  `json:"limit"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
+// ListFields fields of a collection a list can be sorted on or projected to,
+// the json name of the field mapped to its bson path
+type ListFields struct {
+	Sort    map[string]string // scalar fields, sorted on and projected to
+	Project map[string]string // array and document fields, only projected to
+}
+
+// path bson path of the json field
+func (f ListFields) path(field string) (string, bool) {
+	if p, ok := f.Sort[field]; ok {
+		return p, true
+	}
+	p, ok := f.Project[field]
+	return p, ok
+}
+
+// ExpenseListFields list fields of the expenses
+var ExpenseListFields = ListFields{
+	Sort: map[string]string{
+		"id":          "_id",
+		"created_at":  "created_at",
+		"updated_at":  "updated_at",
+		"date":        "date",
+		"title":       "title",
+		"description": "description",
+		"location":    "location",
+		"total":       "total.amount",
+		"status":      "status",
+		"project_id":  "project_id",
+		"category":    "category.name",
+		"user":        "user.name",
+		"paid_by":     "paid_by",
+	},
+	Project: map[string]string{
+		"tags":        "tags",
+		"splits":      "splits",
+		"history":     "history",
+		"attachments": "attachments",
+	},
+}
+
+// UserListFields list fields of the users
+var UserListFields = ListFields{Sort: map[string]string{
+	"id":           "_id",
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+	"email":        "email",
+	"phone_number": "phone_number",
+	"name":         "name",
+	"role":         "role",
+	"is_active":    "is_active",
+}}
+
+// CategoryListFields list fields of the categories
+var CategoryListFields = ListFields{Sort: map[string]string{
+	"id":         "_id",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"name":       "name",
+	"slug":       "slug",
+}}
+
+// ProjectListFields list fields of the projects
+var ProjectListFields = ListFields{Sort: map[string]string{
+	"id":            "_id",
+	"created_at":    "created_at",
+	"updated_at":    "updated_at",
+	"title":         "title",
+	"description":   "description",
+	"base_currency": "base_currency",
+	"is_active":     "is_active",
+}}
+
+// ExchangeRateListFields list fields of the exchange rates
+var ExchangeRateListFields = ListFields{Sort: map[string]string{
+	"id":         "_id",
+	"updated_at": "updated_at",
+	"from":       "from",
+	"to":         "to",
+	"date":       "date",
+	"rate":       "rate",
+}}
+
+// ParseListOptions parse the `limit`, `cursor`, `sort`, `fields` and `count` query params.
+// sort and fields take comma separated or repeated values, a `-` prefix sorts descending.
+func ParseListOptions(qs url.Values, fields ListFields, defaultSort []SortField) (ListOptions, error) {
+	opts := ListOptions{Limit: DefaultPageLimit, Cursor: qs.Get("cursor"), Sort: defaultSort}
+	if x := qs.Get("limit"); x != "" {
+		limit, err := strconv.ParseInt(x, 10, 64)
+		if err != nil || limit < 1 || limit > MaxPageLimit {
+			return opts, fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+		}
+		opts.Limit = limit
+	}
+	if keys := splitValues(qs["sort"]); len(keys) > 0 {
+		opts.Sort = nil
+		for _, key := range keys {
+			sf := SortField{Key: strings.TrimPrefix(key, "-"), Desc: strings.HasPrefix(key, "-")}
+			if _, ok := fields.Sort[sf.Key]; !ok {
+				if _, ok := fields.Project[sf.Key]; ok {
+					return opts, fmt.Errorf("field %s can't be sorted on", sf.Key)
+				}
+				return opts, fmt.Errorf("unknown sort field %s", sf.Key)
+			}
+			opts.Sort = append(opts.Sort, sf)
+		}
+	}
+	for _, field := range splitValues(qs["fields"]) {
+		if _, ok := fields.path(field); !ok {
+			return opts, fmt.Errorf("unknown field %s", field)
+		}
+		opts.Fields = append(opts.Fields, field)
+	}
+	if x := qs.Get("count"); x != "" {
+		count, err := strconv.ParseBool(x)
+		if err != nil {
+			return opts, fmt.Errorf("count must be a boolean")
+		}
+		opts.Count = count
+	}
+	return opts, nil
+}
+
+// splitValues split the repeated and comma separated query values
+func splitValues(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+// SelectFields reduce the items to the requested json fields,
+// the items are returned unchanged when no field is requested
+func SelectFields(items interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var docs []map[string]json.RawMessage
+	if err := json.Unmarshal(b, &docs); err != nil {
+		return nil, err
+	}
+	selected := make([]map[string]json.RawMessage, 0, len(docs))
+	for _, doc := range docs {
+		out := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := doc[f]; ok {
+				out[f] = v
+			}
+		}
+		selected = append(selected, out)
+	}
+	return selected, nil
+}
+
+// cursorValues values of the sort keys of the last document of a page
+type cursorValues struct {
+	Values []bson.RawValue `bson:"v"`
+}
+
+// findPage run a keyset paginated find, the `_id` breaks the ties of the sort keys.
+// decode is called for each document of the page.
+func findPage(ctx context.Context, collection *mongo.Collection, filter interface{}, opts ListOptions, fields ListFields, decode func(*mongo.Cursor) error) (Page, error) {
+	page := Page{Limit: opts.Limit}
+	if filter == nil {
+		filter = bson.D{}
+	}
+
+	keys := make([]string, 0, len(opts.Sort)+1)
+	desc := make([]bool, 0, len(opts.Sort)+1)
+	sort := bson.D{}
+	for _, sf := range opts.Sort {
+		key := fields.Sort[sf.Key]
+		if key == "" || key == "_id" {
+			continue
+		}
+		keys = append(keys, key)
+		desc = append(desc, sf.Desc)
+		sort = append(sort, bson.E{Key: key, Value: direction(sf.Desc)})
+	}
+	keys = append(keys, "_id")
+	desc = append(desc, false)
+	sort = append(sort, bson.E{Key: "_id", Value: 1})
+
+	findOpts := options.Find().SetSort(sort)
+	if opts.Limit > 0 {
+		findOpts.SetLimit(opts.Limit + 1)
+	}
+	if len(opts.Fields) > 0 {
+		findOpts.SetProjection(projection(opts.Fields, fields, keys))
+	}
+
+	query := filter
+	if opts.Cursor != "" {
+		after, err := decodeCursor(opts.Cursor, len(keys))
+		if err != nil {
+			return page, err
+		}
+		query = bson.D{{Key: "$and", Value: bson.A{filter, keysetFilter(keys, desc, after)}}}
+	}
+
+	cur, err := collection.Find(ctx, query, findOpts)
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return page, err
+	}
+	defer cur.Close(ctx)
+
+	var last bson.Raw
+	var n int64
+	for cur.Next(ctx) {
+		n++
+		if opts.Limit > 0 && n > opts.Limit {
+			page.NextCursor, err = encodeCursor(last, keys)
+			if err != nil {
+				return page, err
+			}
+			break
+		}
+		if err := decode(cur); err != nil {
+			log.Printf("Error on Decoding the document: %v\n", err)
+			return page, err
+		}
+		last = append(last[:0], cur.Current...)
+	}
+	if err := cur.Err(); err != nil {
+		return page, err
+	}
+
+	if opts.Count {
+		total, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			log.Printf("ERROR COUNTING DATA: %v\n", err)
+			return page, err
+		}
+		page.Total = &total
+	}
+	return page, nil
+}
+
+// direction mongo sort direction
+func direction(desc bool) int {
+	if desc {
+		return -1
+	}
+	return 1
+}
+
+// projection project the requested fields, the sort keys are kept to build the next cursor
+func projection(requested []string, fields ListFields, keys []string) bson.D {
+	seen := map[string]bool{}
+	proj := bson.D{}
+	add := func(path string) {
+		top := strings.SplitN(path, ".", 2)[0]
+		if !seen[top] {
+			seen[top] = true
+			proj = append(proj, bson.E{Key: top, Value: 1})
+		}
+	}
+	for _, f := range requested {
+		path, _ := fields.path(f)
+		add(path)
+	}
+	for _, k := range keys {
+		add(k)
+	}
+	return proj
+}
+
+// keysetFilter match the documents sorted after the values:
+// k1 > v1 OR (k1 = v1 AND k2 > v2) OR ...
+// the missing values are encoded as null which sorts first,
+// `$gt: null` would only match the nulls so they are matched explicitly
+func keysetFilter(keys []string, desc []bool, values []bson.RawValue) bson.D {
+	or := bson.A{}
+	for i := range keys {
+		cond := bson.D{}
+		for j := 0; j < i; j++ {
+			cond = append(cond, bson.E{Key: keys[j], Value: values[j]})
+		}
+		null := values[i].Type == bsontype.Null
+		switch {
+		case null && desc[i]:
+			// nothing sorts after the nulls
+			continue
+		case null:
+			cond = append(cond, bson.E{Key: keys[i], Value: bson.D{{Key: "$ne", Value: nil}}})
+		case desc[i]:
+			cond = append(cond, bson.E{Key: "$or", Value: bson.A{
+				bson.D{{Key: keys[i], Value: bson.D{{Key: "$lt", Value: values[i]}}}},
+				bson.D{{Key: keys[i], Value: nil}},
+			}})
+		default:
+			cond = append(cond, bson.E{Key: keys[i], Value: bson.D{{Key: "$gt", Value: values[i]}}})
+		}
+		or = append(or, cond)
+	}
+	return bson.D{{Key: "$or", Value: or}}
+}
+
+// encodeCursor encode the sort key values of the document as an opaque cursor
+func encodeCursor(doc bson.Raw, keys []string) (string, error) {
+	values := make([]bson.RawValue, 0, len(keys))
+	for _, key := range keys {
+		v, err := doc.LookupErr(strings.Split(key, ".")...)
+		if err != nil {
+			v = bson.RawValue{Type: bsontype.Null}
+		}
+		values = append(values, v)
+	}
+	b, err := bson.Marshal(cursorValues{values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor decode the sort key values of the cursor
+func decodeCursor(cursor string, n int) ([]bson.RawValue, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cv cursorValues
+	if err := bson.Unmarshal(b, &cv); err != nil || len(cv.Values) != n {
+		return nil, ErrInvalidCursor
+	}
+	return cv.Values, nil
+}
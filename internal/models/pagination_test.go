@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseListOptions(t *testing.T) {
+	qs := url.Values{
+		"limit":  {"20"},
+		"sort":   {"-total,title", "date"},
+		"fields": {"title,total"},
+		"count":  {"true"},
+	}
+	opts, err := ParseListOptions(qs, ExpenseListFields, ExpenseDefaultSort)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(20), opts.Limit)
+	assert.Equal(t, []SortField{{Key: "total", Desc: true}, {Key: "title"}, {Key: "date"}}, opts.Sort)
+	assert.Equal(t, []string{"title", "total"}, opts.Fields)
+	assert.True(t, opts.Count)
+
+	opts, err = ParseListOptions(url.Values{"fields": {"title,tags"}}, ExpenseListFields, ExpenseDefaultSort)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"title", "tags"}, opts.Fields)
+
+	opts, err = ParseListOptions(url.Values{}, ExpenseListFields, ExpenseDefaultSort)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultPageLimit, opts.Limit)
+	assert.Equal(t, ExpenseDefaultSort, opts.Sort)
+
+	for _, qs := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"501"}},
+		{"sort": {"password_hash"}},
+		{"sort": {"tags"}},
+		{"fields": {"secret"}},
+		{"count": {"maybe"}},
+	} {
+		_, err := ParseListOptions(qs, ExpenseListFields, nil)
+		assert.Error(t, err, qs.Encode())
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	date := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	doc, err := bson.Marshal(bson.D{{Key: "_id", Value: id}, {Key: "date", Value: date}, {Key: "total", Value: bson.D{{Key: "amount", Value: int64(1250)}}}})
+	assert.NoError(t, err)
+
+	keys := []string{"date", "total.amount", "_id"}
+	cursor, err := encodeCursor(doc, keys)
+	assert.NoError(t, err)
+
+	values, err := decodeCursor(cursor, len(keys))
+	assert.NoError(t, err)
+	assert.Equal(t, date.UnixNano()/int64(time.Millisecond), values[0].DateTime())
+	assert.Equal(t, int64(1250), values[1].Int64())
+	assert.Equal(t, id, values[2].ObjectID())
+
+	_, err = decodeCursor(cursor, 2)
+	assert.Equal(t, ErrInvalidCursor, err)
+	_, err = decodeCursor("not a cursor", len(keys))
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestKeysetFilterNulls(t *testing.T) {
+	id := primitive.NewObjectID()
+	null := bson.RawValue{Type: bsontype.Null}
+	_, raw, err := bson.MarshalValue(id)
+	assert.NoError(t, err)
+	idValue := bson.RawValue{Type: bsontype.ObjectID, Value: raw}
+	keys := []string{"project_id", "_id"}
+
+	// the nulls sort first, the documents with a value come after them
+	filter := keysetFilter(keys, []bool{false, false}, []bson.RawValue{null, idValue})
+	assert.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "project_id", Value: bson.D{{Key: "$ne", Value: nil}}}},
+		bson.D{{Key: "project_id", Value: null}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: idValue}}}},
+	}}}, filter)
+
+	// nothing sorts after the nulls in a descending sort
+	filter = keysetFilter(keys, []bool{true, false}, []bson.RawValue{null, idValue})
+	assert.Equal(t, bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: "project_id", Value: null}, {Key: "_id", Value: bson.D{{Key: "$gt", Value: idValue}}}},
+	}}}, filter)
+}
+
+func TestSelectFields(t *testing.T) {
+	items := []Category{{ID: primitive.NewObjectID(), Name: "food"}}
+	selected, err := SelectFields(items, []string{"name"})
+	assert.NoError(t, err)
+	b, _ := json.Marshal(selected)
+	assert.JSONEq(t, `[{"name":"food"}]`, string(b))
+
+	same, err := SelectFields(items, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, items, same)
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PasswordReset model for passwordResets collection
+// only the sha256 hash of the token is stored
+type PasswordReset struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	UsedAt    *time.Time         `json:"used_at" bson:"used_at"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	TokenHash string             `json:"-" bson:"token_hash"`
+}
+
+// ForgotPasswordInput input model to request a password reset
+type ForgotPasswordInput struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordInput input model to reset the password with a reset token
+type ResetPasswordInput struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,password"`
+}
+
+// ChangePasswordInput input model to change the password of the current user
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,password,nefield=CurrentPassword"`
+}
+
+// PasswordResetModeler godoc
+type PasswordResetModeler interface {
+	Insert(ctx context.Context, reset *PasswordReset) (interface{}, error)
+	ReadOneByTokenHash(ctx context.Context, tokenHash string) (PasswordReset, error)
+	MarkUsed(ctx context.Context, id primitive.ObjectID) (int64, error)
+	MarkUserUsed(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// PasswordResetModel godoc
+type PasswordResetModel struct {
+	db db.MongoDBClient
+}
+
+// NewPasswordResetModel godoc
+func NewPasswordResetModel(db db.MongoDBClient) *PasswordResetModel {
+	return &PasswordResetModel{db}
+}
+
+// Insert insert a record at passwordResets collection
+func (p *PasswordResetModel) Insert(ctx context.Context, reset *PasswordReset) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := p.db.Client.Database(p.db.DBName).Collection("passwordResets")
+	insertResult, err := collection.InsertOne(ctx, reset)
+	if err != nil {
+		log.Printf("Error on inserting new password reset: %v\n", err)
+		return nil, dbError(err)
+	}
+	return insertResult.InsertedID, nil
+}
+
+// ReadOneByTokenHash read the password reset of the token
+func (p *PasswordResetModel) ReadOneByTokenHash(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var reset PasswordReset
+	collection := p.db.Client.Database(p.db.DBName).Collection("passwordResets")
+	err := findOne(ctx, collection, bson.M{"token_hash": tokenHash}, "password reset", &reset)
+	return reset, err
+}
+
+// MarkUsed consume the reset token, the filter on `used_at` makes sure
+// a token can only be used once even with concurrent requests
+func (p *PasswordResetModel) MarkUsed(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := p.db.Client.Database(p.db.DBName).Collection("passwordResets")
+	filter := bson.M{"_id": id, "used_at": nil}
+	update := bson.M{"$set": bson.M{"used_at": time.Now()}}
+	updatedResult, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		log.Printf("Error on consuming password reset: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// MarkUserUsed consume every unused reset token of the user, once the password changed
+func (p *PasswordResetModel) MarkUserUsed(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := p.db.Client.Database(p.db.DBName).Collection("passwordResets")
+	filter := bson.D{{Key: "user_id", Value: userID}, {Key: "used_at", Value: nil}}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "used_at", Value: time.Now()}}}}
+	updatedResult, err := collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		log.Printf("Error on consuming the password resets of the user: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
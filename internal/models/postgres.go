@@ -0,0 +1,287 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rowError map the error of a single row query, no row is the not found error of the resource
+func rowError(err error, resource string) error {
+	if err == sql.ErrNoRows {
+		return notFound(resource)
+	}
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+	}
+	return dbError(err)
+}
+
+// SQLColumns sortable fields of a table, the json name of the field mapped to its SQL expression
+type SQLColumns map[string]string
+
+// sqlQuery conditions and positional args of a query
+type sqlQuery struct {
+	conds []string
+	args  []interface{}
+}
+
+// arg add the value to the args and return its placeholder
+func (q *sqlQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// where add a condition, the conditions are joined with AND
+func (q *sqlQuery) where(cond string) {
+	q.conds = append(q.conds, cond)
+}
+
+// clause the WHERE clause of the conditions
+func (q *sqlQuery) clause() string {
+	if len(q.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conds, " AND ")
+}
+
+// selectPage run a keyset paginated select, the id column breaks the ties of the sort keys.
+// The sort keys are selected after the columns, scan must pass the keys on to rows.Scan.
+func selectPage(ctx context.Context, db *sql.DB, columns, from string, q sqlQuery, opts ListOptions, sortColumns SQLColumns, id string, scan func(rows *sql.Rows, keys ...interface{}) error) (Page, error) {
+	page := Page{Limit: opts.Limit}
+
+	keys := make([]string, 0, len(opts.Sort)+1)
+	desc := make([]bool, 0, len(opts.Sort)+1)
+	order := make([]string, 0, len(opts.Sort)+1)
+	for _, sf := range opts.Sort {
+		key := sortColumns[sf.Key]
+		if key == "" || key == id {
+			continue
+		}
+		keys = append(keys, key)
+		desc = append(desc, sf.Desc)
+		if sf.Desc {
+			order = append(order, key+" DESC NULLS LAST")
+		} else {
+			order = append(order, key+" ASC NULLS FIRST")
+		}
+	}
+	keys = append(keys, id)
+	desc = append(desc, false)
+	order = append(order, id+" ASC")
+
+	if opts.Count {
+		var total int64
+		err := db.QueryRowContext(ctx, "SELECT count(*) FROM "+from+q.clause(), q.args...).Scan(&total)
+		if err != nil {
+			log.Printf("ERROR COUNTING DATA: %v\n", err)
+			return page, err
+		}
+		page.Total = &total
+	}
+
+	if opts.Cursor != "" {
+		after, err := decodeSQLCursor(opts.Cursor, len(keys))
+		if err != nil {
+			return page, err
+		}
+		q.where(keysetCondition(&q, keys, desc, after))
+	}
+	query := "SELECT " + columns + ", " + strings.Join(keys, ", ") + " FROM " + from + q.clause() +
+		" ORDER BY " + strings.Join(order, ", ")
+	if opts.Limit > 0 {
+		query += " LIMIT " + q.arg(opts.Limit+1)
+	}
+
+	rows, err := db.QueryContext(ctx, query, q.args...)
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return page, err
+	}
+	defer rows.Close()
+
+	var last []interface{}
+	var n int64
+	for rows.Next() {
+		n++
+		if opts.Limit > 0 && n > opts.Limit {
+			page.NextCursor, err = encodeSQLCursor(last)
+			if err != nil {
+				return page, err
+			}
+			break
+		}
+		values := make([]interface{}, len(keys))
+		dest := make([]interface{}, len(keys))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := scan(rows, dest...); err != nil {
+			log.Printf("Error on Scanning the row: %v\n", err)
+			return page, err
+		}
+		last = values
+	}
+	return page, rows.Err()
+}
+
+// keysetCondition match the rows sorted after the values:
+// k1 > v1 OR (k1 = v1 AND k2 > v2) OR ...
+// the nulls sort first like in mongo, a null value is matched with IS NULL
+func keysetCondition(q *sqlQuery, keys []string, desc []bool, values []interface{}) string {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		if v != nil {
+			placeholders[i] = q.arg(v)
+		}
+	}
+	or := make([]string, 0, len(keys))
+	for i := range keys {
+		and := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			if values[j] == nil {
+				and = append(and, keys[j]+" IS NULL")
+			} else {
+				and = append(and, keys[j]+" = "+placeholders[j])
+			}
+		}
+		switch {
+		case values[i] == nil && desc[i]:
+			// nothing sorts after the nulls
+			continue
+		case values[i] == nil:
+			and = append(and, keys[i]+" IS NOT NULL")
+		case desc[i]:
+			and = append(and, "("+keys[i]+" < "+placeholders[i]+" OR "+keys[i]+" IS NULL)")
+		default:
+			and = append(and, keys[i]+" > "+placeholders[i])
+		}
+		or = append(or, "("+strings.Join(and, " AND ")+")")
+	}
+	return "(" + strings.Join(or, " OR ") + ")"
+}
+
+// encodeSQLCursor encode the sort key values of the row as an opaque cursor
+func encodeSQLCursor(values []interface{}) (string, error) {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case []byte:
+			out[i] = string(x)
+		case time.Time:
+			out[i] = x.Format(time.RFC3339Nano)
+		default:
+			out[i] = x
+		}
+	}
+	b, err := json.Marshal(out)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeSQLCursor decode the sort key values of the cursor,
+// the values are sent as text and cast by postgres to the type of the column
+func decodeSQLCursor(cursor string, n int) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	d.UseNumber()
+	var values []interface{}
+	if err := d.Decode(&values); err != nil || len(values) != n {
+		return nil, ErrInvalidCursor
+	}
+	for i, v := range values {
+		switch x := v.(type) {
+		case json.Number:
+			values[i] = x.String()
+		case string, bool, nil:
+		default:
+			return nil, ErrInvalidCursor
+		}
+	}
+	return values, nil
+}
+
+// likePattern case insensitive ILIKE pattern matching the literal text
+func likePattern(text string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + escaper.Replace(text) + "%"
+}
+
+// hexIDs the hex of the object ids
+func hexIDs(ids []primitive.ObjectID) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		out[i] = id.Hex()
+	}
+	return out
+}
+
+// nullableID the hex of the id, NULL for the zero id
+func nullableID(id primitive.ObjectID) interface{} {
+	if id.IsZero() {
+		return nil
+	}
+	return id.Hex()
+}
+
+// objectID scan a CHAR(24) column into the object id, NULL is scanned as the zero id
+type objectID struct {
+	dst *primitive.ObjectID
+}
+
+// Scan implements sql.Scanner
+func (o objectID) Scan(src interface{}) error {
+	var hex string
+	switch x := src.(type) {
+	case nil:
+		*o.dst = primitive.NilObjectID
+		return nil
+	case string:
+		hex = x
+	case []byte:
+		hex = string(x)
+	default:
+		return fmt.Errorf("cannot scan %T into an object id", src)
+	}
+	id, err := primitive.ObjectIDFromHex(strings.TrimSpace(hex))
+	if err != nil {
+		return err
+	}
+	*o.dst = id
+	return nil
+}
+
+// rowsAffected the affected rows of the result
+func rowsAffected(res sql.Result, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// joinSet the SET clause of the assignments
+func joinSet(set []string) string {
+	return strings.Join(set, ", ")
+}
+
+// prefixColumns qualify the comma separated columns with the table alias
+func prefixColumns(alias, columns string) string {
+	names := strings.Split(columns, ", ")
+	for i, name := range names {
+		names[i] = alias + "." + name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// budgetColumns selected columns of a budget, in the order of scanBudget
+const budgetColumns = "id, created_at, updated_at, project_id, category_id, period, amount, currency"
+
+// scanBudget the destinations of budgetColumns
+func scanBudget(budget *Budget) []interface{} {
+	return []interface{}{objectID{&budget.ID}, &budget.CreatedAt, &budget.UpdatedAt, objectID{&budget.ProjectID},
+		objectID{&budget.CategoryID}, &budget.Period, &budget.Amount.Amount, &budget.Amount.Currency}
+}
+
+// PostgresBudgetModel BudgetModeler of the budgets table
+type PostgresBudgetModel struct {
+	db *sql.DB
+}
+
+// NewPostgresBudgetModel godoc
+func NewPostgresBudgetModel(db *sql.DB) *PostgresBudgetModel {
+	return &PostgresBudgetModel{db}
+}
+
+// Insert insert a row in the budgets table
+func (b *PostgresBudgetModel) Insert(ctx context.Context, budget *Budget) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	_, err := b.db.ExecContext(ctx,
+		`INSERT INTO budgets (id, created_at, updated_at, project_id, category_id, period, amount, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		budget.ID.Hex(), budget.CreatedAt, budget.UpdatedAt, budget.ProjectID.Hex(), nullableID(budget.CategoryID),
+		budget.Period, budget.Amount.Amount, budget.Amount.Currency)
+	if err != nil {
+		log.Printf("Error on inserting new budget: %v\n", err)
+		return nil, dbError(err)
+	}
+	return budget.ID, nil
+}
+
+// ReadAll read every budget of the project
+func (b *PostgresBudgetModel) ReadAll(ctx context.Context, projectID primitive.ObjectID) ([]Budget, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	budgets := []Budget{}
+	rows, err := b.db.QueryContext(ctx,
+		"SELECT "+budgetColumns+" FROM budgets WHERE project_id = $1 ORDER BY period, id", projectID.Hex())
+	if err != nil {
+		return budgets, dbError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var budget Budget
+		if err := rows.Scan(scanBudget(&budget)...); err != nil {
+			return budgets, dbError(err)
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, dbError(rows.Err())
+}
+
+// ReadOne read a single budget
+func (b *PostgresBudgetModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Budget, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var budget Budget
+	err := b.db.QueryRowContext(ctx, "SELECT "+budgetColumns+" FROM budgets WHERE id = $1", id.Hex()).
+		Scan(scanBudget(&budget)...)
+	if err != nil {
+		return Budget{}, rowError(err, "budget")
+	}
+	return budget, nil
+}
+
+// UpdateOne change the amount of one budget
+func (b *PostgresBudgetModel) UpdateOne(ctx context.Context, id primitive.ObjectID, amount Money) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(b.db.ExecContext(ctx,
+		`UPDATE budgets SET amount = $1, currency = $2, updated_at = $3 WHERE id = $4`,
+		amount.Amount, amount.Currency, time.Now(), id.Hex()))
+	if err != nil {
+		log.Printf("Error on updating one budget: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// RemoveOne remove one budget from the budgets table
+func (b *PostgresBudgetModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(b.db.ExecContext(ctx, `DELETE FROM budgets WHERE id = $1`, id.Hex()))
+	if err != nil {
+		log.Printf("Error on deleting one budget: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// CountCategory count the budgets on the category
+func (b *PostgresBudgetModel) CountCategory(ctx context.Context, categoryID primitive.ObjectID) (int64, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var count int64
+	err := b.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM budgets WHERE category_id = $1`, categoryID.Hex()).Scan(&count)
+	return count, dbError(err)
+}
+
+// ReassignCategory move the budgets of the category to another one, in one statement
+// failing as a whole when a project already has a budget on the other category for the same period
+func (b *PostgresBudgetModel) ReassignCategory(ctx context.Context, from, to primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(b.db.ExecContext(ctx,
+		`UPDATE budgets SET category_id = $1, updated_at = $2 WHERE category_id = $3`, to.Hex(), time.Now(), from.Hex()))
+	if err != nil {
+		log.Printf("Error on reassigning the category of budgets: %v\n", err)
+	}
+	return count, dbError(err)
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CategorySQLColumns sortable columns of the categories table
+var CategorySQLColumns = SQLColumns{
+	"id":         "id",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"name":       "name",
+	"slug":       "slug",
+}
+
+// categoryColumns selected columns of a category, in the order of scanCategory
+const categoryColumns = "id, created_at, updated_at, name, slug, parent_id, color, icon"
+
+// scanCategory the destinations of categoryColumns
+func scanCategory(category *Category) []interface{} {
+	return []interface{}{objectID{&category.ID}, &category.CreatedAt, &category.UpdatedAt, &category.Name,
+		&category.Slug, objectID{&category.ParentID}, &category.Color, &category.Icon}
+}
+
+// PostgresCategoryModel CategoryModeler of the categories table
+type PostgresCategoryModel struct {
+	db *sql.DB
+}
+
+// NewPostgresCategoryModel godoc
+func NewPostgresCategoryModel(db *sql.DB) *PostgresCategoryModel {
+	return &PostgresCategoryModel{db}
+}
+
+// Insert insert a row in the categories table
+func (c *PostgresCategoryModel) Insert(ctx context.Context, category *Category) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	_, err := c.db.ExecContext(ctx,
+		`INSERT INTO categories (id, created_at, updated_at, name, slug, parent_id, color, icon)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		category.ID.Hex(), category.CreatedAt, category.UpdatedAt, category.Name,
+		category.Slug, nullableID(category.ParentID), category.Color, category.Icon)
+	if err != nil {
+		log.Printf("Error on inserting new category: %v\n", err)
+		return nil, dbError(err)
+	}
+	return category.ID, nil
+}
+
+// ReadAll read a page of the categories
+func (c *PostgresCategoryModel) ReadAll(ctx context.Context, f CategoryFilter, opts ListOptions) ([]Category, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	categories := []Category{}
+	q := sqlQuery{}
+	if f.Name != "" {
+		q.where("name = " + q.arg(f.Name))
+	}
+	page, err := selectPage(ctx, c.db, categoryColumns, "categories", q, opts, CategorySQLColumns, "id", func(rows *sql.Rows, keys ...interface{}) error {
+		var category Category
+		if err := rows.Scan(append(scanCategory(&category), keys...)...); err != nil {
+			return err
+		}
+		categories = append(categories, category)
+		return nil
+	})
+	return categories, page, dbError(err)
+}
+
+// ReadOne read a single category
+func (c *PostgresCategoryModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Category, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var category Category
+	err := c.db.QueryRowContext(ctx, "SELECT "+categoryColumns+" FROM categories WHERE id = $1", id.Hex()).
+		Scan(scanCategory(&category)...)
+	if err != nil {
+		return Category{}, rowError(err, "category")
+	}
+	return category, nil
+}
+
+// UpdateOne update one category of the categories table
+func (c *PostgresCategoryModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update CategoryUpdateInput) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(c.db.ExecContext(ctx,
+		`UPDATE categories SET name = $1, slug = $2, color = $3, icon = $4, updated_at = $5 WHERE id = $6`,
+		update.Name, update.Slug, update.Color, update.Icon, time.Now(), id.Hex()))
+	if err != nil {
+		log.Printf("Error on updating one category: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// RemoveOne remove one category from the categories table
+func (c *PostgresCategoryModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(c.db.ExecContext(ctx, `DELETE FROM categories WHERE id = $1`, id.Hex()))
+	if err != nil {
+		log.Printf("Error on deleting one category: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// ReadTree read every category, the tree is built by the callers with NewCategoryTree
+func (c *PostgresCategoryModel) ReadTree(ctx context.Context) ([]Category, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	categories := []Category{}
+	rows, err := c.db.QueryContext(ctx, "SELECT "+categoryColumns+" FROM categories")
+	if err != nil {
+		return categories, dbError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var category Category
+		if err := rows.Scan(scanCategory(&category)...); err != nil {
+			return categories, dbError(err)
+		}
+		categories = append(categories, category)
+	}
+	return categories, dbError(rows.Err())
+}
+
+// Move change the parent of one category, a zero parent makes it a root category.
+// The caller checks the new parent is not in the subtree of the category.
+func (c *PostgresCategoryModel) Move(ctx context.Context, id primitive.ObjectID, parentID primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(c.db.ExecContext(ctx,
+		`UPDATE categories SET parent_id = $1, updated_at = $2 WHERE id = $3`, nullableID(parentID), time.Now(), id.Hex()))
+	if err != nil {
+		log.Printf("Error on moving one category: %v\n", err)
+	}
+	return count, dbError(err)
+}
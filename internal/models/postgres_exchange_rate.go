@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExchangeRateSQLColumns sortable columns of the exchange_rates table
+var ExchangeRateSQLColumns = SQLColumns{
+	"id":         "id",
+	"updated_at": "updated_at",
+	"from":       "from_currency",
+	"to":         "to_currency",
+	"date":       "date",
+	"rate":       "rate",
+}
+
+// PostgresExchangeRateModel ExchangeRateModeler of the exchange_rates table
+type PostgresExchangeRateModel struct {
+	db *sql.DB
+}
+
+// NewPostgresExchangeRateModel godoc
+func NewPostgresExchangeRateModel(db *sql.DB) *PostgresExchangeRateModel {
+	return &PostgresExchangeRateModel{db}
+}
+
+// Upsert insert or replace the rates of the same pair & date
+func (r *PostgresExchangeRateModel) Upsert(ctx context.Context, rates []ExchangeRate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	if len(rates) == 0 {
+		return 0, nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	defer tx.Rollback()
+
+	var count int64
+	for _, rate := range rates {
+		n, err := rowsAffected(tx.ExecContext(ctx,
+			`INSERT INTO exchange_rates (id, updated_at, from_currency, to_currency, date, rate)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (from_currency, to_currency, date) DO UPDATE SET rate = EXCLUDED.rate, updated_at = EXCLUDED.updated_at`,
+			primitive.NewObjectID().Hex(), time.Now(), rate.From, rate.To, rate.Date, rate.Rate))
+		if err != nil {
+			log.Printf("Error on upserting exchange rates: %v\n", err)
+			return 0, dbError(err)
+		}
+		count += n
+	}
+	return count, tx.Commit()
+}
+
+// ReadAll read a page of the exchange rates
+func (r *PostgresExchangeRateModel) ReadAll(ctx context.Context, f ExchangeRateFilter, opts ListOptions) ([]ExchangeRate, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	rates := []ExchangeRate{}
+	q := sqlQuery{}
+	if f.From != "" {
+		q.where("from_currency = " + q.arg(f.From))
+	}
+	if f.To != "" {
+		q.where("to_currency = " + q.arg(f.To))
+	}
+	if len(opts.Sort) == 0 {
+		opts.Sort = ExchangeRateDefaultSort
+	}
+	columns := "id, updated_at, from_currency, to_currency, date, rate"
+	page, err := selectPage(ctx, r.db, columns, "exchange_rates", q, opts, ExchangeRateSQLColumns, "id", func(rows *sql.Rows, keys ...interface{}) error {
+		var rate ExchangeRate
+		dest := []interface{}{objectID{&rate.ID}, &rate.UpdatedAt, &rate.From, &rate.To, &rate.Date, &rate.Rate}
+		if err := rows.Scan(append(dest, keys...)...); err != nil {
+			return err
+		}
+		rates = append(rates, rate)
+		return nil
+	})
+	return rates, page, dbError(err)
+}
+
+// FindRate find the latest rate of the pair known on the date.
+// The inverse pair is used when only that one is stored.
+func (r *PostgresExchangeRateModel) FindRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	return findRate(ctx, r.latestRate, from, to, on)
+}
+
+func (r *PostgresExchangeRateModel) latestRate(ctx context.Context, from, to string, on time.Time) (float64, error) {
+	var rate float64
+	err := r.db.QueryRowContext(ctx,
+		`SELECT rate FROM exchange_rates WHERE from_currency = $1 AND to_currency = $2 AND date <= $3
+		ORDER BY date DESC LIMIT 1`, from, to, on).Scan(&rate)
+	if err == sql.ErrNoRows {
+		return 0, ErrRateNotFound
+	}
+	if err != nil {
+		log.Printf("Error on finding the exchange rate: %v\n", err)
+		return 0, dbError(err)
+	}
+	return rate, nil
+}
+
+// FindRates load the rates of the pair & of its inverse known from start to end
+func (r *PostgresExchangeRateModel) FindRates(ctx context.Context, from, to string, start, end time.Time) (RateHistory, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	return findRates(ctx, r.latestRate, r.ratesBetween, from, to, start, end)
+}
+
+func (r *PostgresExchangeRateModel) ratesBetween(ctx context.Context, from, to string, start, end time.Time) ([]ExchangeRate, error) {
+	rates := []ExchangeRate{}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, updated_at, from_currency, to_currency, date, rate FROM exchange_rates
+		WHERE from_currency = $1 AND to_currency = $2 AND date > $3 AND date <= $4 ORDER BY date`, from, to, start, end)
+	if err != nil {
+		log.Printf("Error on finding the exchange rates: %v\n", err)
+		return nil, dbError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var rate ExchangeRate
+		if err := rows.Scan(objectID{&rate.ID}, &rate.UpdatedAt, &rate.From, &rate.To, &rate.Date, &rate.Rate); err != nil {
+			return nil, dbError(err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, dbError(rows.Err())
+}
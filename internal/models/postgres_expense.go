@@ -0,0 +1,532 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExpenseSQLColumns sortable columns of the expenses table, the category & user sort on their name
+var ExpenseSQLColumns = SQLColumns{
+	"id":          "e.id",
+	"created_at":  "e.created_at",
+	"updated_at":  "e.updated_at",
+	"date":        "e.date",
+	"title":       "e.title",
+	"description": "e.description",
+	"location":    "e.location",
+	"total":       "e.total_amount",
+	"status":      "e.status",
+	"project_id":  "COALESCE(e.project_id, '')",
+	"category":    "c.name",
+	"user":        "u.name",
+	"paid_by":     "COALESCE(e.paid_by, '')",
+}
+
+// expenseColumns selected columns of an expense joined with the snapshot columns of its category & user, in the order of scanExpense
+const expenseColumns = `e.id, e.created_at, e.updated_at, e.date, e.title, e.description, e.location, e.tags,
+	e.total_amount, e.total_currency, e.status, e.project_id, e.paid_by, e.split_method,
+	c.id, c.name, c.slug, c.color, c.icon,
+	u.id, u.name, u.email, u.is_active`
+
+// expenseFrom the expenses joined with their category & user
+const expenseFrom = `expenses e
+	JOIN categories c ON c.id = e.category_id
+	JOIN users u ON u.id = e.user_id`
+
+// scanExpense the destinations of expenseColumns
+func scanExpense(expense *Expense) []interface{} {
+	dest := []interface{}{
+		objectID{&expense.ID}, &expense.CreatedAt, &expense.UpdatedAt, &expense.Date, &expense.Title,
+		&expense.Description, &expense.Location, pq.Array(&expense.Tags),
+		&expense.Total.Amount, &expense.Total.Currency, &expense.Status, objectID{&expense.ProjectID},
+		objectID{&expense.PaidBy}, &expense.SplitMethod,
+	}
+	category, user := &expense.Category, &expense.InsertedBy
+	return append(dest,
+		objectID{&category.ID}, &category.Name, &category.Slug, &category.Color, &category.Icon,
+		objectID{&user.ID}, &user.Name, &user.Email, &user.IsActive)
+}
+
+// toSQL add the conditions of the filter, the filter is expected to be validated
+func (f ExpenseFilter) toSQL(q *sqlQuery) error {
+	if f.Start != "" {
+		start, err := time.Parse(DateLayout, f.Start)
+		if err != nil {
+			return err
+		}
+		q.where("e.date >= " + q.arg(start))
+	}
+	if f.End != "" {
+		end, err := time.Parse(DateLayout, f.End)
+		if err != nil {
+			return err
+		}
+		q.where("e.date < " + q.arg(end))
+	}
+
+	for _, in := range []struct {
+		column string
+		ids    []string
+	}{
+		{"e.category_id", f.Categories},
+		{"e.project_id", f.Projects},
+		{"e.user_id", f.Users},
+	} {
+		if len(in.ids) == 0 {
+			continue
+		}
+		ids, err := objectIDs(in.ids)
+		if err != nil {
+			return err
+		}
+		q.where(in.column + " = ANY(" + q.arg(pq.Array(hexIDs(ids))) + ")")
+	}
+
+	if len(f.Statuses) > 0 {
+		q.where("e.status = ANY(" + q.arg(pq.Array(f.Statuses)) + ")")
+	}
+
+	// amounts are stored in minor units, so a range only makes sense within one currency
+	if f.Currency != "" {
+		q.where("e.total_currency = " + q.arg(f.Currency))
+		if f.MinTotal != "" {
+			min, err := ParseMoney(f.MinTotal, f.Currency)
+			if err != nil {
+				return err
+			}
+			q.where("e.total_amount >= " + q.arg(min.Amount))
+		}
+		if f.MaxTotal != "" {
+			max, err := ParseMoney(f.MaxTotal, f.Currency)
+			if err != nil {
+				return err
+			}
+			q.where("e.total_amount <= " + q.arg(max.Amount))
+		}
+	}
+
+	if f.Location != "" {
+		q.where("e.location ILIKE " + q.arg(likePattern(f.Location)))
+	}
+	if len(f.Tags) > 0 {
+		q.where("e.tags @> " + q.arg(pq.Array(normalizeTags(f.Tags))) + "::TEXT[]")
+	}
+	if f.Text != "" {
+		text := q.arg(likePattern(f.Text))
+		q.where("(e.title ILIKE " + text + " OR e.description ILIKE " + text + " OR e.location ILIKE " + text + ")")
+	}
+	if f.Scope != nil {
+		projects := q.arg(pq.Array(hexIDs(f.Scope.Projects)))
+		outside := "e.project_id IS NULL"
+		if !f.Scope.Author.IsZero() {
+			outside += " AND e.user_id = " + q.arg(f.Scope.Author.Hex())
+		}
+		q.where("(e.project_id = ANY(" + projects + ") OR (" + outside + "))")
+	}
+	return nil
+}
+
+// PostgresExpenseModel ExpenseModeler of the expenses table,
+// the history & the attachments are kept in their own tables
+type PostgresExpenseModel struct {
+	db *sql.DB
+}
+
+// NewPostgresExpenseModel godoc
+func NewPostgresExpenseModel(db *sql.DB) *PostgresExpenseModel {
+	return &PostgresExpenseModel{db}
+}
+
+// Insert insert a row in the expenses table with its splits, history & attachments
+func (e *PostgresExpenseModel) Insert(ctx context.Context, expense Expense) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, dbError(err)
+	}
+	defer tx.Rollback()
+
+	tags := expense.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO expenses (id, created_at, updated_at, date, title, description, location, tags,
+			total_amount, total_currency, status, project_id, category_id, user_id, paid_by, split_method)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+		expense.ID.Hex(), expense.CreatedAt, expense.UpdatedAt, expense.Date, expense.Title,
+		expense.Description, expense.Location, pq.Array(tags), expense.Total.Amount, expense.Total.Currency,
+		expense.Status, nullableID(expense.ProjectID), expense.Category.ID.Hex(), expense.InsertedBy.ID.Hex(),
+		nullableID(expense.PaidBy), expense.SplitMethod)
+	if err != nil {
+		log.Printf("Error on inserting new expense: %v\n", err)
+		return nil, dbError(err)
+	}
+	if err := insertSplits(ctx, tx, expense.ID, expense.Splits); err != nil {
+		return nil, dbError(err)
+	}
+	for _, entry := range expense.History {
+		if err := insertTransition(ctx, tx, expense.ID, entry); err != nil {
+			return nil, dbError(err)
+		}
+	}
+	for _, attachment := range expense.Attachments {
+		if err := insertAttachment(ctx, tx, expense.ID, attachment); err != nil {
+			return nil, dbError(err)
+		}
+	}
+	return expense.ID, tx.Commit()
+}
+
+// ReadAll read a page of the expenses
+func (e *PostgresExpenseModel) ReadAll(ctx context.Context, f ExpenseFilter, opts ListOptions) ([]Expense, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	q := sqlQuery{}
+	if err := f.toSQL(&q); err != nil {
+		return []Expense{}, Page{}, dbError(err)
+	}
+	if len(opts.Sort) == 0 {
+		opts.Sort = ExpenseDefaultSort
+	}
+	return selectExpenses(ctx, e.db, q, opts)
+}
+
+// selectExpenses read a page of the expenses matching the query
+func selectExpenses(ctx context.Context, db *sql.DB, q sqlQuery, opts ListOptions) ([]Expense, Page, error) {
+	expenses := []Expense{}
+	page, err := selectPage(ctx, db, expenseColumns, expenseFrom, q, opts, ExpenseSQLColumns, "e.id", func(rows *sql.Rows, keys ...interface{}) error {
+		var expense Expense
+		if err := rows.Scan(append(scanExpense(&expense), keys...)...); err != nil {
+			return err
+		}
+		expenses = append(expenses, expense)
+		return nil
+	})
+	if err != nil {
+		return expenses, page, err
+	}
+	return expenses, page, loadExpenseDetails(ctx, db, expenses)
+}
+
+// loadExpenseDetails read the splits, the history & the attachments of the expenses
+func loadExpenseDetails(ctx context.Context, db *sql.DB, expenses []Expense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+	index := make(map[primitive.ObjectID]*Expense, len(expenses))
+	ids := make([]string, 0, len(expenses))
+	for i := range expenses {
+		expense := &expenses[i]
+		expense.Splits = []Split{}
+		expense.History = []StatusTransition{}
+		expense.Attachments = []Attachment{}
+		if expense.Tags == nil {
+			expense.Tags = []string{}
+		}
+		index[expense.ID] = expense
+		ids = append(ids, expense.ID.Hex())
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT expense_id, user_id, value, amount, currency
+		FROM expense_splits WHERE expense_id = ANY($1) ORDER BY expense_id, position`, pq.Array(ids))
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var expenseID primitive.ObjectID
+		var split Split
+		err := rows.Scan(objectID{&expenseID}, objectID{&split.UserID}, &split.Value, &split.Amount.Amount, &split.Amount.Currency)
+		if err != nil {
+			return err
+		}
+		index[expenseID].Splits = append(index[expenseID].Splits, split)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx,
+		`SELECT expense_id, action, from_status, to_status, by_id, by_name, at, reason
+		FROM expense_transitions WHERE expense_id = ANY($1) ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var expenseID primitive.ObjectID
+		var entry StatusTransition
+		err := rows.Scan(objectID{&expenseID}, &entry.Action, &entry.From, &entry.To,
+			objectID{&entry.ByID}, &entry.ByName, &entry.At, &entry.Reason)
+		if err != nil {
+			return err
+		}
+		index[expenseID].History = append(index[expenseID].History, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = db.QueryContext(ctx,
+		`SELECT expense_id, id, file_name, content_type, size, sha256, uploaded_by, uploaded_at
+		FROM expense_attachments WHERE expense_id = ANY($1) ORDER BY uploaded_at, id`, pq.Array(ids))
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var expenseID primitive.ObjectID
+		var attachment Attachment
+		err := rows.Scan(objectID{&expenseID}, objectID{&attachment.ID}, &attachment.FileName, &attachment.ContentType,
+			&attachment.Size, &attachment.SHA256, objectID{&attachment.UploadedBy}, &attachment.UploadedAt)
+		if err != nil {
+			return err
+		}
+		index[expenseID].Attachments = append(index[expenseID].Attachments, attachment)
+	}
+	return rows.Err()
+}
+
+// ReadOne read a single expense
+func (e *PostgresExpenseModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Expense, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	q := sqlQuery{}
+	q.where("e.id = " + q.arg(id.Hex()))
+	expenses, _, err := selectExpenses(ctx, e.db, q, ListOptions{})
+	if err != nil {
+		return Expense{}, dbError(err)
+	}
+	if len(expenses) == 0 {
+		return Expense{}, notFound("expense")
+	}
+	return expenses[0], nil
+}
+
+// Remove remove one expense from the expenses table, its history & attachments are cascaded
+func (e *PostgresExpenseModel) Remove(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(e.db.ExecContext(ctx, `DELETE FROM expenses WHERE id = $1`, id.Hex()))
+	if err != nil {
+		log.Printf("Error on deleting one expense: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// UpdateOne update one expense of the expenses table, its splits are replaced
+func (e *PostgresExpenseModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update ExpenseUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	defer tx.Rollback()
+
+	tags := update.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	count, err := rowsAffected(tx.ExecContext(ctx,
+		`UPDATE expenses SET title = $1, description = $2, date = $3, category_id = $4, location = $5,
+			tags = $6, total_amount = $7, total_currency = $8, paid_by = $9, split_method = $10, updated_at = $11
+		WHERE id = $12`,
+		update.Title, update.Description, update.Date, update.Category.ID.Hex(), update.Location,
+		pq.Array(tags), update.Total.Amount, update.Total.Currency, nullableID(update.PaidBy), update.SplitMethod,
+		time.Now(), id.Hex()))
+	if err != nil || count == 0 {
+		if err != nil {
+			log.Printf("Error on updating one expense: %v\n", err)
+		}
+		return 0, dbError(err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM expense_splits WHERE expense_id = $1`, id.Hex()); err != nil {
+		log.Printf("Error on updating one expense: %v\n", err)
+		return 0, dbError(err)
+	}
+	if err := insertSplits(ctx, tx, id, update.Splits); err != nil {
+		log.Printf("Error on updating one expense: %v\n", err)
+		return 0, dbError(err)
+	}
+	return count, dbError(tx.Commit())
+}
+
+// insertSplits insert the splits of the expense in their order
+func insertSplits(ctx context.Context, tx *sql.Tx, id primitive.ObjectID, splits []Split) error {
+	for position, split := range splits {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO expense_splits (expense_id, position, user_id, value, amount, currency)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			id.Hex(), position, split.UserID.Hex(), split.Value, split.Amount.Amount, split.Amount.Currency)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Transition apply a workflow transition and record it in the history
+// the condition on the `from` status makes concurrent transitions safe
+func (e *PostgresExpenseModel) Transition(ctx context.Context, id primitive.ObjectID, entry StatusTransition) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	defer tx.Rollback()
+
+	count, err := rowsAffected(tx.ExecContext(ctx,
+		`UPDATE expenses SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4`,
+		entry.To, entry.At, id.Hex(), entry.From))
+	if err != nil || count == 0 {
+		if err != nil {
+			log.Printf("Error on transition of expense: %v\n", err)
+		}
+		return 0, dbError(err)
+	}
+	if err := insertTransition(ctx, tx, id, entry); err != nil {
+		log.Printf("Error on transition of expense: %v\n", err)
+		return 0, dbError(err)
+	}
+	return count, tx.Commit()
+}
+
+// insertTransition record the transition in the history of the expense
+func insertTransition(ctx context.Context, tx *sql.Tx, id primitive.ObjectID, entry StatusTransition) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO expense_transitions (expense_id, action, from_status, to_status, by_id, by_name, at, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		id.Hex(), entry.Action, entry.From, entry.To, entry.ByID.Hex(), entry.ByName, entry.At, entry.Reason)
+	return err
+}
+
+// AddAttachment add the attachment metadata to the expense
+func (e *PostgresExpenseModel) AddAttachment(ctx context.Context, id primitive.ObjectID, attachment Attachment) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	defer tx.Rollback()
+
+	count, err := rowsAffected(tx.ExecContext(ctx,
+		`UPDATE expenses SET updated_at = $1 WHERE id = $2`, time.Now(), id.Hex()))
+	if err != nil || count == 0 {
+		if err != nil {
+			log.Printf("Error on adding attachment: %v\n", err)
+		}
+		return 0, dbError(err)
+	}
+	if err := insertAttachment(ctx, tx, id, attachment); err != nil {
+		log.Printf("Error on adding attachment: %v\n", err)
+		return 0, dbError(err)
+	}
+	return count, tx.Commit()
+}
+
+// insertAttachment insert the attachment metadata of the expense
+func insertAttachment(ctx context.Context, tx *sql.Tx, id primitive.ObjectID, attachment Attachment) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO expense_attachments (id, expense_id, file_name, content_type, size, sha256, uploaded_by, uploaded_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		attachment.ID.Hex(), id.Hex(), attachment.FileName, attachment.ContentType, attachment.Size,
+		attachment.SHA256, attachment.UploadedBy.Hex(), attachment.UploadedAt)
+	return err
+}
+
+// RemoveAttachment remove the attachment metadata from the expense
+func (e *PostgresExpenseModel) RemoveAttachment(ctx context.Context, id primitive.ObjectID, attachmentID primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, dbError(err)
+	}
+	defer tx.Rollback()
+
+	count, err := rowsAffected(tx.ExecContext(ctx,
+		`DELETE FROM expense_attachments WHERE id = $1 AND expense_id = $2`, attachmentID.Hex(), id.Hex()))
+	if err != nil || count == 0 {
+		if err != nil {
+			log.Printf("Error on removing attachment: %v\n", err)
+		}
+		return 0, dbError(err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE expenses SET updated_at = $1 WHERE id = $2`, time.Now(), id.Hex()); err != nil {
+		log.Printf("Error on removing attachment: %v\n", err)
+		return 0, dbError(err)
+	}
+	return count, tx.Commit()
+}
+
+// CountAttachmentRefs count the attachments sharing the same content
+func (e *PostgresExpenseModel) CountAttachmentRefs(ctx context.Context, sha256 string) (int64, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var refs int64
+	err := e.db.QueryRowContext(ctx, `SELECT count(*) FROM expense_attachments WHERE sha256 = $1`, sha256).Scan(&refs)
+	return refs, dbError(err)
+}
+
+// ReassignCategory move the expenses of the category to another one
+func (e *PostgresExpenseModel) ReassignCategory(ctx context.Context, from primitive.ObjectID, to CategorySnapshot) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(e.db.ExecContext(ctx,
+		`UPDATE expenses SET category_id = $1, updated_at = $2 WHERE category_id = $3`, to.ID.Hex(), time.Now(), from.Hex()))
+	if err != nil {
+		log.Printf("Error on reassigning the category of expenses: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// RefreshCategory nothing to refresh, the expenses reference their category and its columns are joined on read
+func (e *PostgresExpenseModel) RefreshCategory(ctx context.Context, category CategorySnapshot) (int64, error) {
+	return 0, nil
+}
+
+// RefreshUser nothing to refresh, the expenses reference their author and its columns are joined on read
+func (e *PostgresExpenseModel) RefreshUser(ctx context.Context, user UserSnapshot) (int64, error) {
+	return 0, nil
+}
+
+// TotalsByCategory sum the expenses matching the filter by category & currency
+func (e *PostgresExpenseModel) TotalsByCategory(ctx context.Context, f ExpenseFilter) ([]CategoryTotal, error) {
+	ctx, cancel := reportContext(ctx)
+	defer cancel()
+	totals := []CategoryTotal{}
+	q := sqlQuery{}
+	if err := f.toSQL(&q); err != nil {
+		return totals, dbError(err)
+	}
+	rows, err := e.db.QueryContext(ctx,
+		`SELECT e.category_id, e.total_currency, sum(e.total_amount), count(*) FROM `+expenseFrom+q.clause()+`
+		GROUP BY e.category_id, e.total_currency`, q.args...)
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return totals, dbError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var total CategoryTotal
+		if err := rows.Scan(objectID{&total.CategoryID}, &total.Total.Currency, &total.Total.Amount, &total.Count); err != nil {
+			return totals, dbError(err)
+		}
+		totals = append(totals, total)
+	}
+	return totals, dbError(rows.Err())
+}
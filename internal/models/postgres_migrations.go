@@ -0,0 +1,303 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+// PostgresMigration versioned change of the postgres schema
+type PostgresMigration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// PostgresMigrations the schema history, applied in order.
+// Applied migrations must never be edited, add a new version instead.
+var PostgresMigrations = []PostgresMigration{
+	{1, "initial schema", `
+CREATE TABLE users (
+	id            CHAR(24) PRIMARY KEY,
+	created_at    TIMESTAMPTZ NOT NULL,
+	updated_at    TIMESTAMPTZ NOT NULL,
+	email         TEXT NOT NULL,
+	phone_number  TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	role          TEXT NOT NULL,
+	is_active     BOOLEAN NOT NULL,
+	password_hash TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE categories (
+	id         CHAR(24) PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	name       TEXT NOT NULL
+);
+
+CREATE TABLE projects (
+	id            CHAR(24) PRIMARY KEY,
+	created_at    TIMESTAMPTZ NOT NULL,
+	updated_at    TIMESTAMPTZ NOT NULL,
+	title         TEXT NOT NULL,
+	description   TEXT NOT NULL,
+	base_currency CHAR(3) NOT NULL,
+	is_active     BOOLEAN NOT NULL
+);
+
+CREATE TABLE project_users (
+	id         CHAR(24) PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	project_id CHAR(24) NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+	user_id    CHAR(24) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	role       TEXT NOT NULL,
+	is_active  BOOLEAN NOT NULL
+);
+CREATE INDEX project_users_project_id ON project_users (project_id);
+CREATE INDEX project_users_user_id ON project_users (user_id);
+
+CREATE TABLE expenses (
+	id             CHAR(24) PRIMARY KEY,
+	created_at     TIMESTAMPTZ NOT NULL,
+	updated_at     TIMESTAMPTZ NOT NULL,
+	date           TIMESTAMPTZ NOT NULL,
+	title          TEXT NOT NULL,
+	description    TEXT NOT NULL,
+	location       TEXT NOT NULL,
+	tags           TEXT[] NOT NULL DEFAULT '{}',
+	total_amount   BIGINT NOT NULL,
+	total_currency CHAR(3) NOT NULL,
+	status         TEXT NOT NULL,
+	project_id     CHAR(24) REFERENCES projects (id),
+	category_id    CHAR(24) NOT NULL REFERENCES categories (id),
+	user_id        CHAR(24) NOT NULL REFERENCES users (id)
+);
+CREATE INDEX expenses_date ON expenses (date);
+CREATE INDEX expenses_project_id ON expenses (project_id);
+
+CREATE TABLE expense_transitions (
+	id          BIGSERIAL PRIMARY KEY,
+	expense_id  CHAR(24) NOT NULL REFERENCES expenses (id) ON DELETE CASCADE,
+	action      TEXT NOT NULL,
+	from_status TEXT NOT NULL,
+	to_status   TEXT NOT NULL,
+	by_id       CHAR(24) NOT NULL,
+	by_name     TEXT NOT NULL,
+	at          TIMESTAMPTZ NOT NULL,
+	reason      TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX expense_transitions_expense_id ON expense_transitions (expense_id);
+
+CREATE TABLE expense_attachments (
+	id           CHAR(24) PRIMARY KEY,
+	expense_id   CHAR(24) NOT NULL REFERENCES expenses (id) ON DELETE CASCADE,
+	file_name    TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size         BIGINT NOT NULL,
+	sha256       CHAR(64) NOT NULL,
+	uploaded_by  CHAR(24) NOT NULL,
+	uploaded_at  TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX expense_attachments_expense_id ON expense_attachments (expense_id);
+CREATE INDEX expense_attachments_sha256 ON expense_attachments (sha256);
+
+CREATE TABLE password_resets (
+	id         CHAR(24) PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at    TIMESTAMPTZ,
+	user_id    CHAR(24) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	token_hash CHAR(64) NOT NULL UNIQUE
+);
+
+CREATE TABLE exchange_rates (
+	id            CHAR(24) PRIMARY KEY,
+	updated_at    TIMESTAMPTZ NOT NULL,
+	from_currency CHAR(3) NOT NULL,
+	to_currency   CHAR(3) NOT NULL,
+	date          TIMESTAMPTZ NOT NULL,
+	rate          DOUBLE PRECISION NOT NULL,
+	UNIQUE (from_currency, to_currency, date)
+);
+`},
+	{2, "unique user email", `
+CREATE UNIQUE INDEX users_email ON users (email);
+`},
+	{3, "user locale", `
+ALTER TABLE users ADD COLUMN locale TEXT NOT NULL DEFAULT '';
+`},
+	{4, "category tree", `
+ALTER TABLE categories
+	ADD COLUMN slug TEXT,
+	ADD COLUMN parent_id CHAR(24) REFERENCES categories (id),
+	ADD COLUMN color TEXT NOT NULL DEFAULT '',
+	ADD COLUMN icon TEXT NOT NULL DEFAULT '';
+UPDATE categories SET slug = lower(name);
+UPDATE categories c SET slug = c.slug || '-' || c.id
+	WHERE EXISTS (SELECT 1 FROM categories d WHERE d.slug = c.slug AND d.id < c.id);
+ALTER TABLE categories ALTER COLUMN slug SET NOT NULL;
+CREATE UNIQUE INDEX categories_slug ON categories (slug);
+CREATE INDEX categories_parent_id ON categories (parent_id);
+`},
+	{5, "budgets", `
+CREATE TABLE budgets (
+	id          CHAR(24) PRIMARY KEY,
+	created_at  TIMESTAMPTZ NOT NULL,
+	updated_at  TIMESTAMPTZ NOT NULL,
+	project_id  CHAR(24) NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+	category_id CHAR(24) REFERENCES categories (id) ON DELETE CASCADE,
+	period      TEXT NOT NULL,
+	amount      BIGINT NOT NULL,
+	currency    CHAR(3) NOT NULL
+);
+CREATE UNIQUE INDEX budgets_scope ON budgets (project_id, COALESCE(category_id, ''), period);
+`},
+	{6, "notifications", `
+CREATE TABLE notifications (
+	id         CHAR(24) PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL,
+	user_id    CHAR(24) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	kind       TEXT NOT NULL,
+	title      TEXT NOT NULL,
+	body       TEXT NOT NULL,
+	project_id CHAR(24),
+	expense_id CHAR(24),
+	budget_id  CHAR(24),
+	read_at    TIMESTAMPTZ
+);
+CREATE INDEX notifications_user ON notifications (user_id, created_at DESC);
+
+CREATE TABLE notification_alerts (
+	key        TEXT PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE notification_preferences (
+	user_id     CHAR(24) PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+	updated_at  TIMESTAMPTZ NOT NULL,
+	inbox       BOOLEAN NOT NULL,
+	email       BOOLEAN NOT NULL,
+	webhook     BOOLEAN NOT NULL,
+	webhook_url TEXT NOT NULL DEFAULT '',
+	muted       TEXT[] NOT NULL DEFAULT '{}'
+);
+`},
+	{7, "recurring expenses", `
+CREATE TABLE recurring_expenses (
+	id              CHAR(24) PRIMARY KEY,
+	created_at      TIMESTAMPTZ NOT NULL,
+	updated_at      TIMESTAMPTZ NOT NULL,
+	title           TEXT NOT NULL,
+	description     TEXT NOT NULL,
+	location        TEXT NOT NULL,
+	tags            TEXT[] NOT NULL DEFAULT '{}',
+	total_amount    BIGINT NOT NULL,
+	total_currency  CHAR(3) NOT NULL,
+	project_id      CHAR(24) REFERENCES projects (id),
+	category_id     CHAR(24) NOT NULL,
+	category_name   TEXT NOT NULL,
+	category_slug   TEXT NOT NULL,
+	category_color  TEXT NOT NULL DEFAULT '',
+	category_icon   TEXT NOT NULL DEFAULT '',
+	user_id         CHAR(24) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	user_name       TEXT NOT NULL,
+	user_email      TEXT NOT NULL,
+	user_is_active  BOOLEAN NOT NULL,
+	rrule           TEXT NOT NULL,
+	start           TIMESTAMPTZ NOT NULL,
+	status          TEXT NOT NULL,
+	scheduled_until TIMESTAMPTZ,
+	skipped         TEXT[] NOT NULL DEFAULT '{}'
+);
+CREATE INDEX recurring_expenses_status ON recurring_expenses (status);
+CREATE INDEX recurring_expenses_user ON recurring_expenses (user_id);
+`},
+	{8, "split expenses", `
+ALTER TABLE expenses ADD COLUMN paid_by CHAR(24);
+ALTER TABLE expenses ADD COLUMN split_method TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE expense_splits (
+	expense_id CHAR(24) NOT NULL REFERENCES expenses (id) ON DELETE CASCADE,
+	position   INTEGER NOT NULL,
+	user_id    CHAR(24) NOT NULL,
+	value      TEXT NOT NULL,
+	amount     BIGINT NOT NULL,
+	currency   CHAR(3) NOT NULL,
+	PRIMARY KEY (expense_id, position)
+);
+
+CREATE TABLE settlements (
+	id          CHAR(24) PRIMARY KEY,
+	created_at  TIMESTAMPTZ NOT NULL,
+	project_id  CHAR(24) NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+	from_id     CHAR(24) NOT NULL,
+	to_id       CHAR(24) NOT NULL,
+	amount      BIGINT NOT NULL,
+	currency    CHAR(3) NOT NULL,
+	date        TIMESTAMPTZ NOT NULL,
+	note        TEXT NOT NULL,
+	inserted_by CHAR(24) NOT NULL
+);
+CREATE INDEX settlements_project ON settlements (project_id, date);
+`},
+	{9, "user password changed at", `
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMPTZ NOT NULL DEFAULT '0001-01-01 00:00:00+00';
+`},
+}
+
+// postgresMigrationLock advisory lock key serializing concurrent migration runs
+const postgresMigrationLock = 7265314
+
+// MigratePostgres apply the pending migrations, each one in its own transaction.
+// The applied versions are recorded in the schema_migrations table.
+func MigratePostgres(db *sql.DB) ([]int, error) {
+	ctx := context.TODO()
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []int
+	for _, m := range PostgresMigrations {
+		done, err := applyPostgresMigration(ctx, db, m)
+		if err != nil {
+			log.Printf("Error on migration %d %s: %v\n", m.Version, m.Name, err)
+			return applied, err
+		}
+		if done {
+			applied = append(applied, m.Version)
+		}
+	}
+	return applied, nil
+}
+
+// applyPostgresMigration apply the migration unless it is already recorded
+func applyPostgresMigration(ctx context.Context, db *sql.DB, m PostgresMigration) (bool, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+		return false, err
+	}
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.Version).Scan(&exists)
+	if err != nil || exists {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NotificationSQLColumns sortable columns of the notifications table
+var NotificationSQLColumns = SQLColumns{
+	"id":         "id",
+	"created_at": "created_at",
+	"kind":       "kind",
+}
+
+// notificationColumns selected columns of a notification, in the order of scanNotification
+const notificationColumns = "id, created_at, user_id, kind, title, body, project_id, expense_id, budget_id, read_at"
+
+// scanNotification the destinations of notificationColumns
+func scanNotification(n *Notification) []interface{} {
+	return []interface{}{objectID{&n.ID}, &n.CreatedAt, objectID{&n.UserID}, &n.Kind, &n.Title, &n.Body,
+		objectID{&n.ProjectID}, objectID{&n.ExpenseID}, objectID{&n.BudgetID}, &n.ReadAt}
+}
+
+// PostgresNotificationModel NotificationModeler of the notifications, notification_alerts
+// & notification_preferences tables
+type PostgresNotificationModel struct {
+	db *sql.DB
+}
+
+// NewPostgresNotificationModel godoc
+func NewPostgresNotificationModel(db *sql.DB) *PostgresNotificationModel {
+	return &PostgresNotificationModel{db}
+}
+
+// Insert insert a row in the notifications table
+func (n *PostgresNotificationModel) Insert(ctx context.Context, notification *Notification) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	_, err := n.db.ExecContext(ctx,
+		`INSERT INTO notifications (`+notificationColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		notification.ID.Hex(), notification.CreatedAt, notification.UserID.Hex(), notification.Kind,
+		notification.Title, notification.Body, nullableID(notification.ProjectID), nullableID(notification.ExpenseID),
+		nullableID(notification.BudgetID), notification.ReadAt)
+	if err != nil {
+		log.Printf("Error on inserting new notification: %v\n", err)
+		return nil, dbError(err)
+	}
+	return notification.ID, nil
+}
+
+// ReadAll read a page of the inbox of the user
+func (n *PostgresNotificationModel) ReadAll(ctx context.Context, f NotificationFilter, opts ListOptions) ([]Notification, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	notifications := []Notification{}
+	q := sqlQuery{}
+	q.where("user_id = " + q.arg(f.UserID.Hex()))
+	if f.Unread {
+		q.where("read_at IS NULL")
+	}
+	page, err := selectPage(ctx, n.db, notificationColumns, "notifications", q, opts, NotificationSQLColumns, "id", func(rows *sql.Rows, keys ...interface{}) error {
+		var notification Notification
+		if err := rows.Scan(append(scanNotification(&notification), keys...)...); err != nil {
+			return err
+		}
+		notifications = append(notifications, notification)
+		return nil
+	})
+	return notifications, page, dbError(err)
+}
+
+// MarkRead mark one notification of the user read or unread
+func (n *PostgresNotificationModel) MarkRead(ctx context.Context, userID, id primitive.ObjectID, read bool) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	var readAt *time.Time
+	if read {
+		now := time.Now()
+		readAt = &now
+	}
+	count, err := rowsAffected(n.db.ExecContext(ctx,
+		`UPDATE notifications SET read_at = $1 WHERE id = $2 AND user_id = $3`, readAt, id.Hex(), userID.Hex()))
+	if err != nil {
+		log.Printf("Error on updating one notification: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// MarkAllRead mark every unread notification of the user read
+func (n *PostgresNotificationModel) MarkAllRead(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(n.db.ExecContext(ctx,
+		`UPDATE notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL`, time.Now(), userID.Hex()))
+	if err != nil {
+		log.Printf("Error on updating the notifications: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// Claim insert the key in the notification_alerts table, a conflicting key is already claimed
+func (n *PostgresNotificationModel) Claim(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(n.db.ExecContext(ctx,
+		`INSERT INTO notification_alerts (key, created_at) VALUES ($1, $2) ON CONFLICT (key) DO NOTHING`, key, time.Now()))
+	if err != nil {
+		log.Printf("Error on claiming the alert %s: %v\n", key, err)
+		return false, dbError(err)
+	}
+	return count == 1, nil
+}
+
+// ReadPreferences read the preferences of the user, the defaults when the user never changed them
+func (n *PostgresNotificationModel) ReadPreferences(ctx context.Context, userID primitive.ObjectID) (NotificationPreferences, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	prefs := DefaultNotificationPreferences(userID)
+	var muted []string
+	err := n.db.QueryRowContext(ctx,
+		`SELECT updated_at, inbox, email, webhook, webhook_url, muted FROM notification_preferences WHERE user_id = $1`,
+		userID.Hex()).Scan(&prefs.UpdatedAt, &prefs.Inbox, &prefs.Email, &prefs.Webhook, &prefs.WebhookURL, pq.Array(&muted))
+	if err == sql.ErrNoRows {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, rowError(err, "notification preferences")
+	}
+	for _, kind := range muted {
+		prefs.Muted = append(prefs.Muted, NotificationKind(kind))
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences insert or replace the preferences of the user
+func (n *PostgresNotificationModel) UpdatePreferences(ctx context.Context, prefs NotificationPreferences) error {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	muted := make([]string, 0, len(prefs.Muted))
+	for _, kind := range prefs.Muted {
+		muted = append(muted, string(kind))
+	}
+	_, err := n.db.ExecContext(ctx,
+		`INSERT INTO notification_preferences (user_id, updated_at, inbox, email, webhook, webhook_url, muted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = EXCLUDED.updated_at, inbox = EXCLUDED.inbox,
+			email = EXCLUDED.email, webhook = EXCLUDED.webhook, webhook_url = EXCLUDED.webhook_url, muted = EXCLUDED.muted`,
+		prefs.UserID.Hex(), prefs.UpdatedAt, prefs.Inbox, prefs.Email, prefs.Webhook, prefs.WebhookURL, pq.Array(muted))
+	if err != nil {
+		log.Printf("Error on updating the notification preferences: %v\n", err)
+	}
+	return dbError(err)
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PostgresPasswordResetModel PasswordResetModeler of the password_resets table
+type PostgresPasswordResetModel struct {
+	db *sql.DB
+}
+
+// NewPostgresPasswordResetModel godoc
+func NewPostgresPasswordResetModel(db *sql.DB) *PostgresPasswordResetModel {
+	return &PostgresPasswordResetModel{db}
+}
+
+// Insert insert a row in the password_resets table
+func (p *PostgresPasswordResetModel) Insert(ctx context.Context, reset *PasswordReset) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	if reset.ID.IsZero() {
+		reset.ID = primitive.NewObjectID()
+	}
+	_, err := p.db.ExecContext(ctx,
+		`INSERT INTO password_resets (id, created_at, expires_at, used_at, user_id, token_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		reset.ID.Hex(), reset.CreatedAt, reset.ExpiresAt, reset.UsedAt, reset.UserID.Hex(), reset.TokenHash)
+	if err != nil {
+		log.Printf("Error on inserting new password reset: %v\n", err)
+		return nil, dbError(err)
+	}
+	return reset.ID, nil
+}
+
+// ReadOneByTokenHash read the password reset of the token
+func (p *PostgresPasswordResetModel) ReadOneByTokenHash(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var reset PasswordReset
+	var usedAt sql.NullTime
+	err := p.db.QueryRowContext(ctx,
+		`SELECT id, created_at, expires_at, used_at, user_id, token_hash FROM password_resets WHERE token_hash = $1`, tokenHash).
+		Scan(objectID{&reset.ID}, &reset.CreatedAt, &reset.ExpiresAt, &usedAt, objectID{&reset.UserID}, &reset.TokenHash)
+	if err != nil {
+		return PasswordReset{}, rowError(err, "password reset")
+	}
+	if usedAt.Valid {
+		reset.UsedAt = &usedAt.Time
+	}
+	return reset, nil
+}
+
+// MarkUsed consume the reset token, the condition on `used_at` makes sure
+// a token can only be used once even with concurrent requests
+func (p *PostgresPasswordResetModel) MarkUsed(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(p.db.ExecContext(ctx,
+		`UPDATE password_resets SET used_at = $1 WHERE id = $2 AND used_at IS NULL`, time.Now(), id.Hex()))
+	if err != nil {
+		log.Printf("Error on consuming password reset: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// MarkUserUsed consume every unused reset token of the user, once the password changed
+func (p *PostgresPasswordResetModel) MarkUserUsed(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(p.db.ExecContext(ctx,
+		`UPDATE password_resets SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL`, time.Now(), userID.Hex()))
+	if err != nil {
+		log.Printf("Error on consuming the password resets of the user: %v\n", err)
+	}
+	return count, dbError(err)
+}
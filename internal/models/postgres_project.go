@@ -0,0 +1,294 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ProjectSQLColumns sortable columns of the projects table
+var ProjectSQLColumns = SQLColumns{
+	"id":            "id",
+	"created_at":    "created_at",
+	"updated_at":    "updated_at",
+	"title":         "title",
+	"description":   "description",
+	"base_currency": "base_currency",
+	"is_active":     "is_active",
+}
+
+// projectColumns selected columns of a project, in the order of scanProject
+const projectColumns = "id, created_at, updated_at, title, description, base_currency, is_active"
+
+// scanProject the destinations of projectColumns
+func scanProject(project *Project) []interface{} {
+	return []interface{}{
+		objectID{&project.ID}, &project.CreatedAt, &project.UpdatedAt, &project.Title,
+		&project.Description, &project.BaseCurrency, &project.IsActive,
+	}
+}
+
+// projectUserColumns selected columns of a membership, in the order of scanProjectUser
+const projectUserColumns = "pu.id, pu.created_at, pu.updated_at, pu.project_id, pu.user_id, pu.role, pu.is_active"
+
+// scanProjectUser the destinations of projectUserColumns
+func scanProjectUser(pu *ProjectUser) []interface{} {
+	return []interface{}{
+		objectID{&pu.ID}, &pu.CreatedAt, &pu.UpdatedAt, objectID{&pu.ProjectID},
+		objectID{&pu.UserID}, &pu.Role, &pu.IsActive,
+	}
+}
+
+// toSQL build the conditions of the memberships
+func (f ProjectUserFilter) toSQL() sqlQuery {
+	q := sqlQuery{}
+	if !f.ID.IsZero() {
+		q.where("pu.id = " + q.arg(f.ID.Hex()))
+	}
+	if !f.ProjectID.IsZero() {
+		q.where("pu.project_id = " + q.arg(f.ProjectID.Hex()))
+	}
+	if !f.UserID.IsZero() {
+		q.where("pu.user_id = " + q.arg(f.UserID.Hex()))
+	}
+	if f.IsActive != nil {
+		q.where("pu.is_active = " + q.arg(*f.IsActive))
+	}
+	return q
+}
+
+// PostgresProjectModel ProjectModeler of the projects & project_users tables
+type PostgresProjectModel struct {
+	db *sql.DB
+}
+
+// NewPostgresProjectModel godoc
+func NewPostgresProjectModel(db *sql.DB) *PostgresProjectModel {
+	return &PostgresProjectModel{db}
+}
+
+// Insert insert a row in the projects table
+func (c *PostgresProjectModel) Insert(ctx context.Context, project *Project) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	_, err := c.db.ExecContext(ctx,
+		`INSERT INTO projects (id, created_at, updated_at, title, description, base_currency, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		project.ID.Hex(), project.CreatedAt, project.UpdatedAt, project.Title,
+		project.Description, project.BaseCurrency, project.IsActive)
+	if err != nil {
+		log.Printf("Error on inserting new project: %v\n", err)
+		return nil, dbError(err)
+	}
+	return project.ID, nil
+}
+
+// ReadAll read a page of the projects
+func (c *PostgresProjectModel) ReadAll(ctx context.Context, f ProjectFilter, opts ListOptions) ([]Project, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	projects := []Project{}
+	q := sqlQuery{}
+	if f.Title != "" {
+		q.where("title = " + q.arg(f.Title))
+	}
+	page, err := selectPage(ctx, c.db, projectColumns, "projects", q, opts, ProjectSQLColumns, "id", func(rows *sql.Rows, keys ...interface{}) error {
+		var project Project
+		if err := rows.Scan(append(scanProject(&project), keys...)...); err != nil {
+			return err
+		}
+		projects = append(projects, project)
+		return nil
+	})
+	return projects, page, dbError(err)
+}
+
+// ReadOne read a single project
+func (c *PostgresProjectModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Project, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var project Project
+	err := c.db.QueryRowContext(ctx, "SELECT "+projectColumns+" FROM projects WHERE id = $1", id.Hex()).
+		Scan(scanProject(&project)...)
+	if err != nil {
+		return Project{}, rowError(err, "project")
+	}
+	return project, nil
+}
+
+// UpdateOne update one project of the projects table
+func (c *PostgresProjectModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update ProjectUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	q := sqlQuery{}
+	set := []string{"updated_at = " + q.arg(time.Now())}
+	if update.IsActive != nil {
+		set = append(set, "is_active = "+q.arg(*update.IsActive))
+	}
+	q.where("id = " + q.arg(id.Hex()))
+	count, err := rowsAffected(c.db.ExecContext(ctx, "UPDATE projects SET "+joinSet(set)+q.clause(), q.args...))
+	if err != nil {
+		log.Printf("Error on updating one project: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// LookupProjectDetails read the project with its expenses of the period, latest first,
+// and its memberships joined with their user account
+func (c *PostgresProjectModel) LookupProjectDetails(ctx context.Context, id primitive.ObjectID, qsFilter ProjectDetailsQS) (ProjectDetails, error) {
+	ctx, cancel := reportContext(ctx)
+	defer cancel()
+	var project Project
+	err := c.db.QueryRowContext(ctx, "SELECT "+projectColumns+" FROM projects WHERE id = $1", id.Hex()).
+		Scan(scanProject(&project)...)
+	if err != nil {
+		return ProjectDetails{}, rowError(err, "project")
+	}
+	details := ProjectDetails{
+		ID:           project.ID,
+		CreatedAt:    project.CreatedAt,
+		UpdatedAt:    project.UpdatedAt,
+		Title:        project.Title,
+		Description:  project.Description,
+		BaseCurrency: project.BaseCurrency,
+	}
+
+	q := sqlQuery{}
+	q.where("e.project_id = " + q.arg(id.Hex()))
+	q.where("e.date >= " + q.arg(qsFilter.Start))
+	q.where("e.date < " + q.arg(qsFilter.End))
+	details.Expenses, _, err = selectExpenses(ctx, c.db, q, ListOptions{Sort: ExpenseDefaultSort})
+	if err != nil {
+		return details, dbError(err)
+	}
+
+	details.Users, err = c.ReadAllProjectMembers(ctx, ProjectUserFilter{ProjectID: id, IsActive: Bool(qsFilter.IsActive)})
+	if details.Users == nil {
+		details.Users = []ProjectMember{}
+	}
+	return details, dbError(err)
+}
+
+// InsertProjectUser insert a row in the project_users table
+func (c *PostgresProjectModel) InsertProjectUser(ctx context.Context, user *ProjectUser) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	if user.ID.IsZero() {
+		user.ID = primitive.NewObjectID()
+	}
+	_, err := c.db.ExecContext(ctx,
+		`INSERT INTO project_users (id, created_at, updated_at, project_id, user_id, role, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		user.ID.Hex(), user.CreatedAt, user.UpdatedAt, user.ProjectID.Hex(), user.UserID.Hex(), user.Role, user.IsActive)
+	if err != nil {
+		log.Printf("Error on inserting new project user: %v\n", err)
+		return nil, dbError(err)
+	}
+	return user.ID, nil
+}
+
+// ReadAllProjectUser read all the memberships
+func (c *PostgresProjectModel) ReadAllProjectUser(ctx context.Context, f ProjectUserFilter) ([]ProjectUser, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var users []ProjectUser
+	q := f.toSQL()
+	rows, err := c.db.QueryContext(ctx, "SELECT "+projectUserColumns+" FROM project_users pu"+q.clause()+" ORDER BY pu.id", q.args...)
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return users, dbError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user ProjectUser
+		if err := rows.Scan(scanProjectUser(&user)...); err != nil {
+			log.Printf("Error on Scanning the row: %v\n", err)
+			return users, dbError(err)
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// ReadAllProjectMembers read all the memberships joined with their user account
+func (c *PostgresProjectModel) ReadAllProjectMembers(ctx context.Context, f ProjectUserFilter) ([]ProjectMember, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var members []ProjectMember
+	q := f.toSQL()
+	rows, err := c.db.QueryContext(ctx, "SELECT "+projectUserColumns+", "+prefixColumns("u", userColumns)+
+		" FROM project_users pu JOIN users u ON u.id = pu.user_id"+q.clause()+" ORDER BY pu.id", q.args...)
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return members, dbError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var member ProjectMember
+		if err := rows.Scan(append(scanProjectUser(&member.ProjectUser), scanUser(&member.User)...)...); err != nil {
+			log.Printf("Error on Scanning the row: %v\n", err)
+			return members, dbError(err)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// ReadAllUserProjects read all the memberships joined with their project
+func (c *PostgresProjectModel) ReadAllUserProjects(ctx context.Context, f ProjectUserFilter) ([]UserProject, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var projects []UserProject
+	q := f.toSQL()
+	rows, err := c.db.QueryContext(ctx, "SELECT "+projectUserColumns+", "+prefixColumns("p", projectColumns)+
+		" FROM project_users pu JOIN projects p ON p.id = pu.project_id"+q.clause()+" ORDER BY pu.id", q.args...)
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return projects, dbError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var project UserProject
+		if err := rows.Scan(append(scanProjectUser(&project.ProjectUser), scanProject(&project.Project)...)...); err != nil {
+			log.Printf("Error on Scanning the row: %v\n", err)
+			return projects, dbError(err)
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+// ReadOneProjectUser read a single membership
+func (c *PostgresProjectModel) ReadOneProjectUser(ctx context.Context, f ProjectUserFilter) (ProjectUser, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var user ProjectUser
+	q := f.toSQL()
+	err := c.db.QueryRowContext(ctx, "SELECT "+projectUserColumns+" FROM project_users pu"+q.clause()+" LIMIT 1", q.args...).
+		Scan(scanProjectUser(&user)...)
+	if err != nil {
+		return ProjectUser{}, rowError(err, "project member")
+	}
+	return user, nil
+}
+
+// UpdateOneProjectUser update the first membership matching the filter
+func (c *PostgresProjectModel) UpdateOneProjectUser(ctx context.Context, f ProjectUserFilter, update ProjectUserUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	q := f.toSQL()
+	set := []string{"updated_at = " + q.arg(time.Now())}
+	if update.IsActive != nil {
+		set = append(set, "is_active = "+q.arg(*update.IsActive))
+	}
+	query := "UPDATE project_users SET " + joinSet(set) +
+		" WHERE id = (SELECT pu.id FROM project_users pu" + q.clause() + " LIMIT 1)"
+	count, err := rowsAffected(c.db.ExecContext(ctx, query, q.args...))
+	if err != nil {
+		log.Printf("Error on updating one project user: %v\n", err)
+	}
+	return count, dbError(err)
+}
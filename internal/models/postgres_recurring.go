@@ -0,0 +1,189 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecurringSQLColumns sortable columns of the recurring_expenses table
+var RecurringSQLColumns = SQLColumns{
+	"id":         "id",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
+	"start":      "start",
+	"status":     "status",
+}
+
+// recurringColumns selected columns of a recurring expense, in the order of scanRecurring.
+// The snapshots of the category & user are copied in the row, the expenses get the current ones when they are created.
+const recurringColumns = `id, created_at, updated_at, title, description, location, tags, total_amount, total_currency, project_id,
+	category_id, category_name, category_slug, category_color, category_icon,
+	user_id, user_name, user_email, user_is_active,
+	rrule, start, status, scheduled_until, skipped`
+
+// scanRecurring the destinations of recurringColumns, skipped is scanned into the dates
+func scanRecurring(r *RecurringExpense, skipped *[]string) []interface{} {
+	category, user := &r.Category, &r.InsertedBy
+	return []interface{}{objectID{&r.ID}, &r.CreatedAt, &r.UpdatedAt, &r.Title, &r.Description, &r.Location,
+		pq.Array(&r.Tags), &r.Total.Amount, &r.Total.Currency, objectID{&r.ProjectID},
+		objectID{&category.ID}, &category.Name, &category.Slug, &category.Color, &category.Icon,
+		objectID{&user.ID}, &user.Name, &user.Email, &user.IsActive,
+		&r.RRule, &r.Start, &r.Status, &r.ScheduledUntil, pq.Array(skipped)}
+}
+
+// skippedDates the skipped dates stored as YYYY-MM-DD
+func skippedDates(r *RecurringExpense, skipped []string) error {
+	r.Skipped = make([]time.Time, 0, len(skipped))
+	for _, s := range skipped {
+		date, err := time.Parse(DateLayout, s)
+		if err != nil {
+			return err
+		}
+		r.Skipped = append(r.Skipped, date)
+	}
+	if r.Tags == nil {
+		r.Tags = []string{}
+	}
+	return nil
+}
+
+// formatDates the dates as YYYY-MM-DD
+func formatDates(dates []time.Time) []string {
+	out := make([]string, 0, len(dates))
+	for _, date := range dates {
+		out = append(out, date.Format(DateLayout))
+	}
+	return out
+}
+
+// PostgresRecurringExpenseModel RecurringExpenseModeler of the recurring_expenses table
+type PostgresRecurringExpenseModel struct {
+	db *sql.DB
+}
+
+// NewPostgresRecurringExpenseModel godoc
+func NewPostgresRecurringExpenseModel(db *sql.DB) *PostgresRecurringExpenseModel {
+	return &PostgresRecurringExpenseModel{db}
+}
+
+// Insert insert a row in the recurring_expenses table
+func (r *PostgresRecurringExpenseModel) Insert(ctx context.Context, recurring *RecurringExpense) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	tags := recurring.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	category, user := recurring.Category, recurring.InsertedBy
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO recurring_expenses (`+recurringColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)`,
+		recurring.ID.Hex(), recurring.CreatedAt, recurring.UpdatedAt, recurring.Title, recurring.Description,
+		recurring.Location, pq.Array(tags), recurring.Total.Amount, recurring.Total.Currency, nullableID(recurring.ProjectID),
+		category.ID.Hex(), category.Name, category.Slug, category.Color, category.Icon,
+		user.ID.Hex(), user.Name, user.Email, user.IsActive,
+		recurring.RRule, recurring.Start, recurring.Status, recurring.ScheduledUntil, pq.Array(formatDates(recurring.Skipped)))
+	if err != nil {
+		log.Printf("Error on inserting new recurring expense: %v\n", err)
+		return nil, dbError(err)
+	}
+	return recurring.ID, nil
+}
+
+// ReadAll read a page of the recurring expenses
+func (r *PostgresRecurringExpenseModel) ReadAll(ctx context.Context, f RecurringFilter, opts ListOptions) ([]RecurringExpense, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	recurring := []RecurringExpense{}
+	q := sqlQuery{}
+	if !f.UserID.IsZero() {
+		q.where("user_id = " + q.arg(f.UserID.Hex()))
+	}
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, 0, len(f.Statuses))
+		for _, status := range f.Statuses {
+			statuses = append(statuses, string(status))
+		}
+		q.where("status = ANY(" + q.arg(pq.Array(statuses)) + ")")
+	}
+	page, err := selectPage(ctx, r.db, recurringColumns, "recurring_expenses", q, opts, RecurringSQLColumns, "id", func(rows *sql.Rows, keys ...interface{}) error {
+		var series RecurringExpense
+		var skipped []string
+		if err := rows.Scan(append(scanRecurring(&series, &skipped), keys...)...); err != nil {
+			return err
+		}
+		if err := skippedDates(&series, skipped); err != nil {
+			return err
+		}
+		recurring = append(recurring, series)
+		return nil
+	})
+	return recurring, page, dbError(err)
+}
+
+// ReadOne read a single recurring expense
+func (r *PostgresRecurringExpenseModel) ReadOne(ctx context.Context, id primitive.ObjectID) (RecurringExpense, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var recurring RecurringExpense
+	var skipped []string
+	err := r.db.QueryRowContext(ctx, "SELECT "+recurringColumns+" FROM recurring_expenses WHERE id = $1", id.Hex()).
+		Scan(scanRecurring(&recurring, &skipped)...)
+	if err != nil {
+		return RecurringExpense{}, rowError(err, "recurring expense")
+	}
+	return recurring, dbError(skippedDates(&recurring, skipped))
+}
+
+// UpdateOne change the status, the schedule or the skipped occurrences of one recurring expense
+func (r *PostgresRecurringExpenseModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update RecurringUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	q := sqlQuery{}
+	set := []string{"updated_at = " + q.arg(time.Now())}
+	if update.Status != nil {
+		set = append(set, "status = "+q.arg(*update.Status))
+	}
+	if update.ScheduledUntil != nil {
+		set = append(set, "scheduled_until = "+q.arg(*update.ScheduledUntil))
+	}
+	if update.Skipped != nil {
+		set = append(set, "skipped = "+q.arg(pq.Array(formatDates(update.Skipped))))
+	}
+	q.where("id = " + q.arg(id.Hex()))
+	count, err := rowsAffected(r.db.ExecContext(ctx, "UPDATE recurring_expenses SET "+joinSet(set)+q.clause(), q.args...))
+	if err != nil {
+		log.Printf("Error on updating one recurring expense: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// ReassignCategory move the recurring expenses of the category to another one
+func (r *PostgresRecurringExpenseModel) ReassignCategory(ctx context.Context, from primitive.ObjectID, to CategorySnapshot) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(r.db.ExecContext(ctx,
+		`UPDATE recurring_expenses SET category_id = $1, category_name = $2, category_slug = $3, category_color = $4, category_icon = $5,
+		updated_at = $6 WHERE category_id = $7`,
+		to.ID.Hex(), to.Name, to.Slug, to.Color, to.Icon, time.Now(), from.Hex()))
+	if err != nil {
+		log.Printf("Error on reassigning the category of recurring expenses: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// CountCategory count the recurring expenses of the category which are not ended
+func (r *PostgresRecurringExpenseModel) CountCategory(ctx context.Context, categoryID primitive.ObjectID) (int64, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var count int64
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM recurring_expenses WHERE category_id = $1 AND status <> $2`,
+		categoryID.Hex(), RecurringEnded).Scan(&count)
+	return count, dbError(err)
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// settlementColumns selected columns of a settlement, in the order of scanSettlement
+const settlementColumns = "id, created_at, project_id, from_id, to_id, amount, currency, date, note, inserted_by"
+
+// scanSettlement the destinations of settlementColumns
+func scanSettlement(settlement *Settlement) []interface{} {
+	return []interface{}{objectID{&settlement.ID}, &settlement.CreatedAt, objectID{&settlement.ProjectID},
+		objectID{&settlement.FromID}, objectID{&settlement.ToID}, &settlement.Amount.Amount, &settlement.Amount.Currency,
+		&settlement.Date, &settlement.Note, objectID{&settlement.InsertedBy}}
+}
+
+// PostgresSettlementModel SettlementModeler of the settlements table
+type PostgresSettlementModel struct {
+	db *sql.DB
+}
+
+// NewPostgresSettlementModel godoc
+func NewPostgresSettlementModel(db *sql.DB) *PostgresSettlementModel {
+	return &PostgresSettlementModel{db}
+}
+
+// Insert insert a row in the settlements table
+func (s *PostgresSettlementModel) Insert(ctx context.Context, settlement *Settlement) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO settlements (id, created_at, project_id, from_id, to_id, amount, currency, date, note, inserted_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		settlement.ID.Hex(), settlement.CreatedAt, settlement.ProjectID.Hex(), settlement.FromID.Hex(), settlement.ToID.Hex(),
+		settlement.Amount.Amount, settlement.Amount.Currency, settlement.Date, settlement.Note, settlement.InsertedBy.Hex())
+	if err != nil {
+		log.Printf("Error on inserting new settlement: %v\n", err)
+		return nil, dbError(err)
+	}
+	return settlement.ID, nil
+}
+
+// ReadAll read every settlement of the project, oldest first
+func (s *PostgresSettlementModel) ReadAll(ctx context.Context, projectID primitive.ObjectID) ([]Settlement, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	settlements := []Settlement{}
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT "+settlementColumns+" FROM settlements WHERE project_id = $1 ORDER BY date, id", projectID.Hex())
+	if err != nil {
+		return settlements, dbError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var settlement Settlement
+		if err := rows.Scan(scanSettlement(&settlement)...); err != nil {
+			return settlements, dbError(err)
+		}
+		settlements = append(settlements, settlement)
+	}
+	return settlements, dbError(rows.Err())
+}
+
+// ReadOne read a single settlement
+func (s *PostgresSettlementModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Settlement, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var settlement Settlement
+	err := s.db.QueryRowContext(ctx, "SELECT "+settlementColumns+" FROM settlements WHERE id = $1", id.Hex()).
+		Scan(scanSettlement(&settlement)...)
+	if err != nil {
+		return Settlement{}, rowError(err, "settlement")
+	}
+	return settlement, nil
+}
+
+// RemoveOne remove one settlement from the settlements table
+func (s *PostgresSettlementModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(s.db.ExecContext(ctx, `DELETE FROM settlements WHERE id = $1`, id.Hex()))
+	if err != nil {
+		log.Printf("Error on deleting one settlement: %v\n", err)
+	}
+	return count, dbError(err)
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExpenseFilterSQL(t *testing.T) {
+	category := primitive.NewObjectID()
+	f := ExpenseFilter{
+		Start:      "2021-01-01",
+		Categories: []string{category.Hex()},
+		Statuses:   []string{"submitted", "approved"},
+		MinTotal:   "10.5",
+		Currency:   "EUR",
+		Tags:       []string{" Travel", "travel", "hotel"},
+		Text:       "50%_off",
+	}
+	q := sqlQuery{}
+	assert.NoError(t, f.toSQL(&q))
+	assert.Equal(t, []string{
+		"e.date >= $1",
+		"e.category_id = ANY($2)",
+		"e.status = ANY($3)",
+		"e.total_currency = $4",
+		"e.total_amount >= $5",
+		"e.tags @> $6::TEXT[]",
+		"(e.title ILIKE $7 OR e.description ILIKE $7 OR e.location ILIKE $7)",
+	}, q.conds)
+	assert.Equal(t, []interface{}{
+		time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		pq.Array([]string{category.Hex()}),
+		pq.Array([]string{"submitted", "approved"}),
+		"EUR",
+		int64(1050),
+		pq.Array([]string{"travel", "hotel"}),
+		`%50\%\_off%`,
+	}, q.args)
+
+	empty := sqlQuery{}
+	assert.NoError(t, ExpenseFilter{}.toSQL(&empty))
+	assert.Equal(t, "", empty.clause())
+
+	project, author := primitive.NewObjectID(), primitive.NewObjectID()
+	scoped := sqlQuery{}
+	assert.NoError(t, ExpenseFilter{Scope: &ExpenseScope{Projects: []primitive.ObjectID{project}, Author: author}}.toSQL(&scoped))
+	assert.Equal(t, []string{"(e.project_id = ANY($1) OR (e.project_id IS NULL AND e.user_id = $2))"}, scoped.conds)
+	assert.Equal(t, []interface{}{pq.Array([]string{project.Hex()}), author.Hex()}, scoped.args)
+}
+
+func TestKeysetCondition(t *testing.T) {
+	q := sqlQuery{}
+	q.where("e.status = " + q.arg("draft"))
+	cond := keysetCondition(&q, []string{"e.date", "e.id"}, []bool{true, false}, []interface{}{"2021-01-01T00:00:00Z", "abc"})
+	assert.Equal(t, "(((e.date < $2 OR e.date IS NULL)) OR (e.date = $2 AND e.id > $3))", cond)
+	assert.Equal(t, []interface{}{"draft", "2021-01-01T00:00:00Z", "abc"}, q.args)
+
+	// the nulls sort first
+	q = sqlQuery{}
+	cond = keysetCondition(&q, []string{"e.project_id", "e.id"}, []bool{false, false}, []interface{}{nil, "abc"})
+	assert.Equal(t, "((e.project_id IS NOT NULL) OR (e.project_id IS NULL AND e.id > $1))", cond)
+	assert.Equal(t, []interface{}{"abc"}, q.args)
+	q = sqlQuery{}
+	cond = keysetCondition(&q, []string{"e.project_id", "e.id"}, []bool{true, false}, []interface{}{nil, "abc"})
+	assert.Equal(t, "((e.project_id IS NULL AND e.id > $1))", cond)
+}
+
+func TestSQLCursor(t *testing.T) {
+	date := time.Date(2021, 3, 4, 5, 6, 7, 8, time.UTC)
+	cursor, err := encodeSQLCursor([]interface{}{date, int64(1250), []byte("5f1e2c"), true, nil})
+	assert.NoError(t, err)
+
+	values, err := decodeSQLCursor(cursor, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"2021-03-04T05:06:07.000000008Z", "1250", "5f1e2c", true, nil}, values)
+
+	_, err = decodeSQLCursor(cursor, 2)
+	assert.Equal(t, ErrInvalidCursor, err)
+	_, err = decodeSQLCursor("not a cursor", 5)
+	assert.Equal(t, ErrInvalidCursor, err)
+}
+
+func TestObjectIDScan(t *testing.T) {
+	id := primitive.NewObjectID()
+	var scanned primitive.ObjectID
+	assert.NoError(t, objectID{&scanned}.Scan([]byte(id.Hex())))
+	assert.Equal(t, id, scanned)
+	assert.NoError(t, objectID{&scanned}.Scan(nil))
+	assert.True(t, scanned.IsZero())
+	assert.Error(t, objectID{&scanned}.Scan("zz"))
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserSQLColumns sortable columns of the users table
+var UserSQLColumns = SQLColumns{
+	"id":           "id",
+	"created_at":   "created_at",
+	"updated_at":   "updated_at",
+	"email":        "email",
+	"phone_number": "phone_number",
+	"name":         "name",
+	"role":         "role",
+	"is_active":    "is_active",
+}
+
+// userColumns selected columns of a user, in the order of scanUser
+const userColumns = "id, created_at, updated_at, email, phone_number, name, role, is_active, password_hash, password_changed_at, locale"
+
+// scanUser the destinations of userColumns
+func scanUser(user *User) []interface{} {
+	return []interface{}{
+		objectID{&user.ID}, &user.CreatedAt, &user.UpdatedAt, &user.Email,
+		&user.PhoneNumber, &user.Name, &user.Role, &user.IsActive, &user.PasswordHash, &user.PasswordChangedAt, &user.Locale,
+	}
+}
+
+// PostgresUserModel UserModel of the users table
+type PostgresUserModel struct {
+	db *sql.DB
+}
+
+// NewPostgresUserModel godoc
+func NewPostgresUserModel(db *sql.DB) *PostgresUserModel {
+	return &PostgresUserModel{db}
+}
+
+// InsertNewUser insert a row in the users table
+func (c *PostgresUserModel) InsertNewUser(ctx context.Context, user *User) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	_, err := c.db.ExecContext(ctx,
+		`INSERT INTO users (id, created_at, updated_at, email, phone_number, name, role, is_active, password_hash, password_changed_at, locale)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		user.ID.Hex(), user.CreatedAt, user.UpdatedAt, user.Email, user.PhoneNumber,
+		user.Name, user.Role, user.IsActive, user.PasswordHash, user.PasswordChangedAt, user.Locale)
+	if err != nil {
+		log.Printf("Error on inserting new User: %v\n", err)
+		return nil, dbError(err)
+	}
+	return user.ID, nil
+}
+
+// ReadOneUser read a single user
+func (c *PostgresUserModel) ReadOneUser(ctx context.Context, q UserQuery) (User, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var user User
+	query := sqlQuery{}
+	if !q.ID.IsZero() {
+		query.where("id = " + query.arg(q.ID.Hex()))
+	}
+	if q.Email != "" {
+		query.where("email = " + query.arg(q.Email))
+	}
+	if len(query.conds) == 0 {
+		// an empty query must not match the first user
+		return user, notFound("user")
+	}
+	err := c.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users"+query.clause()+" LIMIT 1", query.args...).
+		Scan(scanUser(&user)...)
+	if err != nil {
+		return User{}, rowError(err, "user")
+	}
+	return user, nil
+}
+
+// ReadAllUsers read a page of the users
+func (c *PostgresUserModel) ReadAllUsers(ctx context.Context, f UserFilter, opts ListOptions) ([]*User, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	users := []*User{}
+	q := sqlQuery{}
+	if f.IsActive != nil {
+		q.where("is_active = " + q.arg(*f.IsActive))
+	}
+	if f.Role != "" {
+		q.where("role = " + q.arg(f.Role))
+	}
+	page, err := selectPage(ctx, c.db, userColumns, "users", q, opts, UserSQLColumns, "id", func(rows *sql.Rows, keys ...interface{}) error {
+		var user User
+		if err := rows.Scan(append(scanUser(&user), keys...)...); err != nil {
+			return err
+		}
+		users = append(users, &user)
+		return nil
+	})
+	return users, page, dbError(err)
+}
+
+// RemoveOneUser remove one user from the users table
+func (c *PostgresUserModel) RemoveOneUser(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	count, err := rowsAffected(c.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id.Hex()))
+	if err != nil {
+		log.Printf("Error on deleting one user: %v\n", err)
+	}
+	return count, dbError(err)
+}
+
+// UpdateOneUser update one user of the users table
+func (c *PostgresUserModel) UpdateOneUser(ctx context.Context, id primitive.ObjectID, update UserUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	q := sqlQuery{}
+	now := q.arg(time.Now())
+	set := []string{"updated_at = " + now}
+	if update.Name != nil {
+		set = append(set, "name = "+q.arg(*update.Name))
+	}
+	if update.IsActive != nil {
+		set = append(set, "is_active = "+q.arg(*update.IsActive))
+	}
+	if update.PasswordHash != nil {
+		set = append(set, "password_hash = "+q.arg(*update.PasswordHash), "password_changed_at = "+now)
+	}
+	if update.Locale != nil {
+		set = append(set, "locale = "+q.arg(*update.Locale))
+	}
+	q.where("id = " + q.arg(id.Hex()))
+	count, err := rowsAffected(c.db.ExecContext(ctx, "UPDATE users SET "+joinSet(set)+q.clause(), q.args...))
+	if err != nil {
+		log.Printf("Error on updating one user: %v\n", err)
+	}
+	return count, dbError(err)
+}
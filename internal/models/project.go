@@ -13,13 +13,15 @@ import (
 
 // ProjectDetails collection structure
 type ProjectDetails struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
-	Title       string             `json:"title" bson:"title"`
-	Description string             `json:"description" bson:"description"`
-	Expenses    []Expense          `json:"expenses" bson:"expenses"`
-	Users       []User             `json:"users" bson:"users"`
+	ID           primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+	Title        string             `json:"title" bson:"title"`
+	Description  string             `json:"description" bson:"description"`
+	BaseCurrency string             `json:"base_currency" bson:"base_currency"`
+	Total        Money              `json:"total" bson:"-"` // sum of the expenses in the base currency
+	Expenses     []Expense          `json:"expenses" bson:"expenses"`
+	Users        []ProjectMember    `json:"users" bson:"users"`
 }
 
 // ProjectDetailsQS Query String parser for project details query
@@ -31,38 +33,99 @@ type ProjectDetailsQS struct {
 
 // Project collection structure
 type Project struct {
-	ID          primitive.ObjectID `json:"id" bson:"_id"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
-	Title       string             `json:"title" bson:"title" validate:"required,alpha"`
-	Description string             `json:"description" bson:"description" validate:"required,alpha"`
-	IsActive    bool               `json:"is_active" bson:"is_active" validate:"required"`
+	ID           primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at" bson:"updated_at"`
+	Title        string             `json:"title" bson:"title" validate:"required,alpha"`
+	Description  string             `json:"description" bson:"description" validate:"required,alpha"`
+	BaseCurrency string             `json:"base_currency" bson:"base_currency" validate:"required,currency"`
+	IsActive     bool               `json:"is_active" bson:"is_active" validate:"required"`
 }
 
 // ProjectUser collection structure for projectUser
+// the membership references the user account with a per project role
 type ProjectUser struct {
-	ID          primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
-	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
-	ProjectID   primitive.ObjectID `json:"project_id" bson:"project_id"`
-	Email       string             `json:"email" bson:"email" validate:"required,email"`                 // TODO: needs to decide of if we want to make it unique
-	PhoneNumber string             `json:"phone_number" bson:"phone_number" validate:"required,numeric"` // TODO: needs to decide of if we want to make it unique
-	Name        string             `json:"name" bson:"name" validate:"required,alpha"`
-	Role        Role               `json:"role" bson:"role" validate:"required,oneof=ADMIN SUPERVISOR STAFF USER"`
-	IsActive    bool               `json:"is_active" bson:"is_active" validate:"required"`
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ProjectID primitive.ObjectID `json:"project_id" bson:"project_id"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Role      Role               `json:"role" bson:"role"`
+	IsActive  bool               `json:"is_active" bson:"is_active"`
+}
+
+// ProjectUserInput input model to add a user to a project
+type ProjectUserInput struct {
+	UserID string `json:"user_id" validate:"required"`
+	Role   Role   `json:"role" validate:"required,oneof=ADMIN SUPERVISOR STAFF USER"`
+}
+
+// ProjectMember project membership joined with the user account
+type ProjectMember struct {
+	ProjectUser `bson:",inline"`
+	User        User `json:"user" bson:"user"`
+}
+
+// UserProject project membership joined with the project
+type UserProject struct {
+	ProjectUser `bson:",inline"`
+	Project     Project `json:"project" bson:"project"`
+}
+
+// ProjectFilter filter of the project list, zero fields match every project
+type ProjectFilter struct {
+	Title string
+}
+
+// ProjectUpdate fields of the project to update, nil fields are kept
+type ProjectUpdate struct {
+	IsActive *bool
+}
+
+// ProjectUserFilter filter of the project memberships, zero fields match every membership
+type ProjectUserFilter struct {
+	ID        primitive.ObjectID
+	ProjectID primitive.ObjectID
+	UserID    primitive.ObjectID
+	IsActive  *bool
+}
+
+// ProjectUserUpdate fields of the membership to update, nil fields are kept
+type ProjectUserUpdate struct {
+	IsActive *bool
+}
+
+// toBSON build the mongo filter of the memberships
+func (f ProjectUserFilter) toBSON() bson.D {
+	filter := bson.D{}
+	if !f.ID.IsZero() {
+		filter = append(filter, bson.E{Key: "_id", Value: f.ID})
+	}
+	if !f.ProjectID.IsZero() {
+		filter = append(filter, bson.E{Key: "project_id", Value: f.ProjectID})
+	}
+	if !f.UserID.IsZero() {
+		filter = append(filter, bson.E{Key: "user_id", Value: f.UserID})
+	}
+	if f.IsActive != nil {
+		filter = append(filter, bson.E{Key: "is_active", Value: *f.IsActive})
+	}
+	return filter
 }
 
 // ProjectModeler godoc
 type ProjectModeler interface {
-	Insert(project *Project) (interface{}, error)
-	ReadAll(filter interface{}) ([]Project, error)
-	ReadOne(filter interface{}) (Project, error)
-	UpdateOne(updatedData interface{}, filter interface{}) (int64, error)
-	LookupProjectDetails(filter bson.D, expfilter ProjectDetailsQS) (ProjectDetails, error)
-	InsertProjectUser(projectUser *ProjectUser) (interface{}, error)
-	ReadAllProjectUser(filter interface{}) ([]ProjectUser, error)
-	ReadOneProjectUser(filter interface{}) (ProjectUser, error)
-	UpdateOneProjectUser(updatedData interface{}, filter interface{}) (int64, error)
+	Insert(ctx context.Context, project *Project) (interface{}, error)
+	ReadAll(ctx context.Context, f ProjectFilter, opts ListOptions) ([]Project, Page, error)
+	ReadOne(ctx context.Context, id primitive.ObjectID) (Project, error)
+	UpdateOne(ctx context.Context, id primitive.ObjectID, update ProjectUpdate) (int64, error)
+	LookupProjectDetails(ctx context.Context, id primitive.ObjectID, expfilter ProjectDetailsQS) (ProjectDetails, error)
+	InsertProjectUser(ctx context.Context, projectUser *ProjectUser) (interface{}, error)
+	ReadAllProjectUser(ctx context.Context, f ProjectUserFilter) ([]ProjectUser, error)
+	ReadAllProjectMembers(ctx context.Context, f ProjectUserFilter) ([]ProjectMember, error)
+	ReadAllUserProjects(ctx context.Context, f ProjectUserFilter) ([]UserProject, error)
+	ReadOneProjectUser(ctx context.Context, f ProjectUserFilter) (ProjectUser, error)
+	UpdateOneProjectUser(ctx context.Context, f ProjectUserFilter, update ProjectUserUpdate) (int64, error)
 }
 
 // ProjectModel godoc
@@ -76,67 +139,75 @@ func NewProjectModel(db db.MongoDBClient) *ProjectModel {
 }
 
 // Insert insert a record at projects collection
-func (c *ProjectModel) Insert(project *Project) (interface{}, error) {
+func (c *ProjectModel) Insert(ctx context.Context, project *Project) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("projects")
-	insertResult, err := collection.InsertOne(context.TODO(), project)
+	insertResult, err := collection.InsertOne(ctx, project)
 	if err != nil {
-		log.Fatalf("Error on inserting new project: %v\n", err)
-		return nil, err
+		log.Printf("Error on inserting new project: %v\n", err)
+		return nil, dbError(err)
 	}
 	return insertResult.InsertedID, nil
 }
 
-// ReadAll read all the projects
-func (c *ProjectModel) ReadAll(filter interface{}) ([]Project, error) {
-	var projects []Project
+// ReadAll read a page of the projects
+func (c *ProjectModel) ReadAll(ctx context.Context, f ProjectFilter, opts ListOptions) ([]Project, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	projects := []Project{}
 	collection := c.db.Client.Database(c.db.DBName).Collection("projects")
-
-	cur, err := collection.Find(context.TODO(), filter)
-	if err != nil {
-		log.Printf("ERROR FINDING DATA: %v\n", err)
-		return projects, err
+	filter := bson.D{}
+	if f.Title != "" {
+		filter = append(filter, bson.E{Key: "title", Value: f.Title})
 	}
-	for cur.Next(context.TODO()) {
+	page, err := findPage(ctx, collection, filter, opts, ProjectListFields, func(cur *mongo.Cursor) error {
 		var project Project
-
-		err = cur.Decode(&project)
-		if err != nil {
-			log.Printf("Error on Decoding the document: %v\n", err)
+		if err := cur.Decode(&project); err != nil {
+			return err
 		}
-
 		projects = append(projects, project)
-	}
-	log.Printf("documentReturned: %v\n", projects)
-	return projects, nil
+		return nil
+	})
+	return projects, page, dbError(err)
 }
 
 // ReadOne read a single project
-func (c *ProjectModel) ReadOne(filter interface{}) (Project, error) {
+func (c *ProjectModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Project, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
 	var project Project
 	collection := c.db.Client.Database(c.db.DBName).Collection("projects")
-	projectReturned := collection.FindOne(context.TODO(), filter)
-	projectReturned.Decode(&project)
-	return project, nil
+	err := findOne(ctx, collection, bson.M{"_id": id}, "project", &project)
+	return project, err
 }
 
 // UpdateOne update one project from collections
-func (c *ProjectModel) UpdateOne(updatedData interface{}, filter interface{}) (int64, error) {
+func (c *ProjectModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update ProjectUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("projects")
+	updatedData := bson.M{"updated_at": time.Now()}
+	if update.IsActive != nil {
+		updatedData["is_active"] = *update.IsActive
+	}
 	atualizacao := bson.D{{Key: "$set", Value: updatedData}}
-	deleteResult, err := collection.UpdateOne(context.TODO(), filter, atualizacao)
+	deleteResult, err := collection.UpdateOne(ctx, bson.M{"_id": id}, atualizacao)
 	if err != nil {
-		log.Fatal("Error on updating one Project", err)
-		return 0, err
+		log.Printf("Error on updating one Project: %v\n", err)
+		return 0, dbError(err)
 	}
 	return deleteResult.ModifiedCount, nil
 }
 
 // LookupProjectDetails parse all the project details with the project_id
-func (c *ProjectModel) LookupProjectDetails(projectFilter bson.D, qsFilter ProjectDetailsQS) (ProjectDetails, error) {
+func (c *ProjectModel) LookupProjectDetails(ctx context.Context, id primitive.ObjectID, qsFilter ProjectDetailsQS) (ProjectDetails, error) {
+	ctx, cancel := reportContext(ctx)
+	defer cancel()
 	var project ProjectDetails
 	collection := c.db.Client.Database(c.db.DBName).Collection("projects")
 	pipeline := mongo.Pipeline{
-		{{"$match", projectFilter}},
+		{{Key: "$match", Value: bson.D{{Key: "_id", Value: id}}}},
 		{{"$lookup", bson.D{
 			{"from", "expenses"},
 			{"localField", "_id"},
@@ -152,10 +223,17 @@ func (c *ProjectModel) LookupProjectDetails(projectFilter bson.D, qsFilter Proje
 			{"foreignField", "project_id"},
 			{"as", "users"},
 		}}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "users"},
+			{Key: "localField", Value: "users.user_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "accounts"},
+		}}},
 		{{"$project", bson.D{
 			{"_id", 1},
 			{"title", 1},
 			{"description", 1},
+			{Key: "base_currency", Value: 1},
 			{"created_at", 1},
 			{"updated_at", 1},
 			{"expenses", bson.D{
@@ -170,56 +248,84 @@ func (c *ProjectModel) LookupProjectDetails(projectFilter bson.D, qsFilter Proje
 					}},
 				}},
 			}},
+			// join every membership with its user account
 			{"users", bson.D{
-				{"$filter", bson.D{
-					{"input", "$users"},
-					{"as", "user"},
-					{"cond", bson.D{
-						{"$eq", bson.A{"$$user.is_active", qsFilter.IsActive}},
+				{Key: "$map", Value: bson.D{
+					{Key: "input", Value: bson.D{
+						{Key: "$filter", Value: bson.D{
+							{Key: "input", Value: "$users"},
+							{Key: "as", Value: "user"},
+							{Key: "cond", Value: bson.D{
+								{Key: "$eq", Value: bson.A{"$$user.is_active", qsFilter.IsActive}},
+							}},
+						}},
+					}},
+					{Key: "as", Value: "member"},
+					{Key: "in", Value: bson.D{
+						{Key: "$mergeObjects", Value: bson.A{
+							"$$member",
+							bson.D{{Key: "user", Value: bson.D{
+								{Key: "$arrayElemAt", Value: bson.A{
+									bson.D{{Key: "$filter", Value: bson.D{
+										{Key: "input", Value: "$accounts"},
+										{Key: "as", Value: "account"},
+										{Key: "cond", Value: bson.D{{Key: "$eq", Value: bson.A{"$$account._id", "$$member.user_id"}}}},
+									}}},
+									0,
+								}},
+							}}},
+						}},
 					}},
 				}},
 			}},
 		}}},
 	}
 
-	cur, err := collection.Aggregate(context.TODO(), pipeline)
+	cur, err := collection.Aggregate(ctx, pipeline)
 	if err != nil {
 		log.Printf("ERROR FINDING DATA: %v\n", err)
-		return project, err
+		return project, dbError(err)
 	}
-	for cur.Next(context.TODO()) {
-		err = cur.Decode(&project)
-		if err != nil {
-			log.Printf("Error on Decoding the document: %v\n", err)
-			return project, err
+	defer cur.Close(ctx)
+	if !cur.Next(ctx) {
+		if err := cur.Err(); err != nil {
+			return project, dbError(err)
 		}
+		return project, notFound("project")
+	}
+	if err := cur.Decode(&project); err != nil {
+		log.Printf("Error on Decoding the document: %v\n", err)
+		return project, dbError(err)
 	}
-
 	return project, nil
 }
 
 // InsertProjectUser insert a record at projectUsers collection
-func (c *ProjectModel) InsertProjectUser(user *ProjectUser) (interface{}, error) {
+func (c *ProjectModel) InsertProjectUser(ctx context.Context, user *ProjectUser) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("projectUsers")
-	insertResult, err := collection.InsertOne(context.TODO(), user)
+	insertResult, err := collection.InsertOne(ctx, user)
 	if err != nil {
-		log.Fatalf("Error on inserting new project user: %v\n", err)
-		return nil, err
+		log.Printf("Error on inserting new project user: %v\n", err)
+		return nil, dbError(err)
 	}
 	return insertResult.InsertedID, nil
 }
 
 // ReadAllProjectUser read all the projectUsers
-func (c *ProjectModel) ReadAllProjectUser(filter interface{}) ([]ProjectUser, error) {
+func (c *ProjectModel) ReadAllProjectUser(ctx context.Context, f ProjectUserFilter) ([]ProjectUser, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
 	var users []ProjectUser
 	collection := c.db.Client.Database(c.db.DBName).Collection("projectUsers")
 
-	cur, err := collection.Find(context.TODO(), filter)
+	cur, err := collection.Find(ctx, f.toBSON())
 	if err != nil {
 		log.Printf("ERROR FINDING DATA: %v\n", err)
-		return users, err
+		return users, dbError(err)
 	}
-	for cur.Next(context.TODO()) {
+	for cur.Next(ctx) {
 		var user ProjectUser
 
 		err = cur.Decode(&user)
@@ -233,23 +339,145 @@ func (c *ProjectModel) ReadAllProjectUser(filter interface{}) ([]ProjectUser, er
 	return users, nil
 }
 
+// ReadAllProjectMembers read all the projectUsers joined with their user account
+func (c *ProjectModel) ReadAllProjectMembers(ctx context.Context, f ProjectUserFilter) ([]ProjectMember, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var members []ProjectMember
+	collection := c.db.Client.Database(c.db.DBName).Collection("projectUsers")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: f.toBSON()}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "users"},
+			{Key: "localField", Value: "user_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "user"},
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return members, dbError(err)
+	}
+	if err := cur.All(ctx, &members); err != nil {
+		log.Printf("Error on Decoding the document: %v\n", err)
+		return members, dbError(err)
+	}
+	return members, nil
+}
+
+// ReadAllUserProjects read all the projectUsers joined with their project
+func (c *ProjectModel) ReadAllUserProjects(ctx context.Context, f ProjectUserFilter) ([]UserProject, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var projects []UserProject
+	collection := c.db.Client.Database(c.db.DBName).Collection("projectUsers")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: f.toBSON()}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "projects"},
+			{Key: "localField", Value: "project_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "project"},
+		}}},
+		{{Key: "$unwind", Value: "$project"}},
+	}
+
+	cur, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		log.Printf("ERROR FINDING DATA: %v\n", err)
+		return projects, dbError(err)
+	}
+	if err := cur.All(ctx, &projects); err != nil {
+		log.Printf("Error on Decoding the document: %v\n", err)
+		return projects, dbError(err)
+	}
+	return projects, nil
+}
+
 // ReadOneProjectUser read a single project user
-func (c *ProjectModel) ReadOneProjectUser(filter interface{}) (ProjectUser, error) {
+func (c *ProjectModel) ReadOneProjectUser(ctx context.Context, f ProjectUserFilter) (ProjectUser, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
 	var project ProjectUser
 	collection := c.db.Client.Database(c.db.DBName).Collection("projectUsers")
-	projectReturned := collection.FindOne(context.TODO(), filter)
-	projectReturned.Decode(&project)
-	return project, nil
+	err := findOne(ctx, collection, f.toBSON(), "project member", &project)
+	return project, err
 }
 
 // UpdateOneProjectUser remove one project user from collections
-func (c *ProjectModel) UpdateOneProjectUser(updatedData interface{}, filter interface{}) (int64, error) {
+func (c *ProjectModel) UpdateOneProjectUser(ctx context.Context, f ProjectUserFilter, update ProjectUserUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("projectUsers")
+	updatedData := bson.M{"updated_at": time.Now()}
+	if update.IsActive != nil {
+		updatedData["is_active"] = *update.IsActive
+	}
 	atualizacao := bson.D{{Key: "$set", Value: updatedData}}
-	deleteResult, err := collection.UpdateOne(context.TODO(), filter, atualizacao)
+	deleteResult, err := collection.UpdateOne(ctx, f.toBSON(), atualizacao)
 	if err != nil {
-		log.Fatal("Error on updating one Project User", err)
-		return 0, err
+		log.Printf("Error on updating one Project User: %v\n", err)
+		return 0, dbError(err)
 	}
 	return deleteResult.ModifiedCount, nil
 }
+
+// LinkProjectUsersToUsers migrate the legacy projectUsers documents, which copy
+// the email, phone_number & name of the user, to reference the user account.
+// Documents are matched to the users by email, unmatched ones are left untouched.
+func (c *ProjectModel) LinkProjectUsersToUsers() (linked int64, unmatched int64, err error) {
+	database := c.db.Client.Database(c.db.DBName)
+	projectUsers := database.Collection("projectUsers")
+	users := database.Collection("users")
+
+	cur, err := projectUsers.Find(context.TODO(), bson.M{"user_id": bson.M{"$exists": false}})
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cur.Close(context.TODO())
+
+	for cur.Next(context.TODO()) {
+		var legacy struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Email string             `bson:"email"`
+		}
+		if err := cur.Decode(&legacy); err != nil {
+			return linked, unmatched, err
+		}
+
+		var user User
+		err := users.FindOne(context.TODO(), bson.M{"email": legacy.Email}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			log.Printf("NO USER FOUND FOR PROJECT USER %s (%s)\n", legacy.ID.Hex(), legacy.Email)
+			unmatched++
+			continue
+		}
+		if err != nil {
+			return linked, unmatched, err
+		}
+
+		update := bson.M{
+			"$set":   bson.M{"user_id": user.ID, "updated_at": time.Now()},
+			"$unset": bson.M{"email": "", "phone_number": "", "name": ""},
+		}
+		if _, err := projectUsers.UpdateOne(context.TODO(), bson.M{"_id": legacy.ID}, update); err != nil {
+			return linked, unmatched, err
+		}
+		linked++
+	}
+	return linked, unmatched, cur.Err()
+}
+
+// MigrateMissingBaseCurrency set the base currency of the projects created before the multi currency support
+func (c *ProjectModel) MigrateMissingBaseCurrency(currency string) (int64, error) {
+	collection := c.db.Client.Database(c.db.DBName).Collection("projects")
+	filter := bson.M{"base_currency": bson.M{"$in": bson.A{nil, ""}}}
+	res, err := collection.UpdateMany(context.TODO(), filter, bson.M{"$set": bson.M{"base_currency": currency}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
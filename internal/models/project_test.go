@@ -0,0 +1,20 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestProjectUserFilterBSON(t *testing.T) {
+	project, user := primitive.NewObjectID(), primitive.NewObjectID()
+
+	assert.Equal(t, bson.D{}, ProjectUserFilter{}.toBSON())
+	assert.Equal(t, bson.D{
+		{Key: "project_id", Value: project},
+		{Key: "user_id", Value: user},
+		{Key: "is_active", Value: false},
+	}, ProjectUserFilter{ProjectID: project, UserID: user, IsActive: Bool(false)}.toBSON())
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RecurringStatus state of a recurring expense
+type RecurringStatus string
+
+// the states of the recurring expenses
+const (
+	RecurringActive RecurringStatus = "active"
+	RecurringPaused RecurringStatus = "paused" // no expense is created while paused, the missed ones are not caught up
+	RecurringEnded  RecurringStatus = "ended"  // the series is over, for good
+)
+
+// recurringHorizon how far the next occurrences of a series are looked for
+const recurringHorizon = 10 * 366 * 24 * time.Hour
+
+// RecurringExpense template of the expenses created on each date of its recurrence rule, see RRule.
+// The RecurringJob creates the draft expenses when they are due, up to ScheduledUntil.
+type RecurringExpense struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedAt      time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at" bson:"updated_at"`
+	Title          string             `json:"title" bson:"title"`
+	Description    string             `json:"description" bson:"description"`
+	Location       string             `json:"location" bson:"location"`
+	Tags           []string           `json:"tags" bson:"tags"`
+	Total          Money              `json:"total" bson:"total"`
+	ProjectID      primitive.ObjectID `json:"project_id" bson:"project_id"`
+	Category       CategorySnapshot   `json:"category" bson:"category"`
+	InsertedBy     UserSnapshot       `json:"user" bson:"user"`
+	RRule          string             `json:"rrule" bson:"rrule"`
+	Start          time.Time          `json:"start" bson:"start"`
+	Status         RecurringStatus    `json:"status" bson:"status"`
+	ScheduledUntil *time.Time         `json:"scheduled_until" bson:"scheduled_until"` // the last day the expenses were created for, nil before the first run
+	Skipped        []time.Time        `json:"skipped" bson:"skipped"`                 // the occurrences not created
+}
+
+// RecurringExpenseInput recurring expense create input model, the fields of an expense with the schedule instead of the date
+type RecurringExpenseInput struct {
+	Title       string      `json:"title" validate:"required"`
+	Description string      `json:"description" validate:"required"`
+	Location    string      `json:"location"`
+	Tags        []string    `json:"tags" validate:"max=20,dive,required,max=32"`
+	Total       json.Number `json:"total" validate:"required"`
+	Currency    string      `json:"currency" validate:"required,currency"`
+	CategoryID  string      `json:"category_id" validate:"required"`
+	ProjectID   string      `json:"project_id"`
+	RRule       string      `json:"rrule" validate:"required,rrule"` // e.g. FREQ=MONTHLY;BYMONTHDAY=1
+	Start       string      `json:"start" validate:"required,date"`  // the first possible date of the series
+}
+
+// RecurringSkipInput model for the skip endpoint
+type RecurringSkipInput struct {
+	Date string `json:"date" validate:"required,date"`
+}
+
+// NormalizedTags the tags of the input trimmed, lower cased and deduplicated
+func (i RecurringExpenseInput) NormalizedTags() []string {
+	return normalizeTags(i.Tags)
+}
+
+// Money the exact total of the input
+func (i RecurringExpenseInput) Money() (Money, error) {
+	return ParseMoney(i.Total.String(), i.Currency)
+}
+
+// IsSkipped check the occurrence of the date is skipped
+func (r RecurringExpense) IsSkipped(date time.Time) bool {
+	for _, skipped := range r.Skipped {
+		if skipped.Equal(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next the next occurrences not created yet, after ScheduledUntil and after the day, without the skipped ones.
+// The rule is expected to be valid, it was checked when the series was created.
+func (r RecurringExpense) Next(after time.Time, limit int) []time.Time {
+	rule, err := ParseRRule(r.RRule)
+	if err != nil {
+		return []time.Time{}
+	}
+	from := utcDay(after).AddDate(0, 0, 1)
+	if r.ScheduledUntil != nil && !r.ScheduledUntil.Before(from) {
+		from = utcDay(*r.ScheduledUntil).AddDate(0, 0, 1)
+	}
+	dates := []time.Time{}
+	for _, date := range rule.Between(r.Start, from, from.Add(recurringHorizon), 0) {
+		if r.IsSkipped(date) {
+			continue
+		}
+		dates = append(dates, date)
+		if limit > 0 && len(dates) == limit {
+			break
+		}
+	}
+	return dates
+}
+
+// RecurringFilter filter of the recurring expenses
+type RecurringFilter struct {
+	UserID   primitive.ObjectID // the series of the author, every series when zero
+	Statuses []RecurringStatus
+}
+
+// RecurringUpdate changes of a recurring expense, the nil fields are kept
+type RecurringUpdate struct {
+	Status         *RecurringStatus
+	ScheduledUntil *time.Time
+	Skipped        []time.Time
+}
+
+// RecurringListFields list fields of the recurring expenses
+var RecurringListFields = ListFields{Sort: map[string]string{
+	"id":         "_id",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
+	"start":      "start",
+	"status":     "status",
+}}
+
+// RecurringExpenseModeler godoc
+type RecurringExpenseModeler interface {
+	Insert(ctx context.Context, recurring *RecurringExpense) (interface{}, error)
+	ReadAll(ctx context.Context, f RecurringFilter, opts ListOptions) ([]RecurringExpense, Page, error)
+	ReadOne(ctx context.Context, id primitive.ObjectID) (RecurringExpense, error)
+	UpdateOne(ctx context.Context, id primitive.ObjectID, update RecurringUpdate) (int64, error)
+	CountCategory(ctx context.Context, categoryID primitive.ObjectID) (int64, error)
+	ReassignCategory(ctx context.Context, from primitive.ObjectID, to CategorySnapshot) (int64, error)
+}
+
+// RecurringExpenseModel godoc
+type RecurringExpenseModel struct {
+	db db.MongoDBClient
+}
+
+// NewRecurringExpenseModel godoc
+func NewRecurringExpenseModel(db db.MongoDBClient) *RecurringExpenseModel {
+	return &RecurringExpenseModel{db}
+}
+
+// recurringIndexes the active series read by the job, the series of an author
+var recurringIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "status", Value: 1}}, Options: options.Index().SetName("status")},
+	{Keys: bson.D{{Key: "user._id", Value: 1}}, Options: options.Index().SetName("user_id")},
+}
+
+// toBSON the mongo filter of the recurring expenses
+func (f RecurringFilter) toBSON() bson.D {
+	filter := bson.D{}
+	if !f.UserID.IsZero() {
+		filter = append(filter, bson.E{Key: "user._id", Value: f.UserID})
+	}
+	if len(f.Statuses) > 0 {
+		filter = append(filter, bson.E{Key: "status", Value: bson.M{"$in": f.Statuses}})
+	}
+	return filter
+}
+
+// Insert insert a record at recurringExpenses collection
+func (r *RecurringExpenseModel) Insert(ctx context.Context, recurring *RecurringExpense) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := r.db.Client.Database(r.db.DBName).Collection("recurringExpenses")
+	insertResult, err := collection.InsertOne(ctx, recurring)
+	if err != nil {
+		log.Printf("Error on inserting new recurring expense: %v\n", err)
+		return nil, dbError(err)
+	}
+	return insertResult.InsertedID, nil
+}
+
+// ReadAll read a page of the recurring expenses
+func (r *RecurringExpenseModel) ReadAll(ctx context.Context, f RecurringFilter, opts ListOptions) ([]RecurringExpense, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	recurring := []RecurringExpense{}
+	collection := r.db.Client.Database(r.db.DBName).Collection("recurringExpenses")
+	page, err := findPage(ctx, collection, f.toBSON(), opts, RecurringListFields, func(cur *mongo.Cursor) error {
+		var series RecurringExpense
+		if err := cur.Decode(&series); err != nil {
+			return err
+		}
+		recurring = append(recurring, series)
+		return nil
+	})
+	return recurring, page, dbError(err)
+}
+
+// ReadOne read a single recurring expense
+func (r *RecurringExpenseModel) ReadOne(ctx context.Context, id primitive.ObjectID) (RecurringExpense, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var recurring RecurringExpense
+	collection := r.db.Client.Database(r.db.DBName).Collection("recurringExpenses")
+	err := findOne(ctx, collection, bson.M{"_id": id}, "recurring expense", &recurring)
+	return recurring, err
+}
+
+// UpdateOne change the status, the schedule or the skipped occurrences of one recurring expense
+func (r *RecurringExpenseModel) UpdateOne(ctx context.Context, id primitive.ObjectID, update RecurringUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := r.db.Client.Database(r.db.DBName).Collection("recurringExpenses")
+	set := bson.M{"updated_at": time.Now()}
+	if update.Status != nil {
+		set["status"] = *update.Status
+	}
+	if update.ScheduledUntil != nil {
+		set["scheduled_until"] = *update.ScheduledUntil
+	}
+	if update.Skipped != nil {
+		set["skipped"] = update.Skipped
+	}
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		log.Printf("Error on updating one recurring expense: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// ReassignCategory move the recurring expenses of the category to another one
+func (r *RecurringExpenseModel) ReassignCategory(ctx context.Context, from primitive.ObjectID, to CategorySnapshot) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := r.db.Client.Database(r.db.DBName).Collection("recurringExpenses")
+	update := bson.M{"$set": bson.M{"category": to, "updated_at": time.Now()}}
+	updatedResult, err := collection.UpdateMany(ctx, bson.M{"category._id": from}, update)
+	if err != nil {
+		log.Printf("Error on reassigning the category of recurring expenses: %v\n", err)
+		return 0, dbError(err)
+	}
+	return updatedResult.ModifiedCount, nil
+}
+
+// CountCategory count the recurring expenses of the category which are not ended
+func (r *RecurringExpenseModel) CountCategory(ctx context.Context, categoryID primitive.ObjectID) (int64, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	collection := r.db.Client.Database(r.db.DBName).Collection("recurringExpenses")
+	count, err := collection.CountDocuments(ctx, bson.M{"category._id": categoryID, "status": bson.M{"$ne": RecurringEnded}})
+	return count, dbError(err)
+}
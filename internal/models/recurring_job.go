@@ -0,0 +1,226 @@
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"log"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecurringJob create the expenses of the active recurring expenses when they are due.
+// Each run creates the occurrences between the ScheduledUntil of the series and today, so the
+// occurrences missed while the server was down are caught up. The expense of an occurrence has
+// an id derived from the series & the date, an occurrence created twice is a duplicate key.
+type RecurringJob struct {
+	models Models
+	now    func() time.Time
+	wake   chan struct{}
+}
+
+// NewRecurringJob the job creating the expenses of the recurring expenses of the models
+func NewRecurringJob(m Models) *RecurringJob {
+	return &RecurringJob{models: m, now: time.Now, wake: make(chan struct{}, 1)}
+}
+
+// Wrap the models so the created & resumed series are run at once
+func (j *RecurringJob) Wrap(m Models) Models {
+	m.Recurring = recurringSeries{m.Recurring, j}
+	return m
+}
+
+// signal wake the job up, a pending signal already covers the new series
+func (j *RecurringJob) signal() {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run create the due expenses at once, every interval and when a series changes, until the context is done
+func (j *RecurringJob) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	if err := j.Materialize(ctx); err != nil {
+		log.Printf("RECURRING EXPENSE ERROR: %v\n", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-j.wake:
+		}
+		if err := j.Materialize(ctx); err != nil {
+			log.Printf("RECURRING EXPENSE ERROR: %v\n", err)
+		}
+	}
+}
+
+// Materialize create the due expenses of every active series, a failed series is retried on the next run
+func (j *RecurringJob) Materialize(ctx context.Context) error {
+	series, _, err := j.models.Recurring.ReadAll(ctx, RecurringFilter{Statuses: []RecurringStatus{RecurringActive}}, ListOptions{})
+	if err != nil {
+		return err
+	}
+	today := utcDay(j.now())
+	var failed error
+	for _, s := range series {
+		if err := j.materialize(ctx, s, today); err != nil {
+			log.Printf("Error on recurring expense %s: %v\n", s.ID.Hex(), err)
+			failed = err
+		}
+	}
+	return failed
+}
+
+// materialize create the occurrences of the series due up to today, then move its ScheduledUntil to today.
+// The series ends when its author or category is removed, or when no occurrence is left.
+func (j *RecurringJob) materialize(ctx context.Context, s RecurringExpense, today time.Time) error {
+	if s.ScheduledUntil != nil && !s.ScheduledUntil.Before(today) {
+		return nil
+	}
+	rule, err := ParseRRule(s.RRule)
+	if err != nil {
+		return err
+	}
+	from := s.Start
+	if s.ScheduledUntil != nil {
+		from = utcDay(*s.ScheduledUntil).AddDate(0, 0, 1)
+	}
+	due := rule.Between(s.Start, from, today.AddDate(0, 0, 1), 0)
+	if len(due) > 0 {
+		user, err := j.models.Users.ReadOneUser(ctx, UserQuery{ID: s.InsertedBy.ID})
+		if errs.Is(err, errs.NotFound) {
+			return j.end(ctx, s, "the author was removed")
+		}
+		if err != nil {
+			return err
+		}
+		category, err := j.models.Categories.ReadOne(ctx, s.Category.ID)
+		if errs.Is(err, errs.NotFound) {
+			return j.end(ctx, s, "the category was removed")
+		}
+		if err != nil {
+			return err
+		}
+		filing, err := j.canFile(ctx, s, user)
+		if err != nil {
+			return err
+		}
+		for _, date := range due {
+			if s.IsSkipped(date) {
+				continue
+			}
+			if !filing {
+				log.Printf("recurring expense %s of %s not created: the author can not file it\n", s.ID.Hex(), date.Format(DateLayout))
+				continue
+			}
+			if err := j.create(ctx, s, date, user, category); err != nil {
+				// the occurrences before the failed one are done
+				until := date.AddDate(0, 0, -1)
+				if _, uerr := j.models.Recurring.UpdateOne(ctx, s.ID, RecurringUpdate{ScheduledUntil: &until}); uerr != nil {
+					log.Printf("Error on updating recurring expense %s: %v\n", s.ID.Hex(), uerr)
+				}
+				return err
+			}
+		}
+	}
+
+	update := RecurringUpdate{ScheduledUntil: &today}
+	s.ScheduledUntil = &today
+	if len(s.Next(today, 1)) == 0 {
+		ended := RecurringEnded
+		update.Status = &ended
+	}
+	_, err = j.models.Recurring.UpdateOne(ctx, s.ID, update)
+	return err
+}
+
+// canFile check the author can still file the expenses of the series: an active user,
+// and an active member of the project of the series unless an ADMIN
+func (j *RecurringJob) canFile(ctx context.Context, s RecurringExpense, user User) (bool, error) {
+	if !user.IsActive {
+		return false, nil
+	}
+	if s.ProjectID.IsZero() || user.Role == RoleAdmin {
+		return true, nil
+	}
+	_, err := j.models.Projects.ReadOneProjectUser(ctx, ProjectUserFilter{ProjectID: s.ProjectID, UserID: user.ID, IsActive: Bool(true)})
+	if errs.Is(err, errs.NotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// create insert the draft expense of the occurrence, an occurrence already created is skipped
+func (j *RecurringJob) create(ctx context.Context, s RecurringExpense, date time.Time, user User, category Category) error {
+	now := j.now()
+	expense := Expense{
+		ID:          OccurrenceID(s.ID, date),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Date:        date,
+		Title:       s.Title,
+		Description: s.Description,
+		Location:    s.Location,
+		Tags:        append([]string{}, s.Tags...),
+		Total:       s.Total,
+		Status:      StatusDraft,
+		ProjectID:   s.ProjectID,
+		Category:    category.Snapshot(),
+		InsertedBy:  user.Snapshot(),
+		History:     []StatusTransition{},
+		Attachments: []Attachment{},
+	}
+	_, err := j.models.Expenses.Insert(ctx, expense)
+	if err == ErrDuplicateKey {
+		return nil
+	}
+	return err
+}
+
+// end end the series for good
+func (j *RecurringJob) end(ctx context.Context, s RecurringExpense, reason string) error {
+	log.Printf("recurring expense %s ended: %s\n", s.ID.Hex(), reason)
+	ended := RecurringEnded
+	_, err := j.models.Recurring.UpdateOne(ctx, s.ID, RecurringUpdate{Status: &ended})
+	return err
+}
+
+// OccurrenceID the id of the expense of the occurrence of the series on the date,
+// the timestamp of the date followed by the first bytes of the hash of the series & the date
+func OccurrenceID(seriesID primitive.ObjectID, date time.Time) primitive.ObjectID {
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[0:4], uint32(date.Unix()))
+	sum := sha256.Sum256([]byte(seriesID.Hex() + date.Format(DateLayout)))
+	copy(id[4:], sum[:8])
+	return id
+}
+
+// recurringSeries RecurringExpenseModeler waking the job up when a series is created or resumed
+type recurringSeries struct {
+	RecurringExpenseModeler
+	job *RecurringJob
+}
+
+// Insert insert the series and run the job
+func (r recurringSeries) Insert(ctx context.Context, recurring *RecurringExpense) (interface{}, error) {
+	id, err := r.RecurringExpenseModeler.Insert(ctx, recurring)
+	if err == nil {
+		r.job.signal()
+	}
+	return id, err
+}
+
+// UpdateOne update the series and run the job when it is active again
+func (r recurringSeries) UpdateOne(ctx context.Context, id primitive.ObjectID, update RecurringUpdate) (int64, error) {
+	count, err := r.RecurringExpenseModeler.UpdateOne(ctx, id, update)
+	if err == nil && count > 0 && update.Status != nil && *update.Status == RecurringActive {
+		r.job.signal()
+	}
+	return count, err
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecurringJob(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	job := NewRecurringJob(m)
+	today := time.Date(2021, 3, 10, 9, 30, 0, 0, time.UTC)
+	job.now = func() time.Time { return today }
+
+	rent := Category{ID: primitive.NewObjectID(), Name: "Rent", Slug: "rent"}
+	alice := User{ID: primitive.NewObjectID(), Name: "alice", Email: "alice@example.com", Role: RoleStaff, IsActive: true}
+	_, err := m.Categories.Insert(ctx, &rent)
+	assert.NoError(t, err)
+	_, err = m.Users.InsertNewUser(ctx, &alice)
+	assert.NoError(t, err)
+	insert := func(rule string, start time.Time) RecurringExpense {
+		series := RecurringExpense{
+			ID: primitive.NewObjectID(), Title: "office rent", Total: NewMoney(150000, "EUR"), Tags: []string{"office"},
+			Category: rent.Snapshot(), InsertedBy: alice.Snapshot(), RRule: rule, Start: start, Status: RecurringActive,
+		}
+		_, err := m.Recurring.Insert(ctx, &series)
+		assert.NoError(t, err)
+		return series
+	}
+	expenses := func() []Expense {
+		all, _, err := m.Expenses.ReadAll(ctx, ExpenseFilter{}, ListOptions{Sort: []SortField{{Key: "date"}}})
+		assert.NoError(t, err)
+		return all
+	}
+	read := func(id primitive.ObjectID) RecurringExpense {
+		series, err := m.Recurring.ReadOne(ctx, id)
+		assert.NoError(t, err)
+		return series
+	}
+
+	// the occurrences since the start are caught up, as drafts of the author
+	monthly := insert("FREQ=MONTHLY;BYMONTHDAY=1", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, job.Materialize(ctx))
+	created := expenses()
+	if assert.Len(t, created, 3) {
+		assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), created[0].Date)
+		assert.Equal(t, OccurrenceID(monthly.ID, created[0].Date), created[0].ID)
+		assert.Equal(t, StatusDraft, created[2].Status)
+		assert.Equal(t, alice.ID, created[2].InsertedBy.ID)
+		assert.Equal(t, []string{"office"}, created[2].Tags)
+	}
+	series := read(monthly.ID)
+	assert.Equal(t, time.Date(2021, 3, 10, 0, 0, 0, 0, time.UTC), *series.ScheduledUntil)
+
+	// a run on the same day, or a second instance of the job, creates nothing new
+	assert.NoError(t, job.Materialize(ctx))
+	_, err = m.Recurring.UpdateOne(ctx, monthly.ID, RecurringUpdate{ScheduledUntil: &time.Time{}})
+	assert.NoError(t, err)
+	assert.NoError(t, job.Materialize(ctx))
+	assert.Len(t, expenses(), 3)
+
+	// the skipped occurrences are not created, the paused series are not run
+	_, err = m.Recurring.UpdateOne(ctx, monthly.ID, RecurringUpdate{Skipped: []time.Time{time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC)}})
+	assert.NoError(t, err)
+	weekly := insert("FREQ=WEEKLY", time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC))
+	paused := RecurringPaused
+	_, err = m.Recurring.UpdateOne(ctx, weekly.ID, RecurringUpdate{Status: &paused})
+	assert.NoError(t, err)
+	today = time.Date(2021, 5, 2, 8, 0, 0, 0, time.UTC)
+	assert.NoError(t, job.Materialize(ctx))
+	created = expenses()
+	if assert.Len(t, created, 4) {
+		assert.Equal(t, time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC), created[3].Date)
+	}
+	assert.Nil(t, read(weekly.ID).ScheduledUntil)
+
+	// the occurrences of an inactive author are not created, the schedule moves on
+	_, err = m.Users.UpdateOneUser(ctx, alice.ID, UserUpdate{IsActive: Bool(false)})
+	assert.NoError(t, err)
+	today = time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC)
+	assert.NoError(t, job.Materialize(ctx))
+	assert.Len(t, expenses(), 4)
+	assert.Equal(t, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC), *read(monthly.ID).ScheduledUntil)
+	_, err = m.Users.UpdateOneUser(ctx, alice.ID, UserUpdate{IsActive: Bool(true)})
+	assert.NoError(t, err)
+
+	// the series ends after its last occurrence
+	twice := insert("FREQ=DAILY;COUNT=2", time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC))
+	today = time.Date(2021, 6, 3, 8, 0, 0, 0, time.UTC)
+	assert.NoError(t, job.Materialize(ctx))
+	assert.Len(t, expenses(), 6)
+	assert.Equal(t, RecurringEnded, read(twice.ID).Status)
+	assert.Equal(t, RecurringActive, read(monthly.ID).Status)
+
+	// the series ends when its category is removed
+	_, err = m.Categories.RemoveOne(ctx, rent.ID)
+	assert.NoError(t, err)
+	today = time.Date(2021, 7, 1, 8, 0, 0, 0, time.UTC)
+	assert.NoError(t, job.Materialize(ctx))
+	assert.Len(t, expenses(), 6)
+	assert.Equal(t, RecurringEnded, read(monthly.ID).Status)
+}
+
+func TestRecurringJobRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	job := NewRecurringJob(NewMemoryModels(NewMemoryStore()))
+	m := job.Wrap(job.models)
+	go job.Run(ctx, time.Hour)
+
+	food := Category{ID: primitive.NewObjectID(), Name: "Food", Slug: "food"}
+	bob := User{ID: primitive.NewObjectID(), Name: "bob", IsActive: true}
+	_, err := m.Categories.Insert(ctx, &food)
+	assert.NoError(t, err)
+	_, err = m.Users.InsertNewUser(ctx, &bob)
+	assert.NoError(t, err)
+	// the created series is run at once
+	series := RecurringExpense{ID: primitive.NewObjectID(), Title: "lunch", Total: NewMoney(1200, "EUR"), Category: food.Snapshot(),
+		InsertedBy: bob.Snapshot(), RRule: "FREQ=DAILY", Start: utcDay(time.Now()), Status: RecurringActive}
+	_, err = m.Recurring.Insert(ctx, &series)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		_, err := m.Expenses.ReadOne(ctx, OccurrenceID(series.ID, utcDay(time.Now())))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
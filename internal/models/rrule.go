@@ -0,0 +1,322 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+)
+
+// Frequency the FREQ of a recurrence rule
+type Frequency string
+
+// the frequencies of the recurrence rules
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// rruleUntilLayout layout of the UNTIL dates, the time of a `YYYYMMDDTHHMMSSZ` value is ignored
+const rruleUntilLayout = "20060102"
+
+// weekdays the BYDAY codes of the weekdays
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// RRule recurrence rule of the dates of a series, the subset of the RFC 5545 RRULE with
+// FREQ, INTERVAL, BYDAY, BYMONTHDAY, BYSETPOS, COUNT and UNTIL. The weeks start on monday.
+//
+//	FREQ=MONTHLY;BYMONTHDAY=1                      monthly on the 1st
+//	FREQ=WEEKLY;INTERVAL=2                         every 2 weeks, on the weekday of the start
+//	FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1  the last business day of the month
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int // negative days count from the end of the month, -1 is the last day
+	BySetPos   []int // the n-th dates of each period, negative positions count from the end
+	Count      int
+	Until      time.Time // the last possible date, zero when the rule has no end
+}
+
+// ParseRRule parse the rule, with or without the `RRULE:` prefix
+func ParseRRule(s string) (RRule, error) {
+	r := RRule{Interval: 1}
+	invalid := func(format string, args ...interface{}) (RRule, error) {
+		return RRule{}, errs.Wrap(errs.Validation, "invalid recurrence rule", fmt.Errorf(format, args...))
+	}
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return invalid("malformed part %q", part)
+		}
+		key, value := kv[0], kv[1]
+		if seen[key] {
+			return invalid("repeated %s", key)
+		}
+		seen[key] = true
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(value)
+			if r.Freq != Daily && r.Freq != Weekly && r.Freq != Monthly && r.Freq != Yearly {
+				return invalid("unsupported FREQ %s", value)
+			}
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(value); err != nil || r.Interval < 1 {
+				return invalid("INTERVAL must be a positive number")
+			}
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdays[code]
+				if !ok {
+					return invalid("unsupported BYDAY %s", code)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			if r.ByMonthDay, err = ruleInts(value, 31); err != nil {
+				return invalid("BYMONTHDAY %v", err)
+			}
+		case "BYSETPOS":
+			if r.BySetPos, err = ruleInts(value, 366); err != nil {
+				return invalid("BYSETPOS %v", err)
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(value); err != nil || r.Count < 1 {
+				return invalid("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			if len(value) < len(rruleUntilLayout) {
+				return invalid("UNTIL must be a date")
+			}
+			if r.Until, err = time.Parse(rruleUntilLayout, value[:len(rruleUntilLayout)]); err != nil {
+				return invalid("UNTIL must be a date")
+			}
+		default:
+			return invalid("unsupported %s", key)
+		}
+	}
+	if r.Freq == "" {
+		return invalid("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return invalid("COUNT and UNTIL can not be combined")
+	}
+	return r, nil
+}
+
+// ruleInts parse the comma separated non zero numbers between -max and max
+func ruleInts(value string, max int) ([]int, error) {
+	var ints []int
+	for _, x := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(x)
+		if err != nil || n == 0 || n < -max || n > max {
+			return nil, fmt.Errorf("must be non zero numbers between -%d and %d", max, max)
+		}
+		ints = append(ints, n)
+	}
+	return ints, nil
+}
+
+// String the canonical form of the rule
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			codes = append(codes, strings.ToUpper(day.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	join := func(ints []int) string {
+		s := make([]string, 0, len(ints))
+		for _, n := range ints {
+			s = append(s, strconv.Itoa(n))
+		}
+		return strings.Join(s, ",")
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+join(r.ByMonthDay))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+join(r.BySetPos))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.Format(rruleUntilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between the dates of the series starting on the start date in [from, to), the first limit ones when limit > 0.
+// The dates are days in UTC like the dates of the expenses, COUNT counts from the start.
+func (r RRule) Between(start, from, to time.Time, limit int) []time.Time {
+	start = utcDay(start)
+	dates := []time.Time{}
+	n := 0
+	for period := r.periodStart(start); period.Before(to); period = r.nextPeriod(period) {
+		if !r.Until.IsZero() && period.After(r.Until) {
+			break
+		}
+		for _, date := range r.candidates(start, period) {
+			if date.Before(start) {
+				continue
+			}
+			n++
+			if (r.Count > 0 && n > r.Count) || (!r.Until.IsZero() && date.After(r.Until)) || !date.Before(to) {
+				return dates
+			}
+			if !date.Before(from) {
+				dates = append(dates, date)
+				if limit > 0 && len(dates) == limit {
+					return dates
+				}
+			}
+		}
+	}
+	return dates
+}
+
+// periodStart the first day of the period of the frequency containing the date
+func (r RRule) periodStart(date time.Time) time.Time {
+	switch r.Freq {
+	case Weekly:
+		return date.AddDate(0, 0, -(int(date.Weekday())+6)%7)
+	case Monthly:
+		return time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case Yearly:
+		return time.Date(date.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return date
+}
+
+// nextPeriod the first day of the period INTERVAL periods later
+func (r RRule) nextPeriod(period time.Time) time.Time {
+	switch r.Freq {
+	case Weekly:
+		return period.AddDate(0, 0, 7*r.Interval)
+	case Monthly:
+		return period.AddDate(0, r.Interval, 0)
+	case Yearly:
+		return period.AddDate(r.Interval, 0, 0)
+	}
+	return period.AddDate(0, 0, r.Interval)
+}
+
+// candidates the sorted dates of the period matching the BYxxx parts, the days default to the ones of the start
+func (r RRule) candidates(start, period time.Time) []time.Time {
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		days = []time.Time{period}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			day := period.AddDate(0, 0, i)
+			if len(r.ByDay) > 0 || day.Weekday() == start.Weekday() {
+				days = append(days, day)
+			}
+		}
+	case Monthly, Yearly:
+		month := period
+		if r.Freq == Yearly {
+			month = time.Date(period.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+		last := month.AddDate(0, 1, -1).Day()
+		monthDays := r.ByMonthDay
+		if len(monthDays) == 0 && len(r.ByDay) == 0 {
+			monthDays = []int{start.Day()}
+		}
+		if len(monthDays) == 0 {
+			for d := 1; d <= last; d++ {
+				days = append(days, month.AddDate(0, 0, d-1))
+			}
+		}
+		for _, d := range monthDays {
+			if d < 0 {
+				d = last + d + 1
+			}
+			// the months without the day are skipped, like the RFC
+			if d >= 1 && d <= last {
+				days = append(days, month.AddDate(0, 0, d-1))
+			}
+		}
+	}
+
+	var matched []time.Time
+	for _, day := range days {
+		if r.matchDay(day) {
+			matched = append(matched, day)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Before(matched[j]) })
+	if len(r.BySetPos) == 0 {
+		return matched
+	}
+	var selected []time.Time
+	for _, pos := range r.BySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(matched) + pos
+		}
+		if i >= 0 && i < len(matched) {
+			selected = append(selected, matched[i])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return dedupeDates(selected)
+}
+
+// matchDay check the day matches the BYDAY and, for the daily & weekly rules, the BYMONTHDAY parts
+func (r RRule) matchDay(day time.Time) bool {
+	if len(r.ByDay) > 0 {
+		found := false
+		for _, weekday := range r.ByDay {
+			found = found || day.Weekday() == weekday
+		}
+		if !found {
+			return false
+		}
+	}
+	if (r.Freq == Daily || r.Freq == Weekly) && len(r.ByMonthDay) > 0 {
+		last := day.AddDate(0, 1, -day.Day()).Day()
+		for _, d := range r.ByMonthDay {
+			if d == day.Day() || last+d+1 == day.Day() {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// dedupeDates remove the repeated dates of the sorted dates
+func dedupeDates(dates []time.Time) []time.Time {
+	out := dates[:0]
+	for i, date := range dates {
+		if i == 0 || !date.Equal(dates[i-1]) {
+			out = append(out, date)
+		}
+	}
+	return out
+}
+
+// utcDay the day of the time in UTC, at midnight
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
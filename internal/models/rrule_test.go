@@ -0,0 +1,97 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRRule(t *testing.T) {
+	for _, c := range []struct {
+		rule      string
+		canonical string
+	}{
+		{"FREQ=MONTHLY;BYMONTHDAY=1", "FREQ=MONTHLY;BYMONTHDAY=1"},
+		{"rrule:freq=weekly;interval=2", "FREQ=WEEKLY;INTERVAL=2"},
+		{"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{"FREQ=DAILY;INTERVAL=1;UNTIL=20211231T235959Z", "FREQ=DAILY;UNTIL=20211231"},
+		{"FREQ=YEARLY;COUNT=3", "FREQ=YEARLY;COUNT=3"},
+	} {
+		rule, err := ParseRRule(c.rule)
+		if assert.NoError(t, err, c.rule) {
+			assert.Equal(t, c.canonical, rule.String())
+		}
+	}
+
+	for _, rule := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20211231",
+		"FREQ=DAILY;UNTIL=2021-12-31",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;WKST=MO",
+		"FREQ=DAILY;COUNT",
+	} {
+		_, err := ParseRRule(rule)
+		assert.True(t, errs.Is(err, errs.Validation), rule)
+	}
+}
+
+func TestRRuleBetween(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse(DateLayout, s)
+		return d
+	}
+	days := func(dates []time.Time) []string {
+		out := []string{}
+		for _, d := range dates {
+			out = append(out, d.Format(DateLayout))
+		}
+		return out
+	}
+	for _, c := range []struct {
+		name  string
+		rule  string
+		start string
+		from  string
+		to    string
+		limit int
+		want  []string
+	}{
+		{"monthly on the 1st", "FREQ=MONTHLY;BYMONTHDAY=1", "2021-01-15", "2021-01-01", "2021-04-02", 0,
+			[]string{"2021-02-01", "2021-03-01", "2021-04-01"}},
+		{"monthly on the day of the start, the short months skipped", "FREQ=MONTHLY", "2021-01-31", "2021-01-01", "2021-06-01", 0,
+			[]string{"2021-01-31", "2021-03-31", "2021-05-31"}},
+		{"last day of the month", "FREQ=MONTHLY;BYMONTHDAY=-1", "2021-01-01", "2021-01-01", "2021-04-01", 0,
+			[]string{"2021-01-31", "2021-02-28", "2021-03-31"}},
+		{"every 2 weeks on the weekday of the start", "FREQ=WEEKLY;INTERVAL=2", "2021-03-03", "2021-03-01", "2021-04-15", 0,
+			[]string{"2021-03-03", "2021-03-17", "2021-03-31", "2021-04-14"}},
+		{"every 2 weeks on monday & friday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "2021-03-03", "2021-03-01", "2021-03-20", 0,
+			[]string{"2021-03-05", "2021-03-15", "2021-03-19"}},
+		// 2021-07-31 is a saturday, 2021-10-31 a sunday
+		{"last business day", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "2021-07-01", "2021-07-01", "2021-11-01", 0,
+			[]string{"2021-07-30", "2021-08-31", "2021-09-30", "2021-10-29"}},
+		{"count from the start", "FREQ=DAILY;COUNT=3", "2021-03-01", "2021-03-02", "2021-04-01", 0,
+			[]string{"2021-03-02", "2021-03-03"}},
+		{"until", "FREQ=WEEKLY;UNTIL=20210315", "2021-03-01", "2021-03-01", "2021-04-01", 0,
+			[]string{"2021-03-01", "2021-03-08", "2021-03-15"}},
+		{"yearly on the 29th of february", "FREQ=YEARLY", "2020-02-29", "2020-01-01", "2025-01-01", 0,
+			[]string{"2020-02-29", "2024-02-29"}},
+		{"limit", "FREQ=DAILY", "2021-03-01", "2021-03-01", "2022-03-01", 2,
+			[]string{"2021-03-01", "2021-03-02"}},
+		{"to is excluded", "FREQ=DAILY", "2021-03-01", "2021-03-01", "2021-03-01", 0,
+			[]string{}},
+	} {
+		rule, err := ParseRRule(c.rule)
+		if assert.NoError(t, err, c.name) {
+			assert.Equal(t, c.want, days(rule.Between(day(c.start), day(c.from), day(c.to), c.limit)), c.name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Settlement payment between two members of a project paying back the shared expenses,
+// it moves the balances of both members towards zero
+type Settlement struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+	ProjectID  primitive.ObjectID `json:"project_id" bson:"project_id"`
+	FromID     primitive.ObjectID `json:"from_id" bson:"from_id"` // the member who paid
+	ToID       primitive.ObjectID `json:"to_id" bson:"to_id"`     // the member who received the payment
+	Amount     Money              `json:"amount" bson:"amount"`
+	Date       time.Time          `json:"date" bson:"date"`
+	Note       string             `json:"note" bson:"note"`
+	InsertedBy primitive.ObjectID `json:"inserted_by" bson:"inserted_by"`
+}
+
+// SettlementInput settlement create input model
+type SettlementInput struct {
+	FromID   string      `json:"from_id" validate:"required,objectid"`
+	ToID     string      `json:"to_id" validate:"required,objectid,nefield=FromID"`
+	Amount   json.Number `json:"amount" validate:"required"`
+	Currency string      `json:"currency" validate:"omitempty,currency"` // the base currency of the project by default
+	Date     string      `json:"date" validate:"required,date"`
+	Note     string      `json:"note" validate:"max=200"`
+}
+
+// SettlementModeler godoc
+type SettlementModeler interface {
+	Insert(ctx context.Context, settlement *Settlement) (interface{}, error)
+	ReadAll(ctx context.Context, projectID primitive.ObjectID) ([]Settlement, error)
+	ReadOne(ctx context.Context, id primitive.ObjectID) (Settlement, error)
+	RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error)
+}
+
+// SettlementModel godoc
+type SettlementModel struct {
+	db db.MongoDBClient
+}
+
+// NewSettlementModel godoc
+func NewSettlementModel(db db.MongoDBClient) *SettlementModel {
+	return &SettlementModel{db}
+}
+
+// settlementIndexes the settlements are read per project
+var settlementIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "date", Value: 1}}, Options: options.Index().SetName("project_date")},
+}
+
+// Insert insert a record at settlements collection
+func (s *SettlementModel) Insert(ctx context.Context, settlement *Settlement) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := s.db.Client.Database(s.db.DBName).Collection("settlements")
+	insertResult, err := collection.InsertOne(ctx, settlement)
+	if err != nil {
+		log.Printf("Error on inserting new settlement: %v\n", err)
+		return nil, dbError(err)
+	}
+	return insertResult.InsertedID, nil
+}
+
+// ReadAll read every settlement of the project, oldest first
+func (s *SettlementModel) ReadAll(ctx context.Context, projectID primitive.ObjectID) ([]Settlement, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	settlements := []Settlement{}
+	collection := s.db.Client.Database(s.db.DBName).Collection("settlements")
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}})
+	cur, err := collection.Find(ctx, bson.M{"project_id": projectID}, opts)
+	if err != nil {
+		return settlements, dbError(err)
+	}
+	err = cur.All(ctx, &settlements)
+	return settlements, dbError(err)
+}
+
+// ReadOne read a single settlement
+func (s *SettlementModel) ReadOne(ctx context.Context, id primitive.ObjectID) (Settlement, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	var settlement Settlement
+	collection := s.db.Client.Database(s.db.DBName).Collection("settlements")
+	err := findOne(ctx, collection, bson.M{"_id": id}, "settlement", &settlement)
+	return settlement, err
+}
+
+// RemoveOne remove one settlement from collections
+func (s *SettlementModel) RemoveOne(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
+	collection := s.db.Client.Database(s.db.DBName).Collection("settlements")
+	deleteResult, err := collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		log.Printf("Error on deleting one settlement: %v\n", err)
+		return 0, dbError(err)
+	}
+	return deleteResult.DeletedCount, nil
+}
@@ -0,0 +1,237 @@
+package models
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CategorySnapshot category of an expense, the reference to the category with a copy of its display fields.
+// The copy is refreshed by the SnapshotJob when the category changes and kept when the category is removed.
+type CategorySnapshot struct {
+	ID    primitive.ObjectID `json:"id" bson:"_id"`
+	Name  string             `json:"name" bson:"name"`
+	Slug  string             `json:"slug" bson:"slug"`
+	Color string             `json:"color,omitempty" bson:"color,omitempty"`
+	Icon  string             `json:"icon,omitempty" bson:"icon,omitempty"`
+}
+
+// UserSnapshot author of an expense, the reference to the user with a copy of its display fields.
+// The copy is refreshed by the SnapshotJob when the user changes and kept when the user is removed.
+type UserSnapshot struct {
+	ID       primitive.ObjectID `json:"id" bson:"_id"`
+	Name     string             `json:"name" bson:"name"`
+	Email    string             `json:"email" bson:"email"`
+	IsActive bool               `json:"is_active" bson:"is_active"`
+}
+
+// Snapshot the snapshot of the category stored with the expenses
+func (c Category) Snapshot() CategorySnapshot {
+	return CategorySnapshot{ID: c.ID, Name: c.Name, Slug: c.Slug, Color: c.Color, Icon: c.Icon}
+}
+
+// Snapshot the snapshot of the user stored with the expenses
+func (u User) Snapshot() UserSnapshot {
+	return UserSnapshot{ID: u.ID, Name: u.Name, Email: u.Email, IsActive: u.IsActive}
+}
+
+// SnapshotRetryDelay delay before a failed refresh of the snapshots is run again
+var SnapshotRetryDelay = 30 * time.Second
+
+// SnapshotJob refresh in the background the snapshots of the expenses of the changed categories & users.
+// The changes are deduplicated, a burst of updates of one category is refreshed once with its latest state.
+// The queue is kept in memory, the periodic Reconcile refreshes the changes lost by a restart.
+type SnapshotJob struct {
+	models     Models
+	mu         sync.Mutex
+	categories map[primitive.ObjectID]bool
+	users      map[primitive.ObjectID]bool
+	wake       chan struct{}
+}
+
+// NewSnapshotJob the job refreshing the snapshots of the expenses of the models
+func NewSnapshotJob(m Models) *SnapshotJob {
+	return &SnapshotJob{
+		models:     m,
+		categories: map[primitive.ObjectID]bool{},
+		users:      map[primitive.ObjectID]bool{},
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Wrap the models so the updates of the categories & users are queued to the job
+func (j *SnapshotJob) Wrap(m Models) Models {
+	m.Categories = snapshotCategories{m.Categories, j}
+	m.Users = snapshotUsers{m.Users, j}
+	return m
+}
+
+// CategoryChanged queue the refresh of the snapshots of the category
+func (j *SnapshotJob) CategoryChanged(id primitive.ObjectID) {
+	j.mu.Lock()
+	j.categories[id] = true
+	j.mu.Unlock()
+	j.signal()
+}
+
+// UserChanged queue the refresh of the snapshots of the user
+func (j *SnapshotJob) UserChanged(id primitive.ObjectID) {
+	j.mu.Lock()
+	j.users[id] = true
+	j.mu.Unlock()
+	j.signal()
+}
+
+// signal wake the job up, a pending signal already covers the new changes
+func (j *SnapshotJob) signal() {
+	select {
+	case j.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run refresh the queued snapshots until the context is done, the failed refreshes are retried after SnapshotRetryDelay.
+// Every category & user is reconciled on start and every interval.
+func (j *SnapshotJob) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	j.reconcile(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.reconcile(ctx)
+		case <-j.wake:
+			if err := j.Refresh(ctx); err != nil {
+				log.Printf("SNAPSHOT REFRESH ERROR: %v\n", err)
+				time.AfterFunc(SnapshotRetryDelay, j.signal)
+			}
+		}
+	}
+}
+
+// reconcile run Reconcile, a failed pass is retried on the next interval
+func (j *SnapshotJob) reconcile(ctx context.Context) {
+	if err := j.Reconcile(ctx); err != nil {
+		log.Printf("SNAPSHOT RECONCILE ERROR: %v\n", err)
+	}
+}
+
+// Reconcile queue every category & user, to refresh the stale snapshots whose change was never refreshed,
+// like the changes still queued when the server stopped. Only the stale snapshots are rewritten.
+func (j *SnapshotJob) Reconcile(ctx context.Context) error {
+	categories, _, err := j.models.Categories.ReadAll(ctx, CategoryFilter{}, ListOptions{})
+	if err != nil {
+		return err
+	}
+	users, _, err := j.models.Users.ReadAllUsers(ctx, UserFilter{}, ListOptions{})
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	for _, category := range categories {
+		j.categories[category.ID] = true
+	}
+	for _, user := range users {
+		j.users[user.ID] = true
+	}
+	j.mu.Unlock()
+	j.signal()
+	return nil
+}
+
+// Refresh the snapshots of the queued categories & users, the failed ones are queued again
+func (j *SnapshotJob) Refresh(ctx context.Context) error {
+	j.mu.Lock()
+	categories, users := j.categories, j.users
+	j.categories, j.users = map[primitive.ObjectID]bool{}, map[primitive.ObjectID]bool{}
+	j.mu.Unlock()
+
+	var failed error
+	for id := range categories {
+		if err := j.refreshCategory(ctx, id); err != nil {
+			failed = err
+			j.mu.Lock()
+			j.categories[id] = true
+			j.mu.Unlock()
+		}
+	}
+	for id := range users {
+		if err := j.refreshUser(ctx, id); err != nil {
+			failed = err
+			j.mu.Lock()
+			j.users[id] = true
+			j.mu.Unlock()
+		}
+	}
+	return failed
+}
+
+// refreshCategory copy the latest state of the category into its expenses
+func (j *SnapshotJob) refreshCategory(ctx context.Context, id primitive.ObjectID) error {
+	category, err := j.models.Categories.ReadOne(ctx, id)
+	if errs.Is(err, errs.NotFound) {
+		// the expenses keep the last snapshot of a removed category
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	count, err := j.models.Expenses.RefreshCategory(ctx, category.Snapshot())
+	if count > 0 {
+		log.Printf("expense snapshots of category %s refreshed: %d\n", id.Hex(), count)
+	}
+	return err
+}
+
+// refreshUser copy the latest state of the user into its expenses
+func (j *SnapshotJob) refreshUser(ctx context.Context, id primitive.ObjectID) error {
+	user, err := j.models.Users.ReadOneUser(ctx, UserQuery{ID: id})
+	if errs.Is(err, errs.NotFound) {
+		// the expenses keep the last snapshot of a removed user
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	count, err := j.models.Expenses.RefreshUser(ctx, user.Snapshot())
+	if count > 0 {
+		log.Printf("expense snapshots of user %s refreshed: %d\n", id.Hex(), count)
+	}
+	return err
+}
+
+// snapshotCategories CategoryModeler queuing the updated categories to the job
+type snapshotCategories struct {
+	CategoryModeler
+	job *SnapshotJob
+}
+
+// UpdateOne update the category and queue the refresh of its snapshots
+func (c snapshotCategories) UpdateOne(ctx context.Context, id primitive.ObjectID, update CategoryUpdateInput) (int64, error) {
+	count, err := c.CategoryModeler.UpdateOne(ctx, id, update)
+	if err == nil && count > 0 {
+		c.job.CategoryChanged(id)
+	}
+	return count, err
+}
+
+// snapshotUsers UserModel queuing the users with an updated name or status to the job
+type snapshotUsers struct {
+	UserModel
+	job *SnapshotJob
+}
+
+// UpdateOneUser update the user and queue the refresh of its snapshots
+func (u snapshotUsers) UpdateOneUser(ctx context.Context, id primitive.ObjectID, update UserUpdate) (int64, error) {
+	count, err := u.UserModel.UpdateOneUser(ctx, id, update)
+	if err == nil && count > 0 && (update.Name != nil || update.IsActive != nil) {
+		u.job.UserChanged(id)
+	}
+	return count, err
+}
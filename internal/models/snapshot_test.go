@@ -0,0 +1,104 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSnapshotJob(t *testing.T) {
+	ctx := context.Background()
+	job := NewSnapshotJob(NewMemoryModels(NewMemoryStore()))
+	m := job.Wrap(job.models)
+
+	hotel := Category{ID: primitive.NewObjectID(), Name: "Hotel", Slug: "hotel"}
+	alice := User{ID: primitive.NewObjectID(), Name: "alice", Email: "alice@example.com", IsActive: true}
+	_, err := m.Categories.Insert(ctx, &hotel)
+	assert.NoError(t, err)
+	_, err = m.Users.InsertNewUser(ctx, &alice)
+	assert.NoError(t, err)
+	expense := newMemoryExpense("night", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), 12000)
+	expense.Category, expense.InsertedBy = hotel.Snapshot(), alice.Snapshot()
+	_, err = m.Expenses.Insert(ctx, expense)
+	assert.NoError(t, err)
+
+	_, err = m.Categories.UpdateOne(ctx, hotel.ID, CategoryUpdateInput{Name: "Hotels & Motels", Slug: "hotels-motels", Color: "#aa0000"})
+	assert.NoError(t, err)
+	_, err = m.Users.UpdateOneUser(ctx, alice.ID, UserUpdate{Name: &alice.Name, IsActive: Bool(false)})
+	assert.NoError(t, err)
+
+	// the snapshots are stale until the job runs
+	stored, err := m.Expenses.ReadOne(ctx, expense.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hotel", stored.Category.Name)
+
+	assert.NoError(t, job.Refresh(ctx))
+	stored, err = m.Expenses.ReadOne(ctx, expense.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, CategorySnapshot{ID: hotel.ID, Name: "Hotels & Motels", Slug: "hotels-motels", Color: "#aa0000"}, stored.Category)
+	assert.Equal(t, UserSnapshot{ID: alice.ID, Name: "alice", Email: "alice@example.com", IsActive: false}, stored.InsertedBy)
+	assert.Equal(t, expense.UpdatedAt, stored.UpdatedAt)
+
+	// a removed category leaves its last snapshot
+	_, err = m.Categories.RemoveOne(ctx, hotel.ID)
+	assert.NoError(t, err)
+	job.CategoryChanged(hotel.ID)
+	assert.NoError(t, job.Refresh(ctx))
+	stored, err = m.Expenses.ReadOne(ctx, expense.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hotels & Motels", stored.Category.Name)
+}
+
+func TestSnapshotJobRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	job := NewSnapshotJob(NewMemoryModels(NewMemoryStore()))
+	m := job.Wrap(job.models)
+	go job.Run(ctx, time.Hour)
+
+	food := Category{ID: primitive.NewObjectID(), Name: "Food", Slug: "food"}
+	_, err := m.Categories.Insert(ctx, &food)
+	assert.NoError(t, err)
+	expense := newMemoryExpense("lunch", time.Now(), 1500)
+	expense.Category = food.Snapshot()
+	_, err = m.Expenses.Insert(ctx, expense)
+	assert.NoError(t, err)
+
+	_, err = m.Categories.UpdateOne(ctx, food.ID, CategoryUpdateInput{Name: "Meals", Slug: "meals"})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		stored, err := m.Expenses.ReadOne(ctx, expense.ID)
+		return err == nil && stored.Category.Name == "Meals"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSnapshotJobReconcile(t *testing.T) {
+	ctx := context.Background()
+	job := NewSnapshotJob(NewMemoryModels(NewMemoryStore()))
+	m := job.models
+
+	food := Category{ID: primitive.NewObjectID(), Name: "Food", Slug: "food"}
+	_, err := m.Categories.Insert(ctx, &food)
+	assert.NoError(t, err)
+	expense := newMemoryExpense("lunch", time.Now(), 1500)
+	expense.Category = food.Snapshot()
+	_, err = m.Expenses.Insert(ctx, expense)
+	assert.NoError(t, err)
+
+	// a change never queued to the job, like one queued before a restart
+	_, err = m.Categories.UpdateOne(ctx, food.ID, CategoryUpdateInput{Name: "Meals", Slug: "meals"})
+	assert.NoError(t, err)
+	assert.NoError(t, job.Refresh(ctx))
+	stored, err := m.Expenses.ReadOne(ctx, expense.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Food", stored.Category.Name)
+
+	assert.NoError(t, job.Reconcile(ctx))
+	assert.NoError(t, job.Refresh(ctx))
+	stored, err = m.Expenses.ReadOne(ctx, expense.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Meals", stored.Category.Name)
+}
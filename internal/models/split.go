@@ -0,0 +1,163 @@
+package models
+
+import (
+	"encoding/json"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SplitMethod how the total of a shared expense is divided between the users
+type SplitMethod string
+
+// the split methods
+const (
+	SplitEqual   SplitMethod = "equal"   // the same amount for everyone
+	SplitExact   SplitMethod = "exact"   // the amount of each user, adding up to the total
+	SplitPercent SplitMethod = "percent" // the percentage of each user, adding up to 100
+	SplitShares  SplitMethod = "shares"  // the total divided in proportion to the shares of the users
+)
+
+// split errors
+var (
+	ErrSplitDuplicateUser = errs.New(errs.Validation, "a user can only be once in the splits")
+	ErrSplitValue         = errs.New(errs.Validation, "invalid split value")
+	ErrSplitTotal         = errs.New(errs.Validation, "the split amounts must add up to the total")
+	ErrSplitPercent       = errs.New(errs.Validation, "the split percentages must add up to 100")
+	ErrSplitShares        = errs.New(errs.Validation, "the split shares must add up to more than zero")
+)
+
+// Split the share of one user in the total of an expense, in the currency of the expense
+type Split struct {
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Value  string             `json:"value,omitempty" bson:"value,omitempty"` // the amount, percentage or shares as given, empty for the equal splits
+	Amount Money              `json:"amount" bson:"amount"`
+}
+
+// SplitInput the share of one user in the expense input, the value is ignored by the equal splits
+type SplitInput struct {
+	UserID string      `json:"user_id" validate:"required,objectid"`
+	Value  json.Number `json:"value"`
+}
+
+// ResolveSplits the amounts of the splits of the total with the method.
+// The amounts always add up to the total: the minor units left over by the rounding
+// go to the users with the largest remainders, the first ones on a tie.
+func ResolveSplits(total Money, method SplitMethod, inputs []SplitInput) ([]Split, error) {
+	splits := make([]Split, len(inputs))
+	seen := make(map[primitive.ObjectID]bool, len(inputs))
+	for i, input := range inputs {
+		id, err := primitive.ObjectIDFromHex(input.UserID)
+		if err != nil {
+			return nil, ErrSplitValue
+		}
+		if seen[id] {
+			return nil, ErrSplitDuplicateUser
+		}
+		seen[id] = true
+		splits[i] = Split{UserID: id, Amount: NewMoney(0, total.Currency)}
+		if method != SplitEqual {
+			splits[i].Value = strings.TrimSpace(input.Value.String())
+		}
+	}
+	if len(splits) == 0 {
+		return splits, nil
+	}
+
+	weights := make([]*big.Rat, len(splits))
+	switch method {
+	case SplitEqual:
+		for i := range weights {
+			weights[i] = big.NewRat(1, 1)
+		}
+	case SplitExact:
+		sum := NewMoney(0, total.Currency)
+		for i := range splits {
+			amount, err := ParseMoney(splits[i].Value, total.Currency)
+			if err != nil {
+				return nil, err
+			}
+			splits[i].Amount = amount
+			sum.Amount += amount.Amount
+		}
+		if sum.Amount != total.Amount {
+			return nil, ErrSplitTotal
+		}
+		return splits, nil
+	case SplitPercent, SplitShares:
+		sum := new(big.Rat)
+		for i := range splits {
+			value, err := parseDecimal(splits[i].Value)
+			if err != nil {
+				return nil, err
+			}
+			weights[i] = value
+			sum.Add(sum, value)
+		}
+		if method == SplitPercent && sum.Cmp(big.NewRat(100, 1)) != 0 {
+			return nil, ErrSplitPercent
+		}
+		if sum.Sign() == 0 {
+			return nil, ErrSplitShares
+		}
+	default:
+		return nil, ErrSplitValue
+	}
+
+	for i, amount := range allocate(total.Amount, weights) {
+		splits[i].Amount.Amount = amount
+	}
+	return splits, nil
+}
+
+// parseDecimal parse a non negative decimal like "33.3"
+func parseDecimal(value string) (*big.Rat, error) {
+	if value == "" || strings.Count(value, ".") > 1 || strings.Trim(value, "0123456789.") != "" || strings.Trim(value, ".") == "" {
+		return nil, ErrSplitValue
+	}
+	r, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, ErrSplitValue
+	}
+	return r, nil
+}
+
+// allocate divide the non negative total in proportion to the weights with the largest remainder method,
+// the amounts add up to the total unless every weight is zero
+func allocate(total int64, weights []*big.Rat) []int64 {
+	amounts := make([]int64, len(weights))
+	sum := new(big.Rat)
+	for _, w := range weights {
+		sum.Add(sum, w)
+	}
+	if sum.Sign() == 0 {
+		return amounts
+	}
+
+	remainders := make([]*big.Rat, len(weights))
+	left := total
+	for i, w := range weights {
+		exact := new(big.Rat).Mul(big.NewRat(total, 1), w)
+		exact.Quo(exact, sum)
+		floor := new(big.Int).Quo(exact.Num(), exact.Denom())
+		amounts[i] = floor.Int64()
+		remainders[i] = exact.Sub(exact, new(big.Rat).SetInt(floor))
+		left -= amounts[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+	for i := 0; left > 0; i++ {
+		amounts[order[i%len(order)]]++
+		left--
+	}
+	return amounts
+}
@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestResolveSplits(t *testing.T) {
+	users := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+	inputs := func(values ...string) []SplitInput {
+		out := make([]SplitInput, len(values))
+		for i, v := range values {
+			out[i] = SplitInput{UserID: users[i].Hex(), Value: json.Number(v)}
+		}
+		return out
+	}
+	amounts := func(splits []Split) []int64 {
+		out := []int64{}
+		for _, s := range splits {
+			out = append(out, s.Amount.Amount)
+		}
+		return out
+	}
+
+	for _, c := range []struct {
+		name   string
+		total  Money
+		method SplitMethod
+		inputs []SplitInput
+		want   []int64
+	}{
+		{"equal, the cent left over to the first user", NewMoney(1000, "EUR"), SplitEqual, inputs("", "", ""), []int64{334, 333, 333}},
+		{"equal without minor units", NewMoney(1000, "JPY"), SplitEqual, inputs("", "", ""), []int64{334, 333, 333}},
+		{"exact", NewMoney(1000, "EUR"), SplitExact, inputs("2.50", "7.5"), []int64{250, 750}},
+		{"percent, the largest remainder gets the cent", NewMoney(1000, "EUR"), SplitPercent, inputs("33.3", "33.3", "33.4"), []int64{333, 333, 334}},
+		{"percent with a zero share", NewMoney(999, "EUR"), SplitPercent, inputs("50", "50", "0"), []int64{500, 499, 0}},
+		{"shares", NewMoney(1000, "EUR"), SplitShares, inputs("2", "1"), []int64{667, 333}},
+		{"fractional shares", NewMoney(100, "EUR"), SplitShares, inputs("0.5", "1.5"), []int64{25, 75}},
+	} {
+		splits, err := ResolveSplits(c.total, c.method, c.inputs)
+		if assert.NoError(t, err, c.name) {
+			assert.Equal(t, c.want, amounts(splits), c.name)
+			assert.Equal(t, users[0], splits[0].UserID, c.name)
+			assert.Equal(t, c.total.Currency, splits[0].Amount.Currency, c.name)
+		}
+	}
+
+	for _, c := range []struct {
+		name   string
+		method SplitMethod
+		inputs []SplitInput
+	}{
+		{"exact not adding up", SplitExact, inputs("2.50", "7")},
+		{"exact too precise", SplitExact, inputs("2.505", "7.495")},
+		{"percent not adding up", SplitPercent, inputs("50", "40")},
+		{"negative percent", SplitPercent, inputs("150", "-50")},
+		{"zero shares", SplitShares, inputs("0", "0")},
+		{"not a number", SplitShares, inputs("1/2", "1")},
+		{"duplicate user", SplitEqual, []SplitInput{{UserID: users[0].Hex()}, {UserID: users[0].Hex()}}},
+		{"unknown method", SplitMethod("halves"), inputs("1", "1")},
+	} {
+		_, err := ResolveSplits(NewMoney(1000, "EUR"), c.method, c.inputs)
+		assert.True(t, errs.Is(err, errs.Validation), c.name)
+	}
+}
+
+func TestSettleUp(t *testing.T) {
+	ids := make([]primitive.ObjectID, 6)
+	for i := range ids {
+		ids[i] = primitive.NewObjectID()
+	}
+	balances := func(nets ...int64) []Balance {
+		out := make([]Balance, len(nets))
+		for i, net := range nets {
+			out[i] = Balance{UserID: ids[i], Name: string(rune('a' + i)), Net: NewMoney(net, "EUR")}
+		}
+		return out
+	}
+	settled := func(bs []Balance, transfers []Transfer) bool {
+		nets := map[primitive.ObjectID]int64{}
+		for _, b := range bs {
+			nets[b.UserID] = b.Net.Amount
+		}
+		for _, tr := range transfers {
+			if tr.Amount.Amount <= 0 {
+				return false
+			}
+			nets[tr.FromID] += tr.Amount.Amount
+			nets[tr.ToID] -= tr.Amount.Amount
+		}
+		for _, net := range nets {
+			if net != 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, c := range []struct {
+		name      string
+		nets      []int64
+		transfers int
+	}{
+		{"nothing to settle", []int64{0, 0}, 0},
+		{"one debtor", []int64{-300, 100, 200}, 2},
+		{"one creditor", []int64{300, -100, -200}, 2},
+		{"two pairs settled apart", []int64{-500, 500, -100, 100}, 2},
+		// the greedy plan alone pairs -500 with 600 first and needs 5 transfers
+		{"pairs hidden by the greedy order", []int64{-500, -100, 600, -400, 150, 250}, 4},
+	} {
+		bs := balances(c.nets...)
+		transfers := SettleUp(bs)
+		assert.Len(t, transfers, c.transfers, c.name)
+		assert.True(t, settled(bs, transfers), c.name)
+	}
+
+	// the largest debtor pays the largest creditor
+	transfers := SettleUp(balances(-300, 100, 200))
+	if assert.Len(t, transfers, 2) {
+		assert.Equal(t, Transfer{FromID: ids[0], FromName: "a", ToID: ids[2], ToName: "c", Amount: NewMoney(200, "EUR")}, transfers[0])
+	}
+}
+
+func TestComputeBalances(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryModels(NewMemoryStore())
+	day := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	_, err := m.ExchangeRates.Upsert(ctx, []ExchangeRate{{From: "USD", To: "EUR", Date: day, Rate: 0.5}})
+	assert.NoError(t, err)
+	alice, bob, carol, dave := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	split := func(user primitive.ObjectID, amount int64, currency string) Split {
+		return Split{UserID: user, Amount: NewMoney(amount, currency)}
+	}
+
+	expenses := []Expense{
+		// alice paid 90 EUR for the three of them
+		{InsertedBy: UserSnapshot{ID: alice}, Date: day, Total: NewMoney(9000, "EUR"), Status: StatusApproved,
+			Splits: []Split{split(alice, 3000, "EUR"), split(bob, 3000, "EUR"), split(carol, 3000, "EUR")}},
+		// bob filed the 20 USD carol paid, half each
+		{InsertedBy: UserSnapshot{ID: bob}, PaidBy: carol, Date: day, Total: NewMoney(2000, "USD"), Status: StatusDraft,
+			Splits: []Split{split(bob, 1000, "USD"), split(carol, 1000, "USD")}},
+		// neither the rejected nor the not shared expenses count
+		{InsertedBy: UserSnapshot{ID: bob}, Date: day, Total: NewMoney(5000, "EUR"), Status: StatusRejected,
+			Splits: []Split{split(alice, 5000, "EUR")}},
+		{InsertedBy: UserSnapshot{ID: carol}, Date: day, Total: NewMoney(5000, "EUR"), Status: StatusApproved},
+	}
+	settlements := []Settlement{{FromID: bob, ToID: alice, Amount: NewMoney(2000, "EUR"), Date: day}}
+
+	balances, err := ComputeBalances(ctx, m.ExchangeRates, "EUR", []primitive.ObjectID{alice, bob, carol, dave}, expenses, settlements)
+	if !assert.NoError(t, err) || !assert.Len(t, balances, 4) {
+		return
+	}
+	nets := map[primitive.ObjectID]int64{}
+	for _, b := range balances {
+		assert.Equal(t, "EUR", b.Net.Currency)
+		assert.Equal(t, b.Paid.Amount-b.Owed.Amount+b.Sent.Amount-b.Received.Amount, b.Net.Amount)
+		nets[b.UserID] = b.Net.Amount
+	}
+	assert.Equal(t, map[primitive.ObjectID]int64{alice: 4000, bob: -1500, carol: -2500, dave: 0}, nets)
+	assert.Equal(t, int64(1000), balances[2].Paid.Amount)
+	assert.Equal(t, int64(2000), balances[1].Sent.Amount)
+
+	// a rate is needed for every currency
+	expenses[1].Total.Currency = "GBP"
+	_, err = ComputeBalances(ctx, m.ExchangeRates, "EUR", nil, expenses, settlements)
+	assert.True(t, errors.Is(err, ErrRateNotFound))
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrTimeout reported to the clients when a storage operation is aborted by its deadline
+var ErrTimeout = errs.New(errs.Timeout, "the storage did not answer in time")
+
+// Timeouts deadlines of the storage operations, a zero duration disables the deadline
+type Timeouts struct {
+	Read   time.Duration
+	Write  time.Duration
+	Report time.Duration // aggregations over many documents, e.g. the project details
+}
+
+// OperationTimeouts deadlines applied by the models on top of the request context, set once on startup
+var OperationTimeouts = Timeouts{Read: 5 * time.Second, Write: 5 * time.Second, Report: 15 * time.Second}
+
+// readContext bound a read with its deadline, the cancel func must be called
+func readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, OperationTimeouts.Read)
+}
+
+// writeContext bound a write with its deadline, the cancel func must be called
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, OperationTimeouts.Write)
+}
+
+// reportContext bound an aggregation with its deadline, the cancel func must be called
+func reportContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, OperationTimeouts.Report)
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// IsTimeout check if the storage operation was aborted by its deadline
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errs.Is(err, errs.Timeout) {
+		return true
+	}
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && cmdErr.IsMaxTimeMSExpiredError()
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := withTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+	assert.True(t, IsTimeout(ctx.Err()))
+
+	// a zero duration keeps the deadline of the parent
+	ctx, cancel = withTimeout(context.Background(), 0)
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+}
+
+func TestIsTimeout(t *testing.T) {
+	assert.True(t, IsTimeout(context.DeadlineExceeded))
+	assert.True(t, IsTimeout(fmt.Errorf("server selection error: %w", context.DeadlineExceeded)))
+	assert.True(t, IsTimeout(mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}))
+	assert.False(t, IsTimeout(context.Canceled))
+	assert.False(t, IsTimeout(errors.New("boom")))
+	assert.False(t, IsTimeout(nil))
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/masihur1989/expense-tracker-api/internal/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // User model for user to map mongodb document
@@ -15,17 +16,58 @@ type User struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id"`
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
-	Email       string             `json:"email" bson:"email" validate:"required,email"`                 // TODO needs to decide of if we want to make it unique
+	Email       string             `json:"email" bson:"email" validate:"required,email"`                 // unique, enforced by the storage
 	PhoneNumber string             `json:"phone_number" bson:"phone_number" validate:"required,numeric"` // TODO needs to decide of if we want to make it unique
 	Name        string             `json:"name" bson:"name" validate:"required,alpha"`
 	Role        Role               `json:"role" bson:"role" validate:"required,oneof=ADMIN SUPERVISOR STAFF USER"`
 	IsActive    bool               `json:"is_active" bson:"is_active" validate:"required"`
+	// Locale preferred locale of the messages, the Accept-Language header is used when empty
+	Locale string `json:"locale,omitempty" bson:"locale,omitempty" validate:"omitempty,locale"`
+	// Password plain password, only accepted on create and never stored
+	Password string `json:"password,omitempty" bson:"-" validate:"required,password"`
+	// PasswordHash bcrypt hash of the user password, never serialized in responses
+	PasswordHash string `json:"-" bson:"password_hash,omitempty"`
+	// PasswordChangedAt time of the last password change, the tokens issued before are revoked
+	PasswordChangedAt time.Time `json:"-" bson:"password_changed_at,omitempty"`
+}
+
+// LoginInput credentials for the login endpoint
+type LoginInput struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// RefreshInput input model for the token refresh endpoint
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
 // UserUpdateInput godoc
 type UserUpdateInput struct {
 	Name     string `json:"name" bson:"name" validate:"required,max=20" `
 	IsActive bool   `json:"is_active" bson:"is_active" validate:"required"`
+	Locale   string `json:"locale" bson:"locale" validate:"omitempty,locale"`
+}
+
+// UserQuery lookup of a single user, by ID or by email
+type UserQuery struct {
+	ID    primitive.ObjectID
+	Email string
+}
+
+// UserFilter filter of the user list, zero fields match every user
+type UserFilter struct {
+	IsActive *bool
+	Role     Role
+}
+
+// UserUpdate fields of the user to update, nil fields are kept.
+// A new PasswordHash sets the PasswordChangedAt of the user.
+type UserUpdate struct {
+	Name         *string
+	IsActive     *bool
+	PasswordHash *string
+	Locale       *string
 }
 
 // Role user role
@@ -39,13 +81,21 @@ const (
 	RoleUser       Role = "USER"
 )
 
+// roleRanks the order of the roles, a higher rank is granted more
+var roleRanks = map[Role]int{RoleUser: 1, RoleStaff: 2, RoleSupervisor: 3, RoleAdmin: 4}
+
+// Outranks check if the role is above the other one
+func (r Role) Outranks(other Role) bool {
+	return roleRanks[r] > roleRanks[other]
+}
+
 // UserModel godoc
 type UserModel interface {
-	InsertNewUser(user *User) (interface{}, error)
-	ReadOneUser(filter interface{}) (User, error)
-	ReadAllUsers(filter interface{}) ([]*User, error)
-	RemoveOneUser(filter interface{}) (int64, error)
-	UpdateOneUser(updatedData interface{}, filter interface{}) (int64, error)
+	InsertNewUser(ctx context.Context, user *User) (interface{}, error)
+	ReadOneUser(ctx context.Context, q UserQuery) (User, error)
+	ReadAllUsers(ctx context.Context, f UserFilter, opts ListOptions) ([]*User, Page, error)
+	RemoveOneUser(ctx context.Context, id primitive.ObjectID) (int64, error)
+	UpdateOneUser(ctx context.Context, id primitive.ObjectID, update UserUpdate) (int64, error)
 }
 
 // UserModelImpl godoc
@@ -61,65 +111,101 @@ func NewUserModelImpl(db db.MongoDBClient) *UserModelImpl {
 }
 
 // InsertNewUser create a nre record at users collection
-func (c *UserModelImpl) InsertNewUser(user *User) (interface{}, error) {
+func (c *UserModelImpl) InsertNewUser(ctx context.Context, user *User) (interface{}, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("users")
-	insertResult, err := collection.InsertOne(context.TODO(), user)
+	insertResult, err := collection.InsertOne(ctx, user)
 	if err != nil {
-		log.Fatalf("Error on inserting new User: %v\n", err)
-		return nil, err
+		log.Printf("Error on inserting new User: %v\n", err)
+		return nil, dbError(err)
 	}
 	return insertResult.InsertedID, nil
 }
 
 // ReadOneUser read a single user
-func (c *UserModelImpl) ReadOneUser(filter interface{}) (User, error) {
+func (c *UserModelImpl) ReadOneUser(ctx context.Context, q UserQuery) (User, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
 	var user User
 	collection := c.db.Client.Database(c.db.DBName).Collection("users")
-	documentReturned := collection.FindOne(context.TODO(), filter)
-	documentReturned.Decode(&user)
-	return user, nil
+	filter := bson.D{}
+	if !q.ID.IsZero() {
+		filter = append(filter, bson.E{Key: "_id", Value: q.ID})
+	}
+	if q.Email != "" {
+		filter = append(filter, bson.E{Key: "email", Value: q.Email})
+	}
+	if len(filter) == 0 {
+		// an empty query must not match the first user
+		return user, notFound("user")
+	}
+	err := findOne(ctx, collection, filter, "user", &user)
+	return user, err
 }
 
-// ReadAllUsers read all the users
-func (c *UserModelImpl) ReadAllUsers(filter interface{}) ([]*User, error) {
-	var users []*User
+// ReadAllUsers read a page of the users
+func (c *UserModelImpl) ReadAllUsers(ctx context.Context, f UserFilter, opts ListOptions) ([]*User, Page, error) {
+	ctx, cancel := readContext(ctx)
+	defer cancel()
+	users := []*User{}
 	collection := c.db.Client.Database(c.db.DBName).Collection("users")
-	cur, err := collection.Find(context.TODO(), filter)
-	if err != nil {
-		log.Printf("ERROR FINDING DATA: %v\n", err)
-		return users, err
+	filter := bson.D{}
+	if f.IsActive != nil {
+		filter = append(filter, bson.E{Key: "is_active", Value: *f.IsActive})
+	}
+	if f.Role != "" {
+		filter = append(filter, bson.E{Key: "role", Value: f.Role})
 	}
-	for cur.Next(context.TODO()) {
+	page, err := findPage(ctx, collection, filter, opts, UserListFields, func(cur *mongo.Cursor) error {
 		var user User
-		err = cur.Decode(&user)
-		if err != nil {
-			log.Printf("Error on Decoding the document: %v\n", err)
+		if err := cur.Decode(&user); err != nil {
+			return err
 		}
 		users = append(users, &user)
-	}
-	log.Printf("documentReturned: %v\n", users)
-	return users, nil
+		return nil
+	})
+	return users, page, dbError(err)
 }
 
 // RemoveOneUser remove one user from collctions
-func (c *UserModelImpl) RemoveOneUser(filter interface{}) (int64, error) {
+func (c *UserModelImpl) RemoveOneUser(ctx context.Context, id primitive.ObjectID) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("users")
-	deleteResult, err := collection.DeleteOne(context.TODO(), filter)
+	deleteResult, err := collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
-		log.Fatal("Error on deleting one user", err)
-		return 0, err
+		log.Printf("Error on deleting one user: %v\n", err)
+		return 0, dbError(err)
 	}
 	return deleteResult.DeletedCount, nil
 }
 
 // UpdateOneUser update one user from collections
-func (c *UserModelImpl) UpdateOneUser(updatedData interface{}, filter interface{}) (int64, error) {
+func (c *UserModelImpl) UpdateOneUser(ctx context.Context, id primitive.ObjectID, update UserUpdate) (int64, error) {
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	collection := c.db.Client.Database(c.db.DBName).Collection("users")
+	now := time.Now()
+	updatedData := bson.M{"updated_at": now}
+	if update.Name != nil {
+		updatedData["name"] = *update.Name
+	}
+	if update.IsActive != nil {
+		updatedData["is_active"] = *update.IsActive
+	}
+	if update.PasswordHash != nil {
+		updatedData["password_hash"] = *update.PasswordHash
+		updatedData["password_changed_at"] = now
+	}
+	if update.Locale != nil {
+		updatedData["locale"] = *update.Locale
+	}
 	atualizacao := bson.D{{Key: "$set", Value: updatedData}}
-	updatedResult, err := collection.UpdateOne(context.TODO(), filter, atualizacao)
+	updatedResult, err := collection.UpdateOne(ctx, bson.M{"_id": id}, atualizacao)
 	if err != nil {
-		log.Fatal("Error on updating one user", err)
-		return 0, err
+		log.Printf("Error on updating one user: %v\n", err)
+		return 0, dbError(err)
 	}
 	return updatedResult.ModifiedCount, nil
 }
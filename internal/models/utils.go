@@ -1,9 +1,15 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"reflect"
+	"strings"
+	"unicode"
 
 	ut "github.com/go-playground/universal-translator"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
 	"gopkg.in/go-playground/validator.v9"
 )
 
@@ -19,14 +25,195 @@ func (v *Validator) Validate(i interface{}) error {
 	if err == nil {
 		return nil
 	}
-	errs := err.(validator.ValidationErrors)
-	// return pretty errors
-	msg := ""
-	for _, v := range errs.Translate(v.Trans) {
-		if msg != "" {
-			msg += ", "
+	validationErrs := err.(validator.ValidationErrors)
+	// return pretty errors, one per field, and all of them joined as the message
+	fields := make([]errs.FieldError, 0, len(validationErrs))
+	messages := make([]string, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		message := fe.Translate(v.Trans)
+		fields = append(fields, errs.FieldError{
+			Field:   fieldPath(reflect.TypeOf(i), fe.StructNamespace()),
+			Code:    fe.Tag(),
+			Message: message,
+		})
+		messages = append(messages, message)
+	}
+	return &errs.Error{Kind: errs.Validation, Message: strings.Join(messages, ", "), Err: validationErrs, Fields: fields}
+}
+
+// fieldPath the path of the failed field as sent by the client, the struct namespace
+// `ExpenseInput.Tags[0]` is reported with the json or query names as `tags[0]`
+func fieldPath(t reflect.Type, namespace string) string {
+	segments := strings.Split(namespace, ".")[1:]
+	path := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		name, index := segment, ""
+		if i := strings.IndexByte(segment, '['); i >= 0 {
+			name, index = segment[:i], segment[i:]
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			if f, ok := t.FieldByName(name); ok {
+				name, t = tagName(f), f.Type
+			}
+		}
+		// every index goes one level down into the elements
+		for n := strings.Count(index, "["); n > 0; n-- {
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+				t = t.Elem()
+			}
+		}
+		path = append(path, name+index)
+	}
+	return strings.Join(path, ".")
+}
+
+// tagName the name of the field in the request body or query
+func tagName(f reflect.StructField) string {
+	for _, key := range []string{"json", "query"} {
+		if name := strings.Split(f.Tag.Get(key), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// RegisterCustomValidations register the custom validation tags with their translations
+func RegisterCustomValidations(v *validator.Validate, trans ut.Translator) error {
+	if err := v.RegisterValidation("password", validatePassword); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("currency", validateCurrency); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("date", validateDate); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("objectid", validateObjectID); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("locale", validateLocale); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("slug", validateSlug); err != nil {
+		return err
+	}
+	if err := v.RegisterValidation("rrule", validateRRule); err != nil {
+		return err
+	}
+	v.RegisterStructValidation(validateExpenseInput, ExpenseInput{})
+	v.RegisterStructValidation(validateRecurringExpenseInput, RecurringExpenseInput{})
+	v.RegisterStructValidation(validateExpenseFilter, ExpenseFilter{})
+	return RegisterTranslations(v, trans, i18n.CustomValidationMessages)
+}
+
+// RegisterTranslations register the messages of the validation tags in the translator,
+// `{0}` is replaced by the field and `{1}` by the param of the tag
+func RegisterTranslations(v *validator.Validate, trans ut.Translator, messages map[string]string) error {
+	for tag, message := range messages {
+		tag, message := tag, message
+		err := v.RegisterTranslation(tag, trans, func(ut ut.Translator) error {
+			return ut.Add(tag, message, true)
+		}, func(ut ut.Translator, fe validator.FieldError) string {
+			t, _ := ut.T(tag, fe.Field(), fe.Param())
+			return t
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LocalizeValidation translate the messages of the validation error with the translator of the request
+func LocalizeValidation(err error, trans ut.Translator) error {
+	var typed *errs.Error
+	var validationErrs validator.ValidationErrors
+	if trans == nil || !errors.As(err, &typed) || !errors.As(err, &validationErrs) || len(validationErrs) != len(typed.Fields) {
+		return err
+	}
+	fields := make([]errs.FieldError, len(typed.Fields))
+	messages := make([]string, len(typed.Fields))
+	for i, fe := range validationErrs {
+		fields[i] = typed.Fields[i]
+		fields[i].Message = fe.Translate(trans)
+		messages[i] = fields[i].Message
+	}
+	return &errs.Error{Kind: typed.Kind, Message: strings.Join(messages, ", "), Err: typed.Err, Fields: fields}
+}
+
+// validateLocale check the field is a supported locale
+func validateLocale(fl validator.FieldLevel) bool {
+	return i18n.IsSupported(fl.Field().String())
+}
+
+// validateSlug check the field is a slug
+func validateSlug(fl validator.FieldLevel) bool {
+	return isSlug(fl.Field().String())
+}
+
+// validateRRule check the field is a supported recurrence rule
+func validateRRule(fl validator.FieldLevel) bool {
+	_, err := ParseRRule(fl.Field().String())
+	return err == nil
+}
+
+// validateCurrency check the field is a supported currency code
+func validateCurrency(fl validator.FieldLevel) bool {
+	return IsCurrency(fl.Field().String())
+}
+
+// validatePassword check the password strength
+// bcrypt only uses the first 72 bytes so longer passwords are rejected
+func validatePassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < 8 || len(password) > 72 {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
 		}
-		msg += v
 	}
-	return errors.New(msg)
+	return upper && lower && digit
+}
+
+// validateExpenseInput check the total is an exact positive amount of the currency
+func validateExpenseInput(sl validator.StructLevel) {
+	input := sl.Current().Interface().(ExpenseInput)
+	validateTotal(sl, input.Total, input.Currency)
+}
+
+// validateRecurringExpenseInput check the total is an exact positive amount of the currency
+func validateRecurringExpenseInput(sl validator.StructLevel) {
+	input := sl.Current().Interface().(RecurringExpenseInput)
+	validateTotal(sl, input.Total, input.Currency)
+}
+
+// validateTotal report the total field unless it is an exact positive amount of the currency
+func validateTotal(sl validator.StructLevel, total json.Number, currency string) {
+	if total == "" || !IsCurrency(currency) {
+		// reported by the field validations
+		return
+	}
+	money, err := ParseMoney(total.String(), currency)
+	if err != nil || money.IsZero() {
+		sl.ReportError(total, "total", "Total", "money", "")
+	}
+}
+
+// Bool returns a pointer to the value, for the optional fields of the filters and updates
+func Bool(v bool) *bool {
+	return &v
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     interface{}
+		namespace string
+		want      string
+	}{
+		{"json name", ExpenseInput{}, "ExpenseInput.CategoryID", "category_id"},
+		{"slice element", ExpenseInput{}, "ExpenseInput.Tags[1]", "tags[1]"},
+		{"query name", ExpenseFilter{}, "ExpenseFilter.Categories[0]", "category[0]"},
+		{"pointer", &User{}, "User.PhoneNumber", "phone_number"},
+		{"unknown field", ExpenseInput{}, "ExpenseInput.Unknown", "Unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, fieldPath(reflect.TypeOf(tt.input), tt.namespace))
+		})
+	}
+}
@@ -0,0 +1,237 @@
+// Package notify the notifications of the budget thresholds & the pending expenses, delivered on the channels chosen by the users
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+)
+
+// Channel delivery of the notifications to the users
+type Channel interface {
+	Name() string
+	// Enabled check the user chose the channel in the preferences
+	Enabled(prefs models.NotificationPreferences) bool
+	Send(ctx context.Context, user models.User, prefs models.NotificationPreferences, n models.Notification) error
+}
+
+// InboxChannel store the notifications in the in-app inbox of the users
+type InboxChannel struct {
+	model models.NotificationModeler
+}
+
+// NewInboxChannel the inbox stored by the model
+func NewInboxChannel(model models.NotificationModeler) *InboxChannel {
+	return &InboxChannel{model}
+}
+
+// Name godoc
+func (c *InboxChannel) Name() string { return "inbox" }
+
+// Enabled godoc
+func (c *InboxChannel) Enabled(prefs models.NotificationPreferences) bool { return prefs.Inbox }
+
+// Send insert the notification in the inbox of the user
+func (c *InboxChannel) Send(ctx context.Context, user models.User, prefs models.NotificationPreferences, n models.Notification) error {
+	_, err := c.model.Insert(ctx, &n)
+	return err
+}
+
+// SMTPConfig the mail server and the sender of the emails, no auth without a username
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// SMTPChannel email the notifications to the address of the users
+type SMTPChannel struct {
+	config SMTPConfig
+	send   func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewSMTPChannel the emails sent through the mail server of the config
+func NewSMTPChannel(config SMTPConfig) *SMTPChannel {
+	return &SMTPChannel{config: config, send: smtp.SendMail}
+}
+
+// Name godoc
+func (c *SMTPChannel) Name() string { return "email" }
+
+// Enabled godoc
+func (c *SMTPChannel) Enabled(prefs models.NotificationPreferences) bool { return prefs.Email }
+
+// Send email the notification as plain text
+func (c *SMTPChannel) Send(ctx context.Context, user models.User, prefs models.NotificationPreferences, n models.Notification) error {
+	if user.Email == "" {
+		return nil
+	}
+	var auth smtp.Auth
+	if c.config.Username != "" {
+		auth = smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)
+	}
+	addr := net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
+	return c.send(addr, auth, c.config.From, []string{user.Email}, c.message(user, n))
+}
+
+// message the headers & the body of the email
+func (c *SMTPChannel) message(user models.User, n models.Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", c.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", user.Email)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.CreatedAt.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(n.Body)
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
+
+// SignatureHeader header of the HMAC-SHA256 signature of the webhook payloads
+const SignatureHeader = "X-Signature-256"
+
+// ErrNotPublic the webhook host is not a public address
+var ErrNotPublic = errors.New("the webhook address is not public")
+
+// nonPublicNetworks the loopback, private, link-local, shared, multicast & reserved ranges
+var nonPublicNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "64:ff9b::/96", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, networks[i], _ = net.ParseCIDR(cidr)
+	}
+	return networks
+}
+
+// PublicIP check the address is reachable on the internet, the IPv4-mapped addresses are checked as IPv4
+func PublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// PublicHost check the host of a webhook URL is a public address, the names are resolved.
+// A name which does not resolve yet is accepted, the address is checked again when the webhook is posted.
+func PublicHost(ctx context.Context, host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return PublicIP(ip)
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return true
+	}
+	for _, addr := range addrs {
+		if !PublicIP(addr.IP) {
+			return false
+		}
+	}
+	return true
+}
+
+// publicOnly refuse to connect to an address which is not public. It runs on the resolved
+// address of every connection, so neither a DNS rebinding nor a redirect reaches the internal network.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !PublicIP(net.ParseIP(host)) {
+		return fmt.Errorf("%w: %s", ErrNotPublic, host)
+	}
+	return nil
+}
+
+// WebhookChannel post the notifications as JSON to the webhook URL of the users.
+// The payloads are signed with the secret when it is set, see Sign.
+// The webhooks are only posted to public addresses, see PublicIP.
+type WebhookChannel struct {
+	secret string
+	client *http.Client
+}
+
+// NewWebhookChannel the webhooks signed with the secret, posted with the timeout
+func NewWebhookChannel(secret string, timeout time.Duration) *WebhookChannel {
+	dialer := &net.Dialer{Timeout: timeout, Control: publicOnly}
+	// no proxy, the address checked is the one connected to
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &WebhookChannel{secret: secret, client: &http.Client{Timeout: timeout, Transport: transport}}
+}
+
+// Name godoc
+func (c *WebhookChannel) Name() string { return "webhook" }
+
+// Enabled godoc
+func (c *WebhookChannel) Enabled(prefs models.NotificationPreferences) bool {
+	return prefs.Webhook && prefs.WebhookURL != ""
+}
+
+// Send post the notification, any status other than 2xx is an error
+func (c *WebhookChannel) Send(ctx context.Context, user models.User, prefs models.NotificationPreferences, n models.Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, prefs.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.secret != "" {
+		req.Header.Set(SignatureHeader, Sign(c.secret, payload))
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s answered %s", prefs.WebhookURL, res.Status)
+	}
+	return nil
+}
+
+// Sign the `sha256=<hex>` HMAC signature of the payload, the receivers compute it again to check it
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,356 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BudgetLevels the percents of a budget the members are notified of, ascending
+var BudgetLevels = []int64{50, 80, 100}
+
+// RetryDelay delay before a failed check of a queued project is run again
+var RetryDelay = 30 * time.Second
+
+// pendingPageSize page size of the scan of the submitted expenses
+const pendingPageSize = 500
+
+// Notifier notify the users of the budget thresholds crossed by the projects and of the expenses
+// pending approval for too long. Each alert is claimed per recipient before it is sent,
+// so a user is never told twice even when the scans overlap or several instances run.
+type Notifier struct {
+	models       models.Models
+	tracker      *models.BudgetTracker
+	uni          *ut.UniversalTranslator
+	pendingAfter time.Duration
+	channels     []Channel
+	now          func() time.Time
+
+	mu       sync.Mutex
+	projects map[primitive.ObjectID]bool
+	wake     chan struct{}
+}
+
+// NewNotifier the notifier of the models, an expense is pending for too long after pendingAfter.
+// The titles & bodies are translated into the locale of each recipient.
+func NewNotifier(m models.Models, uni *ut.UniversalTranslator, pendingAfter time.Duration, channels ...Channel) *Notifier {
+	return &Notifier{
+		models:       m,
+		tracker:      models.NewBudgetTracker(m, BudgetLevels[len(BudgetLevels)-1]),
+		uni:          uni,
+		pendingAfter: pendingAfter,
+		channels:     channels,
+		now:          time.Now,
+		projects:     map[primitive.ObjectID]bool{},
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// Wrap the models so the projects of the created expenses are queued for a budget check
+func (n *Notifier) Wrap(m models.Models) models.Models {
+	m.Expenses = notifyingExpenses{m.Expenses, n}
+	return m
+}
+
+// ProjectChanged queue the budget check of the project
+func (n *Notifier) ProjectChanged(id primitive.ObjectID) {
+	n.mu.Lock()
+	n.projects[id] = true
+	n.mu.Unlock()
+	n.signal()
+}
+
+// signal wake the notifier up, a pending signal already covers the new projects
+func (n *Notifier) signal() {
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run check the queued projects as they come and scan everything every interval until the context is done.
+// The first scan runs at once, it catches up with the alerts missed while the server was down.
+func (n *Notifier) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	if err := n.Scan(ctx); err != nil {
+		log.Printf("NOTIFICATION SCAN ERROR: %v\n", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := n.Scan(ctx); err != nil {
+				log.Printf("NOTIFICATION SCAN ERROR: %v\n", err)
+			}
+		case <-n.wake:
+			if err := n.checkQueued(ctx); err != nil {
+				log.Printf("BUDGET NOTIFICATION ERROR: %v\n", err)
+				time.AfterFunc(RetryDelay, n.signal)
+			}
+		}
+	}
+}
+
+// checkQueued check the budgets of the queued projects, the failed ones are queued again
+func (n *Notifier) checkQueued(ctx context.Context) error {
+	n.mu.Lock()
+	projects := n.projects
+	n.projects = map[primitive.ObjectID]bool{}
+	n.mu.Unlock()
+
+	var failed error
+	for id := range projects {
+		if err := n.CheckBudgets(ctx, id); err != nil {
+			failed = err
+			n.mu.Lock()
+			n.projects[id] = true
+			n.mu.Unlock()
+		}
+	}
+	return failed
+}
+
+// Scan check the budgets of every active project and the pending expenses
+func (n *Notifier) Scan(ctx context.Context) error {
+	projects, _, err := n.models.Projects.ReadAll(ctx, models.ProjectFilter{}, models.ListOptions{})
+	if err != nil {
+		return err
+	}
+	var failed error
+	for _, project := range projects {
+		if !project.IsActive {
+			continue
+		}
+		if err := n.CheckBudgets(ctx, project.ID); err != nil {
+			log.Printf("Error on checking the budgets of project %s: %v\n", project.ID.Hex(), err)
+			failed = err
+		}
+	}
+	if err := n.CheckPending(ctx); err != nil {
+		failed = err
+	}
+	return failed
+}
+
+// CheckBudgets notify the budget managers of the project of the levels its budgets reached in their current period.
+// A budget jumping over several levels at once is notified of the highest one only.
+func (n *Notifier) CheckBudgets(ctx context.Context, projectID primitive.ObjectID) error {
+	project, err := n.models.Projects.ReadOne(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	today := n.now().UTC().Truncate(24 * time.Hour)
+	lines, err := n.tracker.Report(ctx, projectID, models.ProjectDetailsQS{Start: today, End: today.AddDate(0, 0, 1)})
+	if err != nil {
+		return err
+	}
+	var recipients []models.User
+	for _, line := range lines {
+		var reached []int64
+		for _, level := range BudgetLevels {
+			if line.Reached(level) {
+				reached = append(reached, level)
+			}
+		}
+		if len(reached) == 0 {
+			continue
+		}
+		if recipients == nil {
+			if recipients, err = n.projectMembers(ctx, projectID, auth.PermProjectBudgets); err != nil {
+				return err
+			}
+		}
+		period := string(line.Period)
+		if line.Start != nil {
+			period = line.Start.Format(models.DateLayout)
+		}
+		for _, user := range recipients {
+			// every reached level is claimed, so the lower ones are not sent later
+			var level int64
+			for _, l := range reached {
+				claimed, err := n.claim(ctx, fmt.Sprintf("budget:%s:%s:%d", line.BudgetID.Hex(), period, l), user)
+				if err != nil {
+					return err
+				}
+				if claimed {
+					level = l
+				}
+			}
+			if level == 0 {
+				continue
+			}
+			n.deliver(ctx, user, models.Notification{
+				Kind:      models.NotifyBudgetThreshold,
+				Title:     n.localize(user, i18n.NotifyBudgetTitle, strconv.FormatInt(level, 10)),
+				Body:      n.localize(user, i18n.NotifyBudgetBody, money(line.Spent), money(line.Budgeted), string(line.Period), project.Title),
+				ProjectID: projectID,
+				BudgetID:  line.BudgetID,
+			})
+		}
+	}
+	return nil
+}
+
+// CheckPending notify the approvers of the expenses submitted for longer than the pending delay.
+// An expense is notified once per submission, it is notified again when it is submitted again.
+func (n *Notifier) CheckPending(ctx context.Context) error {
+	deadline := n.now().Add(-n.pendingAfter)
+	opts := models.ListOptions{Limit: pendingPageSize}
+	for {
+		expenses, page, err := n.models.Expenses.ReadAll(ctx, models.ExpenseFilter{Statuses: []string{string(models.StatusSubmitted)}}, opts)
+		if err != nil {
+			return err
+		}
+		for _, expense := range expenses {
+			submittedAt := submittedAt(expense)
+			if submittedAt.After(deadline) {
+				continue
+			}
+			approvers, err := n.approvers(ctx, expense)
+			if err != nil {
+				return err
+			}
+			key := fmt.Sprintf("expense-pending:%s:%d", expense.ID.Hex(), submittedAt.Unix())
+			for _, user := range approvers {
+				claimed, err := n.claim(ctx, key, user)
+				if err != nil {
+					return err
+				}
+				if !claimed {
+					continue
+				}
+				n.deliver(ctx, user, models.Notification{
+					Kind:      models.NotifyExpensePending,
+					Title:     n.localize(user, i18n.NotifyPendingTitle),
+					Body:      n.localize(user, i18n.NotifyPendingBody, expense.Title, money(expense.Total), submittedAt.Format(models.DateLayout)),
+					ProjectID: expense.ProjectID,
+					ExpenseID: expense.ID,
+				})
+			}
+		}
+		if page.NextCursor == "" {
+			return nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// claim claim the alert for the user
+func (n *Notifier) claim(ctx context.Context, key string, user models.User) (bool, error) {
+	return n.models.Notifications.Claim(ctx, key+":"+user.ID.Hex())
+}
+
+// deliver send the notification on the channels chosen by the user, unless the user muted its kind.
+// The failures of a channel are logged, they do not stop the other channels.
+func (n *Notifier) deliver(ctx context.Context, user models.User, notification models.Notification) {
+	prefs, err := n.models.Notifications.ReadPreferences(ctx, user.ID)
+	if err != nil {
+		log.Printf("Error on reading the notification preferences of user %s: %v\n", user.ID.Hex(), err)
+		return
+	}
+	if prefs.IsMuted(notification.Kind) {
+		return
+	}
+	notification.ID = primitive.NewObjectID()
+	notification.CreatedAt = n.now()
+	notification.UserID = user.ID
+	for _, channel := range n.channels {
+		if !channel.Enabled(prefs) {
+			continue
+		}
+		if err := channel.Send(ctx, user, prefs, notification); err != nil {
+			log.Printf("Error on sending the %s notification to user %s: %v\n", channel.Name(), user.ID.Hex(), err)
+		}
+	}
+}
+
+// projectMembers the active users of the active memberships of the project granted the permission
+func (n *Notifier) projectMembers(ctx context.Context, projectID primitive.ObjectID, perm auth.Permission) ([]models.User, error) {
+	members, err := n.models.Projects.ReadAllProjectMembers(ctx, models.ProjectUserFilter{ProjectID: projectID, IsActive: models.Bool(true)})
+	if err != nil {
+		return nil, err
+	}
+	users := []models.User{}
+	for _, member := range members {
+		if member.User.IsActive && auth.CanInProject(member.Role, perm) {
+			users = append(users, member.User)
+		}
+	}
+	return users, nil
+}
+
+// approvers the users who can approve the expense, like the approval workflow: the approvers of its project,
+// the users with the global approve permission for the expenses out of projects. The author is not an approver.
+func (n *Notifier) approvers(ctx context.Context, expense models.Expense) ([]models.User, error) {
+	var candidates []models.User
+	if expense.ProjectID.IsZero() {
+		users, _, err := n.models.Users.ReadAllUsers(ctx, models.UserFilter{IsActive: models.Bool(true)}, models.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if auth.Can(user.Role, auth.PermExpensesApprove) {
+				candidates = append(candidates, *user)
+			}
+		}
+	} else {
+		members, err := n.projectMembers(ctx, expense.ProjectID, auth.PermProjectApprove)
+		if err != nil {
+			return nil, err
+		}
+		candidates = members
+	}
+	approvers := []models.User{}
+	for _, user := range candidates {
+		if user.ID != expense.InsertedBy.ID {
+			approvers = append(approvers, user)
+		}
+	}
+	return approvers, nil
+}
+
+// localize the message in the locale of the user
+func (n *Notifier) localize(user models.User, message string, params ...string) string {
+	return i18n.Localize(n.uni, user.Locale, message, params...)
+}
+
+// submittedAt the time of the last submission of the expense, its last update when the history is missing
+func submittedAt(expense models.Expense) time.Time {
+	for i := len(expense.History) - 1; i >= 0; i-- {
+		if expense.History[i].To == models.StatusSubmitted {
+			return expense.History[i].At
+		}
+	}
+	return expense.UpdatedAt
+}
+
+// money the amount with its currency
+func money(m models.Money) string {
+	return m.String() + " " + m.Currency
+}
+
+// notifyingExpenses ExpenseModeler queuing the projects of the created expenses to the notifier
+type notifyingExpenses struct {
+	models.ExpenseModeler
+	notifier *Notifier
+}
+
+// Insert insert the expense and queue the budget check of its project
+func (e notifyingExpenses) Insert(ctx context.Context, expense models.Expense) (interface{}, error) {
+	id, err := e.ExpenseModeler.Insert(ctx, expense)
+	if err == nil && !expense.ProjectID.IsZero() {
+		e.notifier.ProjectChanged(expense.ProjectID)
+	}
+	return id, err
+}
@@ -0,0 +1,233 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recorder Channel keeping the sent notifications
+type recorder struct {
+	sent []models.Notification
+	err  error
+}
+
+func (r *recorder) Name() string                                      { return "recorder" }
+func (r *recorder) Enabled(prefs models.NotificationPreferences) bool { return prefs.Inbox }
+func (r *recorder) Send(ctx context.Context, user models.User, prefs models.NotificationPreferences, n models.Notification) error {
+	r.sent = append(r.sent, n)
+	return r.err
+}
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+	m := models.NewMemoryModels(models.NewMemoryStore())
+	rec := &recorder{}
+	n := NewNotifier(m, i18n.New(), 48*time.Hour, NewInboxChannel(m.Notifications), rec)
+	today := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return today }
+
+	user := func(name string, role models.Role, locale string) models.User {
+		u := models.User{ID: primitive.NewObjectID(), Name: name, Email: name + "@example.com", Role: role, IsActive: true, Locale: locale}
+		_, err := m.Users.InsertNewUser(ctx, &u)
+		assert.NoError(t, err)
+		return u
+	}
+	admin := user("admin", models.RoleAdmin, "")
+	supervisor := user("supervisor", models.RoleSupervisor, "de")
+	staff := user("staff", models.RoleStaff, "")
+
+	project := models.Project{ID: primitive.NewObjectID(), Title: "trip", BaseCurrency: "EUR", IsActive: true}
+	_, err := m.Projects.Insert(ctx, &project)
+	assert.NoError(t, err)
+	for _, member := range []struct {
+		user models.User
+		role models.Role
+	}{{supervisor, models.RoleSupervisor}, {staff, models.RoleStaff}} {
+		_, err := m.Projects.InsertProjectUser(ctx, &models.ProjectUser{ID: primitive.NewObjectID(), ProjectID: project.ID, UserID: member.user.ID, Role: member.role, IsActive: true})
+		assert.NoError(t, err)
+	}
+	budget := models.Budget{ID: primitive.NewObjectID(), ProjectID: project.ID, Period: models.BudgetMonthly, Amount: models.NewMoney(10000, "EUR")}
+	_, err = m.Budgets.Insert(ctx, &budget)
+	assert.NoError(t, err)
+
+	m = n.Wrap(m)
+	insert := func(author models.User, amount int64, status models.ExpenseStatus, history ...models.StatusTransition) models.Expense {
+		expense := models.Expense{
+			ID: primitive.NewObjectID(), CreatedAt: today, UpdatedAt: today, Date: today, Title: "taxi",
+			Total: models.NewMoney(amount, "EUR"), Status: status, ProjectID: project.ID,
+			InsertedBy: author.Snapshot(), History: history,
+		}
+		_, err := m.Expenses.Insert(ctx, expense)
+		assert.NoError(t, err)
+		return expense
+	}
+	budgetAlerts := func() []models.Notification {
+		var alerts []models.Notification
+		for _, sent := range rec.sent {
+			if sent.Kind == models.NotifyBudgetThreshold {
+				alerts = append(alerts, sent)
+			}
+		}
+		return alerts
+	}
+
+	// 40% reaches no level
+	insert(staff, 4000, models.StatusDraft)
+	assert.NoError(t, n.checkQueued(ctx))
+	assert.Empty(t, rec.sent)
+
+	// 90% jumps over 50 & 80, only the highest is sent to the budget managers, in their locale
+	insert(staff, 5000, models.StatusDraft)
+	assert.NoError(t, n.checkQueued(ctx))
+	if alerts := budgetAlerts(); assert.Len(t, alerts, 1) {
+		assert.Equal(t, supervisor.ID, alerts[0].UserID)
+		assert.Equal(t, budget.ID, alerts[0].BudgetID)
+		assert.Equal(t, "80% des Budgets erreicht", alerts[0].Title)
+		assert.Contains(t, alerts[0].Body, "90.00 EUR")
+	}
+	// the same level is not sent again, by the queue nor by the scan
+	assert.NoError(t, n.Scan(ctx))
+	assert.Len(t, budgetAlerts(), 1)
+
+	// 100% is a new level, the user muted the budgets meanwhile
+	prefs := models.DefaultNotificationPreferences(supervisor.ID)
+	prefs.Muted = []models.NotificationKind{models.NotifyBudgetThreshold}
+	assert.NoError(t, m.Notifications.UpdatePreferences(ctx, prefs))
+	insert(staff, 1000, models.StatusDraft)
+	assert.NoError(t, n.checkQueued(ctx))
+	assert.Len(t, budgetAlerts(), 1)
+	// the muted alert is claimed, it is not sent once unmuted
+	assert.NoError(t, m.Notifications.UpdatePreferences(ctx, models.DefaultNotificationPreferences(supervisor.ID)))
+	assert.NoError(t, n.Scan(ctx))
+	assert.Len(t, budgetAlerts(), 1)
+
+	// the expenses submitted 2 days ago are pending for too long, the author is not told
+	submitted := func(at time.Time) models.StatusTransition {
+		return models.StatusTransition{Action: models.ActionSubmit, From: models.StatusDraft, To: models.StatusSubmitted, At: at}
+	}
+	insert(supervisor, 100, models.StatusSubmitted, submitted(today.Add(-49*time.Hour)))
+	insert(staff, 100, models.StatusSubmitted, submitted(today.Add(-47*time.Hour)))
+	rec.sent = nil
+	assert.NoError(t, n.CheckPending(ctx))
+	assert.Empty(t, rec.sent)
+	late := insert(staff, 100, models.StatusSubmitted, submitted(today.Add(-50*time.Hour)))
+	assert.NoError(t, n.CheckPending(ctx))
+	if assert.Len(t, rec.sent, 1) {
+		assert.Equal(t, models.NotifyExpensePending, rec.sent[0].Kind)
+		assert.Equal(t, supervisor.ID, rec.sent[0].UserID)
+		assert.Equal(t, late.ID, rec.sent[0].ExpenseID)
+	}
+	// the catch up after a restart does not send them again
+	n2 := NewNotifier(m, i18n.New(), 48*time.Hour, rec)
+	n2.now = n.now
+	assert.NoError(t, n2.Scan(ctx))
+	assert.Len(t, rec.sent, 1)
+
+	// the expenses out of projects are approved by the users with the global approve permission
+	outside := models.Expense{ID: primitive.NewObjectID(), UpdatedAt: today.Add(-72 * time.Hour), Date: today, Title: "lunch",
+		Total: models.NewMoney(100, "EUR"), Status: models.StatusSubmitted, InsertedBy: staff.Snapshot()}
+	_, err = m.Expenses.Insert(ctx, outside)
+	assert.NoError(t, err)
+	rec.sent = nil
+	assert.NoError(t, n.CheckPending(ctx))
+	var approvers []primitive.ObjectID
+	for _, sent := range rec.sent {
+		approvers = append(approvers, sent.UserID)
+	}
+	assert.ElementsMatch(t, []primitive.ObjectID{admin.ID, supervisor.ID}, approvers)
+
+	// the inbox channel stored the notifications, a failing channel does not stop the others
+	inbox, _, err := m.Notifications.ReadAll(ctx, models.NotificationFilter{UserID: admin.ID}, models.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, inbox, 1)
+	rec.err = errors.New("down")
+	retried := insert(staff, 100, models.StatusSubmitted, submitted(today.Add(-60*time.Hour)))
+	assert.NoError(t, n.CheckPending(ctx))
+	inbox, _, err = m.Notifications.ReadAll(ctx, models.NotificationFilter{UserID: supervisor.ID, Unread: true}, models.ListOptions{})
+	assert.NoError(t, err)
+	found := false
+	for _, notification := range inbox {
+		found = found || notification.ExpenseID == retried.ID
+	}
+	assert.True(t, found)
+}
+
+func TestSMTPChannel(t *testing.T) {
+	c := NewSMTPChannel(SMTPConfig{Host: "smtp.example.com", Port: 587, Username: "bot", Password: "secret", From: "bot@example.com"})
+	var addr, from string
+	var to []string
+	var msg []byte
+	c.send = func(a string, auth smtp.Auth, f string, t []string, m []byte) error {
+		addr, from, to, msg = a, f, t, m
+		return nil
+	}
+	user := models.User{Email: "jane@example.com"}
+	n := models.Notification{Title: "Budget überschritten", Body: "8000 EUR", CreatedAt: time.Now()}
+	assert.True(t, c.Enabled(models.DefaultNotificationPreferences(user.ID)))
+	assert.NoError(t, c.Send(context.Background(), user, models.NotificationPreferences{}, n))
+	assert.Equal(t, "smtp.example.com:587", addr)
+	assert.Equal(t, "bot@example.com", from)
+	assert.Equal(t, []string{"jane@example.com"}, to)
+	assert.Contains(t, string(msg), "Subject: =?utf-8?q?Budget_=C3=BCberschritten?=\r\n")
+	assert.True(t, strings.HasSuffix(string(msg), "\r\n\r\n8000 EUR\r\n"))
+}
+
+func TestPublicAddress(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		assert.False(t, PublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "93.184.216.34", "2606:4700::1111"} {
+		assert.True(t, PublicIP(net.ParseIP(ip)), ip)
+	}
+	ctx := context.Background()
+	for _, host := range []string{"localhost", "api.localhost", "169.254.169.254", "::1"} {
+		assert.False(t, PublicHost(ctx, host), host)
+	}
+	assert.True(t, PublicHost(ctx, "8.8.8.8"))
+}
+
+func TestWebhookChannel(t *testing.T) {
+	var signature string
+	var received models.Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		assert.Equal(t, Sign("secret", body), signature)
+		assert.NoError(t, json.Unmarshal(body, &received))
+		if received.Title == "fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	c := NewWebhookChannel("secret", time.Second)
+	prefs := models.NotificationPreferences{Webhook: true, WebhookURL: server.URL}
+	assert.True(t, c.Enabled(prefs))
+	assert.False(t, c.Enabled(models.NotificationPreferences{Webhook: true}))
+	n := models.Notification{ID: primitive.NewObjectID(), Kind: models.NotifyExpensePending, Title: "pending"}
+	// the test server listens on the loopback
+	err := c.Send(context.Background(), models.User{}, prefs, n)
+	assert.True(t, errors.Is(err, ErrNotPublic), err)
+	assert.True(t, received.ID.IsZero())
+
+	c.client = server.Client()
+	assert.NoError(t, c.Send(context.Background(), models.User{}, prefs, n))
+	assert.Equal(t, n.ID, received.ID)
+	assert.True(t, strings.HasPrefix(signature, "sha256="))
+	n.Title = "fail"
+	assert.Error(t, c.Send(context.Background(), models.User{}, prefs, n))
+}
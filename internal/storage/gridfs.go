@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/masihur1989/expense-tracker-api/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GridFSStorage store the objects in a MongoDB GridFS bucket
+type GridFSStorage struct {
+	bucket *gridfs.Bucket
+}
+
+// NewGridFSStorage godoc
+func NewGridFSStorage(client db.MongoDBClient, bucketName string) (*GridFSStorage, error) {
+	opts := options.GridFSBucket().SetName(bucketName)
+	bucket, err := gridfs.NewBucket(client.Client.Database(client.DBName), opts)
+	if err != nil {
+		return nil, err
+	}
+	return &GridFSStorage{bucket}, nil
+}
+
+// Put upload the object with the key as file id
+func (s *GridFSStorage) Put(key string, r io.Reader) error {
+	return s.bucket.UploadFromStreamWithID(key, key, r)
+}
+
+// Get open a download stream of the object
+func (s *GridFSStorage) Get(key string) (io.ReadCloser, error) {
+	stream, err := s.bucket.OpenDownloadStream(key)
+	if err == gridfs.ErrFileNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// Exists check if the object is stored
+func (s *GridFSStorage) Exists(key string) (bool, error) {
+	count, err := s.bucket.GetFilesCollection().CountDocuments(context.TODO(), bson.M{"_id": key})
+	return count > 0, err
+}
+
+// Delete remove the object and its chunks
+func (s *GridFSStorage) Delete(key string) error {
+	err := s.bucket.Delete(key)
+	if err == gridfs.ErrFileNotFound {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotFound returned when no object is stored under the key
+var ErrNotFound = errors.New("object not found")
+
+// Storage blob storage for the uploaded files.
+// Objects are content addressed, the key is the sha256 of the content.
+type Storage interface {
+	Put(key string, r io.Reader) error
+	Get(key string) (io.ReadCloser, error)
+	Exists(key string) (bool, error)
+	Delete(key string) error
+}
@@ -1,33 +1,109 @@
 package utils
 
-import "github.com/labstack/echo/v4"
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masihur1989/expense-tracker-api/internal/errs"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
+)
 
 // Response contains properties to be responded
 type Response struct {
-	Code    int         `json:"code"`
-	Data    interface{} `json:"data"`
-	Message string      `json:"message"`
-	Success bool        `json:"success"`
+	Code    int               `json:"code"`
+	Data    interface{}       `json:"data"`
+	Message string            `json:"message"`
+	Success bool              `json:"success"`
+	Meta    interface{}       `json:"meta,omitempty"`
+	Errors  []errs.FieldError `json:"errors,omitempty"`
 }
 
-// Data returns wrapped success response
+// Data returns wrapped success response, the message is translated into the locale of the request
 func Data(code int, data interface{}, message string, c echo.Context) error {
 	props := &Response{
 		Code:    code,
 		Data:    data,
-		Message: message,
+		Message: i18n.T(c, message),
 		Success: true,
 	}
 	return c.JSON(code, props)
 }
 
-// Error return the wrapped error response
-func Error(code int, message string, c echo.Context) error {
+// DataMeta returns wrapped success response with metadata about the data
+func DataMeta(code int, data interface{}, meta interface{}, message string, c echo.Context) error {
 	props := &Response{
 		Code:    code,
+		Data:    data,
+		Message: i18n.T(c, message),
+		Success: true,
+		Meta:    meta,
+	}
+	return c.JSON(code, props)
+}
+
+// MIMEApplicationProblemJSON media type of the RFC 7807 problem details
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem RFC 7807 problem details, responded instead of the wrapped error
+// when the client accepts `application/problem+json`
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail"`
+	Instance  string            `json:"instance"`
+	Code      string            `json:"code"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    []errs.FieldError `json:"errors,omitempty"`
+}
+
+// Error return the wrapped error response, the code of the error is derived from the status
+func Error(code int, message string, c echo.Context) error {
+	return Fail(code, StatusCode(code), message, nil, c)
+}
+
+// Fail return the error response with its machine readable code and the failed fields,
+// as problem details when the client accepts them or wrapped otherwise
+func Fail(status int, code, message string, fields []errs.FieldError, c echo.Context) error {
+	message = i18n.T(c, message)
+	if AcceptsProblem(c) {
+		c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+		return c.JSON(status, &Problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Detail:    message,
+			Instance:  c.Request().URL.Path,
+			Code:      code,
+			RequestID: RequestID(c),
+			Errors:    fields,
+		})
+	}
+	props := &Response{
+		Code:    status,
 		Data:    nil,
 		Message: message,
 		Success: false,
+		Errors:  fields,
 	}
-	return c.JSON(code, props)
+	return c.JSON(status, props)
+}
+
+// AcceptsProblem check if the client asked for the problem details
+func AcceptsProblem(c echo.Context) bool {
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), MIMEApplicationProblemJSON)
+}
+
+// RequestID the id of the request, set by the request id middleware
+func RequestID(c echo.Context) string {
+	if id := c.Response().Header().Get(echo.HeaderXRequestID); id != "" {
+		return id
+	}
+	return c.Request().Header.Get(echo.HeaderXRequestID)
+}
+
+// StatusCode machine readable code of the status, e.g. `not_found`
+func StatusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
 }
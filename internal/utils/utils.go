@@ -4,6 +4,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 )
 
 // MustGet will return the env or panic if it is not present
@@ -15,6 +16,15 @@ func MustGet(k string) string {
 	return v
 }
 
+// GetOrDefault will return the env or the fallback if it is not present
+func GetOrDefault(k, fallback string) string {
+	v := os.Getenv(k)
+	if v == "" {
+		return fallback
+	}
+	return v
+}
+
 // MustGetBool will return the env as boolean or panic if it is not present
 func MustGetBool(k string) bool {
 	v := os.Getenv(k)
@@ -36,3 +46,29 @@ func IsProduction() bool {
 	}
 	return false
 }
+
+// GetDuration will return the env parsed as time.Duration or the fallback if it is not present
+func GetDuration(k string, fallback time.Duration) time.Duration {
+	v := os.Getenv(k)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Panicln("ENV err: [" + k + "]\n" + err.Error())
+	}
+	return d
+}
+
+// GetInt64 will return the env parsed as int64 or the fallback if it is not present
+func GetInt64(k string, fallback int64) int64 {
+	v := os.Getenv(k)
+	if v == "" {
+		return fallback
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		log.Panicln("ENV err: [" + k + "]\n" + err.Error())
+	}
+	return i
+}
@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"log"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	_ "github.com/masihur1989/expense-tracker-api/docs" // you need to update github.com/rizalgowandy/go-swag-sample with your own project path
+	"github.com/masihur1989/expense-tracker-api/internal/auth"
 	db "github.com/masihur1989/expense-tracker-api/internal/db"
 	"github.com/masihur1989/expense-tracker-api/internal/handler"
+	"github.com/masihur1989/expense-tracker-api/internal/i18n"
 	"github.com/masihur1989/expense-tracker-api/internal/models"
+	"github.com/masihur1989/expense-tracker-api/internal/notify"
+	"github.com/masihur1989/expense-tracker-api/internal/storage"
+	"github.com/masihur1989/expense-tracker-api/internal/utils"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"gopkg.in/go-playground/validator.v9"
 	en_translations "gopkg.in/go-playground/validator.v9/translations/en"
@@ -41,51 +50,139 @@ func main() {
 	e.GET("/", handler.Ping)
 	e.GET("/docs/*", echoSwagger.WrapHandler)
 
-	// initialize db client
-	client, err := db.GetClient()
-	if err != nil {
-		log.Panicf("DB CONNECTION ERROR: %f", err)
-	}
-	// models
-	userModel := models.NewUserModelImpl(client)
-	categoryModel := models.NewCategoryModel(client)
-	expenseModel := models.NewExpenseModel(client)
-	projectModel := models.NewProjectModel(client)
-	// route versioning /api/v1
-	g := e.Group("/api/v1")
+	// models of the DB_BACKEND storage
+	SetupTimeouts()
+	m := SetupModels()
+	// pending migrations are applied on startup, `go run server.go migrate` only applies them
+	RunMigrations()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		return
+	}
+	// exchange rates import run with `go run server.go rates <file.csv|file.json>`
+	if len(os.Args) > 2 && os.Args[1] == "rates" {
+		ImportExchangeRates(e, m.ExchangeRates, os.Args[2])
+		return
+	}
+	// the snapshots of the categories & users embedded in the expenses are refreshed in the background,
+	// and reconciled every SNAPSHOT_RECONCILE_INTERVAL for the refreshes lost by a restart
+	snapshots := models.NewSnapshotJob(m)
+	m = snapshots.Wrap(m)
+	go snapshots.Run(context.Background(), utils.GetDuration("SNAPSHOT_RECONCILE_INTERVAL", time.Hour))
+	// the created expenses are flagged when they push a project budget over BUDGET_ALERT_THRESHOLD percent
+	budgets := models.NewBudgetTracker(m, utils.GetInt64("BUDGET_ALERT_THRESHOLD", 90))
+	// the budget managers & the approvers are notified of the crossed budget levels and the expenses pending for too long
+	mailer := SetupMailer()
+	notifier := SetupNotifier(m, mailer)
+	m = notifier.Wrap(m)
+	go notifier.Run(context.Background(), utils.GetDuration("NOTIFY_SCAN_INTERVAL", 15*time.Minute))
+	// the expenses of the recurring expenses are created when due, the ones missed while the server was down are caught up
+	recurring := models.NewRecurringJob(m)
+	m = recurring.Wrap(m)
+	go recurring.Run(context.Background(), utils.GetDuration("RECURRING_INTERVAL", time.Hour))
+	// auth tokens
+	tokens := auth.NewTokenManager(
+		utils.MustGet("JWT_SECRET"),
+		utils.GetDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		utils.GetDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+	)
+	// public auth routes
+	// the password reset tokens are emailed, none is issued without a mail server
+	var resetMailer handler.Mailer
+	if mailer != nil {
+		resetMailer = mailer
+	}
+	authHandler := handler.NewAuthHandler(m.Users, m.PasswordResets, tokens, utils.GetDuration("PASSWORD_RESET_TTL", time.Hour), resetMailer)
+	a := e.Group("/api/v1/auth")
+	a.POST("/login", authHandler.Login)
+	a.POST("/refresh", authHandler.Refresh)
+	a.POST("/password/forgot", authHandler.ForgotPassword)
+	a.POST("/password/reset", authHandler.ResetPassword)
+	// route versioning /api/v1, every route requires a valid access token
+	g := e.Group("/api/v1", customMiddleware.JWT(tokens, m.Users))
 	// handlers
-	userHandler := handler.NewUserHandler(userModel)
-	categoryHandler := handler.NewCategoryHandler(categoryModel)
-	expensedeHandler := handler.NewExpenseHandler(expenseModel, userModel, categoryModel)
-	projectHandler := handler.NewProjectHandler(projectModel)
+	userHandler := handler.NewUserHandler(m.Users, m.PasswordResets)
+	categoryHandler := handler.NewCategoryHandler(m.Categories, m.Expenses, m.Budgets, m.Recurring)
+	expensedeHandler := handler.NewExpenseHandler(m.Expenses, m.Users, m.Categories, m.Projects, budgets)
+	projectHandler := handler.NewProjectHandler(m.Projects, m.Users, m.ExchangeRates)
+	exchangeRateHandler := handler.NewExchangeRateHandler(m.ExchangeRates)
+	budgetHandler := handler.NewBudgetHandler(m.Budgets, m.Projects, m.Categories, budgets)
+	notificationHandler := handler.NewNotificationHandler(m.Notifications)
+	recurringHandler := handler.NewRecurringHandler(m.Recurring, m.Categories, m.Projects)
+	balanceHandler := handler.NewBalanceHandler(m)
+	attachmentHandler := handler.NewAttachmentHandler(m.Expenses, m.Projects, SetupStorage(), utils.GetInt64("MAX_ATTACHMENT_SIZE", 10<<20))
 	// users routes
-	g.GET("/users/", userHandler.GetUsers)
-	g.GET("/users/:id", userHandler.GetUser)
-	g.POST("/users", userHandler.CreateUser)
-	g.PUT("/users/:id", userHandler.UpdateUser)
-	g.DELETE("/users/:id", userHandler.DeleteUser)
+	g.GET("/users/", userHandler.GetUsers, customMiddleware.Authorize(auth.PermUsersRead))
+	g.GET("/users/:id", userHandler.GetUser, customMiddleware.Authorize(auth.PermUsersRead))
+	g.POST("/users", userHandler.CreateUser, customMiddleware.Authorize(auth.PermUsersWrite))
+	g.PUT("/users/:id", userHandler.UpdateUser) // owner or users:write, checked by the handler
+	g.PUT("/users/:id/password", userHandler.ChangePassword)
+	g.GET("/users/:id/projects", projectHandler.GetUserProjects) // owner or users:read, checked by the handler
+	g.DELETE("/users/:id", userHandler.DeleteUser, customMiddleware.Authorize(auth.PermUsersDelete))
 	// categories routes
-	g.GET("/categories", categoryHandler.GetCategories)
-	g.POST("/categories", categoryHandler.CreateCategory)
-	g.PUT("/categories/:id", categoryHandler.UpdateCategory)
-	g.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+	g.GET("/categories", categoryHandler.GetCategories, customMiddleware.Authorize(auth.PermCategoriesRead))
+	g.POST("/categories", categoryHandler.CreateCategory, customMiddleware.Authorize(auth.PermCategoriesWrite))
+	g.PUT("/categories/:id", categoryHandler.UpdateCategory, customMiddleware.Authorize(auth.PermCategoriesWrite))
+	g.DELETE("/categories/:id", categoryHandler.DeleteCategory, customMiddleware.Authorize(auth.PermCategoriesWrite))
+	g.POST("/categories/:id/move", categoryHandler.MoveCategory, customMiddleware.Authorize(auth.PermCategoriesWrite))
+	g.POST("/categories/:id/merge", categoryHandler.MergeCategory, customMiddleware.Authorize(auth.PermCategoriesWrite))
 	// expense routes
-	g.GET("/expenses", expensedeHandler.GetExpenses)
-	g.GET("/expenses/:id", expensedeHandler.GetExpense)
-	g.POST("/expenses", expensedeHandler.CreateExpense)
-	g.PUT("/expenses/:id", expensedeHandler.UpdateExpense)
-	g.DELETE("/expenses/:id", expensedeHandler.DeleteExpense)
+	g.GET("/expenses", expensedeHandler.GetExpenses, customMiddleware.Authorize(auth.PermExpensesRead))
+	g.GET("/expenses/category-totals", expensedeHandler.GetCategoryTotals, customMiddleware.Authorize(auth.PermExpensesRead))
+	g.GET("/expenses/:id", expensedeHandler.GetExpense, customMiddleware.Authorize(auth.PermExpensesRead))
+	g.POST("/expenses", expensedeHandler.CreateExpense, customMiddleware.Authorize(auth.PermExpensesWrite))
+	g.PUT("/expenses/:id", expensedeHandler.UpdateExpense, customMiddleware.Authorize(auth.PermExpensesWrite))
+	g.DELETE("/expenses/:id", expensedeHandler.DeleteExpense, customMiddleware.Authorize(auth.PermExpensesWrite))
+	// expense approval workflow, approvals are checked by the handler against the project membership
+	g.POST("/expenses/:id/submit", expensedeHandler.SubmitExpense, customMiddleware.Authorize(auth.PermExpensesWrite))
+	g.POST("/expenses/:id/approve", expensedeHandler.ApproveExpense)
+	g.POST("/expenses/:id/reject", expensedeHandler.RejectExpense)
+	g.POST("/expenses/:id/reimburse", expensedeHandler.ReimburseExpense)
+
+	g.GET("/expenses/:id/attachments", attachmentHandler.GetAttachments, customMiddleware.Authorize(auth.PermExpensesRead))
+	g.GET("/expenses/:id/attachments/:attachmentId", attachmentHandler.DownloadAttachment, customMiddleware.Authorize(auth.PermExpensesRead))
+	g.POST("/expenses/:id/attachments", attachmentHandler.CreateAttachment, customMiddleware.Authorize(auth.PermExpensesWrite))
+	g.DELETE("/expenses/:id/attachments/:attachmentId", attachmentHandler.DeleteAttachment, customMiddleware.Authorize(auth.PermExpensesWrite))
 	// project routes
-	g.GET("/projects", projectHandler.GetProjects)
-	g.GET("/projects/:id/details", projectHandler.GetProjectExpenses)
-	g.GET("/projects/:id", projectHandler.GetProject)
-	g.POST("/projects", projectHandler.CreateProject)
-	g.DELETE("/projects/:id", projectHandler.DeleteProject)
+	g.GET("/projects", projectHandler.GetProjects, customMiddleware.Authorize(auth.PermProjectsRead))
+	g.GET("/projects/:id/details", projectHandler.GetProjectExpenses, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	g.GET("/projects/:id", projectHandler.GetProject, customMiddleware.Authorize(auth.PermProjectsRead))
+	g.POST("/projects", projectHandler.CreateProject, customMiddleware.Authorize(auth.PermProjectsWrite))
+	g.DELETE("/projects/:id", projectHandler.DeleteProject, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectManage))
+	// exchange rates routes
+	g.GET("/exchange-rates", exchangeRateHandler.GetExchangeRates, customMiddleware.Authorize(auth.PermExpensesRead))
+	g.POST("/exchange-rates", exchangeRateHandler.CreateExchangeRates, customMiddleware.Authorize(auth.PermRatesWrite))
 
-	g.GET("/projects/:id/users", projectHandler.GetProjectUsers)
-	g.GET("/projects/:id/users/:userId", projectHandler.GetProjectUser)
-	g.POST("/projects/:id/users", projectHandler.CreateProjectUser)
-	g.DELETE("/projects/:id/users/:userId", projectHandler.DeleteProjectUser)
+	// project members routes, access is driven by the project membership
+	g.GET("/projects/:id/users", projectHandler.GetProjectUsers, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	g.GET("/projects/:id/users/:userId", projectHandler.GetProjectUser, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	g.POST("/projects/:id/users", projectHandler.CreateProjectUser, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectMembers))
+	g.DELETE("/projects/:id/users/:userId", projectHandler.DeleteProjectUser, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectMembers))
+	// project budgets routes
+	g.GET("/projects/:id/budgets", budgetHandler.GetBudgets, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	g.GET("/projects/:id/budgets/report", budgetHandler.GetBudgetReport, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	g.POST("/projects/:id/budgets", budgetHandler.CreateBudget, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectBudgets))
+	g.PUT("/projects/:id/budgets/:budgetId", budgetHandler.UpdateBudget, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectBudgets))
+	g.DELETE("/projects/:id/budgets/:budgetId", budgetHandler.DeleteBudget, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectBudgets))
+	// project balances & settlements routes, a settlement is changed by its payer, its receiver or an approver, checked by the handler
+	g.GET("/projects/:id/balances", balanceHandler.GetBalances, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	g.GET("/projects/:id/settlements", balanceHandler.GetSettlements, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	g.POST("/projects/:id/settlements", balanceHandler.CreateSettlement, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	g.DELETE("/projects/:id/settlements/:settlementId", balanceHandler.DeleteSettlement, customMiddleware.ProjectAccess(m.Projects, auth.PermProjectView))
+	// notification routes, the inbox & the preferences of the authenticated user
+	g.GET("/notifications", notificationHandler.GetNotifications)
+	g.POST("/notifications/read", notificationHandler.ReadAllNotifications)
+	g.POST("/notifications/:id/read", notificationHandler.ReadNotification)
+	g.POST("/notifications/:id/unread", notificationHandler.UnreadNotification)
+	g.GET("/notifications/preferences", notificationHandler.GetPreferences)
+	g.PUT("/notifications/preferences", notificationHandler.UpdatePreferences)
+	// recurring expenses routes, the author or expenses:manage, checked by the handler
+	g.GET("/recurring-expenses", recurringHandler.GetRecurringExpenses, customMiddleware.Authorize(auth.PermExpensesRead))
+	g.GET("/recurring-expenses/:id", recurringHandler.GetRecurring, customMiddleware.Authorize(auth.PermExpensesRead))
+	g.POST("/recurring-expenses", recurringHandler.CreateRecurring, customMiddleware.Authorize(auth.PermExpensesWrite))
+	g.POST("/recurring-expenses/:id/pause", recurringHandler.PauseRecurring, customMiddleware.Authorize(auth.PermExpensesWrite))
+	g.POST("/recurring-expenses/:id/resume", recurringHandler.ResumeRecurring, customMiddleware.Authorize(auth.PermExpensesWrite))
+	g.POST("/recurring-expenses/:id/skip", recurringHandler.SkipRecurring, customMiddleware.Authorize(auth.PermExpensesWrite))
+	g.POST("/recurring-expenses/:id/end", recurringHandler.EndRecurring, customMiddleware.Authorize(auth.PermExpensesWrite))
 
 	e.Logger.Fatal(e.Start(":1323"))
 }
@@ -97,35 +194,190 @@ func main() {
 // SetupEcho set echo server
 func SetupEcho() *echo.Echo {
 	e := echo.New()
+	// the typed errors returned by the handlers are mapped to their status code
+	e.HTTPErrorHandler = handler.HTTPErrorHandler
 	// Middleware
+	e.Use(middleware.RequestID())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 	e.Use(middleware.CORS())
 	// custom middlewares
 	e.Use(customMiddleware.RequestHeaders())
-	// setup validator
-	trans := SetupTranslator()
-	validate := SetupCustomValidator(trans)
+	// setup the locale of the messages and the validator
+	uni := SetupTranslator()
+	e.Use(customMiddleware.Locale(uni))
+	validate := SetupCustomValidator(uni)
+	trans, _ := uni.GetTranslator(i18n.DefaultLocale)
 	e.Validator = &models.Validator{Validator: validate, Trans: trans}
 	return e
 }
 
-// SetupTranslator set the translator
-func SetupTranslator() ut.Translator {
-	translator := en.New()
-	uni := ut.New(translator, translator)
-	trans, found := uni.GetTranslator("en")
-	if !found {
-		log.Fatalln("translator not found")
+// SetupTranslator set the translators of the supported locales
+func SetupTranslator() *ut.UniversalTranslator {
+	uni := i18n.New()
+	for _, locale := range i18n.Locales {
+		if _, found := uni.GetTranslator(locale); !found {
+			log.Fatalf("translator not found: %s", locale)
+		}
 	}
-	return trans
+	return uni
 }
 
-// SetupCustomValidator set the custom validator
-func SetupCustomValidator(trans ut.Translator) *validator.Validate {
+// SetupCustomValidator set the custom validator with the validation messages of every locale
+func SetupCustomValidator(uni *ut.UniversalTranslator) *validator.Validate {
 	v := validator.New()
+	trans, _ := uni.GetTranslator(i18n.DefaultLocale)
 	if err := en_translations.RegisterDefaultTranslations(v, trans); err != nil {
 		log.Fatal(err)
 	}
+	if err := models.RegisterCustomValidations(v, trans); err != nil {
+		log.Fatal(err)
+	}
+	for locale, messages := range i18n.ValidationMessages {
+		trans, _ := uni.GetTranslator(locale)
+		if err := models.RegisterTranslations(v, trans, messages); err != nil {
+			log.Fatal(err)
+		}
+	}
 	return v
 }
+
+// SetupModels set the models of the DB_BACKEND storage, mongo, postgres or memory.
+// The memory storage needs no database server and is lost on exit.
+func SetupModels() models.Models {
+	switch backend := utils.GetOrDefault("DB_BACKEND", "mongo"); backend {
+	case "mongo":
+		return models.NewMongoModels(MongoClient())
+	case "postgres":
+		return models.NewPostgresModels(PostgresDB())
+	case "memory":
+		return models.NewMemoryModels(models.NewMemoryStore())
+	default:
+		log.Fatalf("unknown db backend: %s\n", backend)
+	}
+	return models.Models{}
+}
+
+// SetupMailer the emails sent through the mail server of SMTP_HOST, nil when it is not set
+func SetupMailer() *notify.SMTPChannel {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil
+	}
+	return notify.NewSMTPChannel(notify.SMTPConfig{
+		Host:     host,
+		Port:     int(utils.GetInt64("SMTP_PORT", 587)),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     utils.MustGet("SMTP_FROM"),
+	})
+}
+
+// SetupNotifier set the notifier of the models with the inbox, the webhook and, when there is a mailer, the email channels.
+// A submitted expense is pending for too long after PENDING_ALERT_AFTER.
+func SetupNotifier(m models.Models, mailer *notify.SMTPChannel) *notify.Notifier {
+	channels := []notify.Channel{notify.NewInboxChannel(m.Notifications)}
+	if mailer != nil {
+		channels = append(channels, mailer)
+	}
+	channels = append(channels, notify.NewWebhookChannel(os.Getenv("NOTIFY_WEBHOOK_SECRET"), utils.GetDuration("NOTIFY_WEBHOOK_TIMEOUT", 10*time.Second)))
+	return notify.NewNotifier(m, SetupTranslator(), utils.GetDuration("PENDING_ALERT_AFTER", 72*time.Hour), channels...)
+}
+
+// SetupTimeouts set the deadlines of the storage operations, a slow query
+// is aborted and answered with a 504 instead of holding the request
+func SetupTimeouts() {
+	models.OperationTimeouts = models.Timeouts{
+		Read:   utils.GetDuration("DB_READ_TIMEOUT", models.OperationTimeouts.Read),
+		Write:  utils.GetDuration("DB_WRITE_TIMEOUT", models.OperationTimeouts.Write),
+		Report: utils.GetDuration("DB_REPORT_TIMEOUT", models.OperationTimeouts.Report),
+	}
+}
+
+// MongoClient get the mongo client of MONGO_DB_INSTANCE
+func MongoClient() db.MongoDBClient {
+	client, err := db.GetClient()
+	if err != nil {
+		log.Panicf("DB CONNECTION ERROR: %v", err)
+	}
+	return client
+}
+
+// PostgresDB get the postgres connection pool of POSTGRES_URL
+func PostgresDB() *sql.DB {
+	pg, err := db.GetPostgres()
+	if err != nil {
+		log.Panicf("DB CONNECTION ERROR: %v", err)
+	}
+	return pg
+}
+
+// SetupStorage set the attachment storage from STORAGE_BACKEND, local or gridfs
+func SetupStorage() storage.Storage {
+	switch backend := utils.GetOrDefault("STORAGE_BACKEND", "local"); backend {
+	case "local":
+		s, err := storage.NewLocalStorage(utils.GetOrDefault("STORAGE_PATH", "./uploads"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return s
+	case "gridfs":
+		// gridfs keeps the receipts in MongoDB, whatever the DB_BACKEND
+		s, err := storage.NewGridFSStorage(MongoClient(), "attachments")
+		if err != nil {
+			log.Fatal(err)
+		}
+		return s
+	default:
+		log.Fatalf("unknown storage backend: %s\n", backend)
+	}
+	return nil
+}
+
+// RunMigrations apply the pending migrations of the DB_BACKEND storage
+func RunMigrations() {
+	var versions []int
+	var err error
+	switch utils.GetOrDefault("DB_BACKEND", "mongo") {
+	case "mongo":
+		currency := utils.GetOrDefault("DEFAULT_CURRENCY", models.DefaultCurrency)
+		versions, err = models.MigrateMongo(MongoClient(), models.MongoMigrations(currency))
+	case "postgres":
+		versions, err = models.MigratePostgres(PostgresDB())
+	}
+	if err != nil {
+		log.Fatalf("MIGRATION ERROR: %v", err)
+	}
+	if len(versions) > 0 {
+		log.Printf("migrations applied: %v\n", versions)
+	}
+}
+
+// ImportExchangeRates load the exchange rates of a CSV or JSON file
+func ImportExchangeRates(e *echo.Echo, rm models.ExchangeRateModeler, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("IMPORT ERROR: %v", err)
+	}
+	defer f.Close()
+
+	var inputs []models.ExchangeRateInput
+	if strings.HasSuffix(strings.ToLower(path), ".csv") {
+		inputs, err = models.ParseExchangeRatesCSV(f)
+	} else {
+		inputs, err = models.ParseExchangeRatesJSON(f)
+	}
+	if err != nil {
+		log.Fatalf("IMPORT ERROR: %v", err)
+	}
+
+	rates, err := handler.ValidateExchangeRates(e.Validator, inputs)
+	if err != nil {
+		log.Fatalf("IMPORT ERROR: %v", err)
+	}
+	count, err := rm.Upsert(context.Background(), rates)
+	if err != nil {
+		log.Fatalf("IMPORT ERROR: %v", err)
+	}
+	log.Printf("exchange rates loaded: %d\n", count)
+}